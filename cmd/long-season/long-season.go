@@ -2,21 +2,43 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/cristalhq/jwt/v3"
 	"github.com/go-chi/cors"
 
+	"github.com/hakierspejs/long-season/pkg/services/bot"
 	"github.com/hakierspejs/long-season/pkg/services/config"
+	"github.com/hakierspejs/long-season/pkg/services/devices"
+	"github.com/hakierspejs/long-season/pkg/services/email"
 	"github.com/hakierspejs/long-season/pkg/services/handlers"
 	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/history"
 	"github.com/hakierspejs/long-season/pkg/services/jojo"
+	"github.com/hakierspejs/long-season/pkg/services/keyring"
+	"github.com/hakierspejs/long-season/pkg/services/kiosk"
+	"github.com/hakierspejs/long-season/pkg/services/mailer"
+	"github.com/hakierspejs/long-season/pkg/services/mqtt"
+	"github.com/hakierspejs/long-season/pkg/services/notify"
+	"github.com/hakierspejs/long-season/pkg/services/oui"
+	"github.com/hakierspejs/long-season/pkg/services/registration"
+	"github.com/hakierspejs/long-season/pkg/services/reset"
 	"github.com/hakierspejs/long-season/pkg/services/router"
+	"github.com/hakierspejs/long-season/pkg/services/scanners"
 	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/services/space"
+	"github.com/hakierspejs/long-season/pkg/services/stats"
 	"github.com/hakierspejs/long-season/pkg/services/status"
+	"github.com/hakierspejs/long-season/pkg/services/tokens"
+	"github.com/hakierspejs/long-season/pkg/services/webpush"
 	"github.com/hakierspejs/long-season/pkg/storage"
 	"github.com/hakierspejs/long-season/pkg/storage/abstract"
 	"github.com/hakierspejs/long-season/pkg/storage/temp"
@@ -34,12 +56,203 @@ func main() {
 
 	onlineUsersStorage := temp.NewOnlineUsers()
 	statusTx := temp.NewStatusTx()
+	checkIns := temp.NewCheckIns()
+
+	if !config.OccupiedState.Valid() {
+		log.Fatalf("invalid state of occupied hackerspace: %s", config.OccupiedState)
+	}
+	spaceTracker := &space.Tracker{
+		Users:       factoryStorage.Users(),
+		Counters:    statusTx,
+		Occupied:    config.OccupiedState,
+		OverrideTTL: config.OverrideTTL,
+	}
+
+	if !config.RegistrationMode.Valid() {
+		log.Fatalf("invalid registration mode: %s", config.RegistrationMode)
+	}
+	reg := &registration.Registration{
+		Settings: factoryStorage.Settings(),
+		Invites:  factoryStorage.Invites(),
+		Users:    factoryStorage.Users(),
+		Default:  config.RegistrationMode,
+	}
+
+	resetter := &reset.Resetter{
+		Tokens:    factoryStorage.ResetTokens(),
+		Users:     factoryStorage.Users(),
+		Audit:     factoryStorage.Audit(),
+		APITokens: factoryStorage.APITokens(),
+		TTL:       config.ResetTTL,
+	}
+
+	var mail mailer.Mailer
+	switch {
+	case config.SMTPAddr != "":
+		mail = &mailer.SMTP{
+			Addr:     config.SMTPAddr,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     config.SMTPFrom,
+		}
+	case config.MailFile == "-":
+		mail = &mailer.Writer{W: os.Stderr, From: config.SMTPFrom}
+	case config.MailFile != "":
+		mailFile, err := os.OpenFile(config.MailFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		defer mailFile.Close()
+		mail = &mailer.Writer{W: mailFile, From: config.SMTPFrom}
+	}
+
+	publicURL := config.URL
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("http://%s:%s", config.Host, config.Port)
+	}
+	emailKey, err := email.LoadKey(context.Background(), factoryStorage.Keys())
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	devicesKey, err := devices.LoadKey(context.Background(), factoryStorage.Keys())
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	addresses := &email.Addresses{
+		Users:    factoryStorage.Users(),
+		Resetter: resetter,
+		Key:      emailKey,
+		Mailer:   mail,
+		URL:      strings.TrimSuffix(publicURL, "/"),
+	}
+
+	observers := []status.Observer{
+		// Overrides of hackerspace state are reset before
+		// the state is published by other observers.
+		spaceTracker,
+
+		// Visits are not split by single missed update.
+		&stats.Recorder{
+			Visits: factoryStorage.Visits(),
+			Gap:    2 * config.RefreshTime,
+		},
+	}
+
+	if config.MQTTBroker != "" {
+		publisher := &mqtt.Publisher{
+			Client: mqtt.Connect(mqtt.Options{
+				Broker:   config.MQTTBroker,
+				ClientID: config.MQTTClientID,
+				Username: config.MQTTUsername,
+				Password: config.MQTTPassword,
+				Prefix:   config.MQTTTopic,
+				Timeout:  10 * time.Second,
+			}),
+			Users:  factoryStorage.Users(),
+			Prefix: config.MQTTTopic,
+			NodeID: config.MQTTClientID,
+			Space:  spaceTracker,
+		}
+		if config.MQTTDiscovery {
+			publisher.DiscoveryPrefix = config.MQTTDiscoveryPrefix
+		}
+		observers = append(observers, publisher)
+	}
+
+	userAdapter := storage.UserAdapter{
+		OnlineUsersStorage: onlineUsersStorage,
+	}
+
+	chatBot := &bot.Bot{
+		Users:    factoryStorage.Users(),
+		Adapter:  userAdapter,
+		Counters: statusTx,
+		Space:    spaceTracker,
+		Messages: bot.Messages{
+			Arrival:   config.BotArrival,
+			Departure: config.BotDeparture,
+			Open:      config.BotOpen,
+			Close:     config.BotClose,
+		},
+	}
+	if config.BotMatrixHomeserver != "" && config.BotMatrixRoom != "" {
+		chatBot.Backends = append(chatBot.Backends, &bot.Matrix{
+			Homeserver:  config.BotMatrixHomeserver,
+			AccessToken: config.BotMatrixToken,
+			UserID:      config.BotMatrixUser,
+			RoomID:      config.BotMatrixRoom,
+		})
+	}
+	if config.BotIRCServer != "" && config.BotIRCChannel != "" {
+		chatBot.Backends = append(chatBot.Backends, &bot.IRC{
+			Server:   config.BotIRCServer,
+			TLS:      config.BotIRCTLS,
+			Nick:     config.BotIRCNick,
+			Password: config.BotIRCPassword,
+			Channel:  config.BotIRCChannel,
+		})
+	}
+	if len(chatBot.Backends) > 0 {
+		observers = append(observers, chatBot)
+	}
+
+	notifier := &notify.Notifier{
+		Users:         factoryStorage.Users(),
+		Subscriptions: factoryStorage.Subscriptions(),
+		Senders:       map[string]notify.Sender{},
+	}
+	if mail != nil {
+		notifier.Senders[notify.EmailChannel] = &notify.Email{Mailer: mail}
+	}
+	if len(config.NtfyURLs) > 0 {
+		notifier.Senders["ntfy"] = &notify.Ntfy{
+			Client: &http.Client{
+				Timeout: 10 * time.Second,
+				// Redirects could lead outside of allowed servers.
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			},
+			Allowed: config.NtfyURLs,
+		}
+	}
+
+	var vapid *webpush.VAPID
+	if config.WebPush {
+		vapid, err = webpush.LoadVAPID(context.Background(), factoryStorage.Keys())
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		notifier.Senders["webpush"] = &notify.WebPush{
+			Client: &webpush.Client{
+				VAPID:   vapid,
+				Subject: config.WebPushSubject,
+				TTL:     time.Hour,
+				HTTPClient: &http.Client{
+					Timeout: 10 * time.Second,
+					CheckRedirect: func(*http.Request, []*http.Request) error {
+						return http.ErrUseLastResponse
+					},
+				},
+				Services: config.WebPushServices,
+			},
+		}
+	}
+	observers = append(observers, notifier)
 
 	ctx := context.Background()
 	macChannel, macDeamon := status.NewDaemon(ctx, status.DaemonArgs{
-		OnlineUsers:   onlineUsersStorage,
-		Devices:       factoryStorage.Devices(),
-		Counters:      statusTx,
+		OnlineUsers: onlineUsersStorage,
+		Devices:     factoryStorage.Devices(),
+		Counters:    statusTx,
+		Ignored:     factoryStorage.Ignored(),
+		Classify: func(address net.HardwareAddr) string {
+			return string(oui.Classify(address))
+		},
+		CheckIns:      checkIns,
+		LookupKey:     devicesKey,
+		Observers:     observers,
 		RefreshTime:   config.RefreshTime,
 		SingleAddrTTL: config.SingleAddrTTL,
 	})
@@ -68,38 +281,145 @@ func main() {
 		opener = web.Open
 	}
 
+	// Session tokens are signed with keys read from files,
+	// keys generated and stored in the database or secrets.
+	var (
+		sessionKeys []*keyring.Key
+		keyStore    *keyring.Store
+	)
+	switch {
+	case len(config.JWTKeyFiles) > 0:
+		sessionKeys, err = keyring.ReadFiles(config.JWTKeyFiles...)
+	case config.JWTAlgorithm == string(jwt.HS256):
+		sessionKeys, err = keyring.Secrets(append(
+			[]string{config.JWTSecret}, config.JWTPreviousSecrets...,
+		)...)
+	default:
+		keyStore = &keyring.Store{
+			Keys:      factoryStorage.Keys(),
+			Algorithm: jwt.Algorithm(config.JWTAlgorithm),
+			Rotation:  config.JWTRotation,
+			Grace:     config.JWTGrace,
+		}
+		sessionKeys, err = keyStore.Refresh(ctx, time.Now())
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	sessionKeyring := keyring.New(sessionKeys...)
+
 	jwtSession := &jojo.JWT{
-		Secret:    []byte(config.JWTSecret),
-		Algorithm: jwt.HS256,
-		AppName:   config.AppName,
-		CookieKey: "jwt-token",
+		Keys:           sessionKeyring,
+		AppName:        config.AppName,
+		CookieKey:      "jwt-token",
+		AccessLifetime: config.AccessTTL,
 	}
 
-	userAdapter := storage.UserAdapter{
-		OnlineUsersStorage: onlineUsersStorage,
+	// Check-in codes are signed with their own secret, so they
+	// can never be used as session tokens.
+	kioskSecret := []byte(config.KioskSecret)
+	if len(kioskSecret) == 0 {
+		kioskSecret, err = kiosk.LoadSecret(ctx, factoryStorage.Keys())
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+	kioskKey, err := keyring.NewSecretKey(kioskSecret)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	kioskCodes := kiosk.NewCodes(&jojo.JWT{
+		Keys:    keyring.New(kioskKey),
+		AppName: config.AppName,
+	}, time.Minute)
+
+	// Records of sessions expire together with their
+	// JWT tokens and both are extended on every save.
+	lifetimes := session.Lifetimes{
+		Session:  config.SessionTTL,
+		Remember: config.RememberTTL,
+	}
+	sessionStore := &session.Store{Sessions: factoryStorage.Sessions()}
+	sessionSaver := session.Expiring(sessionStore.Saver(jwtSession), lifetimes)
+	revocable := func(renewer session.Renewer) session.Renewer {
+		return session.Revocable(sessionStore.Renewer(renewer), factoryStorage.Users())
 	}
 
 	r := router.NewRouter(*config, router.Args{
-		Opener:      opener,
-		Users:       factoryStorage.Users(),
-		Devices:     factoryStorage.Devices(),
-		StatusTx:    statusTx,
-		TwoFactor:   factoryStorage.TwoFactor(),
-		OnlineUsers: onlineUsersStorage,
-		UserAdapter: userAdapter,
-		MacsChan:    macChannel,
-		PublicCors:  publicCors,
-		Adapter:     happier.NewAdapter(),
-		SessionRenewer: session.RenewerComposite(
+		Opener:        opener,
+		Users:         factoryStorage.Users(),
+		Devices:       factoryStorage.Devices(),
+		DevicesKey:    devicesKey,
+		Cards:         factoryStorage.Cards(),
+		Subscriptions: factoryStorage.Subscriptions(),
+		Notifier:      notifier,
+		VAPID:         vapid,
+		CheckIns:      checkIns,
+		KioskCodes:    kioskCodes,
+		Visits:        factoryStorage.Visits(),
+		History:       factoryStorage.History(),
+		StatusTx:      statusTx,
+		Space:         spaceTracker,
+		Registration:  reg,
+		Invites:       factoryStorage.Invites(),
+		Resetter:      resetter,
+		Emails:        addresses,
+		Audit:         factoryStorage.Audit(),
+		TwoFactor:     factoryStorage.TwoFactor(),
+		OnlineUsers:   onlineUsersStorage,
+		UserAdapter:   userAdapter,
+		Scanners:      factoryStorage.Scanners(),
+		Ignored:       factoryStorage.Ignored(),
+		APITokens:     factoryStorage.APITokens(),
+		Keys:          factoryStorage.Keys(),
+		ScanQueue:     scanners.NewQueue(macChannel),
+		PublicCors:    publicCors,
+		Adapter:       happier.NewAdapter(),
+		SessionRenewer: revocable(session.RenewerComposite(
 			jwtSession.RenewFromHeaderToken("Authorization", "Bearer"),
 			jwtSession.RenewFromCookies(),
-		),
-		SessionSaver:  jwtSession,
-		SessionKiller: jwtSession,
+			&tokens.Renewer{
+				Tokens: factoryStorage.APITokens(),
+				Users:  factoryStorage.Users(),
+			},
+		)),
+		SessionSaver:     sessionSaver,
+		SessionKiller:    jwtSession,
+		SessionStore:     sessionStore,
+		SessionKeys:      sessionKeyring,
+		SessionRefresher: revocable(jwtSession.RenewFromRefreshToken("Authorization", "Bearer")),
+		SessionIssuer:    jwtSession,
+		SessionLifetimes: lifetimes,
+		SessionSliding:   session.Sliding(revocable(jwtSession.RenewFromCookies()), sessionSaver, lifetimes),
+	})
+
+	historyDaemon := history.NewDaemon(ctx, history.DaemonArgs{
+		Counters:  statusTx,
+		History:   factoryStorage.History(),
+		Interval:  config.SnapshotInterval,
+		Retention: config.HistoryRetention,
 	})
 
 	// start daemon for updating mac addresses
 	go macDeamon()
 
+	// start daemon for taking snapshots of online users counter
+	go historyDaemon()
+
+	// start daemon for rotating generated signing keys
+	if keyStore != nil {
+		keysDaemon := keyring.NewDaemon(ctx, keyring.DaemonArgs{
+			Store:    keyStore,
+			Keyring:  sessionKeyring,
+			Interval: time.Hour,
+		})
+		go keysDaemon()
+	}
+
+	// start chat bot answering commands
+	if len(chatBot.Backends) > 0 {
+		go chatBot.Run(ctx)
+	}
+
 	http.ListenAndServe(config.Address(), r)
 }
@@ -3,22 +3,30 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/cristalhq/jwt/v3"
 	"github.com/urfave/cli/v2"
-	bolt "go.etcd.io/bbolt"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/checkin"
 	"github.com/hakierspejs/long-season/pkg/services/exim"
+	"github.com/hakierspejs/long-season/pkg/services/ignored"
+	"github.com/hakierspejs/long-season/pkg/services/keyring"
+	"github.com/hakierspejs/long-season/pkg/services/oui"
+	"github.com/hakierspejs/long-season/pkg/services/reset"
 	"github.com/hakierspejs/long-season/pkg/services/users"
 	"github.com/hakierspejs/long-season/pkg/storage"
 	"github.com/hakierspejs/long-season/pkg/storage/abstract"
-	"github.com/hakierspejs/long-season/pkg/storage/memory"
 )
 
 const (
@@ -44,8 +52,15 @@ func putRequest(url string, headers map[string]string, data io.Reader) (*http.Re
 	return resp, nil
 }
 
+type host struct {
+	MAC      string `json:"mac"`
+	Hostname string `json:"hostname,omitempty"`
+	ClientID string `json:"clientId,omitempty"`
+}
+
 type body struct {
 	Addresses []string `json:"addresses"`
+	Hosts     []host   `json:"hosts,omitempty"`
 }
 
 func usersStorage(ctx *cli.Context) (storage.Users, func(), error) {
@@ -53,21 +68,107 @@ func usersStorage(ctx *cli.Context) (storage.Users, func(), error) {
 		return nil, nil, fmt.Errorf("database flag is not set. see admin command.")
 	}
 
-	boltPath := ctx.String("database")
-	boltDB, err := bolt.Open(boltPath, 0666, nil)
+	factory, closer, err := factoryStorage(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	closer := func() {
-		boltDB.Close()
+
+	return factory.Users(), closer, nil
+}
+
+// readAddresses reads mac addresses from given reader. Every
+// address should be placed in the separate line.
+func readAddresses(r io.Reader) ([]net.HardwareAddr, error) {
+	res := []net.HardwareAddr{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		address, err := net.ParseMAC(line)
+		if err != nil {
+			return nil, fmt.Errorf("net.ParseMAC: %w", err)
+		}
+		res = append(res, address)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Err: %w", err)
 	}
 
-	factoryStorage, err := memory.New(boltDB)
-	if err != nil {
-		return nil, nil, err
+	return res, nil
+}
+
+type collisionDevice struct {
+	ID      string `json:"id"`
+	Tag     string `json:"tag"`
+	Owner   string `json:"owner"`
+	OwnerID string `json:"ownerId"`
+}
+
+func newCollisionDevice(d models.Device) collisionDevice {
+	return collisionDevice{
+		ID:      d.ID,
+		Tag:     d.Tag,
+		Owner:   d.Owner,
+		OwnerID: d.OwnerID,
 	}
+}
 
-	return factoryStorage.Users(), closer, nil
+type collisionReport struct {
+	Lookup  string            `json:"lookup"`
+	Owners  int               `json:"owners"`
+	Devices []collisionDevice `json:"devices"`
+}
+
+func newCollisionReport(c storage.Collision) collisionReport {
+	res := collisionReport{
+		Lookup:  hex.EncodeToString(c.Lookup),
+		Owners:  c.Owners(),
+		Devices: []collisionDevice{},
+	}
+	for _, d := range c.Devices {
+		res.Devices = append(res.Devices, newCollisionDevice(d))
+	}
+	return res
+}
+
+// collisionsReport contains devices sharing hardware address
+// and devices, which can't be compared with other ones, because
+// some of their addresses have no lookup hashes yet.
+type collisionsReport struct {
+	Collisions []collisionReport `json:"collisions"`
+	Unverified []collisionDevice `json:"unverified"`
+}
+
+type vendorReport struct {
+	Address  string `json:"address"`
+	Vendor   string `json:"vendor,omitempty"`
+	Category string `json:"category"`
+}
+
+type cardReport struct {
+	ID      string `json:"id"`
+	Tag     string `json:"tag"`
+	OwnerID string `json:"ownerId"`
+}
+
+type ignoredReport struct {
+	ID     string `json:"id"`
+	Tag    string `json:"tag"`
+	Prefix string `json:"prefix"`
+}
+
+// factoryStorage returns storage factory for database type and
+// path from admin command flags.
+func factoryStorage(ctx *cli.Context) (storage.Factory, func(), error) {
+	factory, closer, err := abstract.Factory(ctx.String("database"), ctx.String("database-type"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("abstract.Factory: %w", err)
+	}
+	return factory, closer, nil
 }
 
 func app() *cli.App {
@@ -90,6 +191,9 @@ func app() *cli.App {
 			{
 				Name:  "macs",
 				Usage: "upload list of mac addresses to given long-season API",
+				Description: "Reads hosts from stdin, one host per line. Every line contains mac address\n" +
+					"optionally followed by hostname and DHCP client identifier separated with\n" +
+					"whitespaces. Use \"-\" in place of missing hostname.",
 				Action: func(ctx *cli.Context) error {
 					api := ctx.String("api")
 					apiKey := ctx.String("api-key")
@@ -99,9 +203,21 @@ func app() *cli.App {
 					scanner := bufio.NewScanner(os.Stdin)
 
 					for scanner.Scan() {
-						address := scanner.Text()
-						if address != "" {
-							b.Addresses = append(b.Addresses, scanner.Text())
+						fields := strings.Fields(scanner.Text())
+						switch len(fields) {
+						case 0:
+							continue
+						case 1:
+							b.Addresses = append(b.Addresses, fields[0])
+						default:
+							h := host{MAC: fields[0]}
+							if fields[1] != "-" {
+								h.Hostname = fields[1]
+							}
+							if len(fields) > 2 {
+								h.ClientID = fields[2]
+							}
+							b.Hosts = append(b.Hosts, h)
 						}
 					}
 					if err := scanner.Err(); err != nil {
@@ -125,6 +241,71 @@ func app() *cli.App {
 					return nil
 				},
 			},
+			{
+				Name:  "card",
+				Usage: "send card identifiers read from stdin to given long-season API",
+				Description: "Reads card identifiers from stdin, one per line, and sends every one of them\n" +
+					"to the API as soon as it is read. It is meant to be used with card readers\n" +
+					"working in keyboard emulation mode. Prints response for every card.",
+				Action: func(ctx *cli.Context) error {
+					api := ctx.String("api")
+					apiKey := ctx.String("api-key")
+
+					headers := map[string]string{
+						"Authorization": "Status " + apiKey,
+					}
+
+					scanner := bufio.NewScanner(os.Stdin)
+					for scanner.Scan() {
+						uid := strings.TrimSpace(scanner.Text())
+						if uid == "" {
+							continue
+						}
+
+						payload, err := json.Marshal(map[string]string{"uid": uid})
+						if err != nil {
+							return err
+						}
+
+						resp, err := putRequest(api+"/api/v1/checkin/card", headers, bytes.NewBuffer(payload))
+						if err != nil {
+							return err
+						}
+
+						// Unknown cards shouldn't stop the reader.
+						_, err = io.Copy(os.Stdout, resp.Body)
+						resp.Body.Close()
+						if err != nil {
+							return err
+						}
+						fmt.Println()
+					}
+
+					return scanner.Err()
+				},
+			},
+			{
+				Name:  "vendors",
+				Usage: "guess vendors and categories of devices with mac addresses read from stdin",
+				Action: func(ctx *cli.Context) error {
+					addresses, err := readAddresses(os.Stdin)
+					if err != nil {
+						return fmt.Errorf("readAddresses: %w", err)
+					}
+
+					report := []vendorReport{}
+					for _, address := range addresses {
+						vendor, _ := oui.Lookup(address)
+						report = append(report, vendorReport{
+							Address:  address.String(),
+							Vendor:   vendor.Name,
+							Category: string(oui.Classify(address)),
+						})
+					}
+
+					return json.NewEncoder(os.Stdout).Encode(report)
+				},
+			},
 			{
 				Name:  "admin",
 				Usage: "set of administration tools for managing content of long-season database",
@@ -163,6 +344,7 @@ func app() *cli.App {
 								UsersStorage:     factory.Users(),
 								DevicesStorage:   factory.Devices(),
 								TwoFactorStorage: factory.TwoFactor(),
+								Keys:             factory.Keys(),
 							})
 							if err != nil {
 								return fmt.Errorf("exim.Export: %w", err)
@@ -199,6 +381,7 @@ func app() *cli.App {
 								UsersStorage:     factory.Users(),
 								DevicesStorage:   factory.Devices(),
 								TwoFactorStorage: factory.TwoFactor(),
+								Keys:             factory.Keys(),
 							})
 							if err != nil {
 								return fmt.Errorf("exim.Import: %w", err)
@@ -207,6 +390,309 @@ func app() *cli.App {
 							return nil
 						},
 					},
+					{
+						Name:  "bootstrap",
+						Usage: "promote user with given nickname to the first admin",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "nickname",
+								Aliases:  []string{"n"},
+								Usage:    "nickname of user to promote",
+								Required: true,
+							},
+						},
+						Action: func(ctx *cli.Context) error {
+							factory, closer, err := factoryStorage(ctx)
+							if err != nil {
+								return err
+							}
+							defer closer()
+
+							id, err := users.Bootstrap(ctx.Context, factory.Users(), ctx.String("nickname"))
+							if err != nil {
+								return fmt.Errorf("users.Bootstrap: %w", err)
+							}
+
+							fmt.Printf("%s is admin now\n", id)
+							return nil
+						},
+					},
+					{
+						Name:  "devices",
+						Usage: "set of tools for managing devices stored in given database",
+						Action: func(ctx *cli.Context) error {
+							return cli.ShowCommandHelp(ctx, ctx.Command.Name)
+						},
+						Subcommands: []*cli.Command{
+							{
+								Name:  "collisions",
+								Usage: "report devices sharing mac address and devices, that can't be checked yet",
+								Action: func(ctx *cli.Context) error {
+									dbPath := ctx.String("database")
+									dbType := ctx.String("database-type")
+
+									factory, closer, err := abstract.Factory(dbPath, dbType)
+									if err != nil {
+										return fmt.Errorf("abstract.Factory: %w", err)
+									}
+									defer closer()
+
+									devices, err := factory.Devices().All(ctx.Context)
+									if err != nil {
+										return fmt.Errorf("factory.Devices().All: %w", err)
+									}
+
+									report := collisionsReport{
+										Collisions: []collisionReport{},
+										Unverified: []collisionDevice{},
+									}
+									for _, c := range storage.FindCollisions(devices) {
+										report.Collisions = append(report.Collisions, newCollisionReport(c))
+									}
+									for _, d := range devices {
+										if !storage.Verified(d) {
+											report.Unverified = append(report.Unverified, newCollisionDevice(d))
+										}
+									}
+
+									return json.NewEncoder(os.Stdout).Encode(report)
+								},
+							},
+						},
+					},
+					{
+						Name:  "ignored",
+						Usage: "show addresses of infrastructure devices excluded from online statuses",
+						Action: func(ctx *cli.Context) error {
+							factory, closer, err := factoryStorage(ctx)
+							if err != nil {
+								return err
+							}
+							defer closer()
+
+							ignored, err := factory.Ignored().All(ctx.Context)
+							if err != nil {
+								return fmt.Errorf("factory.Ignored().All: %w", err)
+							}
+
+							report := []ignoredReport{}
+							for _, i := range ignored {
+								report = append(report, ignoredReport{
+									ID:     i.ID,
+									Tag:    i.Tag,
+									Prefix: i.PrefixString(),
+								})
+							}
+
+							return json.NewEncoder(os.Stdout).Encode(report)
+						},
+						Subcommands: []*cli.Command{
+							{
+								Name:    "add",
+								Aliases: []string{"a"},
+								Usage:   "ignore mac address or prefix of mac addresses (for example OUI)",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "prefix",
+										Aliases:  []string{"p"},
+										Usage:    "mac address or its prefix, for example b8:27:eb",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:    "tag",
+										Aliases: []string{"t"},
+										Usage:   "description of ignored devices",
+										Value:   "",
+									},
+								},
+								Action: func(ctx *cli.Context) error {
+									factory, closer, err := factoryStorage(ctx)
+									if err != nil {
+										return err
+									}
+									defer closer()
+
+									a, err := ignored.Add(ctx.Context, factory.Ignored(), ctx.String("prefix"), ctx.String("tag"))
+									if err != nil {
+										return fmt.Errorf("ignored.Add: %w", err)
+									}
+
+									fmt.Println(a.ID)
+									return nil
+								},
+							},
+							{
+								Name:    "delete",
+								Aliases: []string{"d"},
+								Usage:   "stop ignoring address with given id",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "id",
+										Aliases:  []string{"i"},
+										Required: true,
+									},
+								},
+								Action: func(ctx *cli.Context) error {
+									factory, closer, err := factoryStorage(ctx)
+									if err != nil {
+										return err
+									}
+									defer closer()
+
+									return factory.Ignored().Remove(ctx.Context, ctx.String("id"))
+								},
+							},
+						},
+					},
+					{
+						Name:  "cards",
+						Usage: "show cards used for checking in",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "user-id",
+								Aliases: []string{"id", "i"},
+								Usage:   "show only cards of user with given id",
+							},
+						},
+						Action: func(ctx *cli.Context) error {
+							factory, closer, err := factoryStorage(ctx)
+							if err != nil {
+								return err
+							}
+							defer closer()
+
+							var cards []models.Card
+							if userID := ctx.String("user-id"); userID != "" {
+								cards, err = factory.Cards().OfUser(ctx.Context, userID)
+							} else {
+								cards, err = factory.Cards().All(ctx.Context)
+							}
+							if err != nil {
+								return fmt.Errorf("factory.Cards: %w", err)
+							}
+
+							report := []cardReport{}
+							for _, c := range cards {
+								report = append(report, cardReport{
+									ID:      c.ID,
+									Tag:     c.Tag,
+									OwnerID: c.OwnerID,
+								})
+							}
+
+							return json.NewEncoder(os.Stdout).Encode(report)
+						},
+						Subcommands: []*cli.Command{
+							{
+								Name:    "add",
+								Aliases: []string{"a"},
+								Usage:   "register card of user with given id",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "user-id",
+										Aliases:  []string{"i"},
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:     "uid",
+										Aliases:  []string{"u"},
+										Usage:    "card identifier, for example 04:a2:5b:1a",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:    "tag",
+										Aliases: []string{"t"},
+										Usage:   "name of the card",
+										Value:   "card",
+									},
+								},
+								Action: func(ctx *cli.Context) error {
+									factory, closer, err := factoryStorage(ctx)
+									if err != nil {
+										return err
+									}
+									defer closer()
+
+									id, err := checkin.AddCard(ctx.Context, checkin.AddCardRequest{
+										OwnerID: ctx.String("user-id"),
+										Tag:     ctx.String("tag"),
+										UID:     ctx.String("uid"),
+										Storage: factory.Cards(),
+									})
+									if err != nil {
+										return fmt.Errorf("checkin.AddCard: %w", err)
+									}
+
+									fmt.Println(id)
+									return nil
+								},
+							},
+							{
+								Name:    "delete",
+								Aliases: []string{"d"},
+								Usage:   "delete card with given id",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "id",
+										Aliases:  []string{"i"},
+										Required: true,
+									},
+								},
+								Action: func(ctx *cli.Context) error {
+									factory, closer, err := factoryStorage(ctx)
+									if err != nil {
+										return err
+									}
+									defer closer()
+
+									return factory.Cards().Remove(ctx.Context, ctx.String("id"))
+								},
+							},
+						},
+					},
+					{
+						Name:  "keys",
+						Usage: "manage keys generated for signing session tokens",
+						Subcommands: []*cli.Command{
+							{
+								Name:  "rotate",
+								Usage: "replace current signing key with new one",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:  "algorithm",
+										Usage: "algorithm of new key: EdDSA, ES256 or RS256",
+										Value: string(jwt.EdDSA),
+									},
+									&cli.DurationFlag{
+										Name:  "grace",
+										Usage: "time for which tokens signed with replaced key are accepted",
+										Value: 48 * time.Hour,
+									},
+								},
+								Action: func(ctx *cli.Context) error {
+									factory, closer, err := factoryStorage(ctx)
+									if err != nil {
+										return err
+									}
+									defer closer()
+
+									store := &keyring.Store{
+										Keys:      factory.Keys(),
+										Algorithm: jwt.Algorithm(ctx.String("algorithm")),
+										Grace:     ctx.Duration("grace"),
+									}
+
+									keys, err := store.Rotate(ctx.Context, time.Now())
+									if err != nil {
+										return fmt.Errorf("store.Rotate: %w", err)
+									}
+
+									fmt.Println(keys[0].ID)
+									return nil
+								},
+							},
+						},
+					},
 					{
 						Name:  "users",
 						Usage: "show users stored in given database",
@@ -268,14 +754,57 @@ func app() *cli.App {
 									}
 
 									storage, closer, err := usersStorage(ctx)
-									defer closer()
 									if err != nil {
 										return err
 									}
+									defer closer()
 
 									return storage.Remove(ctx.Context, ctx.String("user-id"))
 								},
 							},
+							{
+								Name:  "reset",
+								Usage: "issue one-time password reset link for user with given id",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:  "url",
+										Usage: "address of long-season, that is prepended to the link",
+									},
+									&cli.DurationFlag{
+										Name:  "ttl",
+										Usage: "time after which the link expires",
+										Value: reset.DefaultTTL,
+									},
+								},
+								Action: func(ctx *cli.Context) error {
+									if !ctx.IsSet("user-id") {
+										return fmt.Errorf("set user-id flag with users subcommand")
+									}
+
+									factory, closer, err := factoryStorage(ctx)
+									if err != nil {
+										return err
+									}
+									defer closer()
+
+									resetter := &reset.Resetter{
+										Tokens:    factory.ResetTokens(),
+										Users:     factory.Users(),
+										Audit:     factory.Audit(),
+										APITokens: factory.APITokens(),
+										TTL:       ctx.Duration("ttl"),
+									}
+
+									token, t, err := resetter.Issue(ctx.Context, "", ctx.String("user-id"))
+									if err != nil {
+										return fmt.Errorf("resetter.Issue: %w", err)
+									}
+
+									fmt.Printf("%s/reset#%s\n", strings.TrimSuffix(ctx.String("url"), "/"), token)
+									fmt.Fprintf(os.Stderr, "link expires at %s\n", t.Expires.Format(time.RFC3339))
+									return nil
+								},
+							},
 							{
 								Name:    "add",
 								Aliases: []string{"a"},
@@ -308,17 +837,10 @@ func app() *cli.App {
 									}
 									defer closer()
 
-									hashedPassword, err := bcrypt.GenerateFromPassword(
-										[]byte(newPassword), bcrypt.DefaultCost,
-									)
-									if err != nil {
-										return err
-									}
-
-									_, err = s.New(ctx.Context, storage.UserEntry{
-										Nickname:       newNickname,
-										HashedPassword: hashedPassword,
-										Private:        false,
+									_, err = users.Add(ctx.Context, users.AddUserRequest{
+										Nickname: newNickname,
+										Password: []byte(newPassword),
+										Storage:  s,
 									})
 									return err
 								},
@@ -340,17 +862,26 @@ func app() *cli.App {
 										Usage:   "value of new password for user with given id",
 										Value:   "",
 									},
+									&cli.StringFlag{
+										Name:  "role",
+										Usage: "role of user with given id: member, keyholder or admin",
+									},
 								},
 								Action: func(ctx *cli.Context) error {
 									if !ctx.IsSet("user-id") {
 										return fmt.Errorf("set user-id flag with users subcommand")
 									}
 
+									role := models.Role(ctx.String("role"))
+									if ctx.IsSet("role") && !role.Valid() {
+										return fmt.Errorf("role should be member, keyholder or admin")
+									}
+
 									s, closer, err := usersStorage(ctx)
-									defer closer()
 									if err != nil {
 										return err
 									}
+									defer closer()
 
 									user, err := s.Read(ctx.Context, ctx.String("user-id"))
 									if err != nil {
@@ -384,6 +915,9 @@ func app() *cli.App {
 									return s.Update(ctx.Context, newUser.ID, func(u *storage.UserEntry) error {
 										u.Nickname = newUser.Nickname
 										u.HashedPassword = newUser.Password
+										if ctx.IsSet("role") {
+											u.Role = role
+										}
 										return nil
 									})
 								},
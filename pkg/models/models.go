@@ -1,8 +1,13 @@
 package models
 
 import (
+	"bytes"
 	"encoding/gob"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/cristalhq/jwt/v3"
@@ -140,6 +145,62 @@ type Device struct {
 	OwnerID string
 	// MAC contains hashed MAC address of the device.
 	MAC []byte
+
+	// MACs contains additional hashed MAC addresses of the
+	// device. Modern phones use different randomized address
+	// for every network and can rotate them from time to time,
+	// so single device can be known by many addresses.
+	MACs [][]byte
+
+	// Lookups contains keyed hashes of hardware addresses of
+	// the device. Unlike bcrypt hashes, they can be compared
+	// for equality, so storage can refuse to register the same
+	// address twice. Devices registered by older versions get
+	// them, when their addresses are reported by scanners.
+	Lookups [][]byte
+
+	// Hostname is compared, case insensitively, with hostnames
+	// reported by scanners. Empty hostname matches nothing.
+	Hostname string
+
+	// ClientID contains hashed DHCP client identifier of
+	// the device.
+	ClientID []byte
+
+	// ClientIDLookup is keyed hash of DHCP client identifier,
+	// which is unique like lookup hashes of addresses.
+	ClientIDLookup []byte
+}
+
+// Addresses returns every hashed hardware address of device.
+func (d Device) Addresses() [][]byte {
+	res := make([][]byte, 0, len(d.MACs)+1)
+	if len(d.MAC) > 0 {
+		res = append(res, d.MAC)
+	}
+	for _, mac := range d.MACs {
+		if len(mac) > 0 {
+			res = append(res, mac)
+		}
+	}
+	return res
+}
+
+// Host represents single device reported by scanner as
+// present in the network. Besides hardware address, scanners
+// can report other identifiers, that help with recognizing
+// devices with randomized addresses.
+type Host struct {
+	// MAC is hardware address of the host.
+	MAC net.HardwareAddr
+
+	// Hostname is name sent by host, for example within
+	// DHCP request. It is optional.
+	Hostname string
+
+	// ClientID is DHCP client identifier sent by host.
+	// It is optional.
+	ClientID string
 }
 
 type DevicePublicData struct {
@@ -148,6 +209,485 @@ type DevicePublicData struct {
 	Owner string `json:"owner"`
 }
 
+// IgnoredAddress represents hardware address or prefix of hardware
+// addresses (for example OUI) of space owned infrastructure devices
+// like routers, printers or access points. Devices with matching
+// addresses are neither known nor unknown, they are just ignored.
+type IgnoredAddress struct {
+	// ID is unique identifier of ignored address.
+	ID string `json:"id"`
+
+	// Tag is human readable description of ignored devices.
+	Tag string `json:"tag"`
+
+	// Prefix is raw prefix of hardware address. It can be
+	// also complete hardware address.
+	Prefix []byte `json:"prefix"`
+}
+
+// Matches returns true if given address starts with
+// ignored address prefix.
+func (i IgnoredAddress) Matches(address net.HardwareAddr) bool {
+	return len(i.Prefix) > 0 && bytes.HasPrefix(address, i.Prefix)
+}
+
+// PrefixString returns ignored prefix in the same format
+// as net.HardwareAddr.String.
+func (i IgnoredAddress) PrefixString() string {
+	return net.HardwareAddr(i.Prefix).String()
+}
+
+// ErrInvalidPrefix is returned when parsed hardware
+// address prefix is not valid.
+var ErrInvalidPrefix = errors.New("models: invalid hardware address prefix")
+
+// ParseAddressPrefix parses hardware address prefix in the
+// hexadecimal form. Octets can be separated with colons,
+// hyphens or dots, for example: "00:00:5e", "00-00-5E" or
+// "0000.5e".
+func ParseAddressPrefix(s string) ([]byte, error) {
+	cleaned := strings.NewReplacer(":", "", "-", "", ".", "").Replace(s)
+	if cleaned == "" || len(cleaned)%2 != 0 || len(cleaned) > 40 {
+		return nil, ErrInvalidPrefix
+	}
+
+	res, err := hex.DecodeString(cleaned)
+	if err != nil {
+		return nil, ErrInvalidPrefix
+	}
+
+	return res, nil
+}
+
+// Card represents NFC or RFID card used for checking in at
+// the hackerspace door.
+type Card struct {
+	// ID is unique identifier of the card.
+	ID string `json:"id"`
+
+	// OwnerID is id of user that owns this card.
+	OwnerID string `json:"ownerId"`
+
+	// Tag is human readable name of the card.
+	Tag string `json:"tag"`
+
+	// UID contains hashed unique identifier of the card.
+	UID []byte `json:"uid"`
+}
+
+// ScannerKey authorizes single scanner, that reports devices
+// connected to the hackerspace network or cards scanned at
+// the door.
+type ScannerKey struct {
+	// ID is unique identifier of the key.
+	ID string `json:"id"`
+
+	// Name is human readable name of the scanner.
+	Name string `json:"name"`
+
+	// Secret contains hashed secret part of the key.
+	Secret []byte `json:"secret"`
+
+	// Created is time of creating the key.
+	Created time.Time `json:"created"`
+
+	// LastUsed is time of the latest request authorized
+	// with the key. It is zero for keys never used.
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// ResetToken allows user to set new password once, without
+// knowing the old one.
+type ResetToken struct {
+	// ID is unique identifier of the token.
+	ID string `json:"id"`
+
+	// UserID is id of user, whose password can be reset.
+	UserID string `json:"userId"`
+
+	// IssuerID is id of user, that issued the token. It is
+	// empty for tokens issued from command line.
+	IssuerID string `json:"issuerId"`
+
+	// Secret contains hashed secret part of the token.
+	Secret []byte `json:"secret"`
+
+	// Created is time of issuing the token.
+	Created time.Time `json:"created"`
+
+	// Expires is time after which the token cannot be used.
+	Expires time.Time `json:"expires"`
+}
+
+// Scope limits personal API token to single group of
+// endpoints.
+type Scope string
+
+const (
+	// ScopeReadStatus allows to read private data and
+	// statistics of token owner.
+	ScopeReadStatus Scope = "read-status"
+
+	// ScopeManageDevices allows to list, add, edit and
+	// remove devices of token owner.
+	ScopeManageDevices Scope = "manage-devices"
+
+	// ScopeCheckIn allows to check in with codes shown
+	// by the kiosk.
+	ScopeCheckIn Scope = "check-in"
+
+	// ScopeChangeSpace allows to override state of the
+	// hackerspace. Token owner still needs ChangeSpaceState
+	// permission.
+	ScopeChangeSpace Scope = "change-space"
+)
+
+// Valid returns true if s is one of known scopes.
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeReadStatus, ScopeManageDevices, ScopeCheckIn, ScopeChangeSpace:
+		return true
+	default:
+		return false
+	}
+}
+
+// APIToken is personal token, that allows scripts and bots
+// to call API on behalf of its owner.
+type APIToken struct {
+	// ID is unique identifier of the token.
+	ID string `json:"id"`
+
+	// UserID is id of token owner.
+	UserID string `json:"userId"`
+
+	// Name is human readable name of the token.
+	Name string `json:"name"`
+
+	// Scopes are groups of endpoints, that token
+	// can be used for.
+	Scopes []Scope `json:"scopes"`
+
+	// Secret contains hashed secret part of the token.
+	Secret []byte `json:"secret"`
+
+	// Created is time of creating the token.
+	Created time.Time `json:"created"`
+
+	// Expires is time after which the token cannot be
+	// used. It is zero for tokens, that never expire.
+	Expires time.Time `json:"expires"`
+
+	// LastUsed is time of the latest request authorized
+	// with the token. It is zero for tokens never used.
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// Allows returns true if token has given scope.
+func (t APIToken) Allows(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Session is record of single session of logged in user,
+// which can be listed and revoked by its owner.
+type Session struct {
+	// ID is unique identifier of the session.
+	ID string `json:"id"`
+
+	// UserID is id of session owner.
+	UserID string `json:"userId"`
+
+	// UserAgent is user agent of the latest request
+	// authorized with the session.
+	UserAgent string `json:"userAgent"`
+
+	// IP is address of the latest request authorized
+	// with the session.
+	IP string `json:"ip"`
+
+	// Created is time of logging in.
+	Created time.Time `json:"created"`
+
+	// LastUsed is time of the latest request authorized
+	// with the session.
+	LastUsed time.Time `json:"lastUsed"`
+
+	// Expires is time after which the session cannot
+	// be used.
+	Expires time.Time `json:"expires"`
+
+	// Generation is number of refresh tokens of the session
+	// exchanged for new tokens. Only refresh token of current
+	// generation can be exchanged.
+	Generation int `json:"generation"`
+}
+
+// AuditEntry records single security relevant action.
+type AuditEntry struct {
+	// ID is unique identifier of the entry.
+	ID string `json:"id"`
+
+	// Time of the action.
+	Time time.Time `json:"time"`
+
+	// ActorID is id of user, that performed the action. It
+	// is empty for actions performed from command line.
+	ActorID string `json:"actorId,omitempty"`
+
+	// Action is name of the action, for example
+	// "password-reset:issue".
+	Action string `json:"action"`
+
+	// TargetID is id of user affected by the action.
+	TargetID string `json:"targetId,omitempty"`
+}
+
+// Visit is single, continuous interval of user presence
+// in the hackerspace.
+type Visit struct {
+	// ID is unique identifier of the visit.
+	ID string `json:"id"`
+
+	// UserID is id of visiting user.
+	UserID string `json:"userId"`
+
+	// Start is time of arrival.
+	Start time.Time `json:"start"`
+
+	// End is time, when user was seen online for the
+	// last time during this visit.
+	End time.Time `json:"end"`
+}
+
+// Duration returns length of visit.
+func (v Visit) Duration() time.Duration {
+	return v.End.Sub(v.Start)
+}
+
+// Snapshot is number of users present in the hackerspace
+// at given time.
+type Snapshot struct {
+	Time   time.Time `json:"time"`
+	Online int       `json:"online"`
+}
+
+// SubscriptionEvent is kind of event, that user can be
+// notified about.
+type SubscriptionEvent string
+
+const (
+	// ArrivalEvent occurs when followed user arrives
+	// at the hackerspace.
+	ArrivalEvent SubscriptionEvent = "arrival"
+
+	// OpenEvent occurs when the first person arrives
+	// at the hackerspace.
+	OpenEvent SubscriptionEvent = "open"
+
+	// CloseEvent occurs when the last person leaves
+	// the hackerspace.
+	CloseEvent SubscriptionEvent = "close"
+)
+
+// Subscription of user to notifications about single
+// kind of events sent through single channel.
+type Subscription struct {
+	// ID is unique identifier of the subscription.
+	ID string `json:"id"`
+
+	// UserID is id of subscribing user.
+	UserID string `json:"userId"`
+
+	Event SubscriptionEvent `json:"event"`
+
+	// TargetID is id of followed user. Empty for
+	// events other than arrivals.
+	TargetID string `json:"targetId,omitempty"`
+
+	// Channel is name of notification channel, for
+	// example "email" or "ntfy".
+	Channel string `json:"channel"`
+
+	// Address of notification recipient in given
+	// channel, for example email address.
+	Address string `json:"address"`
+}
+
+// Role of user, that grants them permissions.
+type Role string
+
+const (
+	// RoleMember is default role of every user. Members
+	// can manage only their own account.
+	RoleMember Role = "member"
+
+	// RoleKeyholder is role of members, that are able to
+	// open the hackerspace.
+	RoleKeyholder Role = "keyholder"
+
+	// RoleAdmin is role of users administrating the
+	// long-season instance.
+	RoleAdmin Role = "admin"
+)
+
+// Permission allows user to perform some action.
+type Permission string
+
+const (
+	// ChangeSpaceState permission allows to override
+	// state of the hackerspace.
+	ChangeSpaceState Permission = "space:state"
+
+	// ManageUsers permission allows to manage accounts
+	// of other users.
+	ManageUsers Permission = "users:manage"
+
+	// ResetPasswords permission allows to issue password
+	// reset tokens for users without ManageUsers permission.
+	ResetPasswords Permission = "users:reset"
+)
+
+// permissions contains permissions granted by every role.
+var permissions = map[Role][]Permission{
+	RoleMember:    {},
+	RoleKeyholder: {ChangeSpaceState, ResetPasswords},
+	RoleAdmin:     {ChangeSpaceState, ManageUsers, ResetPasswords},
+}
+
+// Valid returns true if r is one of known roles.
+func (r Role) Valid() bool {
+	_, ok := permissions[r]
+	return ok
+}
+
+// Can returns true if role grants given permission.
+func (r Role) Can(p Permission) bool {
+	for _, granted := range permissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// SpaceState tells whether the hackerspace is open
+// to guests.
+type SpaceState string
+
+const (
+	// SpaceOpen means, that everybody is welcome.
+	SpaceOpen SpaceState = "open"
+
+	// SpaceClosed means, that nobody is expected in
+	// the hackerspace.
+	SpaceClosed SpaceState = "closed"
+
+	// SpaceMembersOnly means, that members are inside,
+	// but the hackerspace is closed to the public.
+	SpaceMembersOnly SpaceState = "members-only"
+)
+
+// Valid returns true if s is one of known space states.
+func (s SpaceState) Valid() bool {
+	switch s {
+	case SpaceOpen, SpaceClosed, SpaceMembersOnly:
+		return true
+	default:
+		return false
+	}
+}
+
+// Space is current state of the hackerspace.
+type Space struct {
+	State SpaceState `json:"state"`
+
+	// Override is true, when state was set by keyholder
+	// instead of being derived from occupancy.
+	Override bool `json:"override"`
+}
+
+// RegistrationMode tells who can create new accounts.
+type RegistrationMode string
+
+const (
+	// RegistrationOpen allows everybody to register.
+	RegistrationOpen RegistrationMode = "open"
+
+	// RegistrationClosed disables registration. Only admins
+	// can create new accounts.
+	RegistrationClosed RegistrationMode = "closed"
+
+	// RegistrationInvite allows registration only with
+	// valid invite code.
+	RegistrationInvite RegistrationMode = "invite"
+
+	// RegistrationApproval allows everybody to register,
+	// but new accounts stay pending until admin approves them.
+	RegistrationApproval RegistrationMode = "approval"
+)
+
+// Valid returns true if m is one of known registration modes.
+func (m RegistrationMode) Valid() bool {
+	switch m {
+	case RegistrationOpen, RegistrationClosed, RegistrationInvite, RegistrationApproval:
+		return true
+	default:
+		return false
+	}
+}
+
+// Invite is code allowing registration, when it is
+// invite-only.
+type Invite struct {
+	// ID is unique identifier of the invite and the
+	// code itself.
+	ID string `json:"id"`
+
+	// CreatorID is id of user, that created the invite.
+	CreatorID string `json:"creatorId"`
+
+	// MaxUses is number of accounts, that can be created
+	// with the invite. Zero means no limit.
+	MaxUses int `json:"maxUses"`
+
+	// Uses is number of accounts created with the invite.
+	Uses int `json:"uses"`
+
+	// Created is time of creating the invite.
+	Created time.Time `json:"created"`
+
+	// Expires is time after which the invite cannot be
+	// used. Zero means, that invite never expires.
+	Expires time.Time `json:"expires"`
+}
+
+// ErrInvalidCardUID is returned when parsed card
+// identifier is not valid.
+var ErrInvalidCardUID = errors.New("models: invalid card uid")
+
+// ParseCardUID parses unique identifier of NFC or RFID card
+// in the hexadecimal form. Octets can be separated with colons,
+// hyphens or spaces, for example: "04:a2:5b:1a" or "04A25B1A".
+// Most cards have 4, 7 or 10 bytes long identifiers.
+func ParseCardUID(s string) ([]byte, error) {
+	cleaned := strings.NewReplacer(":", "", "-", "", " ", "").Replace(s)
+	if len(cleaned) < 8 || len(cleaned)%2 != 0 || len(cleaned) > 64 {
+		return nil, ErrInvalidCardUID
+	}
+
+	res, err := hex.DecodeString(cleaned)
+	if err != nil {
+		return nil, ErrInvalidCardUID
+	}
+
+	return res, nil
+}
+
 // Config represents configuration that is
 // being used by server.
 type Config struct {
@@ -169,6 +709,161 @@ type Config struct {
 	AppName       string
 	RefreshTime   time.Duration
 	SingleAddrTTL time.Duration
+
+	// CheckInTTL is duration of presence declared by
+	// scanning card at the door.
+	CheckInTTL time.Duration
+
+	// CheckInToggle changes behaviour of card scans. When
+	// enabled, scanning card of present user checks the user out
+	// instead of refreshing his presence.
+	CheckInToggle bool
+
+	// JWTAlgorithm is algorithm of session tokens. HS256
+	// tokens are signed with JWTSecret, while EdDSA, ES256
+	// and RS256 tokens are signed with keys generated and
+	// stored in the database. JWTPreviousSecrets are still
+	// accepted after changing JWTSecret.
+	JWTAlgorithm       string
+	JWTPreviousSecrets []string
+
+	// JWTKeyFiles are paths of PEM encoded private keys,
+	// that replace JWTSecret and generated keys, when set.
+	// First key signs tokens and the rest is only accepted.
+	JWTKeyFiles []string
+
+	// JWTRotation is age after which generated key is
+	// replaced with new one. Zero disables rotation.
+	// JWTGrace is duration for which tokens signed with
+	// replaced key are still accepted. It should not be
+	// shorter than RememberTTL.
+	JWTRotation time.Duration
+	JWTGrace    time.Duration
+
+	// SessionTTL is lifetime of sessions and RememberTTL
+	// is lifetime of remembered sessions and sessions of
+	// API clients. Sessions of active users are renewed.
+	SessionTTL  time.Duration
+	RememberTTL time.Duration
+
+	// AccessTTL is lifetime of access tokens issued for
+	// API clients, that are renewed with refresh tokens.
+	AccessTTL time.Duration
+
+	// KioskSecret is secret used for signing check-in
+	// codes displayed by kiosk. Random secret kept in
+	// storage is used, when it is empty.
+	KioskSecret string
+
+	// KioskKey grants access to kiosk page. Kiosk and
+	// checking in with its codes are disabled when key
+	// is empty.
+	KioskKey string
+
+	// QRCheckInTTL is duration of presence declared by
+	// scanning code displayed by kiosk.
+	QRCheckInTTL time.Duration
+
+	// Timezone is name of default timezone used
+	// for computing statistics.
+	Timezone string
+
+	// SnapshotInterval is duration between snapshots
+	// of online users counter.
+	SnapshotInterval time.Duration
+
+	// HistoryRetention is duration after which snapshots
+	// of online users counter are removed.
+	HistoryRetention time.Duration
+
+	// MQTTBroker is address of MQTT broker, for example
+	// "tcp://127.0.0.1:1883". Publishing to MQTT is disabled
+	// when address is empty.
+	MQTTBroker   string
+	MQTTClientID string
+	MQTTUsername string
+	MQTTPassword string
+
+	// MQTTTopic is prefix of published MQTT topics.
+	MQTTTopic string
+
+	// MQTTDiscovery enables publishing of Home Assistant
+	// discovery configs with MQTTDiscoveryPrefix.
+	MQTTDiscovery       bool
+	MQTTDiscoveryPrefix string
+
+	// Bot is connected to Matrix room, when both
+	// homeserver and room id are set.
+	BotMatrixHomeserver string
+	BotMatrixUser       string
+	BotMatrixToken      string
+	BotMatrixRoom       string
+
+	// Bot is connected to IRC channel, when both
+	// server address and channel are set.
+	BotIRCServer   string
+	BotIRCTLS      bool
+	BotIRCNick     string
+	BotIRCPassword string
+	BotIRCChannel  string
+
+	// Templates of bot announcements. Every occurrence of
+	// "{nick}" is replaced with nickname of user. Empty
+	// template disables announcement.
+	BotArrival   string
+	BotDeparture string
+	BotOpen      string
+	BotClose     string
+
+	// SMTPAddr is address of SMTP relay in "host:port" form,
+	// used for sending emails. Emails are disabled when
+	// address is empty.
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// MailFile is path of file, that emails are written to
+	// instead of sending them, when there is no SMTP relay.
+	// Emails are written to standard error with "-".
+	MailFile string
+
+	// URL is public address of long-season, used in
+	// links sent by email.
+	URL string
+
+	// NtfyURLs are base URLs of ntfy-style servers, that
+	// users can publish notifications to, for example
+	// "https://ntfy.sh". Ntfy is disabled, when empty.
+	NtfyURLs []string
+
+	// WebPush enables push notifications sent to browsers.
+	// WebPushSubject is contact URI of the server operator,
+	// for example "mailto:admin@example.com", sent to push
+	// services with every notification.
+	WebPush        bool
+	WebPushSubject string
+
+	// WebPushServices are hosts of push services, that browser
+	// subscriptions may point at. Hosts starting with dot match
+	// their subdomains. Push services of major browsers are
+	// allowed, when empty.
+	WebPushServices []string
+
+	// OccupiedState is state of the hackerspace, when anyone
+	// is inside and no keyholder has overridden it.
+	OccupiedState SpaceState
+
+	// OverrideTTL is how long override of hackerspace state
+	// lasts, when no keyholder is inside.
+	OverrideTTL time.Duration
+
+	// RegistrationMode is default registration mode used
+	// until admin changes it.
+	RegistrationMode RegistrationMode
+
+	// ResetTTL is time-to-live of password reset links.
+	ResetTTL time.Duration
 }
 
 // Address returns address string that is compatible
@@ -180,7 +875,10 @@ func (c Config) Address() string {
 // Claims represents custom claims for jwt authentication.
 type Claims struct {
 	jwt.StandardClaims
-	UserID   string                 `json:"id"`
-	Nickname string                 `json:"nck"`
-	Values   map[string]interface{} `json:"vls,omitempty"`
+	UserID     string                 `json:"id"`
+	Nickname   string                 `json:"nck"`
+	Role       Role                   `json:"rol,omitempty"`
+	Remember   bool                   `json:"rmb,omitempty"`
+	Generation int                    `json:"gen,omitempty"`
+	Values     map[string]interface{} `json:"vls,omitempty"`
 }
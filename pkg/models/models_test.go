@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"net"
 	"testing"
 
 	"github.com/matryer/is"
@@ -29,3 +30,47 @@ func TestParsingTwoFactor(t *testing.T) {
 	is.Equal(res.OneTimeCodes["2"].Name, "sda")
 	is.Equal(res.OneTimeCodes["2"].Secret, "somesecret")
 }
+
+func TestIgnoredAddress(t *testing.T) {
+	is := is.New(t)
+
+	for _, valid := range []string{"b8:27:eb", "B8-27-EB", "b827.eb", "b8:27:eb:00:11:22"} {
+		prefix, err := ParseAddressPrefix(valid)
+		is.NoErr(err)
+		is.Equal(prefix[:3], []byte{0xb8, 0x27, 0xeb})
+	}
+
+	for _, invalid := range []string{"", "b8:27:e", "zz:zz:zz", "::"} {
+		_, err := ParseAddressPrefix(invalid)
+		is.Equal(err, ErrInvalidPrefix)
+	}
+
+	address, err := net.ParseMAC("b8:27:eb:12:34:56")
+	is.NoErr(err)
+
+	ignored := IgnoredAddress{Prefix: []byte{0xb8, 0x27, 0xeb}}
+	is.True(ignored.Matches(address))
+	is.Equal(ignored.PrefixString(), "b8:27:eb")
+
+	ignored.Prefix = []byte{0xdc, 0xa6, 0x32}
+	is.True(!ignored.Matches(address))
+
+	ignored.Prefix = nil
+	is.True(!ignored.Matches(address))
+}
+
+func TestRoles(t *testing.T) {
+	is := is.New(t)
+
+	is.True(RoleAdmin.Valid())
+	is.True(!Role("root").Valid())
+
+	is.True(!RoleMember.Can(ChangeSpaceState))
+	is.True(RoleKeyholder.Can(ChangeSpaceState))
+	is.True(!RoleKeyholder.Can(ManageUsers))
+	is.True(RoleAdmin.Can(ChangeSpaceState))
+	is.True(RoleAdmin.Can(ManageUsers))
+
+	// Unknown roles grant nothing.
+	is.True(!Role("").Can(ChangeSpaceState))
+}
@@ -0,0 +1,122 @@
+// Package badge implements rendering of embeddable hackerspace
+// status badges: SVG badges, PNG images for e-ink displays and
+// HTML widgets.
+package badge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Status is public state of the hackerspace.
+type Status struct {
+	// State tells whether the hackerspace is open to guests.
+	// It is derived from number of people, when empty.
+	State models.SpaceState
+
+	// Online is number of people in the hackerspace.
+	Online int
+
+	// Users contains nicknames of online users, that
+	// haven't enabled private mode.
+	Users []string
+}
+
+// Space returns state of the hackerspace.
+func (s Status) Space() models.SpaceState {
+	switch {
+	case s.State != "":
+		return s.State
+	case s.Online > 0:
+		return models.SpaceOpen
+	default:
+		return models.SpaceClosed
+	}
+}
+
+// Open returns true if the hackerspace is open to guests.
+func (s Status) Open() bool {
+	return s.Space() == models.SpaceOpen
+}
+
+// Occupied returns true if there is anyone in the hackerspace,
+// that isn't closed.
+func (s Status) Occupied() bool {
+	return s.Online > 0 && s.Space() != models.SpaceClosed
+}
+
+// Language of badge texts.
+type Language string
+
+const (
+	English Language = "en"
+	Polish  Language = "pl"
+)
+
+// ParseLanguage returns language with given code. Defaults
+// to English for unknown codes.
+func ParseLanguage(code string) Language {
+	switch Language(strings.ToLower(code)) {
+	case Polish:
+		return Polish
+	default:
+		return English
+	}
+}
+
+// Label returns default badge label.
+func (l Language) Label() string {
+	if l == Polish {
+		return "hakierspejs"
+	}
+	return "hackerspace"
+}
+
+// State returns "open", "closed" or "members only" depending
+// on given status.
+func (l Language) State(s Status) string {
+	switch space := s.Space(); {
+	case l == Polish && space == models.SpaceOpen:
+		return "otwarte"
+	case l == Polish && space == models.SpaceMembersOnly:
+		return "tylko dla członków"
+	case l == Polish:
+		return "zamknięte"
+	case space == models.SpaceOpen:
+		return "open"
+	case space == models.SpaceMembersOnly:
+		return "members only"
+	default:
+		return "closed"
+	}
+}
+
+// People returns number of people with properly inflected noun.
+func (l Language) People(n int) string {
+	if l != Polish {
+		if n == 1 {
+			return "1 person"
+		}
+		return fmt.Sprintf("%d people", n)
+	}
+
+	switch {
+	case n == 1:
+		return "1 osoba"
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+		return fmt.Sprintf("%d osoby", n)
+	default:
+		return fmt.Sprintf("%d osób", n)
+	}
+}
+
+// Message returns short description of given status, for
+// example "open, 5 people".
+func (l Language) Message(s Status) string {
+	if !s.Occupied() {
+		return l.State(s)
+	}
+	return l.State(s) + ", " + l.People(s.Online)
+}
@@ -0,0 +1,65 @@
+package badge
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+func TestMessage(t *testing.T) {
+	is := is.New(t)
+
+	for _, tc := range []struct {
+		lang     Language
+		online   int
+		expected string
+	}{
+		{English, 0, "closed"},
+		{English, 1, "open, 1 person"},
+		{English, 5, "open, 5 people"},
+		{Polish, 0, "zamknięte"},
+		{Polish, 1, "otwarte, 1 osoba"},
+		{Polish, 3, "otwarte, 3 osoby"},
+		{Polish, 5, "otwarte, 5 osób"},
+		{Polish, 12, "otwarte, 12 osób"},
+		{Polish, 22, "otwarte, 22 osoby"},
+	} {
+		is.Equal(tc.lang.Message(Status{Online: tc.online}), tc.expected)
+	}
+
+	membersOnly := Status{State: models.SpaceMembersOnly, Online: 2}
+	is.Equal(English.Message(membersOnly), "members only, 2 people")
+	is.Equal(Polish.Message(membersOnly), "tylko dla członków, 2 osoby")
+
+	// Keyholders can close the hackerspace with people inside.
+	is.Equal(English.Message(Status{State: models.SpaceClosed, Online: 2}), "closed")
+
+	is.Equal(ParseLanguage("PL"), Polish)
+	is.Equal(ParseLanguage("klingon"), English)
+}
+
+func TestRender(t *testing.T) {
+	is := is.New(t)
+
+	open := Status{Online: 2, Users: []string{"<script>"}}
+
+	svg, err := SVG("hackerspace", open, English, FlatSquare)
+	is.NoErr(err)
+	is.True(bytes.Contains(svg, []byte("open, 2 people")))
+	is.True(bytes.Contains(svg, []byte(openColor)))
+	is.True(!bytes.Contains(svg, []byte("linearGradient")))
+
+	image, err := PNG("hackerspace", open, Polish, 296, 128)
+	is.NoErr(err)
+	is.Equal(string(image[1:4]), "PNG")
+
+	html, err := Embed("hackerspace", open, English, Dark, time.Minute)
+	is.NoErr(err)
+	is.True(strings.Contains(string(html), `content="60"`))
+	is.True(strings.Contains(string(html), "&lt;script&gt;"))
+}
@@ -0,0 +1,84 @@
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// Theme of HTML widget.
+type Theme string
+
+const (
+	Light Theme = "light"
+	Dark  Theme = "dark"
+)
+
+// ParseTheme returns theme with given name. Defaults to
+// Light for unknown names.
+func ParseTheme(name string) Theme {
+	if Theme(name) == Dark {
+		return Dark
+	}
+	return Light
+}
+
+var embedTemplate = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="{{ .Lang }}">
+  <head>
+    <meta charset="UTF-8">
+    <meta http-equiv="refresh" content="{{ .Refresh }}">
+    <title>{{ .Label }}: {{ .Message }}</title>
+    <style>
+      body {
+        margin: 0;
+        padding: 0.5em;
+        font-family: sans-serif;
+        font-size: 14px;
+      }
+      body.light { color: #404040; background: #fff; }
+      body.dark { color: #fff; background: #404040; }
+      .state { font-weight: bold; }
+      .open .state { color: #4c1; }
+      .closed .state { color: #e05d44; }
+      .members-only .state { color: #dfb317; }
+      ul { margin: 0.25em 0 0 0; padding-left: 1.25em; }
+    </style>
+  </head>
+  <body class="{{ .Theme }}">
+    <div class="{{ .Space }}">
+      {{ .Label }}: <span class="state">{{ .State }}</span>{{ if .Occupied }}, {{ .People }}{{ end }}
+      {{ if .Users }}
+      <ul>
+        {{ range .Users }}<li>{{ . }}</li>{{ end }}
+      </ul>
+      {{ end }}
+    </div>
+  </body>
+</html>
+`))
+
+// Embed returns HTML document, that can be embedded with
+// iframe. Document describes given status and refreshes
+// itself every given duration.
+func Embed(label string, s Status, lang Language, theme Theme, refresh time.Duration) ([]byte, error) {
+	var buf bytes.Buffer
+	err := embedTemplate.Execute(&buf, map[string]interface{}{
+		"Lang":     string(lang),
+		"Refresh":  int(refresh.Seconds()),
+		"Label":    label,
+		"Message":  lang.Message(s),
+		"State":    lang.State(s),
+		"People":   lang.People(s.Online),
+		"Space":    string(s.Space()),
+		"Occupied": s.Occupied(),
+		"Users":    s.Users,
+		"Theme":    string(theme),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedTemplate.Execute: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	fontsOnce    sync.Once
+	regularFont  *opentype.Font
+	boldFont     *opentype.Font
+	errFontParse error
+)
+
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		regularFont, errFontParse = opentype.Parse(goregular.TTF)
+		if errFontParse != nil {
+			return
+		}
+		boldFont, errFontParse = opentype.Parse(gobold.TTF)
+	})
+	return errFontParse
+}
+
+// line is single line of text drawn on image.
+type line struct {
+	font     *opentype.Font
+	size     float64
+	baseline int
+	text     string
+}
+
+// drawCentered draws given line of text on given image. Line is
+// centered horizontally.
+func drawCentered(img *image.Paletted, l line) error {
+	face, err := opentype.NewFace(l.font, &opentype.FaceOptions{
+		Size:    l.size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return fmt.Errorf("opentype.NewFace: %w", err)
+	}
+	defer face.Close()
+
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.Black,
+		Face: face,
+	}
+	width := drawer.MeasureString(l.text)
+	drawer.Dot = fixed.Point26_6{
+		X: (fixed.I(img.Bounds().Dx()) - width) / 2,
+		Y: fixed.I(l.baseline),
+	}
+	drawer.DrawString(l.text)
+
+	return nil
+}
+
+// PNG returns black and white image with given size, suitable
+// for e-ink displays, that contains given label and describes
+// given status.
+func PNG(label string, s Status, lang Language, width, height int) ([]byte, error) {
+	if err := loadFonts(); err != nil {
+		return nil, fmt.Errorf("loadFonts: %w", err)
+	}
+
+	palette := color.Palette{color.White, color.Black}
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+
+	h := float64(height)
+	lines := []line{
+		{regularFont, h * 0.16, int(h * 0.22), label},
+		{boldFont, h * 0.34, int(h * 0.62), lang.State(s)},
+	}
+	if s.Occupied() {
+		lines = append(lines, line{regularFont, h * 0.18, int(h * 0.88), lang.People(s.Online)})
+	}
+
+	for _, l := range lines {
+		if err := drawCentered(img, l); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("png.Encode: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,98 @@
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"unicode/utf8"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Style of SVG badge.
+type Style string
+
+const (
+	// Flat badge has rounded corners and subtle gradient.
+	Flat Style = "flat"
+
+	// FlatSquare badge has square corners and no gradient.
+	FlatSquare Style = "flat-square"
+)
+
+// ParseStyle returns style with given name. Defaults to
+// Flat for unknown names.
+func ParseStyle(name string) Style {
+	if Style(name) == FlatSquare {
+		return FlatSquare
+	}
+	return Flat
+}
+
+const (
+	openColor    = "#4c1"
+	closedColor  = "#e05d44"
+	membersColor = "#dfb317"
+)
+
+var svgTemplate = template.Must(template.New("badge").Parse(
+	`<svg xmlns="http://www.w3.org/2000/svg" width="{{ .Width }}" height="20" role="img" aria-label="{{ .Label }}: {{ .Message }}">` +
+		`<title>{{ .Label }}: {{ .Message }}</title>` +
+		`{{ if .Gradient }}<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>{{ end }}` +
+		`<clipPath id="r"><rect width="{{ .Width }}" height="20" rx="{{ .Radius }}" fill="#fff"/></clipPath>` +
+		`<g clip-path="url(#r)">` +
+		`<rect width="{{ .LabelWidth }}" height="20" fill="#555"/>` +
+		`<rect x="{{ .LabelWidth }}" width="{{ .MessageWidth }}" height="20" fill="{{ .Color }}"/>` +
+		`{{ if .Gradient }}<rect width="{{ .Width }}" height="20" fill="url(#s)"/>{{ end }}` +
+		`</g>` +
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` +
+		`<text x="{{ .LabelX }}" y="14">{{ .Label }}</text>` +
+		`<text x="{{ .MessageX }}" y="14">{{ .Message }}</text>` +
+		`</g>` +
+		`</svg>`,
+))
+
+// textWidth returns approximate width in pixels of given text
+// written with 11px Verdana font with horizontal padding.
+func textWidth(s string) int {
+	return utf8.RuneCountInString(s)*7 + 10
+}
+
+// SVG returns badge with given label and message describing
+// given status.
+func SVG(label string, s Status, lang Language, style Style) ([]byte, error) {
+	message := lang.Message(s)
+	labelWidth, messageWidth := textWidth(label), textWidth(message)
+
+	color := closedColor
+	switch s.Space() {
+	case models.SpaceOpen:
+		color = openColor
+	case models.SpaceMembersOnly:
+		color = membersColor
+	}
+
+	radius := 3
+	if style == FlatSquare {
+		radius = 0
+	}
+
+	var buf bytes.Buffer
+	err := svgTemplate.Execute(&buf, map[string]interface{}{
+		"Label":        label,
+		"Message":      message,
+		"Width":        labelWidth + messageWidth,
+		"LabelWidth":   labelWidth,
+		"MessageWidth": messageWidth,
+		"LabelX":       labelWidth / 2,
+		"MessageX":     labelWidth + messageWidth/2,
+		"Color":        color,
+		"Radius":       radius,
+		"Gradient":     style == Flat,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("svgTemplate.Execute: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
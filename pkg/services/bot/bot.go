@@ -0,0 +1,299 @@
+// Package bot implements chat bot announcing arrivals, departures
+// and opening of the hackerspace, that answers questions about
+// present users. Bot can be connected to Matrix rooms and IRC
+// channels.
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/services/badge"
+	"github.com/hakierspejs/long-season/pkg/services/space"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+// reconnectDelay is duration between connection attempts of
+// disconnected backend.
+const reconnectDelay = 30 * time.Second
+
+// Handler returns reply to given message. Returns false if
+// message is not a command.
+type Handler func(ctx context.Context, text string) (string, bool)
+
+// Backend connects bot to chat network.
+type Backend interface {
+	// Run connects to chat network and replies to received
+	// messages with given handler until connection fails or
+	// given context is done.
+	Run(ctx context.Context, handle Handler) error
+
+	// Send posts given text to the room or channel
+	// of the bot.
+	Send(ctx context.Context, text string) error
+}
+
+// Messages contains templates of announcements. Every occurrence
+// of "{nick}" is replaced with nickname of arriving or departing
+// user. Empty template disables announcement.
+type Messages struct {
+	Arrival   string
+	Departure string
+	Open      string
+	Close     string
+}
+
+// Bot announces changes of status to every backend and answers
+// "!who" and "!status" commands. Users with private mode enabled
+// are never announced nor listed. Bot implements status.Observer
+// interface.
+type Bot struct {
+	Users    storage.Users
+	Adapter  storage.UserAdapter
+	Counters storage.StatusTx
+	Backends []Backend
+	Messages Messages
+
+	// Space is optional tracker of hackerspace state
+	// included in status replies.
+	Space *space.Tracker
+
+	mutex sync.Mutex
+
+	// initialized is true after the first observed tick.
+	initialized bool
+
+	// open is true if anyone was online during last tick.
+	open bool
+
+	// present contains ids of public users online
+	// during last tick.
+	present map[string]struct{}
+}
+
+// Run connects every backend to its chat network and answers
+// commands until given context is done. Backends are reconnected
+// after failures.
+func (b *Bot) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, backend := range b.Backends {
+		wg.Add(1)
+		go func(backend Backend) {
+			defer wg.Done()
+			for {
+				err := backend.Run(ctx, b.Reply)
+				if ctx.Err() != nil {
+					return
+				}
+				log.Println("Bot disconnected, reason: ", err)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(reconnectDelay):
+				}
+			}
+		}(backend)
+	}
+
+	wg.Wait()
+}
+
+// Observe announces arrivals and departures of public users and
+// opening or closing of the hackerspace since the last tick. The
+// first tick is never announced.
+func (b *Bot) Observe(ctx context.Context, tick storage.Tick) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entries, err := b.Users.All(ctx)
+	if err != nil {
+		return fmt.Errorf("b.Users.All: %w", err)
+	}
+
+	online := map[string]struct{}{}
+	for _, id := range tick.OnlineIDs {
+		online[id] = struct{}{}
+	}
+
+	open := tick.Known > 0
+	present := map[string]struct{}{}
+	arrived, departed := []string{}, []string{}
+
+	for _, u := range entries {
+		if u.Private {
+			continue
+		}
+
+		_, now := online[u.ID]
+		_, before := b.present[u.ID]
+		if now {
+			present[u.ID] = struct{}{}
+		}
+
+		switch {
+		case now && !before:
+			arrived = append(arrived, u.Nickname)
+		case !now && before:
+			departed = append(departed, u.Nickname)
+		}
+	}
+
+	announcements := []string{}
+	if b.initialized {
+		if open && !b.open {
+			announcements = append(announcements, b.Messages.Open)
+		}
+		for _, nickname := range arrived {
+			announcements = append(announcements, nickMessage(b.Messages.Arrival, nickname))
+		}
+		for _, nickname := range departed {
+			announcements = append(announcements, nickMessage(b.Messages.Departure, nickname))
+		}
+		if !open && b.open {
+			announcements = append(announcements, b.Messages.Close)
+		}
+	}
+
+	b.initialized = true
+	b.open = open
+	b.present = present
+
+	var last error
+	for _, text := range announcements {
+		if text == "" {
+			continue
+		}
+		for _, backend := range b.Backends {
+			if err := backend.Send(ctx, text); err != nil {
+				last = fmt.Errorf("backend.Send: %w", err)
+			}
+		}
+	}
+
+	return last
+}
+
+func nickMessage(template, nickname string) string {
+	if template == "" {
+		return ""
+	}
+	return strings.ReplaceAll(template, "{nick}", nickname)
+}
+
+// Reply answers "!who" and "!status" commands. It implements
+// Handler function type.
+func (b *Bot) Reply(ctx context.Context, text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	var (
+		reply string
+		err   error
+	)
+	switch strings.ToLower(fields[0]) {
+	case "!who":
+		reply, err = b.who(ctx)
+	case "!status":
+		reply, err = b.status(ctx)
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		log.Println("Failed to answer bot command, reason: ", err.Error())
+		return "sorry, something went wrong", true
+	}
+	return reply, true
+}
+
+// counters returns number of online users and unknown devices.
+func (b *Bot) counters(ctx context.Context) (int, int, error) {
+	online, unknown := 0, 0
+	err := b.Counters.DevicesStatus(ctx, func(ctx context.Context, s storage.Status) error {
+		var err error
+		online, err = s.OnlineUsers(ctx)
+		if err != nil {
+			return fmt.Errorf("s.OnlineUsers: %w", err)
+		}
+		unknown, err = s.UnknownDevices(ctx)
+		if err != nil {
+			return fmt.Errorf("s.UnknownDevices: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("b.Counters.DevicesStatus: %w", err)
+	}
+	return online, unknown, nil
+}
+
+// who returns list of online public users.
+func (b *Bot) who(ctx context.Context) (string, error) {
+	online, _, err := b.counters(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := b.Users.All(ctx)
+	if err != nil {
+		return "", fmt.Errorf("b.Users.All: %w", err)
+	}
+
+	adapted, err := b.Adapter.Users(ctx, entries)
+	if err != nil {
+		return "", fmt.Errorf("b.Adapter.Users: %w", err)
+	}
+
+	nicknames := []string{}
+	filters := append(users.DefaultFilters(), users.Online)
+	for _, u := range users.PublicSlice(users.Filter(adapted, filters...)) {
+		nicknames = append(nicknames, u.Nickname)
+	}
+
+	hidden := online - len(nicknames)
+	switch {
+	case online == 0:
+		return "nobody is here", nil
+	case len(nicknames) == 0:
+		return badge.English.People(hidden) + " in private mode", nil
+	case hidden > 0:
+		return fmt.Sprintf(
+			"present: %s and %s in private mode",
+			strings.Join(nicknames, ", "),
+			badge.English.People(hidden),
+		), nil
+	default:
+		return "present: " + strings.Join(nicknames, ", "), nil
+	}
+}
+
+// status returns short description of hackerspace status.
+func (b *Bot) status(ctx context.Context) (string, error) {
+	online, unknown, err := b.counters(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	s := badge.Status{Online: online}
+	if b.Space != nil {
+		state, err := b.Space.State(ctx)
+		if err != nil {
+			return "", fmt.Errorf("b.Space.State: %w", err)
+		}
+		s.State = state.State
+	}
+	reply := badge.English.Message(s)
+	if unknown > 0 {
+		reply += fmt.Sprintf(", unknown devices: %d", unknown)
+	}
+	return reply, nil
+}
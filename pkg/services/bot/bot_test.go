@@ -0,0 +1,294 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+	"github.com/hakierspejs/long-season/pkg/storage/temp"
+)
+
+// recorder is Backend, that records sent messages.
+type recorder struct {
+	sent []string
+}
+
+func (r *recorder) Run(ctx context.Context, handle Handler) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *recorder) Send(ctx context.Context, text string) error {
+	r.sent = append(r.sent, text)
+	return nil
+}
+
+// newBot returns bot with three users: alice, private bob
+// and carol, where alice and bob are online.
+func newBot(t *testing.T) (*Bot, func() error) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice"},
+		{ID: "2", Nickname: "bob", Private: true},
+		{ID: "3", Nickname: "carol"},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	onlineUsers := temp.NewOnlineUsers()
+	is.NoErr(onlineUsers.Update(ctx, []string{"1", "2"}))
+
+	counters := temp.NewStatusTx()
+	err = counters.DevicesStatus(ctx, func(ctx context.Context, s storage.Status) error {
+		if err := s.SetOnlineUsers(ctx, 2); err != nil {
+			return err
+		}
+		return s.SetUnknownDevices(ctx, 3)
+	})
+	is.NoErr(err)
+
+	return &Bot{
+		Users:    f.Users(),
+		Adapter:  storage.UserAdapter{OnlineUsersStorage: onlineUsers},
+		Counters: counters,
+		Messages: Messages{
+			Arrival:   "{nick} arrived",
+			Departure: "{nick} left",
+			Open:      "open",
+			Close:     "",
+		},
+	}, closer
+}
+
+func TestObserve(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	b, closer := newBot(t)
+	defer closer()
+
+	r := &recorder{}
+	b.Backends = []Backend{r}
+
+	// The first tick is not announced.
+	is.NoErr(b.Observe(ctx, storage.Tick{OnlineIDs: []string{"3"}, Known: 1}))
+	is.Equal(len(r.sent), 0)
+
+	is.NoErr(b.Observe(ctx, storage.Tick{}))
+	is.Equal(r.sent, []string{"carol left"})
+
+	// Private users are not announced.
+	r.sent = nil
+	is.NoErr(b.Observe(ctx, storage.Tick{OnlineIDs: []string{"1", "2"}, Known: 2}))
+	is.Equal(r.sent, []string{"open", "alice arrived"})
+
+	// Closing announcement is disabled.
+	r.sent = nil
+	is.NoErr(b.Observe(ctx, storage.Tick{}))
+	is.Equal(r.sent, []string{"alice left"})
+}
+
+func TestReply(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	b, closer := newBot(t)
+	defer closer()
+
+	reply, ok := b.Reply(ctx, "!who")
+	is.True(ok)
+	is.Equal(reply, "present: alice and 1 person in private mode")
+
+	reply, ok = b.Reply(ctx, "  !STATUS please")
+	is.True(ok)
+	is.Equal(reply, "open, 2 people, unknown devices: 3")
+
+	_, ok = b.Reply(ctx, "hello !who")
+	is.True(!ok)
+}
+
+func TestMatrix(t *testing.T) {
+	is := is.New(t)
+
+	b, closer := newBot(t)
+	defer closer()
+
+	var (
+		mutex sync.Mutex
+		sent  = make(chan string, 1)
+		syncs = 0
+	)
+
+	message := func(sender, body string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":    "m.room.message",
+			"sender":  sender,
+			"content": map[string]string{"msgtype": "m.text", "body": body},
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/_matrix/client/v3/join/!room:localhost":
+			fmt.Fprint(w, `{"room_id": "!room:localhost"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/_matrix/client/v3/sync":
+			mutex.Lock()
+			syncs += 1
+			n := syncs
+			mutex.Unlock()
+
+			events := []interface{}{}
+			switch n {
+			case 1:
+				// History is ignored.
+				events = append(events, message("@alice:localhost", "!status"))
+			case 2:
+				is.Equal(r.URL.Query().Get("since"), "batch1")
+				events = append(events,
+					message("@bot:localhost", "!who"),
+					message("@alice:localhost", "hello"),
+					message("@alice:localhost", "!who"),
+				)
+			default:
+				<-r.Context().Done()
+				return
+			}
+
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"next_batch": fmt.Sprintf("batch%d", n),
+				"rooms": map[string]interface{}{
+					"join": map[string]interface{}{
+						"!room:localhost": map[string]interface{}{
+							"timeline": map[string]interface{}{"events": events},
+						},
+					},
+				},
+			})
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/rooms/!room:localhost/send/m.room.message/"):
+			var body map[string]string
+			is.NoErr(json.NewDecoder(r.Body).Decode(&body))
+			is.Equal(body["msgtype"], "m.notice")
+			sent <- body["body"]
+			fmt.Fprint(w, `{"event_id": "$1"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	m := &Matrix{
+		Homeserver:  server.URL,
+		AccessToken: "secret",
+		UserID:      "@bot:localhost",
+		RoomID:      "!room:localhost",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- m.Run(ctx, b.Reply)
+	}()
+
+	select {
+	case reply := <-sent:
+		is.Equal(reply, "present: alice and 1 person in private mode")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	is.NoErr(m.Send(context.Background(), "alice arrived"))
+	is.Equal(<-sent, "alice arrived")
+
+	cancel()
+	is.True(<-done != nil)
+}
+
+func TestIRC(t *testing.T) {
+	is := is.New(t)
+
+	b, closer := newBot(t)
+	defer closer()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	is.NoErr(err)
+	defer listener.Close()
+
+	i := &IRC{
+		Server:  listener.Addr().String(),
+		Nick:    "ls",
+		Channel: "#hs",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- i.Run(ctx, b.Reply)
+	}()
+
+	conn, err := listener.Accept()
+	is.NoErr(err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	lines := bufio.NewScanner(conn)
+	expect := func(want string) {
+		is.True(lines.Scan())
+		is.Equal(lines.Text(), want)
+	}
+	send := func(line string) {
+		_, err := fmt.Fprintf(conn, "%s\r\n", line)
+		is.NoErr(err)
+	}
+
+	expect("NICK ls")
+	expect("USER ls 0 * :long-season")
+
+	send(":server 433 * ls :Nickname is already in use")
+	expect("NICK ls_")
+
+	send(":server 001 ls_ :Welcome")
+	expect("JOIN #hs")
+
+	send("PING :server")
+	expect("PONG :server")
+
+	send(":alice!a@localhost PRIVMSG #hs :!status")
+	expect("PRIVMSG #hs :open, 2 people, unknown devices: 3")
+
+	// Direct messages are answered directly.
+	send("@time=2023-03-01T18:00:00Z :alice!a@localhost PRIVMSG ls_ :!who")
+	expect("PRIVMSG alice :present: alice and 1 person in private mode")
+
+	send(":alice!a@localhost PRIVMSG #hs :just chatting")
+	is.NoErr(i.Send(context.Background(), "alice arrived\nbob left"))
+	expect("PRIVMSG #hs :alice arrived")
+	expect("PRIVMSG #hs :bob left")
+
+	cancel()
+	is.True(<-done != nil)
+	is.Equal(i.Send(context.Background(), "anyone?"), ErrNotConnected)
+}
@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ErrNotConnected is returned when message is sent by
+// disconnected backend.
+var ErrNotConnected = errors.New("bot: not connected")
+
+// IRC is Backend, that posts messages to single IRC channel.
+type IRC struct {
+	// Server is address of IRC server, for example
+	// "irc.libera.chat:6697".
+	Server string
+
+	// TLS enables encrypted connection.
+	TLS bool
+
+	Nick string
+
+	// Password is optional server password.
+	Password string
+
+	Channel string
+
+	mutex sync.Mutex
+	conn  net.Conn
+}
+
+// ircMessage is single line of IRC protocol.
+type ircMessage struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+// parseIRC parses given line of IRC protocol. Message
+// tags are ignored.
+func parseIRC(line string) ircMessage {
+	var res ircMessage
+
+	if strings.HasPrefix(line, "@") {
+		_, line, _ = strings.Cut(line, " ")
+	}
+	if strings.HasPrefix(line, ":") {
+		res.prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+
+	line, trailing, hasTrailing := strings.Cut(line, " :")
+	if strings.HasPrefix(line, ":") {
+		line, trailing, hasTrailing = "", line[1:], true
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) > 0 {
+		res.command = strings.ToUpper(fields[0])
+		res.params = fields[1:]
+	}
+	if hasTrailing {
+		res.params = append(res.params, trailing)
+	}
+
+	return res
+}
+
+// nick returns nickname from message prefix.
+func (m ircMessage) nick() string {
+	nick, _, _ := strings.Cut(m.prefix, "!")
+	return nick
+}
+
+func (i *IRC) dial(ctx context.Context) (net.Conn, error) {
+	if !i.TLS {
+		dialer := new(net.Dialer)
+		return dialer.DialContext(ctx, "tcp", i.Server)
+	}
+	dialer := new(tls.Dialer)
+	return dialer.DialContext(ctx, "tcp", i.Server)
+}
+
+// write sends given protocol lines to server.
+func (i *IRC) write(lines ...string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.conn == nil {
+		return ErrNotConnected
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(i.conn, "%s\r\n", line); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+	}
+	return nil
+}
+
+// privmsg returns protocol lines with given text sent
+// to given target, line by line.
+func privmsg(target, text string) []string {
+	res := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line != "" {
+			res = append(res, fmt.Sprintf("PRIVMSG %s :%s", target, line))
+		}
+	}
+	return res
+}
+
+// Run connects to server, joins channel and replies to
+// messages sent to the channel or directly to the bot.
+func (i *IRC) Run(ctx context.Context, handle Handler) error {
+	conn, err := i.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("i.dial: %w", err)
+	}
+	defer conn.Close()
+
+	i.mutex.Lock()
+	i.conn = conn
+	i.mutex.Unlock()
+	defer func() {
+		i.mutex.Lock()
+		i.conn = nil
+		i.mutex.Unlock()
+	}()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	nick := i.Nick
+	login := []string{}
+	if i.Password != "" {
+		login = append(login, "PASS "+i.Password)
+	}
+	login = append(login, "NICK "+nick, fmt.Sprintf("USER %s 0 * :long-season", nick))
+	if err := i.write(login...); err != nil {
+		return fmt.Errorf("i.write: %w", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		msg := parseIRC(scanner.Text())
+
+		switch msg.command {
+		case "PING":
+			err = i.write("PONG :" + strings.Join(msg.params, " "))
+		case "001":
+			err = i.write("JOIN " + i.Channel)
+		case "433":
+			// Nickname is already in use.
+			nick += "_"
+			err = i.write("NICK " + nick)
+		case "PRIVMSG":
+			if len(msg.params) < 2 {
+				continue
+			}
+
+			target := msg.params[0]
+			switch {
+			case strings.EqualFold(target, i.Channel):
+			case strings.EqualFold(target, nick):
+				target = msg.nick()
+			default:
+				continue
+			}
+
+			reply, ok := handle(ctx, msg.params[1])
+			if ok {
+				err = i.write(privmsg(target, reply)...)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("i.write: %w", err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scanner.Err: %w", err)
+	}
+	return errors.New("bot: connection closed by server")
+}
+
+// Send posts given text to the channel.
+func (i *IRC) Send(ctx context.Context, text string) error {
+	return i.write(privmsg(i.Channel, text)...)
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// syncTimeout is the longest duration of waiting for new
+// events during single sync request.
+const syncTimeout = 30 * time.Second
+
+// Matrix is Backend, that uses Matrix client-server API to post
+// messages to single room.
+type Matrix struct {
+	// Homeserver is base URL of homeserver, for
+	// example "https://matrix.org".
+	Homeserver string
+
+	// AccessToken of bot account.
+	AccessToken string
+
+	// UserID of bot account. Messages sent by bot
+	// are ignored.
+	UserID string
+
+	// RoomID is id of room, for example
+	// "!abcdef:matrix.org".
+	RoomID string
+
+	// Client is optional http client used for requests.
+	Client *http.Client
+
+	// transaction is counter of sent messages, used
+	// for idempotency of sending.
+	transaction int64
+}
+
+type matrixSync struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []matrixEvent `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+type matrixEvent struct {
+	Type    string `json:"type"`
+	Sender  string `json:"sender"`
+	Content struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+	} `json:"content"`
+}
+
+func (m *Matrix) client() *http.Client {
+	if m.Client == nil {
+		return http.DefaultClient
+	}
+	return m.Client
+}
+
+// do sends request to endpoint of client-server API with given
+// path and decodes response to res, if it isn't nil.
+func (m *Matrix) do(ctx context.Context, method, path string, query url.Values, body, res interface{}) error {
+	endpoint := strings.TrimSuffix(m.Homeserver, "/") + "/_matrix/client/v3" + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return fmt.Errorf("json.Encode: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, &payload)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+m.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("m.client().Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("matrix: %s %s: unexpected status %s", method, path, resp.Status)
+	}
+
+	if res != nil {
+		if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+			return fmt.Errorf("json.Decode: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m *Matrix) sync(ctx context.Context, since string, timeout time.Duration) (*matrixSync, error) {
+	query := url.Values{}
+	query.Set("timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+	if since != "" {
+		query.Set("since", since)
+	}
+
+	res := new(matrixSync)
+	if err := m.do(ctx, http.MethodGet, "/sync", query, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Run joins room and replies to messages sent there. Messages
+// sent before Run was called are ignored.
+func (m *Matrix) Run(ctx context.Context, handle Handler) error {
+	err := m.do(ctx, http.MethodPost, "/join/"+url.PathEscape(m.RoomID), nil, struct{}{}, nil)
+	if err != nil {
+		return fmt.Errorf("m.do: join: %w", err)
+	}
+
+	// Initial sync returns history, that is skipped.
+	res, err := m.sync(ctx, "", 0)
+	if err != nil {
+		return fmt.Errorf("m.sync: %w", err)
+	}
+
+	for {
+		res, err = m.sync(ctx, res.NextBatch, syncTimeout)
+		if err != nil {
+			return fmt.Errorf("m.sync: %w", err)
+		}
+
+		for _, event := range res.Rooms.Join[m.RoomID].Timeline.Events {
+			if event.Type != "m.room.message" || event.Sender == m.UserID {
+				continue
+			}
+			if event.Content.MsgType != "m.text" {
+				continue
+			}
+
+			reply, ok := handle(ctx, event.Content.Body)
+			if !ok {
+				continue
+			}
+			if err := m.Send(ctx, reply); err != nil {
+				return fmt.Errorf("m.Send: %w", err)
+			}
+		}
+	}
+}
+
+// Send posts given text to the room as notice, so other
+// bots don't respond to it.
+func (m *Matrix) Send(ctx context.Context, text string) error {
+	txn := fmt.Sprintf("ls%d.%d", time.Now().UnixNano(), atomic.AddInt64(&m.transaction, 1))
+	path := fmt.Sprintf("/rooms/%s/send/m.room.message/%s", url.PathEscape(m.RoomID), txn)
+
+	body := map[string]string{
+		"msgtype": "m.notice",
+		"body":    text,
+	}
+
+	if err := m.do(ctx, http.MethodPut, path, nil, body, nil); err != nil {
+		return fmt.Errorf("m.do: send: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+// Package checkin implements checking in at the hackerspace
+// with NFC or RFID cards scanned by reader at the door or with
+// codes displayed by kiosk.
+package checkin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+const internalServerErrorResponse = "Internal server error. Please try again later."
+
+// AddCardRequest holds arguments for AddCard service method.
+type AddCardRequest struct {
+	// OwnerID is id of user that owns new card.
+	OwnerID string
+
+	// Tag is a name for new card.
+	Tag string
+
+	// UID is raw identifier of the card in hexadecimal form.
+	UID string
+
+	// Storage for cards.
+	Storage storage.Cards
+}
+
+// AddCard adds new card to given storage. Returns assigned
+// ID if there is no error.
+func AddCard(ctx context.Context, args AddCardRequest) (string, error) {
+	errFactory := happier.FromContext(ctx)
+
+	uid, err := models.ParseCardUID(args.UID)
+	if err != nil {
+		return "", errFactory.BadRequest(
+			fmt.Errorf("models.ParseCardUID: %w", err),
+			fmt.Sprintf("invalid input: invalid card uid %s", args.UID),
+		)
+	}
+
+	registered, err := args.Storage.All(ctx)
+	if err != nil {
+		return "", errFactory.InternalServerError(
+			fmt.Errorf("db.All: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	// Card identifiers are stored as hashes, so we have to
+	// compare raw identifier with every stored hash.
+	if _, ok := storage.CardWithUID(registered, uid); ok {
+		return "", errFactory.Conflict(
+			fmt.Errorf("storage.CardWithUID: %w", serrors.ErrCardDuplication),
+			fmt.Sprintf("card already registered"),
+		)
+	}
+
+	hashedUID, err := bcrypt.GenerateFromPassword(uid, bcrypt.DefaultCost)
+	if err != nil {
+		return "", errFactory.InternalServerError(
+			fmt.Errorf("bcrypt.GenerateFromPassword: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	newID, err := args.Storage.New(ctx, args.OwnerID, models.Card{
+		ID:      uuid.New().String(),
+		OwnerID: args.OwnerID,
+		Tag:     args.Tag,
+		UID:     hashedUID,
+	})
+	if errors.Is(err, serrors.ErrCardDuplication) {
+		return "", errFactory.Conflict(
+			fmt.Errorf("db.New: %w", err),
+			fmt.Sprintf("card already registered"),
+		)
+	}
+	if err != nil {
+		return "", errFactory.InternalServerError(
+			fmt.Errorf("db.New: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	return newID, nil
+}
+
+// ScanRequest holds arguments for Scan service method.
+type ScanRequest struct {
+	// UID is raw identifier of scanned card in
+	// hexadecimal form.
+	UID string
+
+	// TTL is duration of presence declared by scan.
+	TTL time.Duration
+
+	// Toggle enables checking out users, that are
+	// already checked in. Otherwise every scan only
+	// refreshes presence of card owner.
+	Toggle bool
+
+	Cards    storage.Cards
+	Users    storage.Users
+	CheckIns storage.CheckIns
+}
+
+// Result of card scan.
+type Result struct {
+	// UserID is id of card owner.
+	UserID string `json:"id"`
+
+	// Nickname of card owner.
+	Nickname string `json:"nickname"`
+
+	// Present is true if card owner is checked in
+	// after scan.
+	Present bool `json:"present"`
+}
+
+// Scan checks in or checks out owner of scanned card.
+func Scan(ctx context.Context, args ScanRequest) (*Result, error) {
+	errFactory := happier.FromContext(ctx)
+
+	uid, err := models.ParseCardUID(args.UID)
+	if err != nil {
+		return nil, errFactory.BadRequest(
+			fmt.Errorf("models.ParseCardUID: %w", err),
+			fmt.Sprintf("invalid input: invalid card uid %s", args.UID),
+		)
+	}
+
+	cards, err := args.Cards.All(ctx)
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("args.Cards.All: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	card, ok := storage.CardWithUID(cards, uid)
+	if !ok {
+		return nil, errFactory.NotFound(
+			fmt.Errorf("storage.CardWithUID: %w", serrors.ErrNoID),
+			fmt.Sprintf("unknown card"),
+		)
+	}
+
+	user, err := args.Users.Read(ctx, card.OwnerID)
+	if err != nil {
+		return nil, errFactory.NotFound(
+			fmt.Errorf("args.Users.Read: %w", err),
+			fmt.Sprintf("unknown card"),
+		)
+	}
+
+	present, err := args.CheckIns.IsCheckedIn(ctx, user.ID)
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("args.CheckIns.IsCheckedIn: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	if present && args.Toggle {
+		err = args.CheckIns.CheckOut(ctx, user.ID)
+		present = false
+	} else {
+		err = args.CheckIns.CheckIn(ctx, user.ID, args.TTL)
+		present = true
+	}
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("args.CheckIns: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	return &Result{
+		UserID:   user.ID,
+		Nickname: user.Nickname,
+		Present:  present,
+	}, nil
+}
+
+// CodeVerifier verifies check-in codes displayed by kiosk.
+type CodeVerifier interface {
+	// Verify returns nil if given code is valid.
+	Verify(code string) error
+}
+
+// CodeRequest holds arguments for Code service method.
+type CodeRequest struct {
+	// UserID is id of user that scanned code.
+	UserID string
+
+	// Nickname of user that scanned code.
+	Nickname string
+
+	// Code is scanned code.
+	Code string
+
+	// TTL is duration of presence declared by scan.
+	TTL time.Duration
+
+	Codes    CodeVerifier
+	CheckIns storage.CheckIns
+}
+
+// Code checks in user that scanned code displayed by kiosk. Valid
+// code proves that user is physically present in the hackerspace.
+// Scanning code never checks user out.
+func Code(ctx context.Context, args CodeRequest) (*Result, error) {
+	errFactory := happier.FromContext(ctx)
+
+	err := args.Codes.Verify(args.Code)
+	if err != nil {
+		return nil, errFactory.BadRequest(
+			fmt.Errorf("args.Codes.Verify: %w", err),
+			"Invalid or expired code. Please scan the code again.",
+		)
+	}
+
+	err = args.CheckIns.CheckIn(ctx, args.UserID, args.TTL)
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("args.CheckIns.CheckIn: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	return &Result{
+		UserID:   args.UserID,
+		Nickname: args.Nickname,
+		Present:  true,
+	}, nil
+}
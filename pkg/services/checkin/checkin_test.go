@@ -0,0 +1,125 @@
+package checkin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+	"github.com/hakierspejs/long-season/pkg/storage/temp"
+)
+
+func TestScan(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "johnny",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+	})
+	is.NoErr(err)
+
+	_, err = AddCard(ctx, AddCardRequest{
+		OwnerID: "1",
+		Tag:     "keyfob",
+		UID:     "04:A2:5B:1A",
+		Storage: f.Cards(),
+	})
+	is.NoErr(err)
+
+	// The same card written differently.
+	_, err = AddCard(ctx, AddCardRequest{
+		OwnerID: "1",
+		Tag:     "keyfob again",
+		UID:     "04a25b1a",
+		Storage: f.Cards(),
+	})
+	is.True(err != nil)
+
+	checkIns := temp.NewCheckIns()
+	scan := func(uid string) (*Result, error) {
+		return Scan(ctx, ScanRequest{
+			UID:      uid,
+			TTL:      time.Hour,
+			Toggle:   true,
+			Cards:    f.Cards(),
+			Users:    f.Users(),
+			CheckIns: checkIns,
+		})
+	}
+
+	res, err := scan("04a25b1a")
+	is.NoErr(err)
+	is.Equal(res.Nickname, "johnny")
+	is.True(res.Present)
+
+	ids, err := checkIns.All(ctx)
+	is.NoErr(err)
+	is.Equal(ids, []string{"1"})
+
+	// Second scan toggles presence.
+	res, err = scan("04-A2-5B-1A")
+	is.NoErr(err)
+	is.True(!res.Present)
+
+	ids, err = checkIns.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(ids), 0)
+
+	_, err = scan("ffffffff")
+	is.True(err != nil) // unknown card
+}
+
+type codeVerifier string
+
+func (c codeVerifier) Verify(code string) error {
+	if code != string(c) {
+		return errors.New("invalid code")
+	}
+	return nil
+}
+
+func TestCode(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	checkIns := temp.NewCheckIns()
+	code := func(c string) (*Result, error) {
+		return Code(ctx, CodeRequest{
+			UserID:   "1",
+			Nickname: "johnny",
+			Code:     c,
+			TTL:      time.Hour,
+			Codes:    codeVerifier("valid"),
+			CheckIns: checkIns,
+		})
+	}
+
+	_, err := code("forged")
+	is.True(err != nil)
+
+	present, err := checkIns.IsCheckedIn(ctx, "1")
+	is.NoErr(err)
+	is.True(!present)
+
+	// Scanning code twice keeps user present.
+	for i := 0; i < 2; i++ {
+		res, err := code("valid")
+		is.NoErr(err)
+		is.True(res.Present)
+		is.Equal(res.UserID, "1")
+	}
+
+	present, err = checkIns.IsCheckedIn(ctx, "1")
+	is.NoErr(err)
+	is.True(present)
+}
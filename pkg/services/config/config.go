@@ -43,6 +43,156 @@ const (
 	singleAddrTTLEnv     = "LS_SINGLE_ADDR_TTL"
 	defaultSingleAddrTTL = time.Duration(60 * 5) // seconds
 
+	checkInTTLEnv     = "LS_CHECKIN_TTL"
+	defaultCheckInTTL = time.Duration(60 * 60 * 4) // seconds
+
+	checkInToggleEnv     = "LS_CHECKIN_TOGGLE"
+	defaultCheckInToggle = "1"
+
+	jwtAlgorithmEnv     = "LS_JWT_ALGORITHM"
+	defaultJWTAlgorithm = "HS256"
+
+	jwtPreviousSecretsEnv     = "LS_JWT_PREVIOUS_SECRETS"
+	defaultJWTPreviousSecrets = ""
+
+	jwtKeyFilesEnv     = "LS_JWT_KEY_FILES"
+	defaultJWTKeyFiles = ""
+
+	jwtRotationEnv     = "LS_JWT_ROTATION"
+	defaultJWTRotation = time.Duration(60 * 60 * 24 * 30) // seconds
+
+	jwtGraceEnv     = "LS_JWT_GRACE"
+	defaultJWTGrace = time.Duration(60 * 60 * 24 * 30) // seconds
+
+	sessionTTLEnv     = "LS_SESSION_TTL"
+	defaultSessionTTL = time.Duration(60 * 60 * 48) // seconds
+
+	rememberTTLEnv     = "LS_REMEMBER_TTL"
+	defaultRememberTTL = time.Duration(60 * 60 * 24 * 30) // seconds
+
+	accessTTLEnv     = "LS_ACCESS_TTL"
+	defaultAccessTTL = time.Duration(60 * 15) // seconds
+
+	kioskSecretEnv     = "LS_KIOSK_SECRET"
+	defaultKioskSecret = ""
+
+	kioskKeyEnv     = "LS_KIOSK_KEY"
+	defaultKioskKey = ""
+
+	qrCheckInTTLEnv     = "LS_QR_CHECKIN_TTL"
+	defaultQRCheckInTTL = time.Duration(60 * 60 * 4) // seconds
+
+	timezoneEnv     = "LS_TIMEZONE"
+	defaultTimezone = "Europe/Warsaw"
+
+	snapshotIntervalEnv     = "LS_SNAPSHOT_INTERVAL"
+	defaultSnapshotInterval = time.Duration(60 * 10) // seconds
+
+	historyRetentionEnv     = "LS_HISTORY_RETENTION"
+	defaultHistoryRetention = time.Duration(60 * 60 * 24 * 365) // seconds
+
+	mqttBrokerEnv     = "LS_MQTT_BROKER"
+	defaultMQTTBroker = ""
+
+	mqttClientIDEnv     = "LS_MQTT_CLIENT_ID"
+	defaultMQTTClientID = "long-season"
+
+	mqttUsernameEnv     = "LS_MQTT_USERNAME"
+	defaultMQTTUsername = ""
+
+	mqttPasswordEnv     = "LS_MQTT_PASSWORD"
+	defaultMQTTPassword = ""
+
+	mqttTopicEnv     = "LS_MQTT_TOPIC"
+	defaultMQTTTopic = "long-season"
+
+	mqttDiscoveryEnv     = "LS_MQTT_DISCOVERY"
+	defaultMQTTDiscovery = "1"
+
+	mqttDiscoveryPrefixEnv     = "LS_MQTT_DISCOVERY_PREFIX"
+	defaultMQTTDiscoveryPrefix = "homeassistant"
+
+	botMatrixHomeserverEnv     = "LS_BOT_MATRIX_HOMESERVER"
+	defaultBotMatrixHomeserver = ""
+
+	botMatrixUserEnv     = "LS_BOT_MATRIX_USER"
+	defaultBotMatrixUser = ""
+
+	botMatrixTokenEnv     = "LS_BOT_MATRIX_TOKEN"
+	defaultBotMatrixToken = ""
+
+	botMatrixRoomEnv     = "LS_BOT_MATRIX_ROOM"
+	defaultBotMatrixRoom = ""
+
+	botIRCServerEnv     = "LS_BOT_IRC_SERVER"
+	defaultBotIRCServer = ""
+
+	botIRCTLSEnv     = "LS_BOT_IRC_TLS"
+	defaultBotIRCTLS = "1"
+
+	botIRCNickEnv     = "LS_BOT_IRC_NICK"
+	defaultBotIRCNick = "long-season"
+
+	botIRCPasswordEnv     = "LS_BOT_IRC_PASSWORD"
+	defaultBotIRCPassword = ""
+
+	botIRCChannelEnv     = "LS_BOT_IRC_CHANNEL"
+	defaultBotIRCChannel = ""
+
+	botArrivalEnv     = "LS_BOT_ARRIVAL"
+	defaultBotArrival = "{nick} arrived"
+
+	botDepartureEnv     = "LS_BOT_DEPARTURE"
+	defaultBotDeparture = "{nick} left"
+
+	botOpenEnv     = "LS_BOT_OPEN"
+	defaultBotOpen = "hackerspace is open"
+
+	botCloseEnv     = "LS_BOT_CLOSE"
+	defaultBotClose = "hackerspace is closed"
+
+	smtpAddrEnv     = "LS_SMTP_ADDR"
+	defaultSMTPAddr = ""
+
+	smtpUsernameEnv     = "LS_SMTP_USERNAME"
+	defaultSMTPUsername = ""
+
+	smtpPasswordEnv     = "LS_SMTP_PASSWORD"
+	defaultSMTPPassword = ""
+
+	smtpFromEnv     = "LS_SMTP_FROM"
+	defaultSMTPFrom = "long-season@localhost"
+
+	mailFileEnv     = "LS_MAIL_FILE"
+	defaultMailFile = ""
+
+	urlEnv     = "LS_URL"
+	defaultURL = ""
+
+	ntfyURLsEnv     = "LS_NTFY_URLS"
+	defaultNtfyURLs = ""
+
+	webPushEnv     = "LS_WEBPUSH"
+	defaultWebPush = "1"
+
+	webPushSubjectEnv     = "LS_WEBPUSH_SUBJECT"
+	defaultWebPushSubject = "https://github.com/hakierspejs/long-season"
+
+	webPushServicesEnv     = "LS_WEBPUSH_SERVICES"
+	defaultWebPushServices = ""
+
+	occupiedStateEnv     = "LS_OCCUPIED_STATE"
+	defaultOccupiedState = "open"
+
+	overrideTTLEnv     = "LS_OVERRIDE_TTL"
+	defaultOverrideTTL = time.Duration(60 * 60) // seconds
+
+	registrationModeEnv     = "LS_REGISTRATION_MODE"
+	defaultRegistrationMode = "open"
+
+	resetTTLEnv     = "LS_RESET_TTL"
+	defaultResetTTL = time.Duration(60 * 60 * 24) // seconds
+
 	spaceEnv     = "LS_SPACE"
 	defaultSpace = "hs"
 
@@ -67,6 +217,60 @@ func Env() *models.Config {
 		AppName:       DefaultEnv(appNameEnv, defaultAppName),
 		RefreshTime:   time.Second * DefaultDurationEnv(refreshTimeEnv, defaultRefreshTime),
 		SingleAddrTTL: time.Second * DefaultDurationEnv(singleAddrTTLEnv, defaultSingleAddrTTL),
+		CheckInTTL:    time.Second * DefaultDurationEnv(checkInTTLEnv, defaultCheckInTTL),
+		CheckInToggle: parseBoolEnv(DefaultEnv(checkInToggleEnv, defaultCheckInToggle)),
+		KioskSecret:   DefaultEnv(kioskSecretEnv, defaultKioskSecret),
+		KioskKey:      DefaultEnv(kioskKeyEnv, defaultKioskKey),
+		QRCheckInTTL:  time.Second * DefaultDurationEnv(qrCheckInTTLEnv, defaultQRCheckInTTL),
+		Timezone:      DefaultEnv(timezoneEnv, defaultTimezone),
+
+		SnapshotInterval: time.Second * DefaultDurationEnv(snapshotIntervalEnv, defaultSnapshotInterval),
+		HistoryRetention: time.Second * DefaultDurationEnv(historyRetentionEnv, defaultHistoryRetention),
+
+		JWTAlgorithm:       DefaultEnv(jwtAlgorithmEnv, defaultJWTAlgorithm),
+		JWTPreviousSecrets: listEnv(DefaultEnv(jwtPreviousSecretsEnv, defaultJWTPreviousSecrets)),
+		JWTKeyFiles:        listEnv(DefaultEnv(jwtKeyFilesEnv, defaultJWTKeyFiles)),
+		JWTRotation:        time.Second * DefaultDurationEnv(jwtRotationEnv, defaultJWTRotation),
+		JWTGrace:           time.Second * DefaultDurationEnv(jwtGraceEnv, defaultJWTGrace),
+		SessionTTL:         time.Second * DefaultDurationEnv(sessionTTLEnv, defaultSessionTTL),
+		RememberTTL:        time.Second * DefaultDurationEnv(rememberTTLEnv, defaultRememberTTL),
+		AccessTTL:          time.Second * DefaultDurationEnv(accessTTLEnv, defaultAccessTTL),
+
+		MQTTBroker:          DefaultEnv(mqttBrokerEnv, defaultMQTTBroker),
+		MQTTClientID:        DefaultEnv(mqttClientIDEnv, defaultMQTTClientID),
+		MQTTUsername:        DefaultEnv(mqttUsernameEnv, defaultMQTTUsername),
+		MQTTPassword:        DefaultEnv(mqttPasswordEnv, defaultMQTTPassword),
+		MQTTTopic:           DefaultEnv(mqttTopicEnv, defaultMQTTTopic),
+		MQTTDiscovery:       parseBoolEnv(DefaultEnv(mqttDiscoveryEnv, defaultMQTTDiscovery)),
+		MQTTDiscoveryPrefix: DefaultEnv(mqttDiscoveryPrefixEnv, defaultMQTTDiscoveryPrefix),
+
+		BotMatrixHomeserver: DefaultEnv(botMatrixHomeserverEnv, defaultBotMatrixHomeserver),
+		BotMatrixUser:       DefaultEnv(botMatrixUserEnv, defaultBotMatrixUser),
+		BotMatrixToken:      DefaultEnv(botMatrixTokenEnv, defaultBotMatrixToken),
+		BotMatrixRoom:       DefaultEnv(botMatrixRoomEnv, defaultBotMatrixRoom),
+		BotIRCServer:        DefaultEnv(botIRCServerEnv, defaultBotIRCServer),
+		BotIRCTLS:           parseBoolEnv(DefaultEnv(botIRCTLSEnv, defaultBotIRCTLS)),
+		BotIRCNick:          DefaultEnv(botIRCNickEnv, defaultBotIRCNick),
+		BotIRCPassword:      DefaultEnv(botIRCPasswordEnv, defaultBotIRCPassword),
+		BotIRCChannel:       DefaultEnv(botIRCChannelEnv, defaultBotIRCChannel),
+		BotArrival:          announcementEnv(botArrivalEnv, defaultBotArrival),
+		BotDeparture:        announcementEnv(botDepartureEnv, defaultBotDeparture),
+		BotOpen:             announcementEnv(botOpenEnv, defaultBotOpen),
+		BotClose:            announcementEnv(botCloseEnv, defaultBotClose),
+		SMTPAddr:            DefaultEnv(smtpAddrEnv, defaultSMTPAddr),
+		SMTPUsername:        DefaultEnv(smtpUsernameEnv, defaultSMTPUsername),
+		SMTPPassword:        DefaultEnv(smtpPasswordEnv, defaultSMTPPassword),
+		SMTPFrom:            DefaultEnv(smtpFromEnv, defaultSMTPFrom),
+		NtfyURLs:            listEnv(DefaultEnv(ntfyURLsEnv, defaultNtfyURLs)),
+		WebPush:             parseBoolEnv(DefaultEnv(webPushEnv, defaultWebPush)),
+		WebPushSubject:      DefaultEnv(webPushSubjectEnv, defaultWebPushSubject),
+		WebPushServices:     listEnv(DefaultEnv(webPushServicesEnv, defaultWebPushServices)),
+		OccupiedState:       models.SpaceState(DefaultEnv(occupiedStateEnv, defaultOccupiedState)),
+		OverrideTTL:         time.Second * DefaultDurationEnv(overrideTTLEnv, defaultOverrideTTL),
+		RegistrationMode:    models.RegistrationMode(DefaultEnv(registrationModeEnv, defaultRegistrationMode)),
+		ResetTTL:            time.Second * DefaultDurationEnv(resetTTLEnv, defaultResetTTL),
+		MailFile:            DefaultEnv(mailFileEnv, defaultMailFile),
+		URL:                 DefaultEnv(urlEnv, defaultURL),
 	}
 }
 
@@ -98,6 +302,28 @@ func DefaultDurationEnv(key string, fallback time.Duration) time.Duration {
 	return time.Duration(parsed)
 }
 
+// announcementEnv returns template of bot announcement assigned
+// to given key. Announcement is disabled with "-" value.
+func announcementEnv(key, fallback string) string {
+	res := DefaultEnv(key, fallback)
+	if res == "-" {
+		return ""
+	}
+	return res
+}
+
+// listEnv splits comma separated list and skips
+// empty elements.
+func listEnv(env string) []string {
+	res := []string{}
+	for _, elem := range strings.Split(env, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			res = append(res, elem)
+		}
+	}
+	return res
+}
+
 func parseBoolEnv(env string) bool {
 	return !(env == "" || env == "0" || strings.ToLower(env) == "false")
 }
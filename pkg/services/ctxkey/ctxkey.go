@@ -8,4 +8,8 @@ const (
 	// DebugKey represents key used to store
 	// information about debug mode.
 	DebugKey ContextKey = iota
+
+	// ScopeKey represents key used to store scope
+	// required from personal API tokens.
+	ScopeKey
 )
@@ -3,6 +3,8 @@ package ctxkey
 import (
 	"context"
 	"errors"
+
+	"github.com/hakierspejs/long-season/pkg/models"
 )
 
 var ErrValueNotFound = errors.New("ctxkey: value not found in ctx")
@@ -15,3 +17,13 @@ func Debug(ctx context.Context) (bool, error) {
 	}
 	return mode, nil
 }
+
+// Scope returns scope required from personal API tokens
+// stored in context store.
+func Scope(ctx context.Context) (models.Scope, error) {
+	scope, ok := ctx.Value(ScopeKey).(models.Scope)
+	if !ok {
+		return "", ErrValueNotFound
+	}
+	return scope, nil
+}
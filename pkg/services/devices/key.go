@@ -0,0 +1,40 @@
+package devices
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// KeyName is name of key used for lookup hashes of hardware
+// addresses in storage.Keys.
+const KeyName = "devices::lookups"
+
+// keySize is number of random bytes in lookup key.
+const keySize = 32
+
+// LoadKey returns key for lookup hashes of hardware addresses.
+// Key is generated and saved on first use.
+func LoadKey(ctx context.Context, keys storage.Keys) ([]byte, error) {
+	key, err := keys.Read(ctx, KeyName)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, serrors.ErrNoID) {
+		return nil, fmt.Errorf("keys.Read: %w", err)
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	if err := keys.Save(ctx, KeyName, key); err != nil {
+		return nil, fmt.Errorf("keys.Save: %w", err)
+	}
+
+	return key, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -35,6 +36,10 @@ type AddDeviceRequest struct {
 	// adding to storage by Add function.
 	MAC string
 
+	// Key for lookup hash of MAC. Use LoadKey to get key
+	// shared by all instances of long-season.
+	Key []byte
+
 	// Storage for devices.
 	Storage storage.Devices
 }
@@ -70,11 +75,18 @@ func Add(ctx context.Context, args AddDeviceRequest) (string, error) {
 		},
 		OwnerID: args.OwnerID,
 		MAC:     hashedMac,
+		Lookups: [][]byte{storage.LookupHash(args.Key, mac)},
 	})
+	if errors.Is(err, serrors.ErrMACDuplication) {
+		return "", errFactory.Conflict(
+			fmt.Errorf("db.New: %w", err),
+			"mac address already registered",
+		)
+	}
 	if errors.Is(err, serrors.ErrDeviceDuplication) {
 		return "", errFactory.Conflict(
 			fmt.Errorf("db.New: %w", err),
-			fmt.Sprintf("tag already used"),
+			"tag already used",
 		)
 	}
 	if err != nil {
@@ -86,3 +98,177 @@ func Add(ctx context.Context, args AddDeviceRequest) (string, error) {
 
 	return newID, nil
 }
+
+// maxAddresses is maximal number of additional hardware
+// addresses of single device.
+const maxAddresses = 16
+
+var errTooManyAddresses = errors.New("devices: too many addresses")
+
+// EditDeviceRequest holds arguments for Edit service method.
+type EditDeviceRequest struct {
+	// ID of edited device.
+	ID string
+
+	// MAC is raw hardware address, that will be added to
+	// additional addresses of the device. Empty MAC is
+	// ignored.
+	MAC string
+
+	// Key for lookup hashes of MAC and client identifier. Use
+	// LoadKey to get key shared by all instances of long-season.
+	Key []byte
+
+	// Hostname is new hostname of the device. Nil hostname
+	// is ignored and empty one removes hostname.
+	Hostname *string
+
+	// ClientID is new raw DHCP client identifier of the
+	// device. Nil client identifier is ignored and empty one
+	// removes identifier.
+	ClientID *string
+
+	// Storage for devices.
+	Storage storage.Devices
+}
+
+// ValidHostname returns true if given hostname is correct
+// hostname: dot separated labels of letters, digits, hyphens
+// and underscores. Wildcards are not allowed, because they
+// would match devices of other users.
+func ValidHostname(hostname string) bool {
+	if len(hostname) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(hostname, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+		for _, r := range label {
+			valid := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+				(r >= '0' && r <= '9') || r == '-' || r == '_'
+			if !valid {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Edit changes alternative identifiers of device with given id and
+// returns updated device. Identifiers have to be unique, so single
+// device of one user can't be taken for device of another one.
+func Edit(ctx context.Context, args EditDeviceRequest) (*models.Device, error) {
+	errFactory := happier.FromContext(ctx)
+
+	var (
+		hashedMac      []byte
+		lookup         []byte
+		hashedClientID []byte
+		clientIDLookup []byte
+		err            error
+	)
+
+	if args.MAC != "" {
+		mac, err := net.ParseMAC(args.MAC)
+		if err != nil {
+			return nil, errFactory.BadRequest(
+				fmt.Errorf("net.ParseMAC: %w", err),
+				fmt.Sprintf("invalid input: invalid mac address %s", args.MAC),
+			)
+		}
+
+		lookup = storage.LookupHash(args.Key, mac)
+		hashedMac, err = bcrypt.GenerateFromPassword(mac, bcrypt.DefaultCost)
+		if err != nil {
+			return nil, errFactory.InternalServerError(
+				fmt.Errorf("bcrypt.GenerateFromPassword: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+	}
+
+	if args.Hostname != nil && *args.Hostname != "" {
+		if !ValidHostname(*args.Hostname) {
+			return nil, errFactory.BadRequest(
+				fmt.Errorf("ValidHostname: invalid hostname %s", *args.Hostname),
+				fmt.Sprintf("invalid input: invalid hostname %s", *args.Hostname),
+			)
+		}
+	}
+
+	if args.ClientID != nil && *args.ClientID != "" {
+		clientIDLookup = storage.LookupHash(args.Key, []byte(*args.ClientID))
+		hashedClientID, err = bcrypt.GenerateFromPassword([]byte(*args.ClientID), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, errFactory.InternalServerError(
+				fmt.Errorf("bcrypt.GenerateFromPassword: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+	}
+
+	var res models.Device
+	err = args.Storage.Update(ctx, args.ID, func(d *models.Device) error {
+		if hashedMac != nil {
+			if len(d.MACs) >= maxAddresses {
+				return errTooManyAddresses
+			}
+			if storage.HasLookup(*d, lookup) {
+				return serrors.ErrMACDuplication
+			}
+			d.MACs = append(d.MACs, hashedMac)
+			d.Lookups = append(d.Lookups, lookup)
+		}
+		if args.Hostname != nil {
+			d.Hostname = *args.Hostname
+		}
+		if args.ClientID != nil {
+			d.ClientID = hashedClientID
+			d.ClientIDLookup = clientIDLookup
+		}
+
+		res = *d
+		return nil
+	})
+	if errors.Is(err, serrors.ErrNoID) {
+		return nil, errFactory.NotFound(
+			fmt.Errorf("db.Update: %w", err),
+			fmt.Sprintf("there is no device with given id: %s", args.ID),
+		)
+	}
+	if errors.Is(err, serrors.ErrMACDuplication) {
+		return nil, errFactory.Conflict(
+			fmt.Errorf("db.Update: %w", err),
+			"mac address already registered",
+		)
+	}
+	if errors.Is(err, serrors.ErrHostnameTaken) {
+		return nil, errFactory.Conflict(
+			fmt.Errorf("db.Update: %w", err),
+			"hostname already registered",
+		)
+	}
+	if errors.Is(err, serrors.ErrClientIDTaken) {
+		return nil, errFactory.Conflict(
+			fmt.Errorf("db.Update: %w", err),
+			"client id already registered",
+		)
+	}
+	if errors.Is(err, errTooManyAddresses) {
+		return nil, errFactory.BadRequest(
+			fmt.Errorf("db.Update: %w", err),
+			fmt.Sprintf("invalid input: device can't have more than %d additional mac addresses", maxAddresses),
+		)
+	}
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("db.Update: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	return &res, nil
+}
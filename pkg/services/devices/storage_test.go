@@ -0,0 +1,122 @@
+package devices
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+// status returns http status of given error.
+func status(t *testing.T, err error) int {
+	herr, ok := err.(horror.Error)
+	if !ok {
+		t.Fatalf("expected horror error, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	herr.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/", nil))
+	return w.Code
+}
+
+func TestValidHostname(t *testing.T) {
+	is := is.New(t)
+
+	for _, hostname := range []string{"pixel-7", "Alice-iPhone", "laptop.local", "DESKTOP_1"} {
+		is.True(ValidHostname(hostname))
+	}
+
+	for _, hostname := range []string{"*", "*a*", "pixel-*", "pixel-?", "[a-z]*", "a..b", ".local", "a b"} {
+		is.True(!ValidHostname(hostname))
+	}
+}
+
+func TestEdit(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice"},
+		{ID: "2", Nickname: "bob"},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	key := []byte("key")
+
+	alice, err := Add(ctx, AddDeviceRequest{
+		OwnerID: "1", Owner: "alice", Tag: "phone",
+		MAC: "11:11:11:11:11:11", Key: key, Storage: f.Devices(),
+	})
+	is.NoErr(err)
+
+	bob, err := Add(ctx, AddDeviceRequest{
+		OwnerID: "2", Owner: "bob", Tag: "phone",
+		MAC: "22:22:22:22:22:22", Key: key, Storage: f.Devices(),
+	})
+	is.NoErr(err)
+
+	// Address of other user's device can't be registered
+	// again, even in other notation.
+	_, err = Add(ctx, AddDeviceRequest{
+		OwnerID: "2", Owner: "bob", Tag: "laptop",
+		MAC: "11-11-11-11-11-11", Key: key, Storage: f.Devices(),
+	})
+	is.Equal(status(t, err), http.StatusConflict)
+
+	hostname, clientID := "Pixel-7", "01:aa:bb"
+	d, err := Edit(ctx, EditDeviceRequest{
+		ID: alice, Hostname: &hostname, ClientID: &clientID, Key: key, Storage: f.Devices(),
+	})
+	is.NoErr(err)
+	is.Equal(d.Hostname, "Pixel-7")
+
+	// Setting the same identifiers again is fine.
+	_, err = Edit(ctx, EditDeviceRequest{
+		ID: alice, Hostname: &hostname, ClientID: &clientID, Key: key, Storage: f.Devices(),
+	})
+	is.NoErr(err)
+
+	// Identifiers of other devices can't be taken.
+	taken := "pixel-7"
+	_, err = Edit(ctx, EditDeviceRequest{ID: bob, Hostname: &taken, Storage: f.Devices()})
+	is.Equal(status(t, err), http.StatusConflict)
+
+	_, err = Edit(ctx, EditDeviceRequest{ID: bob, ClientID: &clientID, Key: key, Storage: f.Devices()})
+	is.Equal(status(t, err), http.StatusConflict)
+
+	_, err = Edit(ctx, EditDeviceRequest{ID: bob, MAC: "11:11:11:11:11:11", Key: key, Storage: f.Devices()})
+	is.Equal(status(t, err), http.StatusConflict)
+
+	// Device can't have the same address twice.
+	_, err = Edit(ctx, EditDeviceRequest{ID: bob, MAC: "22:22:22:22:22:22", Key: key, Storage: f.Devices()})
+	is.Equal(status(t, err), http.StatusConflict)
+
+	d, err = Edit(ctx, EditDeviceRequest{ID: bob, MAC: "33:33:33:33:33:33", Key: key, Storage: f.Devices()})
+	is.NoErr(err)
+	is.Equal(len(d.Lookups), 2)
+
+	// Wildcards would match devices of other users.
+	pattern := "*"
+	_, err = Edit(ctx, EditDeviceRequest{ID: bob, Hostname: &pattern, Storage: f.Devices()})
+	is.Equal(status(t, err), http.StatusBadRequest)
+
+	other, otherClientID := "iphone", "01:cc:dd"
+	d, err = Edit(ctx, EditDeviceRequest{
+		ID: bob, Hostname: &other, ClientID: &otherClientID, Key: key, Storage: f.Devices(),
+	})
+	is.NoErr(err)
+	is.Equal(d.Hostname, "iphone")
+}
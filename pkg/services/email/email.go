@@ -0,0 +1,385 @@
+// Package email manages optional email addresses of users.
+// Addresses are verified with signed links sent to them and
+// can be used for resetting forgotten passwords.
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/services/mailer"
+	"github.com/hakierspejs/long-season/pkg/services/reset"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+var (
+	// ErrDisabled is returned when there is no mailer
+	// configured.
+	ErrDisabled = errors.New("email: disabled")
+
+	// ErrTooManyRequests is returned, when password reset
+	// was requested for the same address or by the same
+	// client too recently.
+	ErrTooManyRequests = errors.New("email: too many requests")
+
+	// ErrInvalidLink is returned for forged or expired
+	// verification links and for links sent to address,
+	// that user has changed since.
+	ErrInvalidLink = errors.New("email: invalid link")
+)
+
+// KeyName is name of key used for signing verification
+// links in storage.Keys.
+const KeyName = "email::links"
+
+// keySize is number of random bytes in signing key.
+const keySize = 32
+
+// DefaultTTL is time-to-live of verification links used,
+// when Addresses has no TTL set.
+const DefaultTTL = 24 * time.Hour
+
+// DefaultCooldown is minimal time between password reset
+// requests used, when Addresses has no Cooldown set.
+const DefaultCooldown = 5 * time.Minute
+
+// LoadKey returns key for signing verification links.
+// Key is generated and saved on first use.
+func LoadKey(ctx context.Context, keys storage.Keys) ([]byte, error) {
+	key, err := keys.Read(ctx, KeyName)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, serrors.ErrNoID) {
+		return nil, fmt.Errorf("keys.Read: %w", err)
+	}
+
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	if err := keys.Save(ctx, KeyName, key); err != nil {
+		return nil, fmt.Errorf("keys.Save: %w", err)
+	}
+
+	return key, nil
+}
+
+// Addresses sets and verifies email addresses of users and
+// sends them password reset links.
+type Addresses struct {
+	Users    storage.Users
+	Resetter *reset.Resetter
+
+	// Mailer sends emails. All operations, that send emails
+	// fail with ErrDisabled, when it is nil.
+	Mailer mailer.Mailer
+
+	// Key signs verification links. Use LoadKey to get
+	// key shared by all instances of long-season.
+	Key []byte
+
+	// URL is public address of long-season, that is
+	// prepended to links sent in emails.
+	URL string
+
+	// TTL is time-to-live of verification links. Defaults
+	// to DefaultTTL.
+	TTL time.Duration
+
+	// Cooldown is minimal time between password reset
+	// requests for the same address or from the same client.
+	// Defaults to DefaultCooldown.
+	Cooldown time.Duration
+
+	// requested contains times of recent password reset
+	// requests by addresses and clients.
+	requested      map[string]time.Time
+	requestedGuard sync.Mutex
+}
+
+// Enabled returns true if emails can be sent.
+func (a *Addresses) Enabled() bool {
+	return a.Mailer != nil
+}
+
+// sign returns signature of given payload.
+func (a *Addresses) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, a.Key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// token returns signed token verifying, that given address
+// belongs to user with given id until given time.
+func (a *Addresses) token(userID, address string, expires time.Time) string {
+	payload := strings.Join([]string{
+		userID, address, strconv.FormatInt(expires.Unix(), 10),
+	}, "\n")
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) +
+		"." + base64.RawURLEncoding.EncodeToString(a.sign(payload))
+}
+
+// taken returns true if given address is verified email
+// address of user other than user with given id.
+func (a *Addresses) taken(ctx context.Context, userID, address string) (bool, error) {
+	entries, err := a.Users.All(ctx)
+	if err != nil {
+		return false, fmt.Errorf("a.Users.All: %w", err)
+	}
+	for _, u := range entries {
+		if u.ID != userID && u.EmailVerified && strings.EqualFold(u.Email, address) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Set changes email address of user with given id and sends
+// verification link to it. Empty address removes email of the
+// user. Returns mailer.ErrInvalidAddress for invalid addresses.
+//
+// Set behaves the same way for addresses verified by other
+// users, so it doesn't reveal, whether address is known or
+// not, but such addresses can't be verified again.
+func (a *Addresses) Set(ctx context.Context, userID, address string) error {
+	if address == "" {
+		err := a.Users.Update(ctx, userID, func(u *storage.UserEntry) error {
+			u.Email = ""
+			u.EmailVerified = false
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("a.Users.Update: %w", err)
+		}
+		return nil
+	}
+
+	if !a.Enabled() {
+		return ErrDisabled
+	}
+
+	address, err := mailer.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	err = a.Users.Update(ctx, userID, func(u *storage.UserEntry) error {
+		changed = u.Email != address
+		if changed {
+			u.Email = address
+			u.EmailVerified = false
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("a.Users.Update: %w", err)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return a.SendVerification(ctx, userID)
+}
+
+// SendVerification sends verification link to email
+// address of user with given id. When address is already
+// verified by other user, its owner is notified instead.
+func (a *Addresses) SendVerification(ctx context.Context, userID string) error {
+	if !a.Enabled() {
+		return ErrDisabled
+	}
+
+	u, err := a.Users.Read(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("a.Users.Read: %w", err)
+	}
+	if u.Email == "" {
+		return fmt.Errorf("user id=%s has no email address: %w", userID, serrors.ErrNoID)
+	}
+
+	taken, err := a.taken(ctx, u.ID, u.Email)
+	if err != nil {
+		return err
+	}
+	if taken {
+		body := fmt.Sprintf(
+			"Hi,\n\nThis address was added to account %s at %s, but it is already confirmed "+
+				"by another account, so it can't be used. If you didn't add it, ignore this email.\n",
+			u.Nickname, a.URL,
+		)
+		if err := a.Mailer.Send(ctx, u.Email, "Email address already in use", body); err != nil {
+			return fmt.Errorf("a.Mailer.Send: %w", err)
+		}
+		return nil
+	}
+
+	ttl := a.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	expires := time.Now().Add(ttl)
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nOpen the link below to confirm your email address:\n\n%s/verify#%s\n\n"+
+			"The link expires at %s. If you didn't add this address, ignore this email.\n",
+		u.Nickname, a.URL, a.token(u.ID, u.Email, expires), expires.Format(time.RFC1123),
+	)
+
+	if err := a.Mailer.Send(ctx, u.Email, "Confirm your email address", body); err != nil {
+		return fmt.Errorf("a.Mailer.Send: %w", err)
+	}
+
+	return nil
+}
+
+// Verify marks email address as verified with token from
+// verification link. Returns id of user owning the address.
+func (a *Addresses) Verify(ctx context.Context, token string) (string, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidLink
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidLink
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", ErrInvalidLink
+	}
+	if !hmac.Equal(mac, a.sign(string(payload))) {
+		return "", ErrInvalidLink
+	}
+
+	fields := strings.Split(string(payload), "\n")
+	if len(fields) != 3 {
+		return "", ErrInvalidLink
+	}
+	userID, address := fields[0], fields[1]
+
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || !time.Now().Before(time.Unix(expires, 0)) {
+		return "", ErrInvalidLink
+	}
+
+	// Address could be verified by other user after
+	// sending the link.
+	taken, err := a.taken(ctx, userID, address)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrInvalidLink
+	}
+
+	err = a.Users.Update(ctx, userID, func(u *storage.UserEntry) error {
+		if u.Email != address {
+			return ErrInvalidLink
+		}
+		u.EmailVerified = true
+		return nil
+	})
+	if errors.Is(err, serrors.ErrNoID) {
+		return "", fmt.Errorf("%w: a.Users.Update: %s", ErrInvalidLink, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("a.Users.Update: %w", err)
+	}
+
+	return userID, nil
+}
+
+// AllowReset records password reset request for given address
+// sent by given client. Returns ErrTooManyRequests, when reset
+// was requested for the same address or by the same client
+// within cooldown period. Requests for unknown addresses are
+// limited too, so limits don't reveal, which addresses are known.
+func (a *Addresses) AllowReset(address, client string) error {
+	cooldown := a.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	now := time.Now()
+	keys := []string{"address:" + strings.ToLower(address), "client:" + client}
+
+	a.requestedGuard.Lock()
+	defer a.requestedGuard.Unlock()
+
+	if a.requested == nil {
+		a.requested = map[string]time.Time{}
+	}
+	for key, t := range a.requested {
+		if !now.Before(t.Add(cooldown)) {
+			delete(a.requested, key)
+		}
+	}
+
+	for _, key := range keys {
+		if _, ok := a.requested[key]; ok {
+			return ErrTooManyRequests
+		}
+	}
+	for _, key := range keys {
+		a.requested[key] = now
+	}
+
+	return nil
+}
+
+// RequestReset sends password reset link to given address,
+// if it is verified email address of any user. Otherwise it
+// does nothing, so it doesn't reveal, whether address is
+// known or not.
+func (a *Addresses) RequestReset(ctx context.Context, address string) error {
+	if !a.Enabled() {
+		return ErrDisabled
+	}
+
+	entries, err := a.Users.All(ctx)
+	if err != nil {
+		return fmt.Errorf("a.Users.All: %w", err)
+	}
+
+	var user *storage.UserEntry
+	for i, u := range entries {
+		if u.EmailVerified && !u.Pending && strings.EqualFold(u.Email, address) {
+			user = &entries[i]
+			break
+		}
+	}
+	if user == nil {
+		return nil
+	}
+
+	token, t, err := a.Resetter.Request(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("a.Resetter.Request: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"Hi %s,\n\nOpen the link below to set new password:\n\n%s/reset#%s\n\n"+
+			"The link can be used once until %s. If you didn't ask for it, ignore this email.\n",
+		user.Nickname, a.URL, token, t.Expires.Format(time.RFC1123),
+	)
+
+	if err := a.Mailer.Send(ctx, user.Email, "Password reset", body); err != nil {
+		return fmt.Errorf("a.Mailer.Send: %w", err)
+	}
+
+	return nil
+}
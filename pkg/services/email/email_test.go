@@ -0,0 +1,157 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/services/mailer"
+	"github.com/hakierspejs/long-season/pkg/services/reset"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+// outbox is mailer.Mailer, that keeps sent emails.
+type outbox struct {
+	to    []string
+	links []string
+}
+
+var linkRegex = regexp.MustCompile(`https://ls\.example\.com/(verify|reset)#(\S+)`)
+
+func (o *outbox) Send(ctx context.Context, to, subject, body string) error {
+	link := ""
+	if match := linkRegex.FindStringSubmatch(body); match != nil {
+		link = match[2]
+	}
+	o.to = append(o.to, to)
+	o.links = append(o.links, link)
+	return nil
+}
+
+func (o *outbox) last() string {
+	return o.links[len(o.links)-1]
+}
+
+func TestAddresses(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice"},
+		{ID: "2", Nickname: "bob"},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	key, err := LoadKey(ctx, f.Keys())
+	is.NoErr(err)
+	again, err := LoadKey(ctx, f.Keys())
+	is.NoErr(err)
+	is.Equal(key, again)
+
+	mails := &outbox{}
+	a := &Addresses{
+		Users: f.Users(),
+		Resetter: &reset.Resetter{
+			Tokens:    f.ResetTokens(),
+			Users:     f.Users(),
+			Audit:     f.Audit(),
+			APITokens: f.APITokens(),
+		},
+		Key: key,
+		URL: "https://ls.example.com",
+	}
+
+	is.True(errors.Is(a.Set(ctx, "1", "alice@example.com"), ErrDisabled))
+	a.Mailer = mails
+
+	is.True(errors.Is(a.Set(ctx, "1", "alice"), mailer.ErrInvalidAddress))
+	is.NoErr(a.Set(ctx, "1", "alice@example.com"))
+	is.Equal(mails.to, []string{"alice@example.com"})
+	aliceLink := mails.last()
+
+	// Unverified addresses don't block other users.
+	is.NoErr(a.Set(ctx, "2", "Alice@example.com"))
+	is.Equal(len(mails.to), 2)
+	bobLink := mails.last()
+
+	// Password reset is sent only to verified addresses.
+	is.NoErr(a.RequestReset(ctx, "alice@example.com"))
+	is.Equal(len(mails.to), 2)
+
+	_, err = a.Verify(ctx, aliceLink+"x")
+	is.True(errors.Is(err, ErrInvalidLink))
+
+	id, err := a.Verify(ctx, aliceLink)
+	is.NoErr(err)
+	is.Equal(id, "1")
+
+	alice, err := f.Users().Read(ctx, "1")
+	is.NoErr(err)
+	is.True(alice.EmailVerified)
+
+	// Verified addresses can't be verified by anyone else.
+	_, err = a.Verify(ctx, bobLink)
+	is.True(errors.Is(err, ErrInvalidLink))
+
+	// Setting them looks the same way, but owner of the
+	// address gets notice instead of verification link.
+	is.NoErr(a.Set(ctx, "2", "bob@example.com"))
+	is.NoErr(a.Set(ctx, "2", "ALICE@example.com"))
+	is.Equal(len(mails.to), 4)
+	is.Equal(mails.to[3], "ALICE@example.com")
+	is.Equal(mails.last(), "")
+
+	is.NoErr(a.RequestReset(ctx, "ALICE@example.com"))
+	is.Equal(len(mails.to), 5)
+	is.Equal(mails.to[4], "alice@example.com")
+	_, err = a.Resetter.Lookup(ctx, mails.last())
+	is.NoErr(err)
+
+	is.NoErr(a.RequestReset(ctx, "nobody@example.com"))
+	is.Equal(len(mails.to), 5)
+
+	// Changing address invalidates links sent before.
+	is.NoErr(a.SendVerification(ctx, "1"))
+	link := mails.last()
+	is.NoErr(a.Set(ctx, "1", "alice@example.org"))
+	_, err = a.Verify(ctx, link)
+	is.True(errors.Is(err, ErrInvalidLink))
+
+	alice, err = f.Users().Read(ctx, "1")
+	is.NoErr(err)
+	is.Equal(alice.Email, "alice@example.org")
+	is.True(!alice.EmailVerified)
+
+	is.NoErr(a.Set(ctx, "1", ""))
+	alice, err = f.Users().Read(ctx, "1")
+	is.NoErr(err)
+	is.Equal(alice.Email, "")
+}
+
+func TestAllowReset(t *testing.T) {
+	is := is.New(t)
+
+	a := &Addresses{Cooldown: 50 * time.Millisecond}
+
+	is.NoErr(a.AllowReset("alice@example.com", "10.0.0.1"))
+
+	// Both address and client are limited.
+	is.True(errors.Is(a.AllowReset("ALICE@example.com", "10.0.0.2"), ErrTooManyRequests))
+	is.True(errors.Is(a.AllowReset("bob@example.com", "10.0.0.1"), ErrTooManyRequests))
+	is.NoErr(a.AllowReset("bob@example.com", "10.0.0.2"))
+
+	time.Sleep(60 * time.Millisecond)
+	is.NoErr(a.AllowReset("alice@example.com", "10.0.0.1"))
+}
@@ -3,28 +3,63 @@
 // Exim is shortcut for "ex"port and "im"port.
 package exim
 
-import "github.com/hakierspejs/long-season/pkg/models/set"
+import (
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/models/set"
+)
 
 // Data holds whole dump from storage.
 type Data struct {
 	Users map[string]User `json:"users"`
+
+	// LookupKey is key of lookup hashes of hardware
+	// addresses. Lookup hashes of devices are imported
+	// only with the same key.
+	LookupKey []byte `json:"lookupKey,omitempty"`
 }
 
 // User holds information about user and
 // its devices.
 type User struct {
-	ID        string     `json:"id"`
-	Nickname  string     `json:"nickname"`
-	Password  []byte     `json:"password"`
-	Devices   []Device   `json:"devices"`
-	TwoFactor *TwoFactor `json:"twoFactor,omitempty"`
+	ID            string      `json:"id"`
+	Nickname      string      `json:"nickname"`
+	Password      []byte      `json:"password"`
+	Role          models.Role `json:"role,omitempty"`
+	Pending       bool        `json:"pending,omitempty"`
+	Email         string      `json:"email,omitempty"`
+	EmailVerified bool        `json:"emailVerified,omitempty"`
+	Devices       []Device    `json:"devices"`
+	TwoFactor     *TwoFactor  `json:"twoFactor,omitempty"`
 }
 
 // Device represents single users device.
 type Device struct {
-	ID  string `json:"id"`
-	Tag string `json:"tag"`
-	MAC []byte `json:"mac"`
+	ID             string   `json:"id"`
+	Tag            string   `json:"tag"`
+	MAC            []byte   `json:"mac"`
+	MACs           [][]byte `json:"macs,omitempty"`
+	Lookups        [][]byte `json:"lookups,omitempty"`
+	Hostname       string   `json:"hostname,omitempty"`
+	ClientID       []byte   `json:"clientId,omitempty"`
+	ClientIDLookup []byte   `json:"clientIdLookup,omitempty"`
+}
+
+// model returns device model owned by given user.
+func (d Device) model(owner User) models.Device {
+	return models.Device{
+		DevicePublicData: models.DevicePublicData{
+			ID:    d.ID,
+			Tag:   d.Tag,
+			Owner: owner.Nickname,
+		},
+		OwnerID:        owner.ID,
+		MAC:            d.MAC,
+		MACs:           d.MACs,
+		Lookups:        d.Lookups,
+		Hostname:       d.Hostname,
+		ClientID:       d.ClientID,
+		ClientIDLookup: d.ClientIDLookup,
+	}
 }
 
 // TwoFactor holds storage data for two factor methods.
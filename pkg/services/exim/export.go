@@ -2,9 +2,12 @@ package exim
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/hakierspejs/long-season/pkg/services/devices"
 	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
 )
 
 // ExportRequest holds dependencies for Export procedure.
@@ -12,6 +15,10 @@ type ExportRequest struct {
 	UsersStorage     storage.Users
 	DevicesStorage   storage.Devices
 	TwoFactorStorage storage.TwoFactor
+
+	// Keys holds key of lookup hashes of hardware
+	// addresses, which is exported with devices.
+	Keys storage.Keys
 }
 
 // Export all data from storage to the single data structure.
@@ -27,10 +34,14 @@ func Export(ctx context.Context, req ExportRequest) (*Data, error) {
 
 	for _, u := range users {
 		res.Users[u.ID] = User{
-			ID:       u.ID,
-			Nickname: u.Nickname,
-			Password: u.HashedPassword,
-			Devices:  []Device{},
+			ID:            u.ID,
+			Nickname:      u.Nickname,
+			Password:      u.HashedPassword,
+			Role:          u.Role,
+			Pending:       u.Pending,
+			Email:         u.Email,
+			EmailVerified: u.EmailVerified,
+			Devices:       []Device{},
 			TwoFactor: &TwoFactor{
 				OneTimeCodes:  []OneTimeCode{},
 				RecoveryCodes: []Recovery{},
@@ -38,12 +49,18 @@ func Export(ctx context.Context, req ExportRequest) (*Data, error) {
 		}
 	}
 
-	devices, err := req.DevicesStorage.All(ctx)
+	lookupKey, err := req.Keys.Read(ctx, devices.KeyName)
+	if err != nil && !errors.Is(err, serrors.ErrNoID) {
+		return nil, fmt.Errorf("req.Keys.Read: %w", err)
+	}
+	res.LookupKey = lookupKey
+
+	stored, err := req.DevicesStorage.All(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("req.DevicesStorage.All: %w", err)
 	}
 
-	for _, d := range devices {
+	for _, d := range stored {
 		currUser, ok := res.Users[d.OwnerID]
 		if !ok {
 			// There is no user with such user ID.
@@ -58,9 +75,14 @@ func Export(ctx context.Context, req ExportRequest) (*Data, error) {
 		}
 
 		currUser.Devices = append(currUser.Devices, Device{
-			ID:  d.ID,
-			Tag: d.Tag,
-			MAC: d.MAC,
+			ID:             d.ID,
+			Tag:            d.Tag,
+			MAC:            d.MAC,
+			MACs:           d.MACs,
+			Lookups:        d.Lookups,
+			Hostname:       d.Hostname,
+			ClientID:       d.ClientID,
+			ClientIDLookup: d.ClientIDLookup,
 		})
 		res.Users[d.OwnerID] = currUser
 	}
@@ -1,11 +1,15 @@
 package exim
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/devices"
 	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
 )
 
 // ImportRequest holds dependencies for Import procedure.
@@ -14,31 +18,62 @@ type ImportRequest struct {
 	UsersStorage     storage.Users
 	DevicesStorage   storage.Devices
 	TwoFactorStorage storage.TwoFactor
+
+	// Keys holds key of lookup hashes of hardware addresses.
+	// Key from dump is saved, if there is no key yet.
+	Keys storage.Keys
 }
 
 // Import parsed database dump into database storage.
+//
+// Import fails with serrors.ErrMACDuplication, serrors.ErrHostnameTaken
+// or serrors.ErrClientIDTaken before storing anything, if dump contains
+// device with hardware address, hostname or client id equal to the one
+// of other device from dump or storage. Addresses and client ids are
+// compared by their lookup hashes, so lookup hashes from dump are kept
+// only if dump has the same lookup key as storage. Otherwise they are
+// dropped and restored, when scanners report imported devices.
 func Import(ctx context.Context, req ImportRequest) error {
+	key, err := req.Keys.Read(ctx, devices.KeyName)
+	if err != nil && !errors.Is(err, serrors.ErrNoID) {
+		return fmt.Errorf("req.Keys.Read: %w", err)
+	}
+
+	saveKey := key == nil && req.Dump.LookupKey != nil
+	if saveKey {
+		key = req.Dump.LookupKey
+	}
+	if req.Dump.LookupKey == nil || !bytes.Equal(req.Dump.LookupKey, key) {
+		req.Dump = withoutLookups(req.Dump)
+	}
+
+	if err := checkDuplicates(ctx, req); err != nil {
+		return fmt.Errorf("checkDuplicates: %w", err)
+	}
+
+	if saveKey {
+		if err := req.Keys.Save(ctx, devices.KeyName, key); err != nil {
+			return fmt.Errorf("req.Keys.Save: %w", err)
+		}
+	}
+
 	for _, user := range req.Dump.Users {
 		_, err := req.UsersStorage.New(ctx, storage.UserEntry{
 			ID:             user.ID,
 			Nickname:       user.Nickname,
 			HashedPassword: user.Password,
 			Private:        false,
+			Role:           user.Role,
+			Pending:        user.Pending,
+			Email:          user.Email,
+			EmailVerified:  user.EmailVerified,
 		})
 		if err != nil {
 			return fmt.Errorf("req.UsersStorage.New: %w", err)
 		}
 
 		for _, device := range user.Devices {
-			_, err = req.DevicesStorage.New(ctx, user.ID, models.Device{
-				DevicePublicData: models.DevicePublicData{
-					ID:    device.ID,
-					Tag:   device.Tag,
-					Owner: user.Nickname,
-				},
-				OwnerID: user.ID,
-				MAC:     device.MAC,
-			})
+			_, err = req.DevicesStorage.New(ctx, user.ID, device.model(user))
 			if err != nil {
 				return fmt.Errorf("req.DevicesStorage.New: %w", err)
 			}
@@ -77,3 +112,73 @@ func Import(ctx context.Context, req ImportRequest) error {
 
 	return nil
 }
+
+// withoutLookups returns copy of given dump without lookup
+// hashes of devices.
+func withoutLookups(dump Data) Data {
+	res := Data{
+		Users: make(map[string]User, len(dump.Users)),
+	}
+
+	for id, user := range dump.Users {
+		stripped := make([]Device, len(user.Devices))
+		for i, device := range user.Devices {
+			device.Lookups = nil
+			device.ClientIDLookup = nil
+			stripped[i] = device
+		}
+		user.Devices = stripped
+		res.Users[id] = user
+	}
+
+	return res
+}
+
+// checkDuplicates looks for devices with the same lookup hash of
+// hardware address or client id, or the same hostname within given
+// dump and between dump and devices already stored in database.
+//
+// Devices without lookup hashes can't be compared. They are listed
+// by collisions report until scanners report their addresses.
+func checkDuplicates(ctx context.Context, req ImportRequest) error {
+	stored, err := req.DevicesStorage.All(ctx)
+	if err != nil {
+		return fmt.Errorf("req.DevicesStorage.All: %w", err)
+	}
+
+	for _, user := range req.Dump.Users {
+		for _, device := range user.Devices {
+			current := device.model(user)
+
+			if !storage.UniqueLookups(current) {
+				return fmt.Errorf(
+					"device id=%s of user id=%s: %w",
+					current.ID, current.OwnerID, serrors.ErrMACDuplication,
+				)
+			}
+
+			for _, other := range stored {
+				var err error
+				switch {
+				case storage.SharedLookup(current, other):
+					err = serrors.ErrMACDuplication
+				case storage.MatchHostname(current.Hostname, other.Hostname):
+					err = serrors.ErrHostnameTaken
+				case len(current.ClientIDLookup) > 0 &&
+					bytes.Equal(current.ClientIDLookup, other.ClientIDLookup):
+					err = serrors.ErrClientIDTaken
+				}
+				if err != nil {
+					return fmt.Errorf(
+						"device id=%s of user id=%s and device id=%s of user id=%s: %w",
+						current.ID, current.OwnerID, other.ID, other.OwnerID, err,
+					)
+				}
+			}
+
+			stored = append(stored, current)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package exim
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/services/devices"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+// instance returns storage with single user, who registered
+// device with given address, and key of lookup hashes. Key is
+// copied from given storage, if it is not nil.
+func instance(t *testing.T, keyFrom storage.Factory, id, nickname, mac string) (storage.Factory, []byte) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	t.Cleanup(func() { closer() })
+
+	if keyFrom != nil {
+		key, err := keyFrom.Keys().Read(ctx, devices.KeyName)
+		is.NoErr(err)
+		is.NoErr(f.Keys().Save(ctx, devices.KeyName, key))
+	}
+
+	key, err := devices.LoadKey(ctx, f.Keys())
+	is.NoErr(err)
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             id,
+		Nickname:       nickname,
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+		Email:          nickname + "@example.com",
+	})
+	is.NoErr(err)
+
+	_, err = devices.Add(ctx, devices.AddDeviceRequest{
+		OwnerID: id,
+		Owner:   nickname,
+		Tag:     "phone",
+		MAC:     mac,
+		Key:     key,
+		Storage: f.Devices(),
+	})
+	is.NoErr(err)
+
+	return f, key
+}
+
+func export(t *testing.T, f storage.Factory) Data {
+	is := is.New(t)
+
+	dump, err := Export(context.Background(), ExportRequest{
+		UsersStorage:     f.Users(),
+		DevicesStorage:   f.Devices(),
+		TwoFactorStorage: f.TwoFactor(),
+		Keys:             f.Keys(),
+	})
+	is.NoErr(err)
+
+	return *dump
+}
+
+func importRequest(dump Data, f storage.Factory) ImportRequest {
+	return ImportRequest{
+		Dump:             dump,
+		UsersStorage:     f.Users(),
+		DevicesStorage:   f.Devices(),
+		TwoFactorStorage: f.TwoFactor(),
+		Keys:             f.Keys(),
+	}
+}
+
+func TestImportDuplicatedMACs(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	johnny, key := instance(t, nil, "1", "johnny", "11:11:11:11:11:11")
+
+	// Instances share key, so their dumps can be merged.
+	marco, _ := instance(t, johnny, "2", "marco", "11:11:11:11:11:11")
+
+	target, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	// Key of the first import is taken from dump.
+	is.NoErr(Import(ctx, importRequest(export(t, johnny), target)))
+
+	stored, err := target.Keys().Read(ctx, devices.KeyName)
+	is.NoErr(err)
+	is.True(bytes.Equal(stored, key))
+
+	all, err := target.Devices().All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), 1)
+	is.True(storage.Verified(all[0]))
+
+	// Marco registered the same address as johnny.
+	err = Import(ctx, importRequest(export(t, marco), target))
+	is.True(errors.Is(err, serrors.ErrMACDuplication))
+
+	// Nothing should be stored after failed import.
+	users, err := target.Users().All(ctx)
+	is.NoErr(err)
+	is.Equal(len(users), 1)
+
+	// Importing the same dump for the second time collides
+	// with already stored devices.
+	err = Import(ctx, importRequest(export(t, johnny), target))
+	is.True(errors.Is(err, serrors.ErrMACDuplication))
+}
+
+func TestImportOtherKey(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	johnny, key := instance(t, nil, "1", "johnny", "11:11:11:11:11:11")
+	marco, _ := instance(t, nil, "2", "marco", "11:11:11:11:11:11")
+
+	is.NoErr(Import(ctx, importRequest(export(t, marco), johnny)))
+
+	// Lookup hashes made with other key are dropped, so
+	// imported device can't be compared with others yet.
+	stored, err := johnny.Keys().Read(ctx, devices.KeyName)
+	is.NoErr(err)
+	is.True(bytes.Equal(stored, key))
+
+	imported, err := johnny.Devices().OfUser(ctx, "2")
+	is.NoErr(err)
+	is.Equal(len(imported), 1)
+	is.Equal(len(imported[0].Lookups), 0)
+	is.True(!storage.Verified(imported[0]))
+
+	user, err := johnny.Users().Read(ctx, "2")
+	is.NoErr(err)
+	is.Equal(user.Email, "marco@example.com")
+}
@@ -0,0 +1,1070 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/exim"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/ignored"
+	"github.com/hakierspejs/long-season/pkg/services/registration"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/services/scanners"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/services/toussaint"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// AdminArgs holds dependencies for admin handlers. Make sure
+// to allow only users with models.ManageUsers permission
+// before mounting any of them to some mux or router.
+type AdminArgs struct {
+	Config    models.Config
+	Users     storage.Users
+	Devices   storage.Devices
+	TwoFactor storage.TwoFactor
+	Scanners  storage.Scanners
+	Ignored   storage.Ignored
+	APITokens storage.APITokens
+	Queue     *scanners.Queue
+	Invites   storage.Invites
+	Audit     storage.Audit
+	Sessions  *session.Store
+	Keys      storage.Keys
+
+	Registration *registration.Registration
+}
+
+// adminUser is user as seen by admins.
+type adminUser struct {
+	ID            string      `json:"id"`
+	Nickname      string      `json:"nickname"`
+	Role          models.Role `json:"role"`
+	Private       bool        `json:"priv"`
+	Followable    bool        `json:"followable"`
+	TwoFactor     bool        `json:"twoFactor"`
+	Devices       int         `json:"devices"`
+	Pending       bool        `json:"pending"`
+	Email         string      `json:"email,omitempty"`
+	EmailVerified bool        `json:"emailVerified"`
+}
+
+func (a AdminArgs) adminUser(r *http.Request, u storage.UserEntry) (*adminUser, error) {
+	ctx := r.Context()
+
+	tf, err := a.TwoFactor.Get(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("a.TwoFactor.Get: %w", err)
+	}
+
+	devices, err := a.Devices.OfUser(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("a.Devices.OfUser: %w", err)
+	}
+
+	return &adminUser{
+		ID:            u.ID,
+		Nickname:      u.Nickname,
+		Role:          u.Role,
+		Private:       u.Private,
+		Followable:    !u.Unfollowable,
+		TwoFactor:     toussaint.IsTwoFactorEnabled(*tf),
+		Devices:       len(devices),
+		Pending:       u.Pending,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+	}, nil
+}
+
+// adminUserError returns http error for error of operation
+// on user with given id.
+func adminUserError(r *http.Request, id string, err error) error {
+	errFactory := happier.FromRequest(r)
+	switch {
+	case errors.Is(err, serrors.ErrNoID):
+		return errFactory.NotFound(err, fmt.Sprintf("there is no user with id: %s", id))
+	case errors.Is(err, serrors.ErrNicknameTaken):
+		return errFactory.Conflict(err, "Given username is already taken.")
+	case errors.Is(err, users.ErrInvalidRole):
+		return errFactory.BadRequest(err, fmt.Sprintf("Invalid input: %s.", users.ErrInvalidRole.Error()))
+	case errors.Is(err, users.ErrInvaliPassword):
+		return errFactory.BadRequest(err, fmt.Sprintf("Invalid input: %s.", users.ErrInvaliPassword.Error()))
+	case errors.Is(err, users.ErrLastAdmin):
+		return errFactory.Conflict(err, "There has to be at least one admin.")
+	default:
+		return errFactory.InternalServerError(err, internalServerErrorResponse)
+	}
+}
+
+// AdminUsers handler responses with list of all users.
+func AdminUsers(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		entries, err := args.Users.All(r.Context())
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("args.Users.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := []adminUser{}
+		for _, u := range entries {
+			user, err := args.adminUser(r, u)
+			if err != nil {
+				return happier.FromRequest(r).InternalServerError(
+					fmt.Errorf("args.adminUser: %w", err),
+					internalServerErrorResponse,
+				)
+			}
+			res = append(res, *user)
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// AdminUserCreate handler creates user with given nickname,
+// password and role.
+func AdminUserCreate(args AdminArgs) horror.HandlerFunc {
+	type payload struct {
+		Nickname string      `json:"nickname"`
+		Password string      `json:"password"`
+		Role     models.Role `json:"role"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		if err := users.VerifyRegisterData(p.Nickname, p.Password); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("users.VerifyRegisterData: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		if p.Role == "" {
+			p.Role = models.RoleMember
+		}
+		if !p.Role.Valid() {
+			return adminUserError(r, "", fmt.Errorf("%w: %s", users.ErrInvalidRole, p.Role))
+		}
+
+		id, err := users.Add(ctx, users.AddUserRequest{
+			Nickname: p.Nickname,
+			Password: []byte(p.Password),
+			Role:     p.Role,
+			Storage:  args.Users,
+		})
+		if err != nil {
+			return fmt.Errorf("users.Add: %w", err)
+		}
+
+		return happier.Created(w, r, &adminUser{
+			ID:         id,
+			Nickname:   p.Nickname,
+			Role:       p.Role,
+			Followable: true,
+		})
+	}
+}
+
+// AdminUser handler responses with single user.
+func AdminUser(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		id, err := requests.UserID(r)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		entry, err := args.Users.Read(r.Context(), id)
+		if err != nil {
+			return adminUserError(r, id, fmt.Errorf("args.Users.Read: %w", err))
+		}
+
+		res, err := args.adminUser(r, *entry)
+		if err != nil {
+			return adminUserError(r, id, fmt.Errorf("args.adminUser: %w", err))
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// AdminUserUpdate handler changes nickname, role or privacy
+// settings of given user. Omitted fields stay untouched.
+func AdminUserUpdate(args AdminArgs) horror.HandlerFunc {
+	type payload struct {
+		Nickname   *string      `json:"nickname,omitempty"`
+		Role       *models.Role `json:"role,omitempty"`
+		Private    *bool        `json:"priv,omitempty"`
+		Followable *bool        `json:"followable,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		id, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		if p.Nickname != nil && !users.VerifyNickname(*p.Nickname) {
+			return errFactory.BadRequest(
+				users.ErrInvalidNickname,
+				fmt.Sprintf("Invalid input: %s.", users.ErrInvalidNickname.Error()),
+			)
+		}
+
+		if p.Nickname != nil {
+			entries, err := args.Users.All(ctx)
+			if err != nil {
+				return errFactory.InternalServerError(
+					fmt.Errorf("args.Users.All: %w", err),
+					internalServerErrorResponse,
+				)
+			}
+			for _, u := range entries {
+				if u.ID != id && u.Nickname == *p.Nickname {
+					return adminUserError(r, id, serrors.ErrNicknameTaken)
+				}
+			}
+		}
+
+		if p.Role != nil && !p.Role.Valid() {
+			return adminUserError(r, id, fmt.Errorf("%w: %s", users.ErrInvalidRole, *p.Role))
+		}
+
+		// All changes are applied at once, so invalid input
+		// or the last admin guard in storage reject them all.
+		err = args.Users.Update(ctx, id, func(u *storage.UserEntry) error {
+			if p.Role != nil {
+				u.Role = *p.Role
+			}
+			if p.Nickname != nil {
+				u.Nickname = *p.Nickname
+			}
+			if p.Private != nil {
+				u.Private = *p.Private
+			}
+			if p.Followable != nil {
+				u.Unfollowable = !*p.Followable
+			}
+			return nil
+		})
+		if err != nil {
+			return adminUserError(r, id, fmt.Errorf("args.Users.Update: %w", err))
+		}
+
+		entry, err := args.Users.Read(ctx, id)
+		if err != nil {
+			return adminUserError(r, id, fmt.Errorf("args.Users.Read: %w", err))
+		}
+
+		res, err := args.adminUser(r, *entry)
+		if err != nil {
+			return adminUserError(r, id, fmt.Errorf("args.adminUser: %w", err))
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// AdminUserRemove handler removes given user. The last admin
+// cannot be removed.
+func AdminUserRemove(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		id, err := requests.UserID(r)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if err := users.Remove(r.Context(), args.Users, id); err != nil {
+			return adminUserError(r, id, fmt.Errorf("users.Remove: %w", err))
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// AdminUserPassword handler sets new password for given user
+// without knowing the old one. All sessions of user are revoked.
+func AdminUserPassword(args AdminArgs) horror.HandlerFunc {
+	type payload struct {
+		Password string `json:"password"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		id, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		if err := users.SetPassword(r.Context(), args.Users, args.APITokens, id, p.Password); err != nil {
+			return adminUserError(r, id, fmt.Errorf("users.SetPassword: %w", err))
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// AdminTwoFactorDisable handler removes all two factor methods
+// of given user, so locked-out users can login with password.
+func AdminTwoFactorDisable(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+
+		id, err := requests.UserID(r)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if _, err := args.Users.Read(ctx, id); err != nil {
+			return adminUserError(r, id, fmt.Errorf("args.Users.Read: %w", err))
+		}
+
+		if err := args.TwoFactor.Remove(ctx, id); err != nil {
+			return adminUserError(r, id, fmt.Errorf("args.TwoFactor.Remove: %w", err))
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// AdminUserLogout handler revokes all sessions and API tokens
+// of given user.
+func AdminUserLogout(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		id, err := requests.UserID(r)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if err := users.RevokeSessions(r.Context(), args.Users, args.APITokens, id); err != nil {
+			return adminUserError(r, id, fmt.Errorf("users.RevokeSessions: %w", err))
+		}
+
+		if err := args.Sessions.RevokeAll(r.Context(), id, ""); err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("args.Sessions.RevokeAll: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// adminDevice is device as seen by admins.
+type adminDevice struct {
+	singleDevice
+	Owner   string `json:"owner"`
+	OwnerID string `json:"ownerId"`
+}
+
+func newAdminDevice(d models.Device) *adminDevice {
+	return &adminDevice{
+		singleDevice: *newSingleDevice(d),
+		Owner:        d.Owner,
+		OwnerID:      d.OwnerID,
+	}
+}
+
+// AdminDevices handler responses with devices of all users.
+func AdminDevices(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		devices, err := args.Devices.All(r.Context())
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("args.Devices.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := []adminDevice{}
+		for _, d := range devices {
+			res = append(res, *newAdminDevice(d))
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// adminDeviceError returns http error for error of operation
+// on device with given id.
+func adminDeviceError(r *http.Request, id string, err error) error {
+	errFactory := happier.FromRequest(r)
+	if errors.Is(err, serrors.ErrNoID) {
+		return errFactory.NotFound(err, fmt.Sprintf("there is no device with given id: %s", id))
+	}
+	return errFactory.InternalServerError(err, internalServerErrorResponse)
+}
+
+// AdminDeviceUpdate handler renames device of any user.
+func AdminDeviceUpdate(args AdminArgs) horror.HandlerFunc {
+	type payload struct {
+		Tag string `json:"tag"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		id, err := requests.DeviceID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.DeviceID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+		if p.Tag == "" {
+			return errFactory.BadRequest(
+				fmt.Errorf("api.AdminDeviceUpdate: empty tag"),
+				"Invalid input: tag cannot be empty.",
+			)
+		}
+
+		err = args.Devices.Update(ctx, id, func(d *models.Device) error {
+			d.Tag = p.Tag
+			return nil
+		})
+		if err != nil {
+			return adminDeviceError(r, id, fmt.Errorf("args.Devices.Update: %w", err))
+		}
+
+		device, err := args.Devices.Read(ctx, id)
+		if err != nil {
+			return adminDeviceError(r, id, fmt.Errorf("args.Devices.Read: %w", err))
+		}
+
+		return happier.OK(w, r, newAdminDevice(*device))
+	}
+}
+
+// AdminDeviceRemove handler removes device of any user.
+func AdminDeviceRemove(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		id, err := requests.DeviceID(r)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("requests.DeviceID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if err := args.Devices.Remove(r.Context(), id); err != nil {
+			return adminDeviceError(r, id, fmt.Errorf("args.Devices.Remove: %w", err))
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// AdminExport handler responses with dump of users, their devices
+// and two factor methods in the same format as
+// "short-season admin export" command.
+func AdminExport(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		dump, err := exim.Export(r.Context(), exim.ExportRequest{
+			UsersStorage:     args.Users,
+			DevicesStorage:   args.Devices,
+			TwoFactorStorage: args.TwoFactor,
+			Keys:             args.Keys,
+		})
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("exim.Export: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, dump)
+	}
+}
+
+// AdminImport handler imports dump created by AdminExport
+// handler or "short-season admin export" command. Import is
+// not atomic, so users imported before the first conflict
+// stay in storage.
+func AdminImport(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		dump := exim.Data{}
+		if err := json.NewDecoder(r.Body).Decode(&dump); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		err := exim.Import(r.Context(), exim.ImportRequest{
+			Dump:             dump,
+			UsersStorage:     args.Users,
+			DevicesStorage:   args.Devices,
+			TwoFactorStorage: args.TwoFactor,
+			Keys:             args.Keys,
+		})
+		switch {
+		case errors.Is(err, serrors.ErrMACDuplication):
+			return errFactory.Conflict(
+				fmt.Errorf("exim.Import: %w", err),
+				"Dump contains device with mac address used by other device.",
+			)
+		case errors.Is(err, serrors.ErrHostnameTaken):
+			return errFactory.Conflict(
+				fmt.Errorf("exim.Import: %w", err),
+				"Dump contains device with hostname used by other device.",
+			)
+		case errors.Is(err, serrors.ErrClientIDTaken):
+			return errFactory.Conflict(
+				fmt.Errorf("exim.Import: %w", err),
+				"Dump contains device with client id used by other device.",
+			)
+		case errors.Is(err, serrors.ErrNicknameTaken):
+			return errFactory.Conflict(
+				fmt.Errorf("exim.Import: %w", err),
+				"Dump contains user with already taken username.",
+			)
+		case err != nil:
+			return errFactory.InternalServerError(
+				fmt.Errorf("exim.Import: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// adminScanner is scanner key as seen by admins. Key is
+// present only in response for creating new key.
+type adminScanner struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Created  time.Time  `json:"created"`
+	LastUsed *time.Time `json:"lastUsed,omitempty"`
+	Key      string     `json:"key,omitempty"`
+}
+
+func newAdminScanner(k models.ScannerKey) *adminScanner {
+	res := &adminScanner{
+		ID:      k.ID,
+		Name:    k.Name,
+		Created: k.Created,
+	}
+	if !k.LastUsed.IsZero() {
+		res.LastUsed = &k.LastUsed
+	}
+	return res
+}
+
+// AdminScanners handler responses with list of scanner keys.
+func AdminScanners(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		keys, err := args.Scanners.All(r.Context())
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("args.Scanners.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := []adminScanner{}
+		for _, k := range keys {
+			res = append(res, *newAdminScanner(k))
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// AdminScannerCreate handler creates key for scanner with
+// given name. Response contains the only copy of the key.
+func AdminScannerCreate(args AdminArgs) horror.HandlerFunc {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+		if p.Name == "" {
+			return errFactory.BadRequest(
+				fmt.Errorf("api.AdminScannerCreate: empty name"),
+				"Invalid input: name cannot be empty.",
+			)
+		}
+
+		token, key, err := scanners.Generate(r.Context(), args.Scanners, p.Name)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("scanners.Generate: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := newAdminScanner(*key)
+		res.Key = token
+
+		return happier.Created(w, r, res)
+	}
+}
+
+// AdminScannerRemove handler removes scanner key, so it can
+// no longer be used for reporting devices and cards.
+func AdminScannerRemove(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		id, err := requests.ScannerID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.ScannerID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		err = args.Scanners.Remove(r.Context(), id)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("args.Scanners.Remove: %w", err),
+				fmt.Sprintf("there is no scanner with given id: %s", id),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Scanners.Remove: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// adminIgnored is ignored address as seen by admins.
+type adminIgnored struct {
+	ID     string `json:"id"`
+	Tag    string `json:"tag"`
+	Prefix string `json:"prefix"`
+}
+
+func newAdminIgnored(a models.IgnoredAddress) *adminIgnored {
+	return &adminIgnored{
+		ID:     a.ID,
+		Tag:    a.Tag,
+		Prefix: a.PrefixString(),
+	}
+}
+
+// AdminIgnored handler responses with list of addresses
+// of infrastructure devices excluded from online statuses.
+func AdminIgnored(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		addresses, err := args.Ignored.All(r.Context())
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("args.Ignored.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := []adminIgnored{}
+		for _, a := range addresses {
+			res = append(res, *newAdminIgnored(a))
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// AdminIgnoredCreate handler starts ignoring given hardware
+// address or prefix of hardware addresses.
+func AdminIgnoredCreate(args AdminArgs) horror.HandlerFunc {
+	type payload struct {
+		Prefix string `json:"prefix"`
+		Tag    string `json:"tag"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		a, err := ignored.Add(r.Context(), args.Ignored, p.Prefix, p.Tag)
+		if errors.Is(err, models.ErrInvalidPrefix) {
+			return errFactory.BadRequest(
+				fmt.Errorf("ignored.Add: %w", err),
+				"Invalid input: prefix should be hardware address or its prefix, for example b8:27:eb.",
+			)
+		}
+		if errors.Is(err, serrors.ErrPrefixDuplication) {
+			return errFactory.Conflict(
+				fmt.Errorf("ignored.Add: %w", err),
+				fmt.Sprintf("Prefix %s is already ignored.", p.Prefix),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("ignored.Add: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.Created(w, r, newAdminIgnored(*a))
+	}
+}
+
+// AdminIgnoredRemove handler stops ignoring address with
+// given id.
+func AdminIgnoredRemove(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		id, err := requests.IgnoredID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.IgnoredID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		err = args.Ignored.Remove(r.Context(), id)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("args.Ignored.Remove: %w", err),
+				fmt.Sprintf("there is no ignored address with given id: %s", id),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Ignored.Remove: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// AdminStatus handler responses with status of the instance.
+func AdminStatus(args AdminArgs) horror.HandlerFunc {
+	type response struct {
+		Storage  string     `json:"storage"`
+		LastScan *time.Time `json:"lastScan,omitempty"`
+		Queue    int        `json:"queue"`
+		Users    int        `json:"users"`
+		Devices  int        `json:"devices"`
+		Scanners int        `json:"scanners"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		entries, err := args.Users.All(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Users.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		devices, err := args.Devices.All(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Devices.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		keys, err := args.Scanners.All(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Scanners.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := &response{
+			Storage:  args.Config.DatabaseType,
+			Queue:    args.Queue.Pending(),
+			Users:    len(entries),
+			Devices:  len(devices),
+			Scanners: len(keys),
+		}
+		if last := args.Queue.LastScan(); !last.IsZero() {
+			res.LastScan = &last
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// AdminUserApprove handler allows pending user to log in.
+func AdminUserApprove(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		id, err := requests.UserID(r)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if err := args.Registration.Approve(r.Context(), id); err != nil {
+			return adminUserError(r, id, fmt.Errorf("args.Registration.Approve: %w", err))
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// AdminRegistration handler responses with current
+// registration mode.
+func AdminRegistration(args AdminArgs) horror.HandlerFunc {
+	return RegistrationMode(args.Registration)
+}
+
+// AdminRegistrationUpdate handler changes registration mode.
+func AdminRegistrationUpdate(args AdminArgs) horror.HandlerFunc {
+	type payload struct {
+		Mode models.RegistrationMode `json:"mode"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		err := args.Registration.SetMode(r.Context(), p.Mode)
+		if errors.Is(err, registration.ErrInvalidMode) {
+			return errFactory.BadRequest(
+				fmt.Errorf("args.Registration.SetMode: %w", err),
+				"Invalid input: mode should be open, closed, invite or approval.",
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Registration.SetMode: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, p)
+	}
+}
+
+// adminInvite is invite as seen by admins.
+type adminInvite struct {
+	singleInvite
+	Creator   string `json:"creator"`
+	CreatorID string `json:"creatorId"`
+}
+
+// AdminInvites handler responses with list of all invites.
+func AdminInvites(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		invites, err := args.Invites.All(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Invites.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		entries, err := args.Users.All(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Users.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		nicknames := map[string]string{}
+		for _, u := range entries {
+			nicknames[u.ID] = u.Nickname
+		}
+
+		now := time.Now()
+		res := []adminInvite{}
+		for _, i := range invites {
+			res = append(res, adminInvite{
+				singleInvite: *newSingleInvite(i, now),
+				Creator:      nicknames[i.CreatorID],
+				CreatorID:    i.CreatorID,
+			})
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// AdminInviteRemove handler removes invite of any user.
+func AdminInviteRemove(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		id, err := requests.InviteID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.InviteID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		err = args.Invites.Remove(r.Context(), id)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("args.Invites.Remove: %w", err),
+				fmt.Sprintf("there is no invite with given id: %s", id),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Invites.Remove: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// adminAuditEntry is entry of audit log as seen by admins.
+type adminAuditEntry struct {
+	models.AuditEntry
+	Actor  string `json:"actor,omitempty"`
+	Target string `json:"target,omitempty"`
+}
+
+// AdminAudit handler responses with audit log entries from
+// range given with "from", "to" and "tz" query parameters,
+// the newest first. Range defaults to last 30 days.
+func AdminAudit(args AdminArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		auditRange, _, err := parseStatsRange(r, args.Config)
+		if err != nil {
+			return err
+		}
+
+		log, err := args.Audit.Between(ctx, auditRange.From, auditRange.To)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Audit.Between: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		entries, err := args.Users.All(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Users.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		nicknames := map[string]string{}
+		for _, u := range entries {
+			nicknames[u.ID] = u.Nickname
+		}
+
+		res := make([]adminAuditEntry, 0, len(log))
+		for i := len(log) - 1; i >= 0; i-- {
+			res = append(res, adminAuditEntry{
+				AuditEntry: log[i],
+				Actor:      nicknames[log[i].ActorID],
+				Target:     nicknames[log[i].TargetID],
+			})
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
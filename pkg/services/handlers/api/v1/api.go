@@ -7,16 +7,22 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/thinkofher/horror"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/badge"
 	"github.com/hakierspejs/long-season/pkg/services/devices"
 	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/registration"
 	"github.com/hakierspejs/long-season/pkg/services/requests"
 	"github.com/hakierspejs/long-season/pkg/services/result"
+	"github.com/hakierspejs/long-season/pkg/services/scanners"
 	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/services/space"
 	"github.com/hakierspejs/long-season/pkg/services/users"
 	"github.com/hakierspejs/long-season/pkg/storage"
 	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
@@ -32,10 +38,18 @@ func conflict(msg string, w http.ResponseWriter) {
 
 const internalServerErrorResponse = "Internal server error. Please try again later."
 
-func UserCreate(db storage.Users) horror.HandlerFunc {
+// UserCreate handler registers new user according to current
+// registration mode.
+func UserCreate(reg *registration.Registration) horror.HandlerFunc {
 	type payload struct {
 		Nickname string `json:"nickname"`
 		Password string `json:"password"`
+		Invite   string `json:"invite"`
+	}
+
+	type response struct {
+		models.UserPublicData
+		Pending bool `json:"pending,omitempty"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) error {
@@ -57,18 +71,33 @@ func UserCreate(db storage.Users) horror.HandlerFunc {
 			)
 		}
 
-		id, err := users.Add(r.Context(), users.AddUserRequest{
+		id, pending, err := reg.Register(r.Context(), registration.Request{
 			Nickname: p.Nickname,
 			Password: []byte(p.Password),
-			Storage:  db,
+			Invite:   p.Invite,
 		})
+		if errors.Is(err, registration.ErrClosed) {
+			return errFactory.Forbidden(
+				fmt.Errorf("reg.Register: %w", err),
+				"Registration is closed.",
+			)
+		}
+		if errors.Is(err, registration.ErrInvalidInvite) {
+			return errFactory.Forbidden(
+				fmt.Errorf("reg.Register: %w", err),
+				"Invite code is invalid, expired or already used.",
+			)
+		}
 		if err != nil {
-			return fmt.Errorf("users.Add: %w", err)
+			return fmt.Errorf("reg.Register: %w", err)
 		}
 
-		return happier.OK(w, r, &models.UserPublicData{
-			ID:       id,
-			Nickname: p.Nickname,
+		return happier.OK(w, r, &response{
+			UserPublicData: models.UserPublicData{
+				ID:       id,
+				Nickname: p.Nickname,
+			},
+			Pending: pending,
 		})
 	}
 }
@@ -85,7 +114,15 @@ func UsersAll(db storage.Users, adapter storage.UserAdapter) horror.HandlerFunc
 			)
 		}
 
-		adaptedData, err := adapter.Users(ctx, data)
+		// Accounts waiting for approval are not shown.
+		approved := make([]storage.UserEntry, 0, len(data))
+		for _, u := range data {
+			if !u.Pending {
+				approved = append(approved, u)
+			}
+		}
+
+		adaptedData, err := adapter.Users(ctx, approved)
 		if err != nil {
 			return happier.FromRequest(r).InternalServerError(
 				fmt.Errorf("adapter.Users: %w", err),
@@ -103,14 +140,38 @@ func UsersAll(db storage.Users, adapter storage.UserAdapter) horror.HandlerFunc
 		}
 
 		filtered := users.Filter(adaptedData, filters...)
-		return happier.OK(w, r, users.PublicSlice(filtered))
+		return happier.OK(w, r, usersList(users.PublicSlice(filtered)))
 	}
 }
 
+// usersList is payload of UsersAll handler. It can be
+// formatted as plain text or CSV too.
+type usersList []models.UserPublicData
+
+// MarshalPlainText returns nicknames of users, one per line.
+func (l usersList) MarshalPlainText() ([]byte, error) {
+	var b strings.Builder
+	for _, u := range l {
+		b.WriteString(u.Nickname)
+		b.WriteString("\n")
+	}
+	return []byte(b.String()), nil
+}
+
+func (l usersList) MarshalCSV() ([][]string, error) {
+	res := [][]string{{"id", "nickname", "online"}}
+	for _, u := range l {
+		res = append(res, []string{u.ID, u.Nickname, strconv.FormatBool(u.Online)})
+	}
+	return res, nil
+}
+
 func UserRead(renewer session.Renewer, db storage.Users, adapter storage.UserAdapter) horror.HandlerFunc {
 	type response struct {
 		models.UserPublicData
-		Private *bool `json:"priv,omitempty"`
+		Private    *bool        `json:"priv,omitempty"`
+		Followable *bool        `json:"followable,omitempty"`
+		Role       *models.Role `json:"role,omitempty"`
 	}
 	return func(w http.ResponseWriter, r *http.Request) error {
 		ctx := r.Context()
@@ -137,11 +198,21 @@ func UserRead(renewer session.Renewer, db storage.Users, adapter storage.UserAda
 				internalServerErrorResponse,
 			)
 		}
+		if user.Pending {
+			return errFactory.NotFound(
+				fmt.Errorf("user with id=%s is pending", id),
+				fmt.Sprintf("there is no user with id: %s", id),
+			)
+		}
 
-		var privateMode *bool = nil
+		var privateMode, followable *bool = nil, nil
+		var role *models.Role = nil
 		state, err := renewer.Renew(r)
 		if err == nil && (state.UserID == user.ID) {
 			privateMode = &user.Private
+			followable = new(bool)
+			*followable = !user.Unfollowable
+			role = &user.Role
 		}
 
 		adapted, err := adapter.User(ctx, *user)
@@ -155,6 +226,8 @@ func UserRead(renewer session.Renewer, db storage.Users, adapter storage.UserAda
 		return happier.OK(w, r, &response{
 			UserPublicData: adapted.UserPublicData,
 			Private:        privateMode,
+			Followable:     followable,
+			Role:           role,
 		})
 	}
 }
@@ -171,18 +244,10 @@ func UserRemove(db storage.Users) horror.HandlerFunc {
 			)
 		}
 
-		err = db.Remove(r.Context(), id)
-		if errors.Is(err, serrors.ErrNoID) {
-			return errFactory.NotFound(
-				fmt.Errorf("db.Remove: %w", err),
-				fmt.Sprintf("there is no user with id: %s", id),
-			)
-		}
-		if err != nil {
-			return errFactory.InternalServerError(
-				fmt.Errorf("db.Remove: %w", err),
-				internalServerErrorResponse,
-			)
+		// Admins can't remove themselves, when they are
+		// the last admin.
+		if err := users.Remove(r.Context(), db, id); err != nil {
+			return adminUserError(r, id, fmt.Errorf("users.Remove: %w", err))
 		}
 
 		w.WriteHeader(http.StatusNoContent)
@@ -193,6 +258,10 @@ func UserRemove(db storage.Users) horror.HandlerFunc {
 func UserUpdate(db storage.Users, onlineUsers storage.OnlineUsers) horror.HandlerFunc {
 	type payload struct {
 		Private *bool `json:"priv,omitempty"`
+
+		// Followable is false for users, that don't want
+		// others to be notified about their arrivals.
+		Followable *bool `json:"followable,omitempty"`
 	}
 
 	type response struct {
@@ -219,7 +288,7 @@ func UserUpdate(db storage.Users, onlineUsers storage.OnlineUsers) horror.Handle
 			)
 		}
 
-		if p.Private == nil {
+		if p.Private == nil && p.Followable == nil {
 			return happier.Created(w, r, struct{}{})
 		}
 
@@ -227,7 +296,12 @@ func UserUpdate(db storage.Users, onlineUsers storage.OnlineUsers) horror.Handle
 		res.payload = *p
 
 		err = db.Update(ctx, userID, func(u *storage.UserEntry) error {
-			u.Private = *p.Private
+			if p.Private != nil {
+				u.Private = *p.Private
+			}
+			if p.Followable != nil {
+				u.Unfollowable = !*p.Followable
+			}
 			res.UserPublicData = models.UserPublicData{
 				ID:       u.ID,
 				Nickname: u.Nickname,
@@ -261,8 +335,9 @@ func UserUpdate(db storage.Users, onlineUsers storage.OnlineUsers) horror.Handle
 }
 
 // UpdateUserPassword updates password of given user after
-// successfully authentication of previous one.
-func UpdateUserPassword(db storage.Users) horror.HandlerFunc {
+// successfully authentication of previous one. Every other
+// session and every API token of the user is revoked.
+func UpdateUserPassword(renewer session.Renewer, db storage.Users, tokens storage.APITokens, store *session.Store) horror.HandlerFunc {
 	type payload struct {
 		Old string `json:"old"`
 		New string `json:"new"`
@@ -329,16 +404,45 @@ func UpdateUserPassword(db storage.Users) horror.HandlerFunc {
 			)
 		}
 
+		state, err := renewer.Renew(r)
+		if err != nil {
+			return errFactory.Unauthorized(
+				fmt.Errorf("renewer.Renew: %w", err),
+				"Invalid session. Please login in.",
+			)
+		}
+
+		if err := store.RevokeAll(ctx, userID, state.ID); err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("store.RevokeAll: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if err := users.RemoveAPITokens(ctx, tokens, userID); err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("users.RemoveAPITokens: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
 		return happier.NoContent(w, r)
 	}
 }
 
 // UpdateStatus updates online field of every user id database
-// with MAC address equal to one from slice provided by
-// user in request payload.
-func UpdateStatus(ch chan<- []net.HardwareAddr) horror.HandlerFunc {
+// with device matching one of hosts or MAC addresses provided
+// by user in request payload.
+func UpdateStatus(queue *scanners.Queue) horror.HandlerFunc {
+	type host struct {
+		MAC      string `json:"mac"`
+		Hostname string `json:"hostname"`
+		ClientID string `json:"clientId"`
+	}
+
 	type payload struct {
 		Addresses []string `json:"addresses"`
+		Hosts     []host   `json:"hosts"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) error {
@@ -353,36 +457,79 @@ func UpdateStatus(ch chan<- []net.HardwareAddr) horror.HandlerFunc {
 			)
 		}
 
-		parsedAddresses := []net.HardwareAddr{}
 		for _, address := range p.Addresses {
-			parsedAddress, err := net.ParseMAC(address)
+			p.Hosts = append(p.Hosts, host{MAC: address})
+		}
+
+		parsedHosts := []models.Host{}
+		for _, h := range p.Hosts {
+			parsedAddress, err := net.ParseMAC(h.MAC)
 			if err != nil {
 				return errFactory.BadRequest(
 					fmt.Errorf("net.ParseMAC: %w", err),
-					fmt.Sprintf("invalid input: invalid mac address %s", address),
+					fmt.Sprintf("invalid input: invalid mac address %s", h.MAC),
 				)
 			}
-			parsedAddresses = append(parsedAddresses, parsedAddress)
+			parsedHosts = append(parsedHosts, models.Host{
+				MAC:      parsedAddress,
+				Hostname: h.Hostname,
+				ClientID: h.ClientID,
+			})
 		}
 
-		// Send parsed addresses to deamon running in the background
-		ch <- parsedAddresses
+		// Send parsed hosts to deamon running in the background
+		queue.Push(parsedHosts)
 
 		return happier.Accepted(w, r)
 	}
 }
 
-func Status(counters storage.StatusTx) horror.HandlerFunc {
-	var response struct {
-		Online  int `json:"online"`
-		Unknown int `json:"unknown"`
+// statusResponse is payload of Status handler. It can be
+// formatted as plain text or CSV too.
+type statusResponse struct {
+	State     models.SpaceState `json:"state"`
+	Override  bool              `json:"override"`
+	Online    int               `json:"online"`
+	Unknown   int               `json:"unknown"`
+	Breakdown map[string]int    `json:"breakdown,omitempty"`
+
+	// users contains nicknames of online users, that
+	// haven't enabled private mode.
+	users []string
+}
+
+// MarshalPlainText returns short status description, for
+// example "open: 4 people (alice, bob)".
+func (s statusResponse) MarshalPlainText() ([]byte, error) {
+	status := badge.Status{State: s.State, Online: s.Online, Users: s.users}
+	text := badge.English.State(status)
+	if status.Occupied() {
+		text += ": " + badge.English.People(s.Online)
+		if len(s.users) > 0 {
+			text += " (" + strings.Join(s.users, ", ") + ")"
+		}
 	}
+	return []byte(text + "\n"), nil
+}
+
+func (s statusResponse) MarshalCSV() ([][]string, error) {
+	return [][]string{
+		{"state", "online", "unknown", "users"},
+		{string(s.State), strconv.Itoa(s.Online), strconv.Itoa(s.Unknown), strings.Join(s.users, " ")},
+	}, nil
+}
 
+// Status handler responses with state of the hackerspace and
+// numbers of online users and unknown devices.
+func Status(counters storage.StatusTx, tracker *space.Tracker, db storage.Users, adapter storage.UserAdapter) horror.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
 		errFactory := happier.FromRequest(r)
+		withBreakdown := r.URL.Query().Get("breakdown") == "true"
 
+		var res statusResponse
 		err := counters.DevicesStatus(
-			r.Context(),
+			ctx,
 			func(ctx context.Context, s storage.Status) error {
 				online, err := s.OnlineUsers(ctx)
 				if err != nil {
@@ -394,8 +541,16 @@ func Status(counters storage.StatusTx) horror.HandlerFunc {
 					return fmt.Errorf("failed to read unknown devices: %w", err)
 				}
 
-				response.Online = online
-				response.Unknown = unknown
+				if withBreakdown {
+					breakdown, err := s.UnknownBreakdown(ctx)
+					if err != nil {
+						return fmt.Errorf("failed to read unknown breakdown: %w", err)
+					}
+					res.Breakdown = breakdown
+				}
+
+				res.Online = online
+				res.Unknown = unknown
 				return nil
 			},
 		)
@@ -406,7 +561,38 @@ func Status(counters storage.StatusTx) horror.HandlerFunc {
 			)
 		}
 
-		return happier.OK(w, r, response)
+		state, err := tracker.State(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("tracker.State: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+		res.State = state.State
+		res.Override = state.Override
+
+		data, err := db.All(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		adaptedData, err := adapter.Users(ctx, data)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("adapter.Users: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		filters := append(users.DefaultFilters(), users.Online)
+		for _, u := range users.PublicSlice(users.Filter(adaptedData, filters...)) {
+			res.users = append(res.users, u.Nickname)
+		}
+
+		return happier.OK(w, r, res)
 	}
 }
 
@@ -437,10 +623,26 @@ func badRequest(msg string, w http.ResponseWriter) {
 type singleDevice struct {
 	ID  string `json:"id"`
 	Tag string `json:"tag"`
+
+	// Addresses is number of additional mac addresses.
+	Addresses int    `json:"addresses,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	ClientID  bool   `json:"clientId,omitempty"`
+}
+
+func newSingleDevice(d models.Device) *singleDevice {
+	return &singleDevice{
+		ID:        d.ID,
+		Tag:       d.Tag,
+		Addresses: len(d.MACs),
+		Hostname:  d.Hostname,
+		ClientID:  len(d.ClientID) > 0,
+	}
 }
 
 // DeviceAdd handles creation of new device for requesting user.
-func DeviceAdd(renewer session.Renewer, db storage.Devices) horror.HandlerFunc {
+// Given key is used for lookup hashes of hardware addresses.
+func DeviceAdd(renewer session.Renewer, db storage.Devices, key []byte) horror.HandlerFunc {
 	type payload struct {
 		Tag string `json:"tag"`
 		MAC string `json:"mac"`
@@ -483,6 +685,7 @@ func DeviceAdd(renewer session.Renewer, db storage.Devices) horror.HandlerFunc {
 			Owner:   state.Nickname,
 			Tag:     p.Tag,
 			MAC:     p.MAC,
+			Key:     key,
 			Storage: db,
 		})
 		if err != nil {
@@ -520,7 +723,7 @@ func UserDevices(db storage.Devices) horror.HandlerFunc {
 
 		result := make([]singleDevice, len(devices), cap(devices))
 		for i, device := range devices {
-			result[i] = singleDevice{device.ID, device.Tag}
+			result[i] = *newSingleDevice(device)
 		}
 
 		return happier.OK(w, r, result)
@@ -587,10 +790,95 @@ func DeviceRead(renewer session.Renewer, db storage.Devices) horror.HandlerFunc
 			)
 		}
 
-		return happier.OK(w, r, &singleDevice{
-			ID:  device.ID,
-			Tag: device.Tag,
+		return happier.OK(w, r, newSingleDevice(*device))
+	}
+}
+
+// DeviceEdit handles changes of alternative identifiers of
+// device owned by requesting user. Given key is used for lookup
+// hashes of hardware addresses.
+func DeviceEdit(renewer session.Renewer, db storage.Devices, key []byte) horror.HandlerFunc {
+	type payload struct {
+		MAC      string  `json:"mac"`
+		Hostname *string `json:"hostname"`
+		ClientID *string `json:"clientId"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		deviceID, err := requests.DeviceID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.DeviceID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		state, err := renewer.Renew(r)
+		if err != nil {
+			// At this point handler should have
+			// been provided with session, so we
+			// will just return 500.
+			return errFactory.InternalServerError(
+				fmt.Errorf("renewer.Renew: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		device, err := db.Read(r.Context(), deviceID)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("db.Read: %w", err),
+				fmt.Sprintf("there is no device with given id: %s", deviceID),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Read: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		// Check if requesting user owns resources.
+		if !sameOwner(userID, device.OwnerID, state.UserID) {
+			return errFactory.NotFound(
+				fmt.Errorf("sameOwner error: userID=%s, deviceOwnerID=%s, stateUserID=%s",
+					userID, device.OwnerID, state.UserID),
+				fmt.Sprintf("you don't have device with id=%s", deviceID),
+			)
+		}
+
+		p := new(payload)
+		err = json.NewDecoder(r.Body).Decode(p)
+		if err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		edited, err := devices.Edit(r.Context(), devices.EditDeviceRequest{
+			ID:       deviceID,
+			MAC:      p.MAC,
+			Key:      key,
+			Hostname: p.Hostname,
+			ClientID: p.ClientID,
+			Storage:  db,
 		})
+		if err != nil {
+			return fmt.Errorf("devices.Edit: %w", err)
+		}
+
+		return happier.OK(w, r, newSingleDevice(*edited))
 	}
 }
 
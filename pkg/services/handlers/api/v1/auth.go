@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/services/toussaint"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// AuthArgs contains dependencies for handlers issuing
+// tokens for API clients.
+type AuthArgs struct {
+	Users     storage.Users
+	TwoFactor storage.TwoFactor
+	Store     *session.Store
+	Issuer    session.Issuer
+	Lifetimes session.Lifetimes
+
+	// Refresher restores sessions from refresh tokens.
+	Refresher session.Renewer
+}
+
+type authTokens struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken"`
+	TokenType    string    `json:"tokenType"`
+	Expires      time.Time `json:"expires"`
+}
+
+// issueTokens responses with new tokens for given session, which
+// has to be recorded in store before.
+func issueTokens(w http.ResponseWriter, r *http.Request, args AuthArgs, state session.State) error {
+	tokens, err := args.Issuer.Issue(r.Context(), state)
+	if err != nil {
+		return fmt.Errorf("args.Issuer.Issue: %w", err)
+	}
+
+	return happier.OK(w, r, &authTokens{
+		AccessToken:  tokens.Access,
+		RefreshToken: tokens.Refresh,
+		TokenType:    "Bearer",
+		Expires:      tokens.Expires,
+	})
+}
+
+// AuthToken handler authenticates API client with nickname and
+// password of user, and with one time code, when user has two
+// factor authentication enabled. Response contains short-lived
+// access token for "Authorization: Bearer" header and refresh
+// token, that can be exchanged for new tokens with AuthRefresh.
+func AuthToken(args AuthArgs) horror.HandlerFunc {
+	type payload struct {
+		Nickname string `json:"nickname"`
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		input := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder.Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		match, err := users.AuthenticateWithPassword(ctx, users.AuthenticationDependencies{
+			Request: users.AuthenticationRequest{
+				Nickname: input.Nickname,
+				Password: []byte(input.Password),
+			},
+			Storage:      args.Users,
+			ErrorFactory: errFactory,
+		})
+		if err != nil {
+			return fmt.Errorf("users.AuthenticateWithPassword: %w", err)
+		}
+
+		methods, err := args.TwoFactor.Get(ctx, match.UserID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.TwoFactor.Get: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if toussaint.IsTwoFactorEnabled(*methods) {
+			validator := toussaint.ValidatorComposite(
+				toussaint.ValidatorTOTP(*methods),
+				toussaint.ValidatorRecovery(args.TwoFactor, match.UserID),
+			)
+			if input.Code == "" || !validator.Validate(ctx, input.Code) {
+				return errFactory.Unauthorized(
+					fmt.Errorf("api: invalid two factor code of user id=%s", match.UserID),
+					"Two factor authentication code is missing or invalid.",
+				)
+			}
+		}
+
+		// Sessions of API clients are renewed with refresh
+		// tokens, so they live as long as remembered ones.
+		state := session.New(ctx, session.Builder{
+			UserID:   match.UserID,
+			Nickname: match.Nickname,
+			Role:     match.Role,
+			Remember: true,
+		})
+		state.Expires = time.Now().Add(args.Lifetimes.Of(*state))
+
+		if err := args.Store.Record(ctx, *state); err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Store.Record: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return issueTokens(w, r, args, *state)
+	}
+}
+
+// AuthRefresh handler exchanges refresh token sent in
+// "Authorization: Bearer" header for new access and refresh
+// tokens and extends lifetime of their session. Every refresh
+// token can be exchanged only once. Session of refresh token
+// used for the second time is revoked.
+func AuthRefresh(args AuthArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		state, err := args.Refresher.Renew(r)
+		if err != nil {
+			return errFactory.Unauthorized(
+				fmt.Errorf("args.Refresher.Renew: %w", err),
+				"Refresh token is invalid or expired.",
+			)
+		}
+		state.Expires = time.Now().Add(args.Lifetimes.Of(*state))
+
+		rotated, err := args.Store.Rotate(r.Context(), *state)
+		if errors.Is(err, session.ErrRefreshReused) {
+			return errFactory.Unauthorized(
+				fmt.Errorf("args.Store.Rotate: %w", err),
+				"Refresh token was already used. Please login in.",
+			)
+		}
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.Unauthorized(
+				fmt.Errorf("args.Store.Rotate: %w", err),
+				"Refresh token is invalid or expired.",
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Store.Rotate: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return issueTokens(w, r, args, *rotated)
+	}
+}
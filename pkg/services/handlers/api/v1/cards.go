@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/checkin"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+type singleCard struct {
+	ID  string `json:"id"`
+	Tag string `json:"tag"`
+}
+
+// UserCards handler responses with list of cards owned by
+// requesting user. Make sure to make this resource private
+// before mounting to some mux or router.
+func UserCards(db storage.Cards) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		cards, err := db.OfUser(r.Context(), userID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.OfUser: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		result := make([]singleCard, len(cards), cap(cards))
+		for i, card := range cards {
+			result[i] = singleCard{card.ID, card.Tag}
+		}
+
+		return happier.OK(w, r, result)
+	}
+}
+
+// CardAdd handles registration of new card for requesting user.
+// Make sure to make this resource private before mounting to some
+// mux or router.
+func CardAdd(db storage.Cards) horror.HandlerFunc {
+	type payload struct {
+		Tag string `json:"tag"`
+		UID string `json:"uid"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		p := new(payload)
+		err = json.NewDecoder(r.Body).Decode(p)
+		if err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		newID, err := checkin.AddCard(r.Context(), checkin.AddCardRequest{
+			OwnerID: userID,
+			Tag:     p.Tag,
+			UID:     p.UID,
+			Storage: db,
+		})
+		if err != nil {
+			return fmt.Errorf("checkin.AddCard: %w", err)
+		}
+
+		return happier.Created(w, r, &singleCard{
+			ID:  newID,
+			Tag: p.Tag,
+		})
+	}
+}
+
+// CardRemove deletes card of requesting user. Make sure to make
+// this resource private before mounting to some mux or router.
+func CardRemove(db storage.Cards) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		cardID, err := requests.CardID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.CardID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		cards, err := db.OfUser(r.Context(), userID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.OfUser: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		owned := false
+		for _, c := range cards {
+			if c.ID == cardID {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			return errFactory.NotFound(
+				fmt.Errorf("user id=%s doesn't own card id=%s", userID, cardID),
+				fmt.Sprintf("you don't have card with id=%s", cardID),
+			)
+		}
+
+		err = db.Remove(r.Context(), cardID)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("db.Remove: %w", err),
+				fmt.Sprintf("there is no card with given id: %s", cardID),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Remove: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// CardScanArgs contains dependencies for CardScan handler.
+type CardScanArgs struct {
+	Config   models.Config
+	Cards    storage.Cards
+	Users    storage.Users
+	CheckIns storage.CheckIns
+}
+
+// CardScan handles cards scanned by reader at the hackerspace door.
+// Every scan checks in or checks out owner of the card.
+func CardScan(args CardScanArgs) horror.HandlerFunc {
+	type payload struct {
+		UID string `json:"uid"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		p := new(payload)
+		err := json.NewDecoder(r.Body).Decode(p)
+		if err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		res, err := checkin.Scan(r.Context(), checkin.ScanRequest{
+			UID:      p.UID,
+			TTL:      args.Config.CheckInTTL,
+			Toggle:   args.Config.CheckInToggle,
+			Cards:    args.Cards,
+			Users:    args.Users,
+			CheckIns: args.CheckIns,
+		})
+		if err != nil {
+			return fmt.Errorf("checkin.Scan: %w", err)
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/services/email"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/mailer"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+// userEmail is email address of user as seen by its owner.
+type userEmail struct {
+	Email    string `json:"email"`
+	Verified bool   `json:"verified"`
+
+	// Enabled is false, when server cannot send emails.
+	Enabled bool `json:"enabled"`
+}
+
+// emailError returns http error for error of operation
+// on email address.
+func emailError(r *http.Request, err error) error {
+	errFactory := happier.FromRequest(r)
+	switch {
+	case errors.Is(err, email.ErrDisabled):
+		return errFactory.NotFound(err, "Emails are not enabled on this server.")
+	case errors.Is(err, email.ErrTooManyRequests):
+		return errFactory.TooManyRequests(err, "Password reset was requested too recently. Please try again later.")
+	case errors.Is(err, email.ErrInvalidLink):
+		return errFactory.NotFound(err, "Verification link is invalid or expired.")
+	case errors.Is(err, mailer.ErrInvalidAddress):
+		return errFactory.BadRequest(err, "Invalid input: invalid email address.")
+	default:
+		return errFactory.InternalServerError(err, internalServerErrorResponse)
+	}
+}
+
+// readUserEmail returns email address of user with id from url.
+func readUserEmail(r *http.Request, db storage.Users, addresses *email.Addresses) (*userEmail, error) {
+	errFactory := happier.FromRequest(r)
+
+	id, err := requests.UserID(r)
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("requests.UserID: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	u, err := db.Read(r.Context(), id)
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("db.Read: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	return &userEmail{
+		Email:    u.Email,
+		Verified: u.EmailVerified,
+		Enabled:  addresses.Enabled(),
+	}, nil
+}
+
+// UserEmail handler responses with email address of user. Make
+// sure to make this resource private before mounting to some
+// mux or router.
+func UserEmail(db storage.Users, addresses *email.Addresses) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		res, err := readUserEmail(r, db, addresses)
+		if err != nil {
+			return err
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// UserEmailUpdate handler changes email address of user and
+// sends verification link to the new address. Empty address
+// removes email of the user. Make sure to make this resource
+// private before mounting to some mux or router.
+func UserEmailUpdate(db storage.Users, addresses *email.Addresses) horror.HandlerFunc {
+	type payload struct {
+		Email string `json:"email"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		id, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		if err := addresses.Set(r.Context(), id, p.Email); err != nil {
+			return emailError(r, fmt.Errorf("addresses.Set: %w", err))
+		}
+
+		res, err := readUserEmail(r, db, addresses)
+		if err != nil {
+			return err
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// UserEmailVerification handler sends verification link to
+// email address of user once again. Make sure to make this
+// resource private before mounting to some mux or router.
+func UserEmailVerification(addresses *email.Addresses) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		id, err := requests.UserID(r)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if err := addresses.SendVerification(r.Context(), id); err != nil {
+			return emailError(r, fmt.Errorf("addresses.SendVerification: %w", err))
+		}
+
+		return happier.Accepted(w, r)
+	}
+}
+
+// EmailVerify handler marks email address as verified with
+// token from verification link.
+func EmailVerify(addresses *email.Addresses) horror.HandlerFunc {
+	type payload struct {
+		Token string `json:"token"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return happier.FromRequest(r).BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		if _, err := addresses.Verify(r.Context(), p.Token); err != nil {
+			return emailError(r, fmt.Errorf("addresses.Verify: %w", err))
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// resetTimeout is time limit for sending password reset
+// link in the background.
+const resetTimeout = time.Minute
+
+// ResetRequest handler sends password reset link to given
+// email address, if it belongs to any user. Link is sent in
+// the background, so response is the same and takes the same
+// time for known and unknown addresses.
+func ResetRequest(addresses *email.Addresses) horror.HandlerFunc {
+	type payload struct {
+		Email string `json:"email"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return happier.FromRequest(r).BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		if !addresses.Enabled() {
+			return emailError(r, email.ErrDisabled)
+		}
+
+		if err := addresses.AllowReset(p.Email, requests.RemoteIP(r)); err != nil {
+			return emailError(r, fmt.Errorf("addresses.AllowReset: %w", err))
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), resetTimeout)
+			defer cancel()
+
+			if err := addresses.RequestReset(ctx, p.Email); err != nil {
+				log.Println("Failed to send password reset link, reason: ", err.Error())
+			}
+		}()
+
+		return happier.Accepted(w, r)
+	}
+}
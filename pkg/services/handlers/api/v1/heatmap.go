@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/history"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+const (
+	defaultForecastHours = 6
+	maxForecastHours     = 24
+)
+
+// HeatmapArgs contains dependencies for Heatmap handler.
+type HeatmapArgs struct {
+	Config   models.Config
+	History  storage.History
+	Counters storage.StatusTx
+}
+
+// Heatmap responses with average occupancy for every hour
+// of every weekday and forecast of occupancy for the coming
+// hours. Timezone can be changed with "tz" query parameter
+// and number of forecasted hours with "hours" parameter.
+func Heatmap(args HeatmapArgs) horror.HandlerFunc {
+	type response struct {
+		Timezone string                `json:"timezone"`
+		Weekdays [7]string             `json:"weekdays"`
+		Heatmap  history.Heatmap       `json:"heatmap"`
+		Forecast []history.Expectation `json:"forecast"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+		query := r.URL.Query()
+
+		timezone := query.Get("tz")
+		if timezone == "" {
+			timezone = args.Config.Timezone
+		}
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("time.LoadLocation: %w", err),
+				fmt.Sprintf("Invalid input: unknown timezone %s.", timezone),
+			)
+		}
+
+		hours := defaultForecastHours
+		if h := query.Get("hours"); h != "" {
+			hours, err = strconv.Atoi(h)
+			if err != nil || hours < 0 || hours > maxForecastHours {
+				return errFactory.BadRequest(
+					fmt.Errorf("strconv.Atoi: %v", err),
+					fmt.Sprintf("Invalid input: hours should be number from 0 to %d.", maxForecastHours),
+				)
+			}
+		}
+
+		now := time.Now().In(loc)
+		snapshots, err := args.History.Between(r.Context(), now.Add(-args.Config.HistoryRetention), now)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.History.Between: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		var online int
+		err = args.Counters.DevicesStatus(r.Context(), func(ctx context.Context, s storage.Status) error {
+			online, err = s.OnlineUsers(ctx)
+			return err
+		})
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Counters.DevicesStatus: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := response{
+			Timezone: loc.String(),
+			Heatmap:  history.NewHeatmap(snapshots, loc),
+		}
+		for day := range res.Weekdays {
+			res.Weekdays[day] = time.Weekday(day).String()
+		}
+		res.Forecast = res.Heatmap.Forecast(now, online, hours)
+
+		return happier.OK(w, r, &res)
+	}
+}
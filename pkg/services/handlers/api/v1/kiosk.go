@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/checkin"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/kiosk"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+// baseURL returns address of application, that received
+// given request.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// KioskCode responses with current check-in code, its url
+// and data URI with image of QR code that contains url.
+// Make sure to protect this resource with kiosk key before
+// mounting to some mux or router.
+func KioskCode(codes *kiosk.Codes) horror.HandlerFunc {
+	type response struct {
+		URL     string    `json:"url"`
+		Image   string    `json:"image"`
+		Expires time.Time `json:"expires"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		code, err := codes.Current()
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("codes.Current: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		url := kiosk.URL(baseURL(r), code)
+		image, err := kiosk.QR(url, 512)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("kiosk.QR: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, &response{
+			URL: url,
+			Image: fmt.Sprintf(
+				"data:image/png;base64,%s",
+				base64.StdEncoding.EncodeToString(image),
+			),
+			Expires: code.Expires,
+		})
+	}
+}
+
+// CodeCheckInArgs contains dependencies for CodeCheckIn handler.
+type CodeCheckInArgs struct {
+	Config   models.Config
+	Renewer  session.Renewer
+	Codes    *kiosk.Codes
+	CheckIns storage.CheckIns
+}
+
+// CodeCheckIn checks in requesting user with code scanned
+// from kiosk.
+func CodeCheckIn(args CodeCheckInArgs) horror.HandlerFunc {
+	type payload struct {
+		Code string `json:"code"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		state, err := args.Renewer.Renew(r)
+		if err != nil {
+			return errFactory.Unauthorized(
+				fmt.Errorf("args.Renewer.Renew: %w", err),
+				"Invalid session. Please login in.",
+			)
+		}
+
+		p := new(payload)
+		err = json.NewDecoder(r.Body).Decode(p)
+		if err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		res, err := checkin.Code(r.Context(), checkin.CodeRequest{
+			UserID:   state.UserID,
+			Nickname: state.Nickname,
+			Code:     p.Code,
+			TTL:      args.Config.QRCheckInTTL,
+			Codes:    args.Codes,
+			CheckIns: args.CheckIns,
+		})
+		if err != nil {
+			return fmt.Errorf("checkin.Code: %w", err)
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
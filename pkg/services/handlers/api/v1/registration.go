@@ -0,0 +1,233 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/registration"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// singleInvite is invite as seen by its creator. Code is
+// present only in response for creating new invite.
+type singleInvite struct {
+	ID      string     `json:"id"`
+	Code    string     `json:"code,omitempty"`
+	MaxUses int        `json:"maxUses,omitempty"`
+	Uses    int        `json:"uses"`
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"`
+	Valid   bool       `json:"valid"`
+}
+
+func newSingleInvite(i models.Invite, now time.Time) *singleInvite {
+	res := &singleInvite{
+		ID:      i.ID,
+		MaxUses: i.MaxUses,
+		Uses:    i.Uses,
+		Created: i.Created,
+		Valid:   registration.Valid(i, now),
+	}
+	if !i.Expires.IsZero() {
+		res.Expires = &i.Expires
+	}
+	return res
+}
+
+// RegistrationMode handler responses with current
+// registration mode.
+func RegistrationMode(reg *registration.Registration) horror.HandlerFunc {
+	type response struct {
+		Mode models.RegistrationMode `json:"mode"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		mode, err := reg.Mode(r.Context())
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("reg.Mode: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, &response{Mode: mode})
+	}
+}
+
+// UserInvites handler responses with invites created by
+// requesting user. Make sure to make this resource private
+// before mounting to some mux or router.
+func UserInvites(db storage.Invites) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		invites, err := db.OfUser(r.Context(), userID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.OfUser: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		now := time.Now()
+		res := make([]singleInvite, len(invites), len(invites))
+		for i, invite := range invites {
+			res[i] = *newSingleInvite(invite, now)
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// InviteAdd handler creates invite code for requesting user.
+// Zero maxUses means no limit and omitted expires means, that
+// invite never expires, but only for users with models.ManageUsers
+// permission. Invites of other users are limited. Response contains
+// the only copy of the code. Make sure to make this resource private
+// before mounting to some mux or router.
+func InviteAdd(renewer session.Renewer, reg *registration.Registration) horror.HandlerFunc {
+	type payload struct {
+		MaxUses int        `json:"maxUses"`
+		Expires *time.Time `json:"expires,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+		if p.MaxUses < 0 {
+			return errFactory.BadRequest(
+				fmt.Errorf("api.InviteAdd: negative maxUses=%d", p.MaxUses),
+				"Invalid input: maxUses cannot be negative.",
+			)
+		}
+
+		var ttl time.Duration
+		if p.Expires != nil {
+			ttl = time.Until(*p.Expires)
+			if ttl <= 0 {
+				return errFactory.BadRequest(
+					fmt.Errorf("api.InviteAdd: expires=%s in the past", p.Expires),
+					"Invalid input: expiry date has to be in the future.",
+				)
+			}
+		}
+
+		state, err := renewer.Renew(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("renewer.Renew: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		maxUses := p.MaxUses
+		if !state.Role.Can(models.ManageUsers) {
+			maxUses, ttl, err = registration.MemberLimits(maxUses, ttl)
+			if err != nil {
+				return errFactory.BadRequest(
+					fmt.Errorf("registration.MemberLimits: %w", err),
+					fmt.Sprintf(
+						"Invalid input: invite can be used at most %d times and for at most %d days.",
+						registration.MemberMaxUses, registration.MemberMaxTTL/(24*time.Hour),
+					),
+				)
+			}
+		}
+
+		invite, code, err := reg.Invite(r.Context(), userID, maxUses, ttl)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("reg.Invite: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := newSingleInvite(*invite, time.Now())
+		res.Code = code
+		return happier.Created(w, r, res)
+	}
+}
+
+// InviteRemove handler deletes invite of requesting user. Make
+// sure to make this resource private before mounting to some
+// mux or router.
+func InviteRemove(db storage.Invites) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		inviteID, err := requests.InviteID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.InviteID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		invite, err := db.Read(r.Context(), inviteID)
+		if err == nil && invite.CreatorID != userID {
+			err = fmt.Errorf("user id=%s doesn't own invite: %w", userID, serrors.ErrNoID)
+		}
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("db.Read: %w", err),
+				fmt.Sprintf("you don't have invite with id=%s", inviteID),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Read: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if err := db.Remove(r.Context(), inviteID); err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Remove: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/services/reset"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// resetLink returns path of page, where user can set new
+// password with given token. Token is kept in fragment, so
+// it never reaches server logs.
+func resetLink(token string) string {
+	return "/reset#" + token
+}
+
+// ResetIssue handler creates password reset token for given
+// user and responses with link to the reset page. Make sure
+// to allow only users with models.ResetPasswords permission
+// before mounting to some mux or router.
+func ResetIssue(renewer session.Renewer, resetter *reset.Resetter) horror.HandlerFunc {
+	type response struct {
+		Token   string    `json:"token"`
+		Link    string    `json:"link"`
+		Expires time.Time `json:"expires"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		state, err := renewer.Renew(r)
+		if err != nil {
+			return errFactory.Unauthorized(
+				fmt.Errorf("renewer.Renew: %w", err),
+				"Invalid session. Please login in.",
+			)
+		}
+
+		id, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		token, t, err := resetter.Issue(r.Context(), state.UserID, id)
+		switch {
+		case errors.Is(err, serrors.ErrNoID):
+			return errFactory.NotFound(
+				fmt.Errorf("resetter.Issue: %w", err),
+				fmt.Sprintf("there is no user with id: %s", id),
+			)
+		case errors.Is(err, reset.ErrForbidden):
+			return errFactory.Forbidden(
+				fmt.Errorf("resetter.Issue: %w", err),
+				"You are not allowed to reset password of this user.",
+			)
+		case err != nil:
+			return errFactory.InternalServerError(
+				fmt.Errorf("resetter.Issue: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.Created(w, r, &response{
+			Token:   token,
+			Link:    resetLink(token),
+			Expires: t.Expires,
+		})
+	}
+}
+
+// resetError returns http error for error of operation
+// on password reset token.
+func resetError(r *http.Request, err error) error {
+	errFactory := happier.FromRequest(r)
+	switch {
+	case errors.Is(err, reset.ErrInvalidToken):
+		return errFactory.NotFound(err, "Reset link is invalid, expired or has been already used.")
+	case errors.Is(err, users.ErrInvaliPassword):
+		return errFactory.BadRequest(err, fmt.Sprintf("Invalid input: %s.", users.ErrInvaliPassword.Error()))
+	default:
+		return errFactory.InternalServerError(err, internalServerErrorResponse)
+	}
+}
+
+// ResetLookup handler responses with nickname of user, that
+// given password reset token has been issued for.
+func ResetLookup(resetter *reset.Resetter, db storage.Users) horror.HandlerFunc {
+	type payload struct {
+		Token string `json:"token"`
+	}
+
+	type response struct {
+		Nickname string    `json:"nickname"`
+		Expires  time.Time `json:"expires"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		t, err := resetter.Lookup(r.Context(), p.Token)
+		if err != nil {
+			return resetError(r, fmt.Errorf("resetter.Lookup: %w", err))
+		}
+
+		u, err := db.Read(r.Context(), t.UserID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Read: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, &response{
+			Nickname: u.Nickname,
+			Expires:  t.Expires,
+		})
+	}
+}
+
+// ResetConsume handler sets new password with given password
+// reset token. Token can be used only once and all sessions
+// of user are revoked.
+func ResetConsume(resetter *reset.Resetter) horror.HandlerFunc {
+	type payload struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		if err := resetter.Consume(r.Context(), p.Token, p.Password); err != nil {
+			return resetError(r, fmt.Errorf("resetter.Consume: %w", err))
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
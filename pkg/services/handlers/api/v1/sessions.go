@@ -0,0 +1,171 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+type userSession struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"userAgent"`
+	IP        string    `json:"ip"`
+	Created   time.Time `json:"created"`
+	LastUsed  time.Time `json:"lastUsed"`
+	Expires   time.Time `json:"expires"`
+	Current   bool      `json:"current"`
+}
+
+// UserSessions handler responses with list of active sessions
+// of requesting user. Make sure to make this resource private
+// before mounting to some mux or router.
+func UserSessions(renewer session.Renewer, db storage.Users, store *session.Store) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		state, err := renewer.Renew(r)
+		if err != nil {
+			return errFactory.Unauthorized(
+				fmt.Errorf("renewer.Renew: %w", err),
+				"Invalid session. Please login in.",
+			)
+		}
+
+		user, err := db.Read(ctx, userID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Read: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		active, err := store.Active(ctx, userID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("store.Active: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := []userSession{}
+		for _, s := range active {
+			// Sessions created before revoking all sessions of
+			// the user are rejected by session.Revocable.
+			if s.Created.Before(user.SessionsRevoked.Truncate(time.Second)) {
+				continue
+			}
+			res = append(res, userSession{
+				ID:        s.ID,
+				UserAgent: s.UserAgent,
+				IP:        s.IP,
+				Created:   s.Created,
+				LastUsed:  s.LastUsed,
+				Expires:   s.Expires,
+				Current:   s.ID == state.ID,
+			})
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// SessionRemove handler revokes single session of requesting
+// user. Make sure to make this resource private before mounting
+// to some mux or router.
+func SessionRemove(store *session.Store) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		sessionID, err := requests.SessionID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.SessionID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		s, err := store.Sessions.Read(ctx, sessionID)
+		if errors.Is(err, serrors.ErrNoID) || (err == nil && s.UserID != userID) {
+			return errFactory.NotFound(
+				fmt.Errorf("user id=%s doesn't own session id=%s", userID, sessionID),
+				fmt.Sprintf("you don't have session with id=%s", sessionID),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("store.Sessions.Read: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		err = store.Revoke(ctx, sessionID)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("store.Revoke: %w", err),
+				fmt.Sprintf("there is no session with given id: %s", sessionID),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("store.Revoke: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
+
+// SessionsRemove handler revokes every session of requesting
+// user including the current one, which logs them out
+// everywhere. Make sure to make this resource private before
+// mounting to some mux or router.
+func SessionsRemove(store *session.Store) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if err := store.RevokeAll(r.Context(), userID, ""); err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("store.RevokeAll: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/space"
+)
+
+// SpaceState handler responses with current state of
+// the hackerspace.
+func SpaceState(tracker *space.Tracker) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		res, err := tracker.State(r.Context())
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("tracker.State: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// SpaceUpdate handler overrides state of the hackerspace. Make
+// sure to allow only users with models.ChangeSpaceState
+// permission before mounting to some mux or router.
+func SpaceUpdate(tracker *space.Tracker) horror.HandlerFunc {
+	type payload struct {
+		State models.SpaceState `json:"state"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		p := new(payload)
+		err := json.NewDecoder(r.Body).Decode(p)
+		if err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		res, err := tracker.Set(r.Context(), p.State)
+		if errors.Is(err, space.ErrInvalidState) {
+			return errFactory.BadRequest(
+				fmt.Errorf("tracker.Set: %w", err),
+				"Invalid input: state should be open, closed or members-only.",
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("tracker.Set: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// SpaceReset handler removes override of the hackerspace
+// state, so it is derived from occupancy again. Make sure to
+// allow only users with models.ChangeSpaceState permission
+// before mounting to some mux or router.
+func SpaceReset(tracker *space.Tracker) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		res, err := tracker.Reset(r.Context())
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("tracker.Reset: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
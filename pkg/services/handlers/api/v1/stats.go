@@ -0,0 +1,161 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/services/stats"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+type statsRange struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Timezone string `json:"timezone"`
+}
+
+// parseStatsRange reads range of statistics from "from", "to" and
+// "tz" query parameters.
+func parseStatsRange(r *http.Request, config models.Config) (*stats.Range, *statsRange, error) {
+	query := r.URL.Query()
+
+	res, err := stats.ParseRange(
+		query.Get("from"), query.Get("to"), query.Get("tz"),
+		config.Timezone, time.Now(),
+	)
+	if err != nil {
+		return nil, nil, happier.FromRequest(r).BadRequest(
+			fmt.Errorf("stats.ParseRange: %w", err),
+			fmt.Sprintf("Invalid input: %s.", err.Error()),
+		)
+	}
+
+	return res, &statsRange{
+		From:     res.From.Format("2006-01-02"),
+		To:       res.To.AddDate(0, 0, -1).Format("2006-01-02"),
+		Timezone: res.Location.String(),
+	}, nil
+}
+
+// StatsArgs contains dependencies for statistics handlers.
+type StatsArgs struct {
+	Config  models.Config
+	Renewer session.Renewer
+	Users   storage.Users
+	Visits  storage.Visits
+}
+
+// SpaceStats responses with occupancy statistics of the whole
+// hackerspace. Visits of users with private mode enabled are
+// excluded.
+func SpaceStats(args StatsArgs) horror.HandlerFunc {
+	type response struct {
+		statsRange
+		stats.Space
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		statsRange, rangeInfo, err := parseStatsRange(r, args.Config)
+		if err != nil {
+			return err
+		}
+
+		users, err := args.Users.All(ctx)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Users.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		visits, err := args.Visits.Between(ctx, statsRange.From, statsRange.To)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Visits.Between: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, &response{
+			statsRange: *rangeInfo,
+			Space:      stats.ForSpace(stats.Public(visits, users), *statsRange),
+		})
+	}
+}
+
+// UserStats responses with presence statistics of single user.
+// Statistics of users with private mode enabled are available
+// only to themselves.
+func UserStats(args StatsArgs) horror.HandlerFunc {
+	type response struct {
+		statsRange
+		stats.User
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+
+		id, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		user, err := args.Users.Read(ctx, id)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("args.Users.Read: %w", err),
+				fmt.Sprintf("there is no user with id: %s", id),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Users.Read: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		if user.Private {
+			state, err := args.Renewer.Renew(r)
+			if err != nil || state.UserID != user.ID {
+				return errFactory.NotFound(
+					fmt.Errorf("user with id: %s is private", id),
+					fmt.Sprintf("there is no user with id: %s", id),
+				)
+			}
+		}
+
+		statsRange, rangeInfo, err := parseStatsRange(r, args.Config)
+		if err != nil {
+			return err
+		}
+
+		visits, err := args.Visits.Between(ctx, statsRange.From, statsRange.To)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("args.Visits.Between: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, &response{
+			statsRange: *rangeInfo,
+			User:       stats.ForUser(visits, user.ID, *statsRange),
+		})
+	}
+}
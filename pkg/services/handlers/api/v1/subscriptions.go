@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/notify"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/services/webpush"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// UserSubscriptions handler responses with list of subscriptions
+// of requesting user. Make sure to make this resource private
+// before mounting to some mux or router.
+func UserSubscriptions(db storage.Subscriptions) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		subscriptions, err := db.OfUser(r.Context(), userID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.OfUser: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.OK(w, r, subscriptions)
+	}
+}
+
+// SubscriptionOptions handler responses with available channels,
+// events and users, that can be followed by requesting user. Public
+// VAPID key is included, when given one isn't nil. Make sure to make
+// this resource private before mounting to some mux or router.
+func SubscriptionOptions(n *notify.Notifier, vapid *webpush.VAPID) horror.HandlerFunc {
+	type followable struct {
+		ID       string `json:"id"`
+		Nickname string `json:"nickname"`
+	}
+
+	type response struct {
+		Channels []string                   `json:"channels"`
+		Events   []models.SubscriptionEvent `json:"events"`
+		Users    []followable               `json:"users"`
+
+		// VAPIDKey is used by browsers to subscribe
+		// to push notifications.
+		VAPIDKey string `json:"vapidKey,omitempty"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		entries, err := n.Users.All(r.Context())
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("n.Users.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		users := []followable{}
+		for _, u := range entries {
+			if u.ID == userID || u.Private || u.Unfollowable {
+				continue
+			}
+			users = append(users, followable{u.ID, u.Nickname})
+		}
+
+		vapidKey := ""
+		if vapid != nil {
+			vapidKey = vapid.PublicKey()
+		}
+
+		return happier.OK(w, r, &response{
+			Channels: n.Channels(),
+			Events: []models.SubscriptionEvent{
+				models.ArrivalEvent,
+				models.OpenEvent,
+				models.CloseEvent,
+			},
+			Users:    users,
+			VAPIDKey: vapidKey,
+		})
+	}
+}
+
+// SubscriptionAdd handles subscribing requesting user to
+// notifications. Make sure to make this resource private
+// before mounting to some mux or router.
+func SubscriptionAdd(n *notify.Notifier) horror.HandlerFunc {
+	type payload struct {
+		Event    models.SubscriptionEvent `json:"event"`
+		TargetID string                   `json:"targetId"`
+		Channel  string                   `json:"channel"`
+		Address  string                   `json:"address"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		p := new(payload)
+		err = json.NewDecoder(r.Body).Decode(p)
+		if err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		s, err := n.Subscribe(r.Context(), models.Subscription{
+			UserID:   userID,
+			Event:    p.Event,
+			TargetID: p.TargetID,
+			Channel:  p.Channel,
+			Address:  p.Address,
+		})
+		if err != nil {
+			return fmt.Errorf("n.Subscribe: %w", err)
+		}
+
+		return happier.Created(w, r, s)
+	}
+}
+
+// SubscriptionRemove deletes subscription of requesting user. Make
+// sure to make this resource private before mounting to some mux
+// or router.
+func SubscriptionRemove(db storage.Subscriptions) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		subscriptionID, err := requests.SubscriptionID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.SubscriptionID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		subscriptions, err := db.OfUser(r.Context(), userID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.OfUser: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		owned := false
+		for _, s := range subscriptions {
+			if s.ID == subscriptionID {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			return errFactory.NotFound(
+				fmt.Errorf("user id=%s doesn't own subscription id=%s", userID, subscriptionID),
+				fmt.Sprintf("you don't have subscription with id=%s", subscriptionID),
+			)
+		}
+
+		err = db.Remove(r.Context(), subscriptionID)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("db.Remove: %w", err),
+				fmt.Sprintf("there is no subscription with given id: %s", subscriptionID),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Remove: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/services/tokens"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+type apiToken struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Scopes   []models.Scope `json:"scopes"`
+	Created  time.Time      `json:"created"`
+	Expires  *time.Time     `json:"expires,omitempty"`
+	LastUsed *time.Time     `json:"lastUsed,omitempty"`
+	Token    string         `json:"token,omitempty"`
+}
+
+func newAPIToken(t models.APIToken) *apiToken {
+	res := &apiToken{
+		ID:      t.ID,
+		Name:    t.Name,
+		Scopes:  t.Scopes,
+		Created: t.Created,
+	}
+	if !t.Expires.IsZero() {
+		res.Expires = &t.Expires
+	}
+	if !t.LastUsed.IsZero() {
+		res.LastUsed = &t.LastUsed
+	}
+	return res
+}
+
+// UserTokens handler responses with list of personal API
+// tokens of requesting user. Make sure to make this resource
+// private before mounting to some mux or router.
+func UserTokens(db storage.APITokens) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		entries, err := db.OfUser(r.Context(), userID)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.OfUser: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := []apiToken{}
+		for _, t := range entries {
+			res = append(res, *newAPIToken(t))
+		}
+
+		return happier.OK(w, r, res)
+	}
+}
+
+// TokenAdd handler creates personal API token for requesting
+// user. Response contains the only copy of the token. Make sure
+// to make this resource private before mounting to some mux
+// or router.
+func TokenAdd(db storage.APITokens) horror.HandlerFunc {
+	type payload struct {
+		Name    string         `json:"name"`
+		Scopes  []models.Scope `json:"scopes"`
+		Expires *time.Time     `json:"expires"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		p := new(payload)
+		if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+			return errFactory.BadRequest(
+				fmt.Errorf("json.NewDecoder().Decode: %w", err),
+				fmt.Sprintf("Invalid input: %s.", err.Error()),
+			)
+		}
+
+		t := models.APIToken{
+			UserID: userID,
+			Name:   p.Name,
+			Scopes: p.Scopes,
+		}
+		if p.Expires != nil {
+			t.Expires = *p.Expires
+		}
+
+		token, created, err := tokens.Generate(r.Context(), db, t)
+		switch {
+		case errors.Is(err, tokens.ErrInvalidName):
+			return errFactory.BadRequest(
+				fmt.Errorf("tokens.Generate: %w", err),
+				"Invalid input: name should have from 1 to 64 characters.",
+			)
+		case errors.Is(err, tokens.ErrInvalidScopes):
+			return errFactory.BadRequest(
+				fmt.Errorf("tokens.Generate: %w", err),
+				"Invalid input: choose at least one of known scopes.",
+			)
+		case errors.Is(err, tokens.ErrInvalidExpiry):
+			return errFactory.BadRequest(
+				fmt.Errorf("tokens.Generate: %w", err),
+				"Invalid input: expiry time should be in the future.",
+			)
+		case err != nil:
+			return errFactory.InternalServerError(
+				fmt.Errorf("tokens.Generate: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		res := newAPIToken(*created)
+		res.Token = token
+
+		return happier.Created(w, r, res)
+	}
+}
+
+// TokenRemove handler revokes personal API token of requesting
+// user. Make sure to make this resource private before mounting
+// to some mux or router.
+func TokenRemove(db storage.APITokens) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		errFactory := happier.FromRequest(r)
+
+		userID, err := requests.UserID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.UserID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		tokenID, err := requests.TokenID(r)
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("requests.TokenID: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		t, err := db.Read(r.Context(), tokenID)
+		if errors.Is(err, serrors.ErrNoID) || (err == nil && t.UserID != userID) {
+			return errFactory.NotFound(
+				fmt.Errorf("user id=%s doesn't own token id=%s", userID, tokenID),
+				fmt.Sprintf("you don't have token with id=%s", tokenID),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Read: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		err = db.Remove(r.Context(), tokenID)
+		if errors.Is(err, serrors.ErrNoID) {
+			return errFactory.NotFound(
+				fmt.Errorf("db.Remove: %w", err),
+				fmt.Sprintf("there is no token with given id: %s", tokenID),
+			)
+		}
+		if err != nil {
+			return errFactory.InternalServerError(
+				fmt.Errorf("db.Remove: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		return happier.NoContent(w, r)
+	}
+}
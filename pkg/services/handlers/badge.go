@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/badge"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/space"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+const (
+	internalServerErrorResponse = "Internal server error. Please try again later."
+
+	maxLabelLength = 64
+
+	defaultImageWidth  = 296
+	defaultImageHeight = 128
+	maxImageSize       = 1024
+)
+
+// BadgeArgs contains dependencies for badge handlers.
+type BadgeArgs struct {
+	Config   models.Config
+	Counters storage.StatusTx
+	Users    storage.Users
+	Adapter  storage.UserAdapter
+	Space    *space.Tracker
+}
+
+// badgeStatus reads state of the hackerspace, number of online
+// users and nicknames of public online users.
+func badgeStatus(ctx context.Context, args BadgeArgs) (*badge.Status, error) {
+	res := new(badge.Status)
+
+	err := args.Counters.DevicesStatus(ctx, func(ctx context.Context, s storage.Status) error {
+		var err error
+		res.Online, err = s.OnlineUsers(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("args.Counters.DevicesStatus: %w", err)
+	}
+
+	space, err := args.Space.State(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("args.Space.State: %w", err)
+	}
+	res.State = space.State
+
+	entries, err := args.Users.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("args.Users.All: %w", err)
+	}
+
+	adapted, err := args.Adapter.Users(ctx, entries)
+	if err != nil {
+		return nil, fmt.Errorf("args.Adapter.Users: %w", err)
+	}
+
+	filters := append(users.DefaultFilters(), users.Online)
+	for _, u := range users.Filter(adapted, filters...) {
+		res.Users = append(res.Users, u.Nickname)
+	}
+
+	return res, nil
+}
+
+// badgeRequest reads status, language and label of badge from
+// given request. Label can be changed with "label" query parameter
+// and language with "lang" parameter.
+func badgeRequest(r *http.Request, args BadgeArgs) (*badge.Status, badge.Language, string, error) {
+	errFactory := happier.FromRequest(r)
+	query := r.URL.Query()
+
+	lang := badge.ParseLanguage(query.Get("lang"))
+
+	label := query.Get("label")
+	if label == "" {
+		label = lang.Label()
+	}
+	if utf8.RuneCountInString(label) > maxLabelLength {
+		return nil, lang, "", errFactory.BadRequest(
+			fmt.Errorf("label is too long: %d", utf8.RuneCountInString(label)),
+			fmt.Sprintf("Invalid input: label can't be longer than %d characters.", maxLabelLength),
+		)
+	}
+
+	status, err := badgeStatus(r.Context(), args)
+	if err != nil {
+		return nil, lang, "", errFactory.InternalServerError(
+			fmt.Errorf("badgeStatus: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	return status, lang, label, nil
+}
+
+// Badge responses with SVG badge describing hackerspace status.
+// Style of badge can be changed with "style" query parameter.
+func Badge(args BadgeArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		status, lang, label, err := badgeRequest(r, args)
+		if err != nil {
+			return err
+		}
+
+		res, err := badge.SVG(label, *status, lang, badge.ParseStyle(r.URL.Query().Get("style")))
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("badge.SVG: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(res)
+		return err
+	}
+}
+
+// imageSize reads positive dimension of image from query parameter
+// with given key.
+func imageSize(r *http.Request, key string, fallback int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	res, err := strconv.Atoi(value)
+	if err != nil || res < 1 || res > maxImageSize {
+		return 0, happier.FromRequest(r).BadRequest(
+			fmt.Errorf("strconv.Atoi: %v", err),
+			fmt.Sprintf("Invalid input: %s should be number from 1 to %d.", key, maxImageSize),
+		)
+	}
+
+	return res, nil
+}
+
+// StatusImage responses with black and white PNG image describing
+// hackerspace status, that is suitable for e-ink displays. Size of
+// image can be changed with "width" and "height" query parameters.
+func StatusImage(args BadgeArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		width, err := imageSize(r, "width", defaultImageWidth)
+		if err != nil {
+			return err
+		}
+
+		height, err := imageSize(r, "height", defaultImageHeight)
+		if err != nil {
+			return err
+		}
+
+		status, lang, label, err := badgeRequest(r, args)
+		if err != nil {
+			return err
+		}
+
+		res, err := badge.PNG(label, *status, lang, width, height)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("badge.PNG: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(res)
+		return err
+	}
+}
+
+// Embed responses with HTML document describing hackerspace status,
+// that can be embedded with iframe. Theme of document can be changed
+// with "style" query parameter.
+func Embed(args BadgeArgs) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		status, lang, label, err := badgeRequest(r, args)
+		if err != nil {
+			return err
+		}
+
+		theme := badge.ParseTheme(r.URL.Query().Get("style"))
+		res, err := badge.Embed(label, *status, lang, theme, args.Config.RefreshTime)
+		if err != nil {
+			return happier.FromRequest(r).InternalServerError(
+				fmt.Errorf("badge.Embed: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(res)
+		return err
+	}
+}
@@ -36,6 +36,17 @@ func FileServer(r chi.Router, path string, opener Opener) {
 			w.Header().Add("Content-Type", "application/javascript")
 		}
 
+		if strings.HasSuffix(filepath, ".webmanifest") {
+			w.Header().Add("Content-Type", "application/manifest+json")
+		}
+
+		// Service workers served from subdirectories are allowed
+		// to control whole site, so static files don't have to be
+		// mounted at the root.
+		if r.Header.Get("Service-Worker") == "script" {
+			w.Header().Add("Service-Worker-Allowed", "/")
+		}
+
 		file, err := opener(filepath)
 		if err != nil {
 			http.NotFound(w, r)
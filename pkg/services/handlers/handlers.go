@@ -5,6 +5,7 @@ import (
 
 	"github.com/alioygur/gores"
 
+	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/services/result"
 	"github.com/hakierspejs/long-season/pkg/services/session"
 )
@@ -13,9 +14,10 @@ import (
 // used for authentication.
 func Who(renewer session.Renewer) http.HandlerFunc {
 	type response struct {
-		ID       string `json:"id"`
-		Nickname string `json:"nickname"`
-		Private  bool   `json:"priv"`
+		ID       string      `json:"id"`
+		Nickname string      `json:"nickname"`
+		Private  bool        `json:"priv"`
+		Role     models.Role `json:"role"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +34,7 @@ func Who(renewer session.Renewer) http.HandlerFunc {
 		gores.JSON(w, http.StatusOK, &response{
 			ID:       state.UserID,
 			Nickname: state.Nickname,
+			Role:     state.Role,
 		})
 	}
 }
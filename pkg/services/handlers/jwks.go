@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/keyring"
+)
+
+// JWKS responses with public keys used for verifying
+// session tokens, as JSON Web Key Set.
+func JWKS(keys *keyring.Keyring) horror.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return happier.OK(w, r, keys.JWKS())
+	}
+}
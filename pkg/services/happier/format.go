@@ -0,0 +1,134 @@
+package happier
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alioygur/gores"
+)
+
+// Format of response payload.
+type Format string
+
+const (
+	JSON Format = "json"
+	Text Format = "text"
+	CSV  Format = "csv"
+)
+
+// TextMarshaler is the interface implemented by payloads, that can
+// be represented as plain text. It is distinct from the one in
+// encoding package, because json package would use it too.
+type TextMarshaler interface {
+	MarshalPlainText() ([]byte, error)
+}
+
+// CSVMarshaler is the interface implemented by payloads, that can
+// be represented as CSV records. First record should be a header.
+type CSVMarshaler interface {
+	MarshalCSV() ([][]string, error)
+}
+
+// Formats returns response formats accepted by client in order of
+// preference. Format can be chosen with "format" query parameter,
+// which takes precedence over Accept header. JSON is always the
+// last acceptable format.
+func Formats(r *http.Request) []Format {
+	switch f := Format(strings.ToLower(r.URL.Query().Get("format"))); f {
+	case JSON, Text, CSV:
+		return []Format{f, JSON}
+	}
+
+	type accepted struct {
+		format  Format
+		quality float64
+	}
+	res := []accepted{}
+
+	for _, value := range r.Header.Values("Accept") {
+		for _, item := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+			if err != nil {
+				continue
+			}
+
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				quality, err = strconv.ParseFloat(q, 64)
+				if err != nil || quality <= 0 {
+					continue
+				}
+			}
+
+			switch mediaType {
+			case "application/json", "application/*", "*/*":
+				res = append(res, accepted{JSON, quality})
+			case "text/plain", "text/*":
+				res = append(res, accepted{Text, quality})
+			case "text/csv":
+				res = append(res, accepted{CSV, quality})
+			}
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].quality > res[j].quality
+	})
+
+	formats := make([]Format, 0, len(res)+1)
+	for _, a := range res {
+		formats = append(formats, a.format)
+	}
+	return append(formats, JSON)
+}
+
+// write outputs given payload with given status code in the first
+// format accepted by client and supported by payload. Payloads
+// support plain text by implementing TextMarshaler and
+// CSV by implementing CSVMarshaler. Every payload supports JSON.
+func write(w http.ResponseWriter, r *http.Request, code int, payload interface{}) error {
+	w.Header().Add("Vary", "Accept")
+
+	for _, format := range Formats(r) {
+		switch format {
+		case Text:
+			if m, ok := payload.(TextMarshaler); ok {
+				text, err := m.MarshalPlainText()
+				if err != nil {
+					return fmt.Errorf("m.MarshalPlainText: %w", err)
+				}
+				return writeBytes(w, code, "text/plain; charset=utf-8", text)
+			}
+		case CSV:
+			if m, ok := payload.(CSVMarshaler); ok {
+				records, err := m.MarshalCSV()
+				if err != nil {
+					return fmt.Errorf("m.MarshalCSV: %w", err)
+				}
+
+				var buf bytes.Buffer
+				if err := csv.NewWriter(&buf).WriteAll(records); err != nil {
+					return fmt.Errorf("csv.WriteAll: %w", err)
+				}
+				return writeBytes(w, code, "text/csv; charset=utf-8", buf.Bytes())
+			}
+		case JSON:
+			return gores.JSONIndent(w, code, payload, defaultPrefix, defaultIndent)
+		}
+	}
+
+	return gores.JSONIndent(w, code, payload, defaultPrefix, defaultIndent)
+}
+
+func writeBytes(w http.ResponseWriter, code int, contentType string, body []byte) error {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,70 @@
+package happier
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+type textPayload struct {
+	Name string `json:"name"`
+}
+
+func (p textPayload) MarshalPlainText() ([]byte, error) {
+	return []byte(p.Name + "\n"), nil
+}
+
+func TestFormats(t *testing.T) {
+	is := is.New(t)
+
+	for _, tc := range []struct {
+		url    string
+		accept string
+		want   []Format
+	}{
+		{"/", "", []Format{JSON}},
+		{"/", "*/*", []Format{JSON, JSON}},
+		{"/", "text/plain", []Format{Text, JSON}},
+		{"/", "text/csv;q=0.9, application/json;q=0.5, text/plain", []Format{Text, CSV, JSON, JSON}},
+		{"/", "text/html, text/plain;q=0", []Format{JSON}},
+		{"/?format=csv", "text/plain", []Format{CSV, JSON}},
+		{"/?format=yaml", "text/plain", []Format{Text, JSON}},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		if tc.accept != "" {
+			r.Header.Set("Accept", tc.accept)
+		}
+		is.Equal(Formats(r), tc.want)
+	}
+}
+
+func TestOK(t *testing.T) {
+	is := is.New(t)
+
+	serve := func(accept string, payload interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", accept)
+		is.NoErr(OK(w, r, payload))
+		return w
+	}
+
+	w := serve("text/plain", textPayload{"alice"})
+	is.Equal(w.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	is.Equal(w.Body.String(), "alice\n")
+
+	// Payload without CSV representation falls back to JSON.
+	w = serve("text/csv", textPayload{"alice"})
+	is.Equal(w.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	is.Equal(w.Body.String(), "{\n    \"name\": \"alice\"\n}")
+
+	// Errors are formatted too.
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/?format=csv", nil)
+	Default().NotFound(errors.New("test"), "not here").ServeHTTP(w, r)
+	is.Equal(w.Code, http.StatusNotFound)
+	is.Equal(w.Body.String(), "code,message\n404,not here\n")
+}
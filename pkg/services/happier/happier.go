@@ -5,8 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
-	"github.com/alioygur/gores"
 	"github.com/hakierspejs/long-season/pkg/services/ctxkey"
 	"github.com/thinkofher/horror"
 )
@@ -98,6 +98,28 @@ func (f *Factory) Unauthorized(err error, message string) horror.Error {
 	}
 }
 
+// Forbidden implements http forbidden (403) error for horror.Error
+// interface to use in long-season REST API.
+func (f *Factory) Forbidden(err error, message string) horror.Error {
+	return &errorHandler{
+		message: message,
+		wrapped: err,
+		code:    http.StatusForbidden,
+		debug:   f.debug,
+	}
+}
+
+// TooManyRequests implements http too many requests (429) error for
+// horror.Error interface to use in long-season REST API.
+func (f *Factory) TooManyRequests(err error, message string) horror.Error {
+	return &errorHandler{
+		message: message,
+		wrapped: err,
+		code:    http.StatusTooManyRequests,
+		debug:   f.debug,
+	}
+}
+
 type errorHandler struct {
 	message string
 	wrapped error
@@ -118,18 +140,21 @@ func (e *errorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			ErrorMessage: e.wrapped.Error(),
 		}
 	}
-	gores.JSONIndent(w, e.code, res, defaultPrefix, defaultIndent)
+	write(w, r, e.code, res)
 }
 
 // OK outputs given payload to http client with http status OK.
 //
-// If json package fails to marshal given payload, OK returns internal server
-// error.
+// Payload is formatted as JSON, plain text or CSV, depending on
+// format accepted by client and supported by payload. See Formats
+// function for details.
+//
+// If payload cannot be marshaled, OK returns internal server error.
 func OK(w http.ResponseWriter, r *http.Request, payload interface{}) horror.Error {
-	err := gores.JSONIndent(w, http.StatusOK, payload, defaultPrefix, defaultIndent)
+	err := write(w, r, http.StatusOK, payload)
 	if err != nil {
 		return FromRequest(r).InternalServerError(
-			fmt.Errorf("gores.JSONIndent: %w", err),
+			fmt.Errorf("write: %w", err),
 			"internal server error, please try again later",
 		)
 	}
@@ -138,13 +163,14 @@ func OK(w http.ResponseWriter, r *http.Request, payload interface{}) horror.Erro
 
 // Created outputs given payload to http client with http status created.
 //
-// If json package fails to marshal given payload, Created returns internal server
-// error.
+// Payload is formatted in the same way as in OK function.
+//
+// If payload cannot be marshaled, Created returns internal server error.
 func Created(w http.ResponseWriter, r *http.Request, payload interface{}) horror.Error {
-	err := gores.JSONIndent(w, http.StatusCreated, payload, defaultPrefix, defaultIndent)
+	err := write(w, r, http.StatusCreated, payload)
 	if err != nil {
 		return FromRequest(r).InternalServerError(
-			fmt.Errorf("gores.JSONIndent: %w", err),
+			fmt.Errorf("write: %w", err),
 			"internal server error, please try again later",
 		)
 	}
@@ -171,8 +197,23 @@ func SeeOther(w http.ResponseWriter, r *http.Request, url string) horror.Error {
 }
 
 type errorResponse struct {
-	Data  interface{} `json:"error"`
-	Debug interface{} `json:"debug,omitempty"`
+	Data  *dataResponse  `json:"error"`
+	Debug *debugResponse `json:"debug,omitempty"`
+}
+
+func (e *errorResponse) MarshalPlainText() ([]byte, error) {
+	text := fmt.Sprintf("error %d: %s\n", e.Data.Code, e.Data.Message)
+	if e.Debug != nil {
+		text += fmt.Sprintf("debug: %s\n", e.Debug.ErrorMessage)
+	}
+	return []byte(text), nil
+}
+
+func (e *errorResponse) MarshalCSV() ([][]string, error) {
+	return [][]string{
+		{"code", "message"},
+		{strconv.Itoa(e.Data.Code), e.Data.Message},
+	}, nil
 }
 
 type dataResponse struct {
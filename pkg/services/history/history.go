@@ -0,0 +1,147 @@
+// Package history implements periodic snapshots of online users
+// counter and weekly occupancy heatmap computed from them.
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+// Daemon is a background function.
+type Daemon func()
+
+// DaemonArgs contains list of arguments for NewDaemon constructor.
+type DaemonArgs struct {
+	Counters storage.StatusTx
+	History  storage.History
+
+	// Interval is duration between snapshots.
+	Interval time.Duration
+
+	// Retention is duration after which snapshots
+	// are removed.
+	Retention time.Duration
+}
+
+// NewDaemon returns daemon to be run in the background in the
+// separate goroutine, that takes snapshot of online users counter
+// every interval and removes outdated snapshots.
+func NewDaemon(ctx context.Context, args DaemonArgs) Daemon {
+	return func() {
+		ticker := time.NewTicker(args.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := Snapshot(ctx, args, now); err != nil {
+					log.Println("Failed to take snapshot, reason: ", err.Error())
+				}
+			}
+		}
+	}
+}
+
+// Snapshot stores current number of online users as snapshot
+// taken at given time and removes snapshots older than retention.
+func Snapshot(ctx context.Context, args DaemonArgs, now time.Time) error {
+	online := 0
+	err := args.Counters.DevicesStatus(ctx, func(ctx context.Context, s storage.Status) error {
+		var err error
+		online, err = s.OnlineUsers(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("args.Counters.DevicesStatus: %w", err)
+	}
+
+	err = args.History.Add(ctx, models.Snapshot{
+		Time:   now,
+		Online: online,
+	})
+	if err != nil {
+		return fmt.Errorf("args.History.Add: %w", err)
+	}
+
+	err = args.History.Prune(ctx, now.Add(-args.Retention))
+	if err != nil {
+		return fmt.Errorf("args.History.Prune: %w", err)
+	}
+
+	return nil
+}
+
+// Heatmap contains average number of online users for every
+// hour of every weekday. Weekdays are indexed as in time
+// package, so the first one is Sunday.
+type Heatmap [7][24]float64
+
+// NewHeatmap computes heatmap from given snapshots in given
+// location. Hours without any snapshot have zero occupancy.
+func NewHeatmap(snapshots []models.Snapshot, loc *time.Location) Heatmap {
+	var (
+		sums   [7][24]int
+		counts [7][24]int
+		res    Heatmap
+	)
+
+	for _, s := range snapshots {
+		t := s.Time.In(loc)
+		sums[t.Weekday()][t.Hour()] += s.Online
+		counts[t.Weekday()][t.Hour()] += 1
+	}
+
+	for day := range res {
+		for hour := range res[day] {
+			if counts[day][hour] > 0 {
+				res[day][hour] = float64(sums[day][hour]) / float64(counts[day][hour])
+			}
+		}
+	}
+
+	return res
+}
+
+// At returns average occupancy at hour of given time.
+func (h Heatmap) At(t time.Time) float64 {
+	return h[t.Weekday()][t.Hour()]
+}
+
+// Expectation is expected occupancy during single hour.
+type Expectation struct {
+	// Time is beginning of hour.
+	Time time.Time `json:"time"`
+
+	// Online is expected number of online users.
+	Online float64 `json:"online"`
+}
+
+// Forecast returns expected occupancy for given number of hours
+// starting with hour of given time. Expected occupancy is the average
+// occupancy in the same hour of the same weekday. Forecast for
+// current hour takes given current occupancy into account too.
+func (h Heatmap) Forecast(now time.Time, current int, hours int) []Expectation {
+	res := make([]Expectation, 0, hours)
+
+	hour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location())
+	for i := 0; i < hours; i++ {
+		t := hour.Add(time.Duration(i) * time.Hour)
+		res = append(res, Expectation{
+			Time:   t,
+			Online: h.At(t),
+		})
+	}
+
+	if len(res) > 0 {
+		res[0].Online = (res[0].Online + float64(current)) / 2
+	}
+
+	return res
+}
@@ -0,0 +1,81 @@
+package history
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+	"github.com/hakierspejs/long-season/pkg/storage/temp"
+)
+
+func TestSnapshot(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	counters := temp.NewStatusTx()
+	args := DaemonArgs{
+		Counters:  counters,
+		History:   f.History(),
+		Interval:  time.Hour,
+		Retention: 2 * time.Hour,
+	}
+
+	start := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		err := counters.DevicesStatus(ctx, func(ctx context.Context, s storage.Status) error {
+			return s.SetOnlineUsers(ctx, i)
+		})
+		is.NoErr(err)
+		is.NoErr(Snapshot(ctx, args, start.Add(time.Duration(i)*time.Hour)))
+	}
+
+	// The first snapshot is outdated.
+	snapshots, err := f.History().Between(ctx, start, start.Add(24*time.Hour))
+	is.NoErr(err)
+	is.Equal(len(snapshots), 3)
+	is.Equal(snapshots[0].Online, 1)
+	is.Equal(snapshots[2].Online, 3)
+}
+
+func TestHeatmap(t *testing.T) {
+	is := is.New(t)
+
+	loc, err := time.LoadLocation("Europe/Warsaw")
+	is.NoErr(err)
+
+	// Tuesday, 19:00 in Warsaw.
+	tuesday := time.Date(2023, time.March, 7, 18, 0, 0, 0, time.UTC)
+	snapshots := []models.Snapshot{
+		{Time: tuesday, Online: 2},
+		{Time: tuesday.Add(30 * time.Minute), Online: 4},
+		{Time: tuesday.AddDate(0, 0, 7), Online: 6},
+		{Time: tuesday.Add(time.Hour), Online: 1},
+	}
+
+	h := NewHeatmap(snapshots, loc)
+	is.Equal(h[time.Tuesday][19], 4.0)
+	is.Equal(h[time.Tuesday][20], 1.0)
+	is.Equal(h[time.Tuesday][18], 0.0)
+	is.Equal(h[time.Wednesday][19], 0.0)
+
+	now := time.Date(2023, time.March, 14, 18, 40, 0, 0, loc)
+	forecast := h.Forecast(now, 0, 3)
+	is.Equal(len(forecast), 3)
+	is.True(forecast[0].Time.Equal(time.Date(2023, time.March, 14, 18, 0, 0, 0, loc)))
+	is.Equal(forecast[0].Online, 0.0)
+	is.Equal(forecast[1].Online, 4.0)
+	is.Equal(forecast[2].Online, 1.0)
+
+	// Current occupancy is taken into account.
+	forecast = h.Forecast(now.Add(time.Hour), 6, 1)
+	is.Equal(forecast[0].Online, 5.0)
+}
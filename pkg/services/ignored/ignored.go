@@ -0,0 +1,35 @@
+// Package ignored manages hardware addresses of infrastructure
+// devices, that are excluded from online statuses.
+package ignored
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+// Add parses given hardware address or its prefix and stores
+// it with given tag. It returns models.ErrInvalidPrefix for
+// invalid prefixes and errors.ErrPrefixDuplication from
+// storage package for prefixes, which are already ignored.
+func Add(ctx context.Context, s storage.Ignored, prefix, tag string) (*models.IgnoredAddress, error) {
+	parsed, err := models.ParseAddressPrefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("models.ParseAddressPrefix: %w", err)
+	}
+
+	a := models.IgnoredAddress{
+		ID:     uuid.New().String(),
+		Tag:    tag,
+		Prefix: parsed,
+	}
+	if _, err := s.New(ctx, a); err != nil {
+		return nil, fmt.Errorf("s.New: %w", err)
+	}
+
+	return &a, nil
+}
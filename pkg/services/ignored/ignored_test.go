@@ -0,0 +1,39 @@
+package ignored
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestAdd(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	a, err := Add(ctx, f.Ignored(), "b8:27:eb", "raspberry pi")
+	is.NoErr(err)
+	is.Equal(a.Tag, "raspberry pi")
+	is.Equal(a.PrefixString(), "b8:27:eb")
+
+	// The same prefix in other notation is a duplicate.
+	_, err = Add(ctx, f.Ignored(), "B8-27-EB", "")
+	is.True(errors.Is(err, serrors.ErrPrefixDuplication))
+
+	_, err = Add(ctx, f.Ignored(), "b8:27:e", "")
+	is.True(errors.Is(err, models.ErrInvalidPrefix))
+
+	all, err := f.Ignored().All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), 1)
+	is.Equal(all[0].ID, a.ID)
+}
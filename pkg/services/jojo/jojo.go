@@ -4,7 +4,6 @@ package jojo
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -14,116 +13,228 @@ import (
 
 	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/keyring"
 	"github.com/hakierspejs/long-season/pkg/services/session"
 )
 
+const (
+	// accessAudience is audience of tokens authorizing
+	// requests, stored in cookies or sent by API clients.
+	accessAudience = "ls-apiv1"
+
+	// refreshAudience is audience of refresh tokens, that
+	// can only be exchanged for new tokens.
+	refreshAudience = "ls-refresh"
+
+	// DefaultLifetime is lifetime of tokens for sessions
+	// without expiration time.
+	DefaultLifetime = 48 * time.Hour
+
+	// DefaultAccessLifetime is default lifetime of access
+	// tokens issued for API clients.
+	DefaultAccessLifetime = 15 * time.Minute
+)
+
 // JWT implements session's interfaces for stateless session
 // management.
 type JWT struct {
-	// Secret is jwt secret. The longer secret is, the better.
-	Secret []byte
-
-	// Algorithm is JWT algorithm used for signing
-	Algorithm jwt.Algorithm
+	// Keys are keys used for signing and verifying tokens.
+	Keys *keyring.Keyring
 
 	// AppName is issuer application name.
 	AppName string
 
 	// CookieKey is key used to store API token in cookies.
 	CookieKey string
+
+	// AccessLifetime is lifetime of access tokens issued
+	// for API clients. DefaultAccessLifetime is used,
+	// when it is zero.
+	AccessLifetime time.Duration
+
+	// clock returns current time. time.Now is used,
+	// when it is nil.
+	clock func() time.Time
 }
 
 const internalServerErrorResponse = "Internal server error. Please try again later."
 
-// Tokenize outputs JWT token representation of given state.
-func (j *JWT) Tokenize(ctx context.Context, s session.State) (string, error) {
-	errFactory := happier.FromContext(ctx)
+func (j *JWT) now() time.Time {
+	if j.clock == nil {
+		return time.Now()
+	}
+	return j.clock()
+}
 
-	signer, err := jwt.NewSignerHS(j.Algorithm, []byte(j.Secret))
-	if err != nil {
-		return "", errFactory.InternalServerError(
-			fmt.Errorf("jwt.NewSignerHS: %w", err),
-			internalServerErrorResponse,
-		)
+// Sign returns JWT token with given claims signed with
+// current key. Claims have to be serializable to JSON.
+func (j *JWT) Sign(claims interface{}) (string, error) {
+	return j.Keys.Sign(claims)
+}
+
+// Verify checks signature of given token and decodes its
+// claims into given value. Verify does not validate
+// expiration time or any other claim.
+func (j *JWT) Verify(token string, claims interface{}) error {
+	return j.Keys.Verify(token, claims)
+}
+
+// expires returns expiration time of given session.
+func (j *JWT) expires(s session.State) time.Time {
+	if s.Expires.IsZero() {
+		return j.now().Add(DefaultLifetime)
+	}
+	return s.Expires
+}
+
+// tokenize returns token for given audience with given state,
+// that expires at given time. Tokens are issued at the moment
+// of creating session, so they can be revoked together.
+func (j *JWT) tokenize(s session.State, audience string, expires time.Time) (string, error) {
+	issued := s.Issued
+	if issued.IsZero() {
+		issued = j.now()
 	}
 
-	builder := jwt.NewBuilder(signer)
-	now := time.Now()
-	token, err := builder.Build(&models.Claims{
+	subject := "auth"
+	if audience == refreshAudience {
+		subject = "refresh"
+	}
+
+	return j.Sign(&models.Claims{
 		StandardClaims: jwt.StandardClaims{
 			Issuer:    j.AppName,
-			Audience:  []string{"ls-apiv1"},
-			Subject:   "auth",
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour * 48)),
-			IssuedAt:  jwt.NewNumericDate(now),
+			Audience:  []string{audience},
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(expires),
+			IssuedAt:  jwt.NewNumericDate(issued),
 			ID:        s.ID,
 		},
-		Nickname: s.Nickname,
-		UserID:   s.UserID,
-		Values:   s.Values,
+		Nickname:   s.Nickname,
+		UserID:     s.UserID,
+		Role:       s.Role,
+		Remember:   s.Remember,
+		Generation: s.Generation,
+		Values:     s.Values,
 	})
+}
+
+// Tokenize outputs JWT token representation of given state.
+func (j *JWT) Tokenize(ctx context.Context, s session.State) (string, error) {
+	token, err := j.tokenize(s, accessAudience, j.expires(s))
 	if err != nil {
-		return "", errFactory.InternalServerError(
-			fmt.Errorf("builder.Build: %w", err),
+		return "", happier.FromContext(ctx).InternalServerError(
+			fmt.Errorf("j.tokenize: %w", err),
 			internalServerErrorResponse,
 		)
 	}
 
-	return token.String(), nil
+	return token, nil
 }
 
 // Save is method for returning session data or session identifier to client.
+// Cookies of sessions, that are not remembered, are removed after closing
+// the browser.
 func (j *JWT) Save(ctx context.Context, w http.ResponseWriter, s session.State) error {
 	token, err := j.Tokenize(ctx, s)
 	if err != nil {
 		return err
 	}
 
-	http.SetCookie(w, &http.Cookie{
+	cookie := &http.Cookie{
 		Name:     j.CookieKey,
-		Expires:  time.Now().Add(time.Hour * 48),
 		Value:    token,
 		HttpOnly: true,
 		Path:     "/",
-	})
+	}
+	if s.Remember {
+		cookie.Expires = j.expires(s)
+	}
+	http.SetCookie(w, cookie)
 
 	return nil
 }
 
-func (j *JWT) parseToken(ctx context.Context, token string) (*session.State, error) {
-	fail := func(err error) (*session.State, error) {
-		return nil, happier.FromContext(ctx).Unauthorized(
-			fmt.Errorf("jojo: failed to parse token: %w", err),
-			"Failed to parse authorization token.",
+// Issue returns short-lived access token and refresh token,
+// which is valid as long as given session.
+func (j *JWT) Issue(ctx context.Context, s session.State) (*session.Tokens, error) {
+	lifetime := j.AccessLifetime
+	if lifetime == 0 {
+		lifetime = DefaultAccessLifetime
+	}
+
+	expires := j.expires(s)
+	access := j.now().Add(lifetime)
+	if access.After(expires) {
+		access = expires
+	}
+
+	fail := func(err error) (*session.Tokens, error) {
+		return nil, happier.FromContext(ctx).InternalServerError(
+			fmt.Errorf("j.tokenize: %w", err),
+			internalServerErrorResponse,
 		)
 	}
 
-	verifier, err := jwt.NewVerifierHS(j.Algorithm, j.Secret)
+	accessToken, err := j.tokenize(s, accessAudience, access)
 	if err != nil {
 		return fail(err)
 	}
 
-	newToken, err := jwt.ParseAndVerifyString(token, verifier)
+	refreshToken, err := j.tokenize(s, refreshAudience, expires)
 	if err != nil {
 		return fail(err)
 	}
 
+	return &session.Tokens{
+		Access:  accessToken,
+		Refresh: refreshToken,
+		Expires: access,
+	}, nil
+}
+
+func (j *JWT) parseToken(ctx context.Context, token, audience string) (*session.State, error) {
+	fail := func(err error) (*session.State, error) {
+		return nil, happier.FromContext(ctx).Unauthorized(
+			fmt.Errorf("jojo: failed to parse token: %w", err),
+			"Failed to parse authorization token.",
+		)
+	}
+
 	newClaims := new(models.Claims)
-	err = json.Unmarshal(newToken.RawClaims(), newClaims)
+	err := j.Verify(token, newClaims)
 	if err != nil {
 		return fail(err)
 	}
 
-	now := time.Now()
-	if !newClaims.IsValidAt(now) {
+	if !newClaims.IsValidAt(j.now()) {
 		return fail(fmt.Errorf("token had expired"))
 	}
 
+	if !newClaims.IsForAudience(audience) {
+		return fail(fmt.Errorf("token is not for %s audience", audience))
+	}
+
+	issued := time.Time{}
+	if newClaims.IssuedAt != nil {
+		issued = newClaims.IssuedAt.Time
+	}
+
+	expires := time.Time{}
+	if newClaims.ExpiresAt != nil {
+		expires = newClaims.ExpiresAt.Time
+	}
+
 	return &session.State{
-		ID:       newClaims.ID,
-		UserID:   newClaims.UserID,
-		Nickname: newClaims.Nickname,
-		Values:   newClaims.Values,
+		ID:         newClaims.ID,
+		UserID:     newClaims.UserID,
+		Nickname:   newClaims.Nickname,
+		Role:       newClaims.Role,
+		Issued:     issued,
+		Expires:    expires,
+		Remember:   newClaims.Remember,
+		Generation: newClaims.Generation,
+		Values:     newClaims.Values,
 	}, nil
 }
 
@@ -138,30 +249,43 @@ func (f RenewStrategy) Renew(r *http.Request) (*session.State, error) {
 }
 
 // RenewFromCookies returns Renewer for retrieving session from
-// http cookies.
+// http cookies. Browsers don't send expiration time of cookies,
+// so only expiration time of token is checked.
 func (j *JWT) RenewFromCookies() RenewStrategy {
 	return func(r *http.Request) (*session.State, error) {
-		errFactory := happier.FromRequest(r)
-
 		cookie, err := r.Cookie(j.CookieKey)
 		if err != nil {
-			return nil, errFactory.Unauthorized(
+			return nil, happier.FromRequest(r).Unauthorized(
 				fmt.Errorf("r.Cookie: %w", err),
 				"Failed to read authorization cookie.",
 			)
 		}
 
-		valid := cookie.Expires.Before(time.Now())
-		now := time.Now()
-		if !valid {
-			return nil, errFactory.Unauthorized(
-				fmt.Errorf("cookie.Expires.Before: %v", now),
-				"Failed to read authorization cookie.",
-			)
-		}
+		return j.parseToken(r.Context(), cookie.Value, accessAudience)
+	}
+}
+
+// headerToken returns token from given header of given request
+// with given prefix.
+func headerToken(r *http.Request, header, prefix string) (string, error) {
+	errFactory := happier.FromRequest(r)
+
+	value := r.Header.Get(header)
+	if value == "" {
+		return "", errFactory.Unauthorized(
+			fmt.Errorf("jojo: %s header is empty", header),
+			"Failed to read authorization header.",
+		)
+	}
 
-		return j.parseToken(r.Context(), cookie.Value)
+	if !strings.HasPrefix(value, prefix+" ") {
+		return "", errFactory.Unauthorized(
+			fmt.Errorf("jojo: %s header should has '%s ' prefix", header, prefix),
+			"Failed to parse authorization header.",
+		)
 	}
+
+	return strings.TrimPrefix(value, prefix+" "), nil
 }
 
 // RenewFromHeaderToken returns Renewer for retrieving session from
@@ -169,28 +293,29 @@ func (j *JWT) RenewFromCookies() RenewStrategy {
 // "Authorization" and prefix equal to "Bearer" returned RenewStrategy
 // will retrieve JWT token from "Authorization" header in the form of
 // "Bearer $TOKEN" where "$TOKEN" is tokenized JWT session State.
+// Refresh tokens are rejected.
 func (j *JWT) RenewFromHeaderToken(header, prefix string) RenewStrategy {
 	return func(r *http.Request) (*session.State, error) {
-		errFactory := happier.FromRequest(r)
-
-		header := r.Header.Get(header)
-		if header == "" {
-			return nil, errFactory.Unauthorized(
-				fmt.Errorf("jojo: %s header is empty", header),
-				"Failed to read authorization header.",
-			)
+		token, err := headerToken(r, header, prefix)
+		if err != nil {
+			return nil, err
 		}
 
-		if !strings.HasPrefix(header, prefix+" ") {
-			return nil, errFactory.Unauthorized(
-				fmt.Errorf("jojo: %s header should has '%s ' prefix", header, prefix),
-				"Failed to parse authorization header.",
-			)
-		}
+		return j.parseToken(r.Context(), token, accessAudience)
+	}
+}
 
-		token := strings.TrimPrefix(header, prefix+" ")
+// RenewFromRefreshToken returns Renewer for retrieving session from
+// refresh token sent in given header with given prefix, the same way
+// as RenewFromHeaderToken does. Access tokens are rejected.
+func (j *JWT) RenewFromRefreshToken(header, prefix string) RenewStrategy {
+	return func(r *http.Request) (*session.State, error) {
+		token, err := headerToken(r, header, prefix)
+		if err != nil {
+			return nil, err
+		}
 
-		return j.parseToken(r.Context(), token)
+		return j.parseToken(r.Context(), token, refreshAudience)
 	}
 }
 
@@ -0,0 +1,172 @@
+package jojo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/services/keyring"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+)
+
+func newJWT(is *is.I, now *time.Time) *JWT {
+	key, err := keyring.NewSecretKey([]byte("jwt-secret"))
+	is.NoErr(err)
+
+	return &JWT{
+		Keys:           keyring.New(key),
+		AppName:        "long-season-test",
+		CookieKey:      "jwt-token",
+		AccessLifetime: 15 * time.Minute,
+		clock:          func() time.Time { return *now },
+	}
+}
+
+func cookieRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "jwt-token", Value: token})
+	return r
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestSave(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	now := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+	j := newJWT(is, &now)
+
+	state := session.State{
+		ID:       "a",
+		UserID:   "1",
+		Nickname: "alice",
+		Issued:   now.Add(-time.Hour),
+		Expires:  now.Add(2 * time.Hour),
+	}
+
+	// Cookies of sessions, that are not remembered, are
+	// removed after closing browser.
+	w := httptest.NewRecorder()
+	is.NoErr(j.Save(ctx, w, state))
+	cookie := w.Result().Cookies()[0]
+	is.True(cookie.Expires.IsZero())
+
+	// Browsers don't send expiration time of cookies.
+	restored, err := j.RenewFromCookies().Renew(cookieRequest(cookie.Value))
+	is.NoErr(err)
+	is.Equal(restored.ID, "a")
+	is.True(restored.Issued.Equal(state.Issued))
+	is.True(restored.Expires.Equal(state.Expires))
+	is.True(!restored.Remember)
+
+	// Remembered sessions are stored in persistent cookies,
+	// which expire together with tokens.
+	state.Remember = true
+	w = httptest.NewRecorder()
+	is.NoErr(j.Save(ctx, w, state))
+	cookie = w.Result().Cookies()[0]
+	is.True(cookie.Expires.Equal(state.Expires))
+
+	restored, err = j.RenewFromCookies().Renew(cookieRequest(cookie.Value))
+	is.NoErr(err)
+	is.True(restored.Remember)
+
+	// Token is valid until the very last second.
+	now = state.Expires.Add(-time.Second)
+	_, err = j.RenewFromCookies().Renew(cookieRequest(cookie.Value))
+	is.NoErr(err)
+
+	now = state.Expires
+	_, err = j.RenewFromCookies().Renew(cookieRequest(cookie.Value))
+	is.True(err != nil)
+
+	_, err = j.RenewFromCookies().Renew(httptest.NewRequest(http.MethodGet, "/", nil))
+	is.True(err != nil)
+}
+
+func TestTokenizeDefaultLifetime(t *testing.T) {
+	is := is.New(t)
+
+	now := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+	j := newJWT(is, &now)
+
+	token, err := j.Tokenize(context.Background(), session.State{ID: "a", UserID: "1"})
+	is.NoErr(err)
+
+	// Sessions without issue and expiration times are
+	// issued at the moment of tokenization and expire
+	// after default lifetime.
+	now = now.Add(time.Second)
+	restored, err := j.RenewFromHeaderToken("Authorization", "Bearer").Renew(bearerRequest(token))
+	is.NoErr(err)
+	is.True(restored.Expires.Equal(now.Add(DefaultLifetime - time.Second)))
+}
+
+func TestIssue(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	now := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+	j := newJWT(is, &now)
+
+	state := session.State{
+		ID:         "a",
+		UserID:     "1",
+		Remember:   true,
+		Generation: 3,
+		Issued:     now.Add(-time.Hour),
+		Expires:    now.Add(24 * time.Hour),
+	}
+
+	tokens, err := j.Issue(ctx, state)
+	is.NoErr(err)
+	is.True(tokens.Expires.Equal(now.Add(15 * time.Minute)))
+
+	access := j.RenewFromHeaderToken("Authorization", "Bearer")
+	refresh := j.RenewFromRefreshToken("Authorization", "Bearer")
+
+	restored, err := access.Renew(bearerRequest(tokens.Access))
+	is.NoErr(err)
+	is.Equal(restored.ID, "a")
+	is.True(restored.Expires.Equal(tokens.Expires))
+
+	restored, err = refresh.Renew(bearerRequest(tokens.Refresh))
+	is.NoErr(err)
+	is.Equal(restored.ID, "a")
+	is.Equal(restored.Generation, 3)
+	is.True(restored.Expires.Equal(state.Expires))
+
+	// Tokens cannot be used for each other.
+	_, err = access.Renew(bearerRequest(tokens.Refresh))
+	is.True(err != nil)
+	_, err = j.RenewFromCookies().Renew(cookieRequest(tokens.Refresh))
+	is.True(err != nil)
+	_, err = refresh.Renew(bearerRequest(tokens.Access))
+	is.True(err != nil)
+
+	// Access token expires long before refresh token.
+	now = tokens.Expires
+	_, err = access.Renew(bearerRequest(tokens.Access))
+	is.True(err != nil)
+	_, err = refresh.Renew(bearerRequest(tokens.Refresh))
+	is.NoErr(err)
+
+	// Access tokens never outlive their sessions.
+	state.Expires = now.Add(time.Minute)
+	tokens, err = j.Issue(ctx, state)
+	is.NoErr(err)
+	is.True(tokens.Expires.Equal(state.Expires))
+
+	now = state.Expires
+	_, err = refresh.Renew(bearerRequest(tokens.Refresh))
+	is.True(err != nil)
+}
@@ -0,0 +1,73 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+)
+
+// ParsePEM returns key for first PEM encoded private key
+// in given data. PKCS #8, SEC 1 and PKCS #1 encodings
+// are supported.
+func ParsePEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM data", ErrUnsupportedKey)
+	}
+
+	var (
+		private crypto.PrivateKey
+		err     error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("x509: %w", err)
+	}
+
+	return NewKey(private)
+}
+
+// ReadFiles returns keys read from PEM files with given
+// paths, in the same order.
+func ReadFiles(paths ...string) ([]*Key, error) {
+	res := make([]*Key, 0, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ioutil.ReadFile: %w", err)
+		}
+
+		key, err := ParsePEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("keyring.ParsePEM(%s): %w", path, err)
+		}
+
+		res = append(res, key)
+	}
+	return res, nil
+}
+
+// Secrets returns HS256 keys with given secrets, in the
+// same order.
+func Secrets(secrets ...string) ([]*Key, error) {
+	res := make([]*Key, 0, len(secrets))
+	for _, secret := range secrets {
+		key, err := NewSecretKey([]byte(secret))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, key)
+	}
+	return res, nil
+}
@@ -0,0 +1,387 @@
+// Package keyring implements set of keys used for signing
+// and verifying JWT tokens, with rotation of keys and
+// publishing of public keys as JSON Web Key Set.
+package keyring
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/cristalhq/jwt/v3"
+)
+
+var (
+	// ErrNoKeys is returned when keyring has no key
+	// for signing tokens.
+	ErrNoKeys = errors.New("keyring: there are no keys")
+
+	// ErrUnknownKey is returned when token is signed with key
+	// that is not in the keyring or has already expired.
+	ErrUnknownKey = errors.New("keyring: unknown key")
+
+	// ErrUnsupportedKey is returned for keys, that cannot
+	// be used for signing tokens.
+	ErrUnsupportedKey = errors.New("keyring: unsupported key")
+)
+
+// rsaBits is size of generated RSA keys.
+const rsaBits = 2048
+
+// Key is single key used for signing and verifying tokens.
+type Key struct {
+	// ID is value of "kid" header of tokens signed with key.
+	ID string
+
+	// Algorithm is JWT algorithm used with key.
+	Algorithm jwt.Algorithm
+
+	// Created is time of key generation. Zero for keys
+	// read from files or configuration.
+	Created time.Time
+
+	// Expires is time after which tokens signed with key
+	// are no longer accepted. Zero for keys that never expire.
+	Expires time.Time
+
+	private  interface{}
+	public   crypto.PublicKey
+	signer   jwt.Signer
+	verifier jwt.Verifier
+}
+
+// NewSecretKey returns HS256 key with given secret. ID of
+// key is derived from secret.
+func NewSecretKey(secret []byte) (*Key, error) {
+	signer, err := jwt.NewSignerHS(jwt.HS256, secret)
+	if err != nil {
+		return nil, fmt.Errorf("jwt.NewSignerHS: %w", err)
+	}
+	verifier, err := jwt.NewVerifierHS(jwt.HS256, secret)
+	if err != nil {
+		return nil, fmt.Errorf("jwt.NewVerifierHS: %w", err)
+	}
+
+	sum := sha256.Sum256(secret)
+
+	return &Key{
+		ID:        "hs-" + hex.EncodeToString(sum[:8]),
+		Algorithm: jwt.HS256,
+		private:   secret,
+		signer:    signer,
+		verifier:  verifier,
+	}, nil
+}
+
+// NewKey returns key for given Ed25519, ECDSA or RSA private
+// key. Algorithm is chosen by type of key and ID is JWK
+// thumbprint of public key, as described in RFC 7638.
+func NewKey(private crypto.PrivateKey) (*Key, error) {
+	res := &Key{private: private}
+
+	var err error
+	switch key := private.(type) {
+	case ed25519.PrivateKey:
+		res.Algorithm = jwt.EdDSA
+		res.public = key.Public()
+		res.signer, err = jwt.NewSignerEdDSA(key)
+		if err == nil {
+			res.verifier, err = jwt.NewVerifierEdDSA(key.Public().(ed25519.PublicKey))
+		}
+	case *ecdsa.PrivateKey:
+		res.Algorithm, err = curveAlgorithm(key.Curve)
+		if err != nil {
+			return nil, err
+		}
+		res.public = &key.PublicKey
+		res.signer, err = jwt.NewSignerES(res.Algorithm, key)
+		if err == nil {
+			res.verifier, err = jwt.NewVerifierES(res.Algorithm, &key.PublicKey)
+		}
+	case *rsa.PrivateKey:
+		res.Algorithm = jwt.RS256
+		res.public = &key.PublicKey
+		res.signer, err = jwt.NewSignerRS(res.Algorithm, key)
+		if err == nil {
+			res.verifier, err = jwt.NewVerifierRS(res.Algorithm, &key.PublicKey)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, private)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+
+	res.ID, err = res.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Generate returns new random key for given asymmetric
+// algorithm: EdDSA, ES256 or RS256.
+func Generate(algorithm jwt.Algorithm) (*Key, error) {
+	var (
+		private crypto.PrivateKey
+		err     error
+	)
+	switch algorithm {
+	case jwt.EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case jwt.ES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.RS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaBits)
+	default:
+		return nil, fmt.Errorf("%w: cannot generate %s key", ErrUnsupportedKey, algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keyring.Generate: %w", err)
+	}
+
+	return NewKey(private)
+}
+
+func curveAlgorithm(curve elliptic.Curve) (jwt.Algorithm, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.ES256, nil
+	case elliptic.P384():
+		return jwt.ES384, nil
+	case elliptic.P521():
+		return jwt.ES512, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported curve", ErrUnsupportedKey)
+	}
+}
+
+// Valid returns true if tokens signed with key are
+// accepted at given time.
+func (k *Key) Valid(now time.Time) bool {
+	return k.Expires.IsZero() || now.Before(k.Expires)
+}
+
+// Public returns true if key is asymmetric and its
+// public part can be published.
+func (k *Key) Public() bool {
+	return k.public != nil
+}
+
+// JWK is public key in the JSON Web Key format,
+// described in RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// jwk returns public part of key with required members only.
+func (k *Key) jwk() (JWK, error) {
+	switch key := k.public.(type) {
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       b64.EncodeToString(key),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			KeyType: "EC",
+			Curve:   key.Curve.Params().Name,
+			X:       b64.EncodeToString(key.X.FillBytes(make([]byte, size))),
+			Y:       b64.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType: "RSA",
+			N:       b64.EncodeToString(key.N.Bytes()),
+			E:       b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("%w: key has no public part", ErrUnsupportedKey)
+	}
+}
+
+// thumbprint returns JWK thumbprint of public key.
+func (k *Key) thumbprint() (string, error) {
+	key, err := k.jwk()
+	if err != nil {
+		return "", err
+	}
+
+	// Members have to be ordered lexicographically, which
+	// encoding/json does for maps.
+	members := map[string]string{"kty": key.KeyType}
+	switch key.KeyType {
+	case "OKP":
+		members["crv"], members["x"] = key.Curve, key.X
+	case "EC":
+		members["crv"], members["x"], members["y"] = key.Curve, key.X, key.Y
+	case "RSA":
+		members["n"], members["e"] = key.N, key.E
+	}
+
+	raw, err := json.Marshal(members)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	sum := sha256.Sum256(raw)
+	return b64.EncodeToString(sum[:]), nil
+}
+
+// Keyring is concurrently safe set of keys. First key
+// of keyring signs new tokens, all the valid keys are
+// accepted when verifying tokens.
+type Keyring struct {
+	guard sync.RWMutex
+	keys  []*Key
+	now   func() time.Time
+}
+
+// New returns keyring with given keys. First key is used
+// for signing tokens.
+func New(keys ...*Key) *Keyring {
+	return &Keyring{
+		keys: keys,
+		now:  time.Now,
+	}
+}
+
+// Set replaces keys of keyring.
+func (k *Keyring) Set(keys ...*Key) {
+	k.guard.Lock()
+	defer k.guard.Unlock()
+
+	k.keys = keys
+}
+
+// Current returns key used for signing tokens.
+func (k *Keyring) Current() (*Key, error) {
+	k.guard.RLock()
+	defer k.guard.RUnlock()
+
+	if len(k.keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return k.keys[0], nil
+}
+
+// Keys returns keys accepted at the moment.
+func (k *Keyring) Keys() []*Key {
+	k.guard.RLock()
+	defer k.guard.RUnlock()
+
+	now := k.now()
+	res := make([]*Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if key.Valid(now) {
+			res = append(res, key)
+		}
+	}
+	return res
+}
+
+// find returns valid key with given id. Tokens without
+// "kid" header are matched with first key of given algorithm,
+// so tokens issued before keys had ids are still accepted.
+func (k *Keyring) find(id string, algorithm jwt.Algorithm) (*Key, error) {
+	for _, key := range k.Keys() {
+		if id == "" && key.Algorithm == algorithm || id != "" && key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// Sign returns JWT token with given claims signed with
+// current key. Claims have to be serializable to JSON.
+func (k *Keyring) Sign(claims interface{}) (string, error) {
+	key, err := k.Current()
+	if err != nil {
+		return "", err
+	}
+
+	token, err := jwt.NewBuilder(key.signer, jwt.WithKeyID(key.ID)).Build(claims)
+	if err != nil {
+		return "", fmt.Errorf("builder.Build: %w", err)
+	}
+
+	return token.String(), nil
+}
+
+// Verify checks signature of given token with key named
+// in its header and decodes its claims into given value.
+// Verify does not validate expiration time or any other claim.
+func (k *Keyring) Verify(token string, claims interface{}) error {
+	parsed, err := jwt.ParseString(token)
+	if err != nil {
+		return fmt.Errorf("jwt.ParseString: %w", err)
+	}
+
+	header := parsed.Header()
+	key, err := k.find(header.KeyID, header.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	_, err = jwt.ParseAndVerifyString(token, key.verifier)
+	if err != nil {
+		return fmt.Errorf("jwt.ParseAndVerifyString: %w", err)
+	}
+
+	err = json.Unmarshal(parsed.RawClaims(), claims)
+	if err != nil {
+		return fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+// JWKS returns public parts of valid asymmetric keys.
+// Secret keys are never published.
+func (k *Keyring) JWKS() JWKS {
+	res := JWKS{Keys: []JWK{}}
+	for _, key := range k.Keys() {
+		if !key.Public() {
+			continue
+		}
+		jwk, err := key.jwk()
+		if err != nil {
+			continue
+		}
+		jwk.ID = key.ID
+		jwk.Use = "sig"
+		jwk.Algorithm = string(key.Algorithm)
+		res.Keys = append(res.Keys, jwk)
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+package keyring
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cristalhq/jwt/v3"
+
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// KeyName is name of generated signing keys in keys storage.
+const KeyName = "jwt"
+
+// Store keeps generated signing keys in the keys storage
+// and rotates them.
+type Store struct {
+	Keys storage.Keys
+
+	// Algorithm is algorithm of generated keys.
+	Algorithm jwt.Algorithm
+
+	// Rotation is age after which current key is replaced
+	// with new one. Zero disables automatic rotation.
+	Rotation time.Duration
+
+	// Grace is duration for which tokens signed with replaced
+	// key are still accepted. It should not be shorter than
+	// lifetime of tokens.
+	Grace time.Duration
+}
+
+// record is stored form of generated key.
+type record struct {
+	// Key is PKCS #8 encoded private key.
+	Key     []byte    `json:"key"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+func (s *Store) read(ctx context.Context) ([]*Key, error) {
+	data, err := s.Keys.Read(ctx, KeyName)
+	if errors.Is(err, serrors.ErrNoID) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s.Keys.Read: %w", err)
+	}
+
+	var records []record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	res := make([]*Key, 0, len(records))
+	for _, r := range records {
+		private, err := x509.ParsePKCS8PrivateKey(r.Key)
+		if err != nil {
+			return nil, fmt.Errorf("x509.ParsePKCS8PrivateKey: %w", err)
+		}
+
+		key, err := NewKey(private)
+		if err != nil {
+			return nil, err
+		}
+		key.Created = r.Created
+		key.Expires = r.Expires
+
+		res = append(res, key)
+	}
+	return res, nil
+}
+
+func (s *Store) save(ctx context.Context, keys []*Key) error {
+	records := make([]record, 0, len(keys))
+	for _, key := range keys {
+		der, err := x509.MarshalPKCS8PrivateKey(key.private)
+		if err != nil {
+			return fmt.Errorf("x509.MarshalPKCS8PrivateKey: %w", err)
+		}
+
+		records = append(records, record{
+			Key:     der,
+			Created: key.Created,
+			Expires: key.Expires,
+		})
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	if err := s.Keys.Save(ctx, KeyName, data); err != nil {
+		return fmt.Errorf("s.Keys.Save: %w", err)
+	}
+	return nil
+}
+
+// Refresh returns stored keys, that are valid at given time,
+// with current key first. Current key is replaced with new one,
+// when there is none, it uses other algorithm or it is older
+// than Rotation. Expired keys are removed.
+func (s *Store) Refresh(ctx context.Context, now time.Time) ([]*Key, error) {
+	return s.update(ctx, now, false)
+}
+
+// Rotate replaces current key with new one regardless of its
+// age and returns stored keys valid at given time.
+func (s *Store) Rotate(ctx context.Context, now time.Time) ([]*Key, error) {
+	return s.update(ctx, now, true)
+}
+
+func (s *Store) update(ctx context.Context, now time.Time, force bool) ([]*Key, error) {
+	stored, err := s.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	keys := make([]*Key, 0, len(stored)+1)
+	for _, key := range stored {
+		if key.Valid(now) {
+			keys = append(keys, key)
+		} else {
+			changed = true
+		}
+	}
+
+	var current *Key
+	if len(keys) > 0 && keys[0].Expires.IsZero() {
+		current = keys[0]
+	}
+
+	stale := current == nil || current.Algorithm != s.Algorithm ||
+		s.Rotation > 0 && now.Sub(current.Created) >= s.Rotation
+	if force || stale {
+		key, err := Generate(s.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		key.Created = now
+
+		if current != nil {
+			current.Expires = now.Add(s.Grace)
+		}
+
+		keys = append([]*Key{key}, keys...)
+		changed = true
+	}
+
+	if changed {
+		if err := s.save(ctx, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// Daemon is a background function.
+type Daemon func()
+
+// DaemonArgs contains list of arguments for NewDaemon constructor.
+type DaemonArgs struct {
+	Store   *Store
+	Keyring *Keyring
+
+	// Interval is duration between checks of key age.
+	Interval time.Duration
+}
+
+// NewDaemon returns daemon to be run in the background in the
+// separate goroutine, that refreshes stored keys every interval
+// and replaces keys of keyring with them.
+func NewDaemon(ctx context.Context, args DaemonArgs) Daemon {
+	return func() {
+		ticker := time.NewTicker(args.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				keys, err := args.Store.Refresh(ctx, now)
+				if err != nil {
+					log.Println("Failed to refresh signing keys, reason: ", err.Error())
+					continue
+				}
+				args.Keyring.Set(keys...)
+			}
+		}
+	}
+}
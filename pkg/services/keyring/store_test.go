@@ -0,0 +1,77 @@
+package keyring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/jwt/v3"
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	store := &Store{
+		Keys:      f.Keys(),
+		Algorithm: jwt.EdDSA,
+		Rotation:  24 * time.Hour,
+		Grace:     time.Hour,
+	}
+	now := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+
+	// First key is generated and stored.
+	keys, err := store.Refresh(ctx, now)
+	is.NoErr(err)
+	is.Equal(len(keys), 1)
+	first := keys[0]
+	is.Equal(first.Algorithm, jwt.EdDSA)
+
+	keys, err = store.Refresh(ctx, now.Add(time.Hour))
+	is.NoErr(err)
+	is.Equal(len(keys), 1)
+	is.Equal(keys[0].ID, first.ID)
+
+	// Old key is replaced and kept for grace period.
+	now = now.Add(24 * time.Hour)
+	keys, err = store.Refresh(ctx, now)
+	is.NoErr(err)
+	is.Equal(len(keys), 2)
+	is.True(keys[0].ID != first.ID)
+	is.Equal(keys[1].ID, first.ID)
+	is.True(keys[1].Expires.Equal(now.Add(time.Hour)))
+	second := keys[0]
+
+	keys, err = store.Refresh(ctx, now.Add(time.Hour))
+	is.NoErr(err)
+	is.Equal(len(keys), 1)
+	is.Equal(keys[0].ID, second.ID)
+
+	// Changing algorithm replaces key immediately.
+	store.Algorithm = jwt.ES256
+	keys, err = store.Refresh(ctx, now.Add(time.Hour))
+	is.NoErr(err)
+	is.Equal(len(keys), 2)
+	is.Equal(keys[0].Algorithm, jwt.ES256)
+	is.Equal(keys[1].ID, second.ID)
+
+	// Forced rotation ignores age of key.
+	keys, err = store.Rotate(ctx, now.Add(time.Hour))
+	is.NoErr(err)
+	is.Equal(len(keys), 3)
+	is.Equal(keys[0].Algorithm, jwt.ES256)
+
+	// Tokens signed with replaced keys are still verified.
+	ring := New(keys...)
+	ring.now = func() time.Time { return now.Add(time.Hour) }
+	token, err := New(second).Sign(&claims{Subject: "alice"})
+	is.NoErr(err)
+	is.NoErr(ring.Verify(token, new(claims)))
+}
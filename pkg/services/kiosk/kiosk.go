@@ -0,0 +1,187 @@
+// Package kiosk implements rotating check-in codes displayed
+// by kiosk inside the hackerspace. Members scan displayed code
+// with their phones, which proves their physical presence.
+package kiosk
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/url"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/cristalhq/jwt/v3"
+
+	"github.com/hakierspejs/long-season/pkg/services/jojo"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+const (
+	audience = "ls-kiosk"
+	subject  = "checkin"
+)
+
+// KeyName is name of secret used for signing check-in codes
+// in storage.Keys.
+const KeyName = "kiosk::codes"
+
+// secretSize is number of random bytes in signing secret.
+const secretSize = 32
+
+// LoadSecret returns secret for signing check-in codes.
+// Secret is generated and saved on first use, so it is
+// never known to anyone outside of the instance.
+func LoadSecret(ctx context.Context, keys storage.Keys) ([]byte, error) {
+	secret, err := keys.Read(ctx, KeyName)
+	if err == nil {
+		return secret, nil
+	}
+	if !errors.Is(err, serrors.ErrNoID) {
+		return nil, fmt.Errorf("keys.Read: %w", err)
+	}
+
+	secret = make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	if err := keys.Save(ctx, KeyName, secret); err != nil {
+		return nil, fmt.Errorf("keys.Save: %w", err)
+	}
+
+	return secret, nil
+}
+
+// ErrInvalidCode is returned when given code is malformed,
+// has invalid signature or had expired.
+var ErrInvalidCode = errors.New("kiosk: invalid code")
+
+// Code is single check-in code.
+type Code struct {
+	// Token is signed representation of code.
+	Token string
+
+	// Expires is time, after which code is replaced
+	// with the next one.
+	Expires time.Time
+}
+
+type claims struct {
+	jwt.StandardClaims
+	Window int64 `json:"win"`
+}
+
+// Codes generates and verifies check-in codes. Every code is
+// valid for single rotation window and the following one, so
+// members have some time to scan code that has just changed.
+type Codes struct {
+	// Signer is used for signing codes. Make sure to use
+	// different secret than for session tokens.
+	Signer *jojo.JWT
+
+	// Rotation is lifetime of single code.
+	Rotation time.Duration
+
+	now func() time.Time
+}
+
+// NewCodes returns pointer to Codes with given signer and
+// code rotation duration.
+func NewCodes(signer *jojo.JWT, rotation time.Duration) *Codes {
+	return &Codes{
+		Signer:   signer,
+		Rotation: rotation,
+		now:      time.Now,
+	}
+}
+
+// window returns number of rotation window for given time.
+func (c *Codes) window(t time.Time) int64 {
+	return t.UnixNano() / int64(c.Rotation)
+}
+
+// Current returns code for current rotation window. Code
+// doesn't change until the end of window.
+func (c *Codes) Current() (*Code, error) {
+	window := c.window(c.now())
+	start := time.Unix(0, window*int64(c.Rotation))
+	expires := start.Add(c.Rotation)
+
+	token, err := c.Signer.Sign(&claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    c.Signer.AppName,
+			Audience:  []string{audience},
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(expires.Add(c.Rotation)),
+			IssuedAt:  jwt.NewNumericDate(start),
+		},
+		Window: window,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("c.Signer.Sign: %w", err)
+	}
+
+	return &Code{
+		Token:   token,
+		Expires: expires,
+	}, nil
+}
+
+// Verify returns nil if given token is valid code from current
+// or previous rotation window.
+func (c *Codes) Verify(token string) error {
+	res := new(claims)
+	err := c.Signer.Verify(token, res)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCode, err.Error())
+	}
+
+	if !res.IsForAudience(audience) || !res.IsSubject(subject) {
+		return fmt.Errorf("%w: not a check-in code", ErrInvalidCode)
+	}
+
+	now := c.now()
+	current := c.window(now)
+	if res.Window != current && res.Window != current-1 {
+		return fmt.Errorf("%w: code from window %d had expired", ErrInvalidCode, res.Window)
+	}
+
+	if !res.IsValidAt(now) {
+		return fmt.Errorf("%w: code had expired", ErrInvalidCode)
+	}
+
+	return nil
+}
+
+// URL returns check-in address with given code for application
+// hosted at given base url.
+func URL(base string, code *Code) string {
+	return base + "/checkin?" + url.Values{"code": []string{code.Token}}.Encode()
+}
+
+// QR returns PNG image of QR code with given content and size
+// in pixels.
+func QR(content string, size int) ([]byte, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("qr.Encode: %w", err)
+	}
+
+	code, err = barcode.Scale(code, size, size)
+	if err != nil {
+		return nil, fmt.Errorf("barcode.Scale: %w", err)
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, code)
+	if err != nil {
+		return nil, fmt.Errorf("png.Encode: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,89 @@
+package kiosk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/services/jojo"
+	"github.com/hakierspejs/long-season/pkg/services/keyring"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestCodes(t *testing.T) {
+	is := is.New(t)
+
+	now := time.Date(2023, time.March, 1, 18, 0, 30, 0, time.UTC)
+	key, err := keyring.NewSecretKey([]byte("kiosk-secret"))
+	is.NoErr(err)
+	codes := NewCodes(&jojo.JWT{
+		Keys:    keyring.New(key),
+		AppName: "long-season-test",
+	}, time.Minute)
+	codes.now = func() time.Time { return now }
+
+	code, err := codes.Current()
+	is.NoErr(err)
+	is.True(code.Expires.Equal(time.Date(2023, time.March, 1, 18, 1, 0, 0, time.UTC)))
+
+	// Code is the same during whole window.
+	now = now.Add(20 * time.Second)
+	same, err := codes.Current()
+	is.NoErr(err)
+	is.Equal(same.Token, code.Token)
+	is.NoErr(codes.Verify(code.Token))
+
+	// Code from previous window is still accepted.
+	now = now.Add(time.Minute)
+	next, err := codes.Current()
+	is.NoErr(err)
+	is.True(next.Token != code.Token)
+	is.NoErr(codes.Verify(code.Token))
+
+	// But not older ones.
+	now = now.Add(time.Minute)
+	is.True(errors.Is(codes.Verify(code.Token), ErrInvalidCode))
+	is.NoErr(codes.Verify(next.Token))
+
+	// Codes signed with other secret are rejected.
+	otherKey, err := keyring.NewSecretKey([]byte("other-secret"))
+	is.NoErr(err)
+	other := NewCodes(&jojo.JWT{
+		Keys: keyring.New(otherKey),
+	}, time.Minute)
+	other.now = codes.now
+	forged, err := other.Current()
+	is.NoErr(err)
+	is.True(errors.Is(codes.Verify(forged.Token), ErrInvalidCode))
+
+	is.True(errors.Is(codes.Verify("garbage"), ErrInvalidCode))
+}
+
+func TestQR(t *testing.T) {
+	is := is.New(t)
+
+	image, err := QR("http://127.0.0.1:3000/checkin?code=abc", 256)
+	is.NoErr(err)
+	is.True(len(image) > 8)
+	is.Equal(string(image[1:4]), "PNG")
+}
+
+func TestLoadSecret(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	secret, err := LoadSecret(ctx, f.Keys())
+	is.NoErr(err)
+	is.Equal(len(secret), secretSize)
+
+	again, err := LoadSecret(ctx, f.Keys())
+	is.NoErr(err)
+	is.Equal(secret, again)
+}
@@ -4,34 +4,42 @@ import (
 	"context"
 	"net"
 	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
 )
 
 // SetTTL is set for mac addresses with special daemon running
 // in the background that will delete given mac addresses after
-// specified amount of time (TTL).
+// specified amount of time (TTL). Along with every mac address
+// set holds other identifiers of host reported by scanner.
 //
 // SetTTL is completely thread safe. Probably.
 type SetTTL struct {
-	m              map[string]*time.Timer
+	m              map[string]*setEntry
 	toAdd          chan setItem
 	toDel          chan string
 	retrieveSignal chan struct{}
-	macSlice       chan []net.HardwareAddr
+	hostSlice      chan []models.Host
 }
 
 type setItem struct {
-	value string
-	ttl   time.Duration
+	host models.Host
+	ttl  time.Duration
+}
+
+type setEntry struct {
+	host  models.Host
+	timer *time.Timer
 }
 
 // NewSetTTL returns initialised pointer to SetTTL.
 func NewSetTTL(ctx context.Context) *SetTTL {
 	res := &SetTTL{
-		m:              map[string]*time.Timer{},
+		m:              map[string]*setEntry{},
 		toAdd:          make(chan setItem),
 		toDel:          make(chan string),
 		retrieveSignal: make(chan struct{}),
-		macSlice:       make(chan []net.HardwareAddr),
+		hostSlice:      make(chan []models.Host),
 	}
 
 	// start daemon in new goroutine
@@ -44,16 +52,35 @@ func NewSetTTL(ctx context.Context) *SetTTL {
 // If given HardwareAddr is already in the set, it's reset
 // its TTL to given amount of time duration.
 func (s *SetTTL) Push(addr net.HardwareAddr, ttl time.Duration) {
+	s.PushHost(models.Host{MAC: addr}, ttl)
+}
+
+// PushHost adds given host to set and setup its TTL. Hosts
+// are identified by their hardware addresses. If host with the
+// same address is already in the set, its TTL is reset and
+// its hostname and client identifier are overwritten with
+// given ones, unless they are empty.
+func (s *SetTTL) PushHost(host models.Host, ttl time.Duration) {
 	s.toAdd <- setItem{
-		value: string(addr),
-		ttl:   ttl,
+		host: host,
+		ttl:  ttl,
 	}
 }
 
 // Slice returns slice of current Hardware addresses.
 func (s *SetTTL) Slice() []net.HardwareAddr {
+	hosts := s.Hosts()
+	res := make([]net.HardwareAddr, len(hosts), len(hosts))
+	for i, host := range hosts {
+		res[i] = host.MAC
+	}
+	return res
+}
+
+// Hosts returns slice of current hosts.
+func (s *SetTTL) Hosts() []models.Host {
 	s.retrieveSignal <- struct{}{}
-	return <-s.macSlice
+	return <-s.hostSlice
 }
 
 func delMac(val string, c chan string) func() {
@@ -73,24 +100,36 @@ func (s *SetTTL) daemon(ctx context.Context) {
 		// we can ensure that everything
 		// will be synced together
 		select {
-		case newMac := <-s.toAdd:
+		case newHost := <-s.toAdd:
 			// first scenario, client want to
 			// ad new mac address to set
+			key := string(newHost.host.MAC)
 
 			// lets check if new mac address is already in the
 			// map
-			if timer, contains := s.m[newMac.value]; contains {
+			if entry, contains := s.m[key]; contains {
 				// if it is, reset timer with given ttl value
-				timer.Reset(newMac.ttl)
+				entry.timer.Reset(newHost.ttl)
+
+				// and remember freshly reported identifiers
+				if newHost.host.Hostname != "" {
+					entry.host.Hostname = newHost.host.Hostname
+				}
+				if newHost.host.ClientID != "" {
+					entry.host.ClientID = newHost.host.ClientID
+				}
 			} else {
 				// if given mac address is not present
 				// at the map, lets create new timer
 				// that will send delete signal to our
 				// daemon
-				s.m[newMac.value] = time.AfterFunc(
-					newMac.ttl,
-					delMac(newMac.value, s.toDel),
-				)
+				s.m[key] = &setEntry{
+					host: newHost.host,
+					timer: time.AfterFunc(
+						newHost.ttl,
+						delMac(key, s.toDel),
+					),
+				}
 			}
 		case toDel := <-s.toDel:
 			// simple scenario: delete received mac
@@ -100,28 +139,27 @@ func (s *SetTTL) daemon(ctx context.Context) {
 			// we've just received retrieveSignal signal!
 			// lets allocate new slice that we will
 			// send to our client
-			res := make([]net.HardwareAddr, len(s.m), len(s.m))
+			res := make([]models.Host, len(s.m), len(s.m))
 
-			// loop over collection of addresses.
+			// loop over collection of hosts.
 			index := 0
-			for k := range s.m {
-				// we have to cast every key to net.HardwareAddr
+			for _, entry := range s.m {
 				// we can omit net.ParseMAC, because SetTTL permits
 				// only net.HardwareAddr to Push, so we assume here
 				// that client properly parsed HardwareAddr
-				res[index] = net.HardwareAddr(k)
+				res[index] = entry.host
 				index += 1
 			}
 
 			// send result to client
-			s.macSlice <- res
+			s.hostSlice <- res
 		case <-ctx.Done():
 			// context is Done, so we're closing channel and
 			// return to escape from loop
 			close(s.toAdd)
 			close(s.toDel)
 			close(s.retrieveSignal)
-			close(s.macSlice)
+			close(s.hostSlice)
 			return
 		}
 
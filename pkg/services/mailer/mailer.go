@@ -0,0 +1,174 @@
+// Package mailer implements sending plain text emails
+// through SMTP relay or writing them to file or log.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidAddress is returned when recipient address
+// is not valid email address.
+var ErrInvalidAddress = errors.New("mailer: invalid address")
+
+// Mailer sends emails.
+type Mailer interface {
+	// Send sends plain text email with given subject
+	// to given address.
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// ParseAddress returns given email address if it is valid
+// plain address without display name.
+func ParseAddress(address string) (string, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return "", fmt.Errorf("%w: %s", ErrInvalidAddress, address)
+	}
+	return parsed.Address, nil
+}
+
+// SMTP is Mailer, that sends emails through SMTP relay. Connection
+// is upgraded with STARTTLS, when relay supports it.
+type SMTP struct {
+	// Addr is address of SMTP relay in "host:port" form.
+	Addr string
+
+	// Username and Password are used for authentication
+	// when username is not empty.
+	Username string
+	Password string
+
+	// From is address of sender.
+	From string
+}
+
+// Message returns email message with given headers and body
+// encoded as quoted-printable text.
+func Message(from, to, subject, body string, date time.Time) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", " ")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", clean.Replace(subject)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	w.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	w.Close()
+
+	return buf.Bytes()
+}
+
+// Send sends plain text email with given subject
+// to given address.
+func (s *SMTP) Send(ctx context.Context, to, subject, body string) error {
+	to, err := ParseAddress(to)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("net.SplitHostPort: %w", err)
+	}
+
+	dialer := new(net.Dialer)
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("dialer.DialContext: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return fmt.Errorf("smtp.NewClient: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("c.StartTLS: %w", err)
+		}
+	}
+
+	if s.Username != "" {
+		auth := smtp.PlainAuth("", s.Username, s.Password, host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("c.Auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.From); err != nil {
+		return fmt.Errorf("c.Mail: %w", err)
+	}
+	if err := c.Rcpt(to); err != nil {
+		return fmt.Errorf("c.Rcpt: %w", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("c.Data: %w", err)
+	}
+	if _, err := w.Write(Message(s.From, to, subject, body, time.Now())); err != nil {
+		return fmt.Errorf("w.Write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("w.Close: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// Writer is Mailer, that writes emails to W instead of sending
+// them. It is meant for development and tests, when there is
+// no SMTP relay at hand.
+type Writer struct {
+	// W receives every email followed by empty line.
+	W io.Writer
+
+	// From is address of sender.
+	From string
+
+	guard sync.Mutex
+}
+
+// Send writes plain text email with given subject
+// to W.
+func (w *Writer) Send(ctx context.Context, to, subject, body string) error {
+	to, err := ParseAddress(to)
+	if err != nil {
+		return err
+	}
+
+	w.guard.Lock()
+	defer w.guard.Unlock()
+
+	msg := Message(w.From, to, subject, body, time.Now())
+	if _, err := w.W.Write(append(msg, "\r\n\r\n"...)); err != nil {
+		return fmt.Errorf("w.W.Write: %w", err)
+	}
+
+	return nil
+}
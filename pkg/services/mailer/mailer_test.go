@@ -0,0 +1,122 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// relay is fake SMTP server, that accepts single email.
+type relay struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     chan string
+}
+
+func newRelay(t *testing.T) *relay {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &relay{
+		listener: listener,
+		data:     make(chan string, 1),
+	}
+	go r.serve()
+	return r
+}
+
+func (r *relay) serve() {
+	conn, err := r.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			r.from = line
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			r.to = append(r.to, line)
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 Go ahead")
+			lines, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			r.data <- strings.Join(lines, "\n")
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	is := is.New(t)
+
+	r := newRelay(t)
+	defer r.listener.Close()
+
+	m := &SMTP{
+		Addr: r.listener.Addr().String(),
+		From: "long-season@example.com",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.Send(ctx, "alice@example.com", "Zażółć gęślą jaźń", "Bob arrived.\nSee you!")
+	is.NoErr(err)
+
+	is.Equal(r.from, "MAIL FROM:<long-season@example.com>")
+	is.Equal(r.to, []string{"RCPT TO:<alice@example.com>"})
+
+	data := <-r.data
+	is.True(strings.Contains(data, "To: alice@example.com\n"))
+	is.True(strings.Contains(data, "Subject: =?utf-8?q?Za=C5=BC=C3=B3=C5=82=C4=87_g=C4=99=C5=9Bl=C4=85_ja=C5=BA=C5=84?=\n"))
+	is.True(strings.HasSuffix(data, "\nBob arrived.\nSee you!"))
+
+	// Header injection is not possible.
+	err = m.Send(ctx, "alice@example.com\r\nBcc: eve@example.com", "", "")
+	is.True(errors.Is(err, ErrInvalidAddress))
+}
+
+func TestWriter(t *testing.T) {
+	is := is.New(t)
+
+	var buf bytes.Buffer
+	m := &Writer{W: &buf, From: "long-season@example.com"}
+
+	is.NoErr(m.Send(context.Background(), "alice@example.com", "Hello", "Bob arrived."))
+	is.True(strings.Contains(buf.String(), "To: alice@example.com\r\n"))
+	is.True(strings.HasSuffix(buf.String(), "\r\nBob arrived.\r\n\r\n"))
+
+	err := m.Send(context.Background(), "not an address", "", "")
+	is.True(errors.Is(err, ErrInvalidAddress))
+}
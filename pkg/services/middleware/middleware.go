@@ -2,16 +2,20 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/services/ctxkey"
 	"github.com/hakierspejs/long-season/pkg/services/happier"
 	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/services/scanners"
 	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/storage"
 )
 
 // URLParamInjection injects given chi parameter into request context.
@@ -35,8 +39,10 @@ func DeviceID(next http.Handler) http.Handler {
 	return URLParamInjection("device-id")(next)
 }
 
-func UpdateAuth(c *models.Config) func(http.Handler) http.Handler {
-	// TODO(thinkofher) Add doc.
+// UpdateAuth allows only requests with "Authorization: Status $KEY"
+// header, where $KEY is update secret from given config or one of
+// scanner keys from given storage.
+func UpdateAuth(c *models.Config, keys storage.Scanners) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			errFactory := happier.FromRequest(r)
@@ -50,9 +56,14 @@ func UpdateAuth(c *models.Config) func(http.Handler) http.Handler {
 				return
 			}
 
-			if token != c.UpdateSecret {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(c.UpdateSecret)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, err := scanners.Authenticate(r.Context(), keys, token); err != nil {
 				errFactory.Unauthorized(
-					fmt.Errorf("token != c.UpdateSecret"),
+					fmt.Errorf("scanners.Authenticate: %w", err),
 					fmt.Sprintf("invalid authorization token"),
 				).ServeHTTP(w, r)
 				return
@@ -63,6 +74,60 @@ func UpdateAuth(c *models.Config) func(http.Handler) http.Handler {
 	}
 }
 
+// KioskAuth allows only requests with "Authorization: Kiosk $KEY"
+// header, where $KEY is kiosk key from given config. Responds with
+// not found error if kiosk is disabled.
+func KioskAuth(c *models.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			errFactory := happier.FromRequest(r)
+
+			if c.KioskKey == "" {
+				errFactory.NotFound(
+					fmt.Errorf("c.KioskKey is empty"),
+					fmt.Sprintf("kiosk is disabled"),
+				).ServeHTTP(w, r)
+				return
+			}
+
+			token, err := apiExtractor("Kiosk")(r)
+			if err != nil {
+				errFactory.Unauthorized(
+					fmt.Errorf("apiExtractor: %w", err),
+					fmt.Sprintf("invalid authorization header"),
+				).ServeHTTP(w, r)
+				return
+			}
+
+			if subtle.ConstantTimeCompare([]byte(token), []byte(c.KioskKey)) != 1 {
+				errFactory.Unauthorized(
+					fmt.Errorf("token != c.KioskKey"),
+					fmt.Sprintf("invalid authorization token"),
+				).ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Cache overrides headers set by chi's NoCache middleware and
+// allows clients and proxies to cache responses for given duration.
+func Cache(maxAge time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			for _, key := range []string{"Expires", "Pragma", "X-Accel-Expires"} {
+				header.Del(key)
+			}
+			header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // Debug injects information about application debug mode
 // to every http request's context.
 func Debug(c models.Config) func(http.Handler) http.Handler {
@@ -126,6 +191,62 @@ func Private(renewer session.Renewer) func(http.Handler) http.Handler {
 	}
 }
 
+// Permission allows only requests with session of user, whose
+// role grants given permission. Role is read from session, so
+// use session.Revocable renewer for role changes to take effect
+// before logging in again.
+func Permission(renewer session.Renewer, p models.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			errFactory := happier.FromRequest(r)
+
+			state, err := renewer.Renew(r)
+			if err != nil {
+				errFactory.Unauthorized(
+					fmt.Errorf("renewer.Renew: %w", err),
+					"Invalid session. Please login in.",
+				).ServeHTTP(w, r)
+				return
+			}
+
+			if !state.Role.Can(p) {
+				errFactory.Forbidden(
+					fmt.Errorf("user id=%s with role=%s doesn't have permission=%s", state.UserID, state.Role, p),
+					"You are not allowed to operate at requested resources.",
+				).ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Scope allows personal API tokens with given scope to access
+// handler. Tokens are rejected by handlers, that don't require
+// any scope, so use it before other middlewares restoring session.
+func Scope(renewer session.Renewer, scope models.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ctxkey.ScopeKey, scope)
+			r = r.WithContext(ctx)
+
+			// Requests without session are handled by
+			// following middlewares and handlers.
+			state, err := renewer.Renew(r)
+			if err == nil && !state.Allows(scope) {
+				happier.FromRequest(r).Forbidden(
+					fmt.Errorf("session id=%s doesn't have scope=%s", state.ID, scope),
+					fmt.Sprintf("API token doesn't have required scope: %s.", scope),
+				).ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RedirectLoggedIn redirects logged in users to homepage.
 func RedirectLoggedIn(renewer session.Renewer) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
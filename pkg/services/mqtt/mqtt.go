@@ -0,0 +1,278 @@
+// Package mqtt implements publishing of hackerspace status to
+// MQTT broker, with Home Assistant discovery of published sensors.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/hakierspejs/long-season/pkg/services/space"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+const (
+	// Online is payload of availability topic published when
+	// long-season is connected to the broker.
+	Online = "online"
+
+	// Offline is payload of availability topic published by
+	// broker when long-season disconnects.
+	Offline = "offline"
+
+	on  = "ON"
+	off = "OFF"
+)
+
+// Client publishes messages to MQTT broker.
+type Client interface {
+	Publish(topic string, payload []byte, retained bool) error
+}
+
+// AvailabilityTopic returns topic with availability of
+// long-season publishing under given prefix.
+func AvailabilityTopic(prefix string) string {
+	return prefix + "/availability"
+}
+
+// Publisher publishes retained topics with state of the hackerspace:
+//   - <prefix>/open with ON or OFF,
+//   - <prefix>/people with number of online users,
+//   - <prefix>/unknown with number of unknown devices,
+//   - <prefix>/state with open, closed or members-only, when
+//     tracker of hackerspace state is set,
+//   - <prefix>/users/<id> with ON or OFF for every user, that
+//     haven't enabled private mode.
+//
+// Topics of users, that enabled private mode or were removed, are
+// cleared. Publisher implements status.Observer interface.
+type Publisher struct {
+	Client Client
+	Users  storage.Users
+
+	// Prefix of published topics.
+	Prefix string
+
+	// DiscoveryPrefix is prefix of Home Assistant discovery
+	// topics. Discovery configs are not published when empty.
+	DiscoveryPrefix string
+
+	// NodeID identifies long-season instance in Home Assistant.
+	NodeID string
+
+	// Space is optional tracker of hackerspace state.
+	Space *space.Tracker
+
+	mutex sync.Mutex
+
+	// discovered is true when discovery configs of
+	// hackerspace sensors are published.
+	discovered bool
+
+	// users contains nicknames of users with published
+	// presence, indexed by ids.
+	users map[string]string
+}
+
+// Observe publishes state of the hackerspace at given tick.
+func (p *Publisher) Observe(ctx context.Context, tick storage.Tick) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.users == nil {
+		p.users = map[string]string{}
+	}
+
+	entries, err := p.Users.All(ctx)
+	if err != nil {
+		return fmt.Errorf("p.Users.All: %w", err)
+	}
+
+	if !p.discovered {
+		if err := p.discoverSpace(); err != nil {
+			return fmt.Errorf("p.discoverSpace: %w", err)
+		}
+		p.discovered = true
+	}
+
+	open := off
+	if tick.Known > 0 {
+		open = on
+	}
+	topics := map[string]string{
+		p.Prefix + "/open":    open,
+		p.Prefix + "/people":  strconv.Itoa(tick.Known),
+		p.Prefix + "/unknown": strconv.Itoa(tick.Unknown),
+	}
+	if p.Space != nil {
+		state, err := p.Space.State(ctx)
+		if err != nil {
+			return fmt.Errorf("p.Space.State: %w", err)
+		}
+		topics[p.Prefix+"/state"] = string(state.State)
+	}
+	for topic, payload := range topics {
+		if err := p.Client.Publish(topic, []byte(payload), true); err != nil {
+			return fmt.Errorf("p.Client.Publish: %w", err)
+		}
+	}
+
+	online := map[string]struct{}{}
+	for _, id := range tick.OnlineIDs {
+		online[id] = struct{}{}
+	}
+
+	public := map[string]struct{}{}
+	for _, u := range entries {
+		if u.Private {
+			continue
+		}
+		public[u.ID] = struct{}{}
+
+		if nickname, ok := p.users[u.ID]; !ok || nickname != u.Nickname {
+			if err := p.discoverUser(u); err != nil {
+				return fmt.Errorf("p.discoverUser: %w", err)
+			}
+		}
+
+		state := off
+		if _, ok := online[u.ID]; ok {
+			state = on
+		}
+		if err := p.Client.Publish(p.userTopic(u.ID), []byte(state), true); err != nil {
+			return fmt.Errorf("p.Client.Publish: %w", err)
+		}
+		p.users[u.ID] = u.Nickname
+	}
+
+	for id := range p.users {
+		if _, ok := public[id]; ok {
+			continue
+		}
+		if err := p.clearUser(id); err != nil {
+			return fmt.Errorf("p.clearUser: %w", err)
+		}
+		delete(p.users, id)
+	}
+
+	return nil
+}
+
+func (p *Publisher) userTopic(id string) string {
+	return p.Prefix + "/users/" + id
+}
+
+// discoveryTopic returns topic of Home Assistant discovery config
+// of entity with given component type and object id.
+func (p *Publisher) discoveryTopic(component, objectID string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/config", p.DiscoveryPrefix, component, p.NodeID, objectID)
+}
+
+type device struct {
+	Identifiers []string `json:"identifiers"`
+	Name        string   `json:"name"`
+}
+
+// discovery is Home Assistant MQTT discovery config.
+type discovery struct {
+	Name              string  `json:"name"`
+	UniqueID          string  `json:"unique_id"`
+	StateTopic        string  `json:"state_topic"`
+	AvailabilityTopic string  `json:"availability_topic"`
+	DeviceClass       string  `json:"device_class,omitempty"`
+	StateClass        string  `json:"state_class,omitempty"`
+	Unit              string  `json:"unit_of_measurement,omitempty"`
+	Icon              string  `json:"icon,omitempty"`
+	Device            *device `json:"device"`
+}
+
+func (p *Publisher) discover(component, objectID string, d discovery) error {
+	if p.DiscoveryPrefix == "" {
+		return nil
+	}
+
+	d.UniqueID = p.NodeID + "_" + objectID
+	d.AvailabilityTopic = AvailabilityTopic(p.Prefix)
+	d.Device = &device{
+		Identifiers: []string{p.NodeID},
+		Name:        p.NodeID,
+	}
+
+	payload, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	err = p.Client.Publish(p.discoveryTopic(component, objectID), payload, true)
+	if err != nil {
+		return fmt.Errorf("p.Client.Publish: %w", err)
+	}
+	return nil
+}
+
+func (p *Publisher) discoverSpace() error {
+	err := p.discover("binary_sensor", "open", discovery{
+		Name:        "Open",
+		StateTopic:  p.Prefix + "/open",
+		DeviceClass: "occupancy",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.discover("sensor", "people", discovery{
+		Name:       "People",
+		StateTopic: p.Prefix + "/people",
+		StateClass: "measurement",
+		Unit:       "people",
+		Icon:       "mdi:account-group",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.discover("sensor", "unknown", discovery{
+		Name:       "Unknown devices",
+		StateTopic: p.Prefix + "/unknown",
+		StateClass: "measurement",
+		Unit:       "devices",
+		Icon:       "mdi:devices",
+	})
+	if err != nil || p.Space == nil {
+		return err
+	}
+
+	return p.discover("sensor", "state", discovery{
+		Name:       "State",
+		StateTopic: p.Prefix + "/state",
+		Icon:       "mdi:door",
+	})
+}
+
+func (p *Publisher) discoverUser(u storage.UserEntry) error {
+	return p.discover("binary_sensor", "user_"+u.ID, discovery{
+		Name:        u.Nickname,
+		StateTopic:  p.userTopic(u.ID),
+		DeviceClass: "presence",
+	})
+}
+
+// clearUser removes retained presence and discovery config
+// of user with given id, by publishing empty payloads.
+func (p *Publisher) clearUser(id string) error {
+	if err := p.Client.Publish(p.userTopic(id), []byte{}, true); err != nil {
+		return fmt.Errorf("p.Client.Publish: %w", err)
+	}
+
+	if p.DiscoveryPrefix == "" {
+		return nil
+	}
+
+	err := p.Client.Publish(p.discoveryTopic("binary_sensor", "user_"+id), []byte{}, true)
+	if err != nil {
+		return fmt.Errorf("p.Client.Publish: %w", err)
+	}
+	return nil
+}
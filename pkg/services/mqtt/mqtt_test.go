@@ -0,0 +1,147 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/space"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+	"github.com/hakierspejs/long-season/pkg/storage/temp"
+)
+
+// broker keeps retained messages like MQTT broker does.
+type broker map[string]string
+
+func (b broker) Publish(topic string, payload []byte, retained bool) error {
+	if !retained {
+		return nil
+	}
+	if len(payload) == 0 {
+		delete(b, topic)
+		return nil
+	}
+	b[topic] = string(payload)
+	return nil
+}
+
+func TestPublisher(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice"},
+		{ID: "2", Nickname: "bob", Private: true},
+		{ID: "3", Nickname: "carol"},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	b := broker{}
+	p := &Publisher{
+		Client:          b,
+		Users:           f.Users(),
+		Prefix:          "hs",
+		DiscoveryPrefix: "homeassistant",
+		NodeID:          "long-season",
+	}
+
+	err = p.Observe(ctx, storage.Tick{
+		OnlineIDs: []string{"1", "2"},
+		Known:     2,
+		Unknown:   3,
+	})
+	is.NoErr(err)
+
+	is.Equal(b["hs/open"], "ON")
+	is.Equal(b["hs/people"], "2")
+	is.Equal(b["hs/unknown"], "3")
+	is.Equal(b["hs/users/1"], "ON")
+	is.Equal(b["hs/users/3"], "OFF")
+
+	// Private users are never published.
+	_, ok := b["hs/users/2"]
+	is.True(!ok)
+	_, ok = b["homeassistant/binary_sensor/long-season/user_2/config"]
+	is.True(!ok)
+
+	var config discovery
+	err = json.Unmarshal([]byte(b["homeassistant/binary_sensor/long-season/user_1/config"]), &config)
+	is.NoErr(err)
+	is.Equal(config.Name, "alice")
+	is.Equal(config.StateTopic, "hs/users/1")
+	is.Equal(config.AvailabilityTopic, "hs/availability")
+	is.Equal(config.UniqueID, "long-season_user_1")
+
+	for _, topic := range []string{
+		"homeassistant/binary_sensor/long-season/open/config",
+		"homeassistant/sensor/long-season/people/config",
+		"homeassistant/sensor/long-season/unknown/config",
+	} {
+		_, ok := b[topic]
+		is.True(ok)
+	}
+
+	// Enabling private mode clears retained topics.
+	err = f.Users().Update(ctx, "1", func(u *storage.UserEntry) error {
+		u.Private = true
+		return nil
+	})
+	is.NoErr(err)
+
+	err = p.Observe(ctx, storage.Tick{})
+	is.NoErr(err)
+
+	is.Equal(b["hs/open"], "OFF")
+	is.Equal(b["hs/people"], "0")
+	_, ok = b["hs/users/1"]
+	is.True(!ok)
+	_, ok = b["homeassistant/binary_sensor/long-season/user_1/config"]
+	is.True(!ok)
+	is.Equal(b["hs/users/3"], "OFF")
+}
+
+func TestPublisherState(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	counters := temp.NewStatusTx()
+	err = counters.DevicesStatus(ctx, func(ctx context.Context, s storage.Status) error {
+		return s.SetOnlineUsers(ctx, 2)
+	})
+	is.NoErr(err)
+
+	b := broker{}
+	p := &Publisher{
+		Client:          b,
+		Users:           f.Users(),
+		Prefix:          "hs",
+		DiscoveryPrefix: "homeassistant",
+		NodeID:          "long-season",
+		Space: &space.Tracker{
+			Users:    f.Users(),
+			Counters: counters,
+			Occupied: models.SpaceMembersOnly,
+		},
+	}
+
+	is.NoErr(p.Observe(ctx, storage.Tick{Known: 2}))
+	is.Equal(b["hs/state"], "members-only")
+
+	_, ok := b["homeassistant/sensor/long-season/state/config"]
+	is.True(ok)
+}
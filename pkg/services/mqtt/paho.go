@@ -0,0 +1,80 @@
+package mqtt
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ErrTimeout is returned when broker doesn't acknowledge
+// published message in time.
+var ErrTimeout = errors.New("mqtt: timeout")
+
+// Options of connection to MQTT broker.
+type Options struct {
+	// Broker is address of broker, for example
+	// "tcp://127.0.0.1:1883".
+	Broker string
+
+	ClientID string
+	Username string
+	Password string
+
+	// Prefix of published topics.
+	Prefix string
+
+	// Timeout is the longest time of waiting for
+	// acknowledgement of published message.
+	Timeout time.Duration
+}
+
+// PahoClient is Client implementation, that publishes messages
+// with QoS 1.
+type PahoClient struct {
+	client  paho.Client
+	timeout time.Duration
+}
+
+// Connect starts connecting to MQTT broker in the background
+// and returns client, that reconnects automatically. Long-season
+// is marked as online in availability topic after every connection
+// and as offline by broker, when connection is lost.
+func Connect(opts Options) *PahoClient {
+	availability := AvailabilityTopic(opts.Prefix)
+
+	options := paho.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(opts.ClientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetWill(availability, Offline, 1, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(c paho.Client) {
+			log.Println("Connected to MQTT broker.")
+			c.Publish(availability, 1, true, Online)
+		}).
+		SetConnectionLostHandler(func(c paho.Client, err error) {
+			log.Println("Lost connection to MQTT broker, reason: ", err.Error())
+		})
+
+	client := paho.NewClient(options)
+	client.Connect()
+
+	return &PahoClient{
+		client:  client,
+		timeout: opts.Timeout,
+	}
+}
+
+// Publish sends given payload to given topic and waits for
+// acknowledgement from broker.
+func (c *PahoClient) Publish(topic string, payload []byte, retained bool) error {
+	token := c.client.Publish(topic, 1, retained, payload)
+	if !token.WaitTimeout(c.timeout) {
+		return ErrTimeout
+	}
+	return token.Error()
+}
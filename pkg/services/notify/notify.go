@@ -0,0 +1,360 @@
+// Package notify implements personal notifications about arrivals
+// of followed users and opening or closing of the hackerspace,
+// delivered through channels chosen by subscribing users.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+const internalServerErrorResponse = "Internal server error. Please try again later."
+
+// deliveryTimeout is the longest duration of delivering
+// notifications after single status update.
+const deliveryTimeout = time.Minute
+
+// EmailChannel is name of email channel. Email subscriptions
+// without address are sent to verified email address of
+// subscribing user.
+const EmailChannel = "email"
+
+// Notification is single message sent to subscriber.
+type Notification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Sender delivers notifications through single channel.
+type Sender interface {
+	// Validate returns error if given address is not valid
+	// recipient address in the channel.
+	Validate(address string) error
+
+	// Send delivers given notification to given address.
+	Send(ctx context.Context, address string, n Notification) error
+}
+
+// Delivery is notification waiting to be sent
+// to the subscriber.
+type Delivery struct {
+	Subscription models.Subscription
+	Notification Notification
+}
+
+// Notifier sends notifications to subscribers about changes of
+// status. Arrivals of users with private mode enabled or users,
+// that opted out of being followed, are never sent. Subscribers
+// present in the hackerspace are not notified. Notifier implements
+// status.Observer interface.
+type Notifier struct {
+	Users         storage.Users
+	Subscriptions storage.Subscriptions
+
+	// Senders maps names of channels to their senders.
+	Senders map[string]Sender
+
+	mutex sync.Mutex
+
+	// initialized is true after the first observed tick.
+	initialized bool
+
+	// open is true if anyone was online during last tick.
+	open bool
+
+	// online contains ids of users online during last tick.
+	online map[string]struct{}
+}
+
+// Channels returns sorted names of available channels.
+func (n *Notifier) Channels() []string {
+	res := make([]string, 0, len(n.Senders))
+	for name := range n.Senders {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Deliveries returns notifications caused by changes of status
+// since the last tick. The first tick never causes notifications.
+func (n *Notifier) Deliveries(ctx context.Context, tick storage.Tick) ([]Delivery, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	online := map[string]struct{}{}
+	for _, id := range tick.OnlineIDs {
+		online[id] = struct{}{}
+	}
+	open := tick.Known > 0
+
+	defer func() {
+		n.initialized = true
+		n.open = open
+		n.online = online
+	}()
+
+	if !n.initialized {
+		return nil, nil
+	}
+
+	arrived := map[string]struct{}{}
+	for id := range online {
+		if _, before := n.online[id]; !before {
+			arrived[id] = struct{}{}
+		}
+	}
+
+	if len(arrived) == 0 && open == n.open {
+		return nil, nil
+	}
+
+	entries, err := n.Users.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("n.Users.All: %w", err)
+	}
+	users := map[string]storage.UserEntry{}
+	for _, u := range entries {
+		users[u.ID] = u
+	}
+
+	subscriptions, err := n.Subscriptions.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("n.Subscriptions.All: %w", err)
+	}
+
+	res := []Delivery{}
+	for _, s := range subscriptions {
+		subscriber, ok := users[s.UserID]
+		if !ok {
+			continue
+		}
+		if _, present := online[s.UserID]; present {
+			continue
+		}
+
+		var notification Notification
+		switch s.Event {
+		case models.ArrivalEvent:
+			target, ok := users[s.TargetID]
+			if !ok || target.Private || target.Unfollowable {
+				continue
+			}
+			if _, ok := arrived[s.TargetID]; !ok {
+				continue
+			}
+			notification = Notification{
+				Title: target.Nickname + " arrived",
+				Body:  target.Nickname + " has just arrived at the hackerspace.",
+			}
+		case models.OpenEvent:
+			if !open || n.open {
+				continue
+			}
+			notification = Notification{
+				Title: "Hackerspace is open",
+				Body:  "Someone has just arrived at the hackerspace.",
+			}
+		case models.CloseEvent:
+			if open || !n.open {
+				continue
+			}
+			notification = Notification{
+				Title: "Hackerspace is closed",
+				Body:  "The last person has just left the hackerspace.",
+			}
+		default:
+			continue
+		}
+
+		// Email notifications are always sent to current,
+		// verified address of subscriber.
+		if s.Channel == EmailChannel {
+			if !subscriber.EmailVerified {
+				continue
+			}
+			s.Address = subscriber.Email
+		}
+
+		res = append(res, Delivery{
+			Subscription: s,
+			Notification: notification,
+		})
+	}
+
+	return res, nil
+}
+
+// Deliver sends given notifications. Every notification is sent,
+// even if some of them fail, in which case the last error
+// is returned. Subscriptions with expired addresses are removed.
+func (n *Notifier) Deliver(ctx context.Context, deliveries []Delivery) error {
+	var last error
+	for _, d := range deliveries {
+		sender, ok := n.Senders[d.Subscription.Channel]
+		if !ok {
+			continue
+		}
+
+		err := sender.Send(ctx, d.Subscription.Address, d.Notification)
+		if errors.Is(err, ErrExpired) {
+			err = n.Subscriptions.Remove(ctx, d.Subscription.ID)
+			if err != nil && !errors.Is(err, serrors.ErrNoID) {
+				last = fmt.Errorf("n.Subscriptions.Remove: %w", err)
+			}
+			continue
+		}
+		if err != nil {
+			last = fmt.Errorf("sender.Send: channel=%s: %w", d.Subscription.Channel, err)
+		}
+	}
+	return last
+}
+
+// Observe sends notifications caused by given tick in the
+// background, so slow channels don't delay status updates.
+func (n *Notifier) Observe(ctx context.Context, tick storage.Tick) error {
+	deliveries, err := n.Deliveries(ctx, tick)
+	if err != nil {
+		return fmt.Errorf("n.Deliveries: %w", err)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		defer cancel()
+
+		if err := n.Deliver(ctx, deliveries); err != nil {
+			log.Println("Failed to deliver notifications, reason: ", err.Error())
+		}
+	}()
+
+	return nil
+}
+
+// Subscribe validates given subscription and stores it with
+// newly assigned id. Returned errors are happier errors, ready
+// to be returned by http handlers.
+func (n *Notifier) Subscribe(ctx context.Context, s models.Subscription) (*models.Subscription, error) {
+	errFactory := happier.FromContext(ctx)
+
+	sender, ok := n.Senders[s.Channel]
+	if !ok {
+		return nil, errFactory.BadRequest(
+			fmt.Errorf("unknown channel: %s", s.Channel),
+			fmt.Sprintf("invalid input: unknown channel %s", s.Channel),
+		)
+	}
+
+	// Members can't make long-season send emails to addresses,
+	// they haven't verified, so email subscriptions have no
+	// address of their own.
+	address := s.Address
+	if s.Channel == EmailChannel {
+		s.Address = ""
+		u, err := n.Users.Read(ctx, s.UserID)
+		if err != nil {
+			return nil, errFactory.InternalServerError(
+				fmt.Errorf("n.Users.Read: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+		if !u.EmailVerified {
+			return nil, errFactory.BadRequest(
+				fmt.Errorf("user id=%s has no verified email", s.UserID),
+				"invalid input: verify your email address first",
+			)
+		}
+		address = u.Email
+	}
+
+	if err := sender.Validate(address); err != nil {
+		return nil, errFactory.BadRequest(
+			fmt.Errorf("sender.Validate: %w", err),
+			fmt.Sprintf("invalid input: invalid %s address", s.Channel),
+		)
+	}
+
+	switch s.Event {
+	case models.ArrivalEvent:
+		if s.TargetID == s.UserID {
+			return nil, errFactory.BadRequest(
+				fmt.Errorf("user id=%s tried to follow themselves", s.UserID),
+				"invalid input: you can't follow yourself",
+			)
+		}
+
+		entries, err := n.Users.All(ctx)
+		if err != nil {
+			return nil, errFactory.InternalServerError(
+				fmt.Errorf("n.Users.All: %w", err),
+				internalServerErrorResponse,
+			)
+		}
+
+		// Private and unfollowable users are indistinguishable
+		// from users, that don't exist.
+		followable := false
+		for _, u := range entries {
+			if u.ID == s.TargetID {
+				followable = !u.Private && !u.Unfollowable
+				break
+			}
+		}
+		if !followable {
+			return nil, errFactory.NotFound(
+				fmt.Errorf("user id=%s can't be followed", s.TargetID),
+				fmt.Sprintf("there is no followable user with id=%s", s.TargetID),
+			)
+		}
+	case models.OpenEvent, models.CloseEvent:
+		s.TargetID = ""
+	default:
+		return nil, errFactory.BadRequest(
+			fmt.Errorf("unknown event: %s", s.Event),
+			fmt.Sprintf("invalid input: unknown event %s", s.Event),
+		)
+	}
+
+	subscriptions, err := n.Subscriptions.OfUser(ctx, s.UserID)
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("n.Subscriptions.OfUser: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+	for _, old := range subscriptions {
+		if old.Event == s.Event && old.TargetID == s.TargetID &&
+			old.Channel == s.Channel && old.Address == s.Address {
+			return nil, errFactory.Conflict(
+				fmt.Errorf("subscription duplicates id=%s", old.ID),
+				"subscription already exists",
+			)
+		}
+	}
+
+	s.ID = uuid.New().String()
+	s.ID, err = n.Subscriptions.New(ctx, s)
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("n.Subscriptions.New: %w", err),
+			internalServerErrorResponse,
+		)
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,262 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/thinkofher/horror"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+// recorder is Sender, that records sent notifications.
+type recorder struct {
+	sent []string
+}
+
+func (r *recorder) Validate(address string) error {
+	if address == "" {
+		return errors.New("empty address")
+	}
+	return nil
+}
+
+func (r *recorder) Send(ctx context.Context, address string, n Notification) error {
+	if address == "expired" {
+		return ErrExpired
+	}
+	r.sent = append(r.sent, address+": "+n.Title)
+	return nil
+}
+
+// newNotifier returns notifier with four users: alice, private
+// bob, unfollowable carol and dave.
+func newNotifier(t *testing.T) (*Notifier, *recorder, func() error) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice"},
+		{ID: "2", Nickname: "bob", Private: true},
+		{ID: "3", Nickname: "carol", Unfollowable: true},
+		{ID: "4", Nickname: "dave"},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	r := &recorder{}
+	return &Notifier{
+		Users:         f.Users(),
+		Subscriptions: f.Subscriptions(),
+		Senders:       map[string]Sender{"test": r},
+	}, r, closer
+}
+
+func TestSubscribe(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	n, _, closer := newNotifier(t)
+	defer closer()
+
+	s, err := n.Subscribe(ctx, models.Subscription{
+		UserID:   "4",
+		Event:    models.OpenEvent,
+		TargetID: "1",
+		Channel:  "test",
+		Address:  "dave",
+	})
+	is.NoErr(err)
+	is.True(s.ID != "")
+	is.Equal(s.TargetID, "")
+
+	for name, c := range map[string]struct {
+		s    models.Subscription
+		code int
+	}{
+		"duplicate":       {models.Subscription{Event: models.OpenEvent, Channel: "test", Address: "dave"}, http.StatusConflict},
+		"unknown event":   {models.Subscription{Event: "party", Channel: "test", Address: "dave"}, http.StatusBadRequest},
+		"unknown channel": {models.Subscription{Event: models.OpenEvent, Channel: "fax", Address: "dave"}, http.StatusBadRequest},
+		"invalid address": {models.Subscription{Event: models.OpenEvent, Channel: "test"}, http.StatusBadRequest},
+		"yourself":        {models.Subscription{Event: models.ArrivalEvent, TargetID: "4", Channel: "test", Address: "dave"}, http.StatusBadRequest},
+		"private":         {models.Subscription{Event: models.ArrivalEvent, TargetID: "2", Channel: "test", Address: "dave"}, http.StatusNotFound},
+		"unfollowable":    {models.Subscription{Event: models.ArrivalEvent, TargetID: "3", Channel: "test", Address: "dave"}, http.StatusNotFound},
+		"missing":         {models.Subscription{Event: models.ArrivalEvent, TargetID: "5", Channel: "test", Address: "dave"}, http.StatusNotFound},
+	} {
+		c.s.UserID = "4"
+		_, err := n.Subscribe(ctx, c.s)
+
+		herr, ok := err.(horror.Error)
+		if !ok {
+			t.Fatalf("%s: expected horror error, got %v", name, err)
+		}
+
+		w := httptest.NewRecorder()
+		herr.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		if w.Code != c.code {
+			t.Fatalf("%s: expected status %d, got %d", name, c.code, w.Code)
+		}
+	}
+
+	subscriptions, err := n.Subscriptions.OfUser(ctx, "4")
+	is.NoErr(err)
+	is.Equal(len(subscriptions), 1)
+}
+
+func TestObserve(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	n, r, closer := newNotifier(t)
+	defer closer()
+
+	for _, s := range []models.Subscription{
+		{ID: "a", UserID: "4", Event: models.ArrivalEvent, TargetID: "1", Channel: "test", Address: "dave"},
+		{ID: "b", UserID: "4", Event: models.ArrivalEvent, TargetID: "2", Channel: "test", Address: "dave"},
+		{ID: "c", UserID: "4", Event: models.ArrivalEvent, TargetID: "3", Channel: "test", Address: "dave"},
+		{ID: "d", UserID: "4", Event: models.OpenEvent, Channel: "test", Address: "dave"},
+		{ID: "e", UserID: "4", Event: models.CloseEvent, Channel: "test", Address: "dave"},
+		{ID: "f", UserID: "1", Event: models.OpenEvent, Channel: "test", Address: "alice"},
+		{ID: "g", UserID: "1", Event: models.ArrivalEvent, TargetID: "4", Channel: "gone", Address: "alice"},
+		{ID: "h", UserID: "4", Event: models.OpenEvent, Channel: "test", Address: "expired"},
+	} {
+		_, err := n.Subscriptions.New(ctx, s)
+		is.NoErr(err)
+	}
+
+	tick := func(ids ...string) {
+		deliveries, err := n.Deliveries(ctx, storage.Tick{OnlineIDs: ids, Known: len(ids)})
+		is.NoErr(err)
+		is.NoErr(n.Deliver(ctx, deliveries))
+	}
+
+	// The first tick doesn't cause notifications.
+	tick("2")
+	is.Equal(len(r.sent), 0)
+
+	tick()
+	is.Equal(r.sent, []string{"dave: Hackerspace is closed"})
+
+	// Private and unfollowable users are not announced and
+	// alice doesn't need notification about her own arrival.
+	r.sent = nil
+	tick("1", "2", "3")
+	is.Equal(r.sent, []string{"dave: alice arrived", "dave: Hackerspace is open"})
+
+	// Subscriptions with expired addresses are removed.
+	subscriptions, err := n.Subscriptions.OfUser(ctx, "4")
+	is.NoErr(err)
+	is.Equal(len(subscriptions), 5)
+
+	// Present subscribers are not notified and subscriptions
+	// with unavailable channels are skipped.
+	r.sent = nil
+	tick("1", "2", "3", "4")
+	is.Equal(len(r.sent), 0)
+}
+
+func TestProfileEmail(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	n, r, closer := newNotifier(t)
+	defer closer()
+	n.Senders[EmailChannel] = r
+
+	is.NoErr(n.Users.Update(ctx, "4", func(u *storage.UserEntry) error {
+		u.Email = "dave@example.com"
+		u.EmailVerified = true
+		return nil
+	}))
+
+	_, err := n.Subscribe(ctx, models.Subscription{UserID: "1", Event: models.OpenEvent, Channel: EmailChannel})
+	is.True(err != nil)
+
+	// Users without verified email can't pass any address.
+	_, err = n.Subscribe(ctx, models.Subscription{
+		UserID: "1", Event: models.OpenEvent, Channel: EmailChannel, Address: "victim@example.com",
+	})
+	is.True(err != nil)
+
+	// Given addresses are ignored.
+	s, err := n.Subscribe(ctx, models.Subscription{
+		UserID: "4", Event: models.OpenEvent, Channel: EmailChannel, Address: "victim@example.com",
+	})
+	is.NoErr(err)
+	is.Equal(s.Address, "")
+
+	// Addresses of subscriptions stored before are ignored too.
+	_, err = n.Subscriptions.New(ctx, models.Subscription{
+		ID: "z", UserID: "4", Event: models.CloseEvent, Channel: EmailChannel, Address: "victim@example.com",
+	})
+	is.NoErr(err)
+
+	tick := func(ids ...string) {
+		deliveries, err := n.Deliveries(ctx, storage.Tick{OnlineIDs: ids, Known: len(ids)})
+		is.NoErr(err)
+		is.NoErr(n.Deliver(ctx, deliveries))
+	}
+	tick()
+	tick("1")
+	tick()
+	is.Equal(r.sent, []string{
+		"dave@example.com: Hackerspace is open",
+		"dave@example.com: Hackerspace is closed",
+	})
+}
+
+func TestNtfy(t *testing.T) {
+	is := is.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hackerspace" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		is.Equal(r.Method, http.MethodPost)
+		is.Equal(r.Header.Get("Title"), "alice arrived")
+
+		body, err := io.ReadAll(r.Body)
+		is.NoErr(err)
+		is.Equal(string(body), "alice has just arrived at the hackerspace.")
+	}))
+	defer server.Close()
+
+	n := &Ntfy{Allowed: []string{server.URL, "https://ntfy.sh"}}
+	is.NoErr(n.Send(context.Background(), server.URL+"/hackerspace", Notification{
+		Title: "alice arrived",
+		Body:  "alice has just arrived at the hackerspace.",
+	}))
+
+	is.True(n.Send(context.Background(), server.URL+"/private", Notification{}) != nil)
+	is.True(errors.Is(n.Validate("file:///etc/passwd"), ErrInvalidURL))
+	is.True(errors.Is(n.Validate("https://"), ErrInvalidURL))
+
+	// Only topics on allowed servers are accepted.
+	is.NoErr(n.Validate("https://ntfy.sh/hackerspace"))
+	is.NoErr(n.Validate("https://NTFY.sh/hackerspace"))
+	for _, address := range []string{
+		"http://127.0.0.1/hackerspace",
+		"http://169.254.169.254/latest/meta-data",
+		"http://ntfy.sh/hackerspace",
+		"https://ntfy.sh.example.com/hackerspace",
+		"https://user@ntfy.sh/hackerspace",
+		"https://ntfy.sh",
+	} {
+		is.True(errors.Is(n.Validate(address), ErrInvalidURL))
+	}
+	is.True(errors.Is((&Ntfy{}).Validate("https://ntfy.sh/hackerspace"), ErrInvalidURL))
+}
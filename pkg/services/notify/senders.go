@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hakierspejs/long-season/pkg/services/mailer"
+	"github.com/hakierspejs/long-season/pkg/services/webpush"
+)
+
+// ErrExpired is returned by senders when address is no
+// longer valid and subscription should be removed.
+var ErrExpired = errors.New("notify: address expired")
+
+// ErrInvalidURL is returned when address of http
+// endpoint is not valid.
+var ErrInvalidURL = errors.New("notify: invalid url")
+
+// Ntfy is Sender, that publishes notifications to ntfy-style
+// http endpoints. Address is full URL of topic, for example
+// "https://ntfy.sh/my-hackerspace".
+type Ntfy struct {
+	// Client is optional http client used for requests.
+	Client *http.Client
+
+	// Allowed are base URLs of servers, that topics can
+	// be published to, for example "https://ntfy.sh". Topics
+	// on other servers are rejected, so users can't make
+	// long-season send requests to arbitrary hosts.
+	Allowed []string
+}
+
+func (n *Ntfy) client() *http.Client {
+	if n.Client == nil {
+		return http.DefaultClient
+	}
+	return n.Client
+}
+
+// allowed returns true if given URL points to one of
+// allowed servers.
+func (n *Ntfy) allowed(u *url.URL) bool {
+	for _, raw := range n.Allowed {
+		base, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if base.Scheme != u.Scheme || !strings.EqualFold(base.Host, u.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(base.Path, "/") + "/"
+		if strings.HasPrefix(u.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns error if given address is not http or
+// https URL of topic on one of allowed servers.
+func (n *Ntfy) Validate(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, err.Error())
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, address)
+	}
+	if !n.allowed(u) {
+		return fmt.Errorf("%w: %s is not on allowed server", ErrInvalidURL, address)
+	}
+	return nil
+}
+
+// Send publishes given notification with its body as message
+// and its title in the Title header.
+func (n *Ntfy) Send(ctx context.Context, address string, notification Notification) error {
+	if err := n.Validate(address); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, strings.NewReader(notification.Body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+	req.Header.Set("Title", notification.Title)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := n.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("n.client().Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify: ntfy: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Email is Sender, that delivers notifications as emails.
+type Email struct {
+	Mailer mailer.Mailer
+}
+
+// Validate returns error if given address is not
+// valid email address.
+func (e *Email) Validate(address string) error {
+	_, err := mailer.ParseAddress(address)
+	return err
+}
+
+// Send delivers given notification as plain text email.
+func (e *Email) Send(ctx context.Context, address string, notification Notification) error {
+	return e.Mailer.Send(ctx, address, notification.Title, notification.Body)
+}
+
+// WebPush is Sender, that delivers notifications to browsers
+// with Web Push protocol. Address is push subscription of the
+// browser encoded as JSON.
+type WebPush struct {
+	Client *webpush.Client
+}
+
+// Validate returns error if given address is not valid
+// push subscription.
+func (w *WebPush) Validate(address string) error {
+	_, err := webpush.ParseSubscription(address, w.Client.Services)
+	return err
+}
+
+// Send pushes given notification encoded as JSON, which is
+// displayed by service worker of the web UI.
+func (w *WebPush) Send(ctx context.Context, address string, notification Notification) error {
+	s, err := webpush.ParseSubscription(address, w.Client.Services)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	err = w.Client.Push(ctx, s, payload)
+	if errors.Is(err, webpush.ErrExpired) {
+		return fmt.Errorf("%w: %s", ErrExpired, err.Error())
+	}
+	return err
+}
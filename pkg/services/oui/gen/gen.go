@@ -0,0 +1,173 @@
+// Command gen converts IEEE MA-L registry in the CSV format into
+// the table embedded by oui package.
+//
+// By default registry is downloaded from registryURL, so running
+// go generate in pkg/services/oui refreshes the table. Use -in flag
+// to convert local copy of registry instead.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// registryURL is address of complete IEEE MA-L registry.
+const registryURL = "https://standards-oui.ieee.org/oui/oui.csv"
+
+// rules assigns vendor category to organizations with one of
+// given keywords in the name. Rules are checked in order.
+var rules = []struct {
+	category string
+	keywords []string
+}{
+	{"network", []string{"cisco", "ubiquiti", "tp-link", "routerboard", "mikrotik", "netgear", "aruba", "juniper", "d-link", "zyxel"}},
+	{"printer", []string{"brother", "canon", "epson", "xerox", "zebra"}},
+	{"iot", []string{"espressif", "raspberry", "tuya", "philips lighting", "nest labs", "sonos", "microchip", "texas instruments", "nordic semiconductor"}},
+	{"phone", []string{"apple", "samsung", "huawei", "xiaomi", "oneplus", "oppo", "vivo mobile", "motorola", "google", "honor device"}},
+	{"laptop", []string{"intel", "dell", "lenovo", "hewlett", "asustek", "acer", "realtek", "atheros", "qualcomm", "liteon", "azurewave", "wistron", "quanta", "compal"}},
+}
+
+// words returns lower cased words of given name.
+func words(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// containsWords returns true if name contains every word of
+// keyword in the same order. Whole words are compared, so
+// "intel" doesn't match "Intelligent Systems".
+func containsWords(name, keyword []string) bool {
+	for i := 0; i+len(keyword) <= len(name); i++ {
+		matched := true
+		for j, word := range keyword {
+			if name[i+j] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func classify(organization string) string {
+	name := words(organization)
+	for _, rule := range rules {
+		for _, keyword := range rule.keywords {
+			if containsWords(name, words(keyword)) {
+				return rule.category
+			}
+		}
+	}
+	return "other"
+}
+
+type entry struct {
+	assignment string
+	category   string
+	vendor     string
+}
+
+func read(r io.Reader) ([]entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reader.ReadAll: %w", err)
+	}
+
+	res := []entry{}
+	for i, record := range records {
+		if i == 0 || len(record) < 3 {
+			// Skip header and malformed records.
+			continue
+		}
+
+		assignment := strings.ToUpper(strings.TrimSpace(record[1]))
+		if record[0] != "MA-L" || len(assignment) != 6 {
+			// Only 24-bit assignments are supported.
+			continue
+		}
+
+		vendor := strings.Join(strings.Fields(record[2]), " ")
+		res = append(res, entry{
+			assignment: assignment,
+			category:   classify(vendor),
+			vendor:     vendor,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].assignment < res[j].assignment
+	})
+
+	return res, nil
+}
+
+func write(w io.Writer, entries []entry) error {
+	for _, e := range entries {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", e.assignment, e.category, e.vendor)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// open returns registry from given path or downloads it
+// from registryURL, if path is empty.
+func open(path string) (io.ReadCloser, error) {
+	if path != "" {
+		return os.Open(path)
+	}
+
+	res, err := http.Get(registryURL)
+	if err != nil {
+		return nil, fmt.Errorf("http.Get: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("http.Get: unexpected status %s", res.Status)
+	}
+
+	return res.Body, nil
+}
+
+func main() {
+	in := flag.String("in", "", "path to IEEE registry in the CSV format, downloaded if empty")
+	out := flag.String("out", "table.tsv", "path to generated table")
+	flag.Parse()
+
+	input, err := open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer input.Close()
+
+	entries, err := read(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	output, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer output.Close()
+
+	if err := write(output, entries); err != nil {
+		log.Fatal(err)
+	}
+}
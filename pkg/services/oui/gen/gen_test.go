@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestClassify(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(classify("Intel Corporate"), "laptop")
+	is.Equal(classify("TP-LINK TECHNOLOGIES CO.,LTD."), "network")
+	is.Equal(classify("Raspberry Pi Trading Ltd"), "iot")
+	is.Equal(classify("Motorola Mobility LLC, a Lenovo Company"), "phone")
+
+	// Keywords match only whole words.
+	is.Equal(classify("Intelligent Systems"), "other")
+	is.Equal(classify("Italdata Ingegneria dell'Idea S.p.A."), "other")
+}
@@ -0,0 +1,114 @@
+// Package oui identifies vendors of network devices by the
+// organizationally unique identifier (OUI) of their hardware
+// addresses.
+//
+// Table with vendors is embedded into binary. It is generated from
+// complete IEEE MA-L registry. Run go generate in this directory to
+// download current registry and refresh the table, or pass local
+// copy of registry with -in flag of gen command.
+package oui
+
+//go:generate go run ./gen -out table.tsv
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"net"
+	"strings"
+)
+
+// Category of network device guessed by its vendor.
+type Category string
+
+const (
+	// Phone is a smartphone or tablet.
+	Phone Category = "phone"
+
+	// Laptop is a personal computer.
+	Laptop Category = "laptop"
+
+	// IoT is a microcontroller, single board computer or other
+	// smart device.
+	IoT Category = "iot"
+
+	// Network is a router, switch or access point.
+	Network Category = "network"
+
+	// Printer is a printer or scanner.
+	Printer Category = "printer"
+
+	// Other is a device of known vendor, that doesn't
+	// fit other categories.
+	Other Category = "other"
+
+	// Randomized is a device with randomized (locally
+	// administered) address. Vendor of such device is
+	// impossible to guess.
+	Randomized Category = "randomized"
+
+	// Unknown is a device of vendor missing in the table.
+	Unknown Category = "unknown"
+)
+
+// Vendor of network device.
+type Vendor struct {
+	// Name is organization name from the IEEE registry.
+	Name string
+
+	// Category of vendor's devices.
+	Category Category
+}
+
+//go:embed table.tsv
+var table string
+
+var vendors = parse(table)
+
+func parse(t string) map[string]Vendor {
+	res := map[string]Vendor{}
+
+	for _, line := range strings.Split(t, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		res[fields[0]] = Vendor{
+			Name:     fields[2],
+			Category: Category(fields[1]),
+		}
+	}
+
+	return res
+}
+
+// IsRandomized returns true if given address is locally
+// administered. Modern phones and laptops use such addresses
+// to protect privacy of their owners.
+func IsRandomized(address net.HardwareAddr) bool {
+	return len(address) > 0 && address[0]&0x02 != 0
+}
+
+// Lookup returns vendor of device with given address.
+func Lookup(address net.HardwareAddr) (Vendor, bool) {
+	if len(address) < 3 || IsRandomized(address) {
+		return Vendor{}, false
+	}
+
+	vendor, ok := vendors[strings.ToUpper(hex.EncodeToString(address[:3]))]
+	return vendor, ok
+}
+
+// Classify returns category of device with given address.
+func Classify(address net.HardwareAddr) Category {
+	if IsRandomized(address) {
+		return Randomized
+	}
+
+	vendor, ok := Lookup(address)
+	if !ok {
+		return Unknown
+	}
+
+	return vendor.Category
+}
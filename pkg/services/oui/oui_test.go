@@ -0,0 +1,58 @@
+package oui
+
+import (
+	"net"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestClassify(t *testing.T) {
+	is := is.New(t)
+
+	mustParse := func(s string) net.HardwareAddr {
+		addr, err := net.ParseMAC(s)
+		is.NoErr(err)
+		return addr
+	}
+
+	vendor, ok := Lookup(mustParse("b8:27:eb:12:34:56"))
+	is.True(ok)
+	is.Equal(vendor.Name, "Raspberry Pi Foundation")
+	is.Equal(vendor.Category, IoT)
+
+	for _, tc := range []struct {
+		address  string
+		category Category
+	}{
+		{"dc:a6:32:00:00:01", IoT},     // Raspberry Pi Trading
+		{"24:0a:c4:00:00:01", IoT},     // Espressif
+		{"3c:22:fb:00:00:01", Phone},   // Apple
+		{"f4:f5:d8:00:00:01", Phone},   // Google
+		{"00:1b:21:00:00:01", Laptop},  // Intel
+		{"00:00:0c:00:00:01", Network}, // Cisco
+		{"b0:be:76:00:00:01", Network}, // TP-Link
+		{"00:00:48:00:00:01", Printer}, // Seiko Epson
+		{"00:11:32:00:00:01", Other},   // Synology
+		{"da:a1:19:00:00:01", Randomized},
+		{"fc:ff:ff:00:00:01", Unknown}, // not assigned
+	} {
+		is.Equal(Classify(mustParse(tc.address)), tc.category)
+	}
+
+	_, ok = Lookup(mustParse("da:a1:19:00:00:01"))
+	is.True(!ok) // randomized addresses have no vendor
+}
+
+func TestTable(t *testing.T) {
+	is := is.New(t)
+
+	// Table is generated from complete registry, which has
+	// tens of thousands of assignments.
+	is.True(len(vendors) > 30000)
+	for assignment, vendor := range vendors {
+		is.Equal(len(assignment), 6)
+		is.True(vendor.Name != "")
+		is.True(vendor.Category != "")
+	}
+}
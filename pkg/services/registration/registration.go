@@ -0,0 +1,242 @@
+// Package registration decides who can create new accounts.
+// Registration can be open, closed, limited to holders of
+// invite codes or require approval of admin.
+package registration
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/secrets"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+var (
+	// ErrClosed is returned when registration is closed.
+	ErrClosed = errors.New("registration: closed")
+
+	// ErrInvalidMode is returned for unknown registration modes.
+	ErrInvalidMode = errors.New("registration: invalid mode")
+
+	// ErrInvalidInvite is returned for unknown, expired or
+	// used up invite codes.
+	ErrInvalidInvite = errors.New("registration: invalid invite")
+
+	// ErrInviteLimit is returned for invites of members with
+	// more uses or longer time-to-live than allowed.
+	ErrInviteLimit = errors.New("registration: invite exceeds limits")
+)
+
+// modeSetting is name of setting with registration mode.
+const modeSetting = "registration::mode"
+
+// legacyCodeSize is number of random bytes in invite codes
+// created by earlier versions, which were stored as they are.
+const legacyCodeSize = 12
+
+// MemberMaxUses is the highest number of uses of invites
+// created by members without models.ManageUsers permission.
+const MemberMaxUses = 5
+
+// MemberMaxTTL is the longest time-to-live of invites created
+// by members without models.ManageUsers permission.
+const MemberMaxTTL = 7 * 24 * time.Hour
+
+// Registration creates accounts according to current
+// registration mode.
+type Registration struct {
+	Settings storage.Settings
+	Invites  storage.Invites
+	Users    storage.Users
+
+	// Default is registration mode used until admin
+	// changes it. Defaults to models.RegistrationOpen.
+	Default models.RegistrationMode
+}
+
+// Mode returns current registration mode.
+func (r *Registration) Mode(ctx context.Context) (models.RegistrationMode, error) {
+	mode, err := r.Settings.Read(ctx, modeSetting)
+	if errors.Is(err, serrors.ErrNoID) {
+		if r.Default == "" {
+			return models.RegistrationOpen, nil
+		}
+		return r.Default, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("r.Settings.Read: %w", err)
+	}
+
+	return models.RegistrationMode(mode), nil
+}
+
+// SetMode changes registration mode. Make sure, that only
+// users with models.ManageUsers permission can call it.
+func (r *Registration) SetMode(ctx context.Context, mode models.RegistrationMode) error {
+	if !mode.Valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidMode, mode)
+	}
+
+	if err := r.Settings.Save(ctx, modeSetting, string(mode)); err != nil {
+		return fmt.Errorf("r.Settings.Save: %w", err)
+	}
+
+	return nil
+}
+
+// MemberLimits returns number of uses and time-to-live of
+// invite created by member without models.ManageUsers permission.
+// Zero values, which mean no limit, are replaced with the highest
+// allowed ones. It returns ErrInviteLimit for greater values.
+func MemberLimits(maxUses int, ttl time.Duration) (int, time.Duration, error) {
+	if maxUses > MemberMaxUses || ttl > MemberMaxTTL {
+		return 0, 0, ErrInviteLimit
+	}
+	if maxUses == 0 {
+		maxUses = MemberMaxUses
+	}
+	if ttl == 0 {
+		ttl = MemberMaxTTL
+	}
+	return maxUses, ttl, nil
+}
+
+// inviteID returns id of invite with given code. Only hashes
+// of codes are stored, so codes can't be read from storage.
+func inviteID(code string) string {
+	return base64.RawURLEncoding.EncodeToString(secrets.Hash(code))
+}
+
+// Invite creates invite code, that can be used given number
+// of times until given time-to-live passes. Zero maxUses or
+// ttl mean no limit, so use MemberLimits for invites of
+// members. It returns invite and its code, which is not
+// stored anywhere.
+func (r *Registration) Invite(ctx context.Context, creatorID string, maxUses int, ttl time.Duration) (*models.Invite, string, error) {
+	code, _, err := secrets.Generate()
+	if err != nil {
+		return nil, "", fmt.Errorf("secrets.Generate: %w", err)
+	}
+
+	now := time.Now()
+	invite := models.Invite{
+		ID:        inviteID(code),
+		CreatorID: creatorID,
+		MaxUses:   maxUses,
+		Created:   now,
+	}
+	if ttl > 0 {
+		invite.Expires = now.Add(ttl)
+	}
+
+	if _, err := r.Invites.New(ctx, invite); err != nil {
+		return nil, "", fmt.Errorf("r.Invites.New: %w", err)
+	}
+
+	return &invite, code, nil
+}
+
+// Valid returns true if invite can be used at given time.
+func Valid(i models.Invite, now time.Time) bool {
+	if i.MaxUses > 0 && i.Uses >= i.MaxUses {
+		return false
+	}
+	return i.Expires.IsZero() || now.Before(i.Expires)
+}
+
+// use increments uses of invite with given code by
+// given delta, if invite is still valid.
+func (r *Registration) use(ctx context.Context, code string, delta int) error {
+	f := func(i *models.Invite) error {
+		if delta > 0 && !Valid(*i, time.Now()) {
+			return ErrInvalidInvite
+		}
+		i.Uses += delta
+		return nil
+	}
+
+	err := r.Invites.Update(ctx, inviteID(code), f)
+	if errors.Is(err, serrors.ErrNoID) && len(code) == base64.RawURLEncoding.EncodedLen(legacyCodeSize) {
+		// Invites created by earlier versions are stored
+		// with their codes as ids.
+		err = r.Invites.Update(ctx, code, f)
+	}
+	if errors.Is(err, serrors.ErrNoID) {
+		return fmt.Errorf("%w: %s", ErrInvalidInvite, err)
+	}
+	if err != nil {
+		return fmt.Errorf("r.Invites.Update: %w", err)
+	}
+
+	return nil
+}
+
+// Request contains data of new account.
+type Request struct {
+	Nickname string
+	Password []byte
+
+	// Invite is invite code. It is required only when
+	// registration is invite-only.
+	Invite string
+}
+
+// Register creates new account with given data. Returns id
+// of created user and whether the account waits for approval.
+// Nickname and password should be verified before calling it.
+func (r *Registration) Register(ctx context.Context, req Request) (string, bool, error) {
+	mode, err := r.Mode(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("r.Mode: %w", err)
+	}
+
+	switch mode {
+	case models.RegistrationOpen, models.RegistrationApproval:
+	case models.RegistrationInvite:
+		if req.Invite == "" {
+			return "", false, ErrInvalidInvite
+		}
+		if err := r.use(ctx, req.Invite, 1); err != nil {
+			return "", false, fmt.Errorf("r.use: %w", err)
+		}
+	default:
+		return "", false, ErrClosed
+	}
+
+	pending := mode == models.RegistrationApproval
+	id, err := users.Add(ctx, users.AddUserRequest{
+		Nickname: req.Nickname,
+		Password: req.Password,
+		Pending:  pending,
+		Storage:  r.Users,
+	})
+	if err != nil {
+		if mode == models.RegistrationInvite {
+			// Give back use of invite, because no
+			// account has been created.
+			_ = r.use(ctx, req.Invite, -1)
+		}
+		return "", false, fmt.Errorf("users.Add: %w", err)
+	}
+
+	return id, pending, nil
+}
+
+// Approve allows pending user with given id to log in.
+func (r *Registration) Approve(ctx context.Context, userID string) error {
+	err := r.Users.Update(ctx, userID, func(u *storage.UserEntry) error {
+		u.Pending = false
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("r.Users.Update: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package registration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestRegister(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	r := &Registration{
+		Settings: f.Settings(),
+		Invites:  f.Invites(),
+		Users:    f.Users(),
+		Default:  models.RegistrationClosed,
+	}
+
+	request := func(nickname, invite string) Request {
+		return Request{
+			Nickname: nickname,
+			Password: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+			Invite:   invite,
+		}
+	}
+
+	mode, err := r.Mode(ctx)
+	is.NoErr(err)
+	is.Equal(mode, models.RegistrationClosed)
+
+	_, _, err = r.Register(ctx, request("alice", ""))
+	is.True(errors.Is(err, ErrClosed))
+
+	is.True(errors.Is(r.SetMode(ctx, "party"), ErrInvalidMode))
+
+	is.NoErr(r.SetMode(ctx, models.RegistrationOpen))
+	aliceID, pending, err := r.Register(ctx, request("alice", ""))
+	is.NoErr(err)
+	is.True(!pending)
+
+	// Invite can be used once.
+	is.NoErr(r.SetMode(ctx, models.RegistrationInvite))
+	invite, code, err := r.Invite(ctx, aliceID, 1, time.Hour)
+	is.NoErr(err)
+
+	// Only hash of the code is stored.
+	is.True(invite.ID != code)
+	_, _, err = r.Register(ctx, request("bob", invite.ID))
+	is.True(errors.Is(err, ErrInvalidInvite))
+
+	_, _, err = r.Register(ctx, request("bob", ""))
+	is.True(errors.Is(err, ErrInvalidInvite))
+	_, _, err = r.Register(ctx, request("bob", "nope"))
+	is.True(errors.Is(err, ErrInvalidInvite))
+
+	// Failed registration doesn't use the invite.
+	_, _, err = r.Register(ctx, request("alice", code))
+	is.True(err != nil)
+
+	_, pending, err = r.Register(ctx, request("bob", code))
+	is.NoErr(err)
+	is.True(!pending)
+
+	_, _, err = r.Register(ctx, request("carol", code))
+	is.True(errors.Is(err, ErrInvalidInvite))
+
+	// Invites created by earlier versions are stored with
+	// their codes as ids.
+	legacy := "abcdefghijklmnop"
+	_, err = f.Invites().New(ctx, models.Invite{ID: legacy, CreatorID: aliceID, MaxUses: 1})
+	is.NoErr(err)
+	_, _, err = r.Register(ctx, request("dave", legacy))
+	is.NoErr(err)
+
+	// Expired invites cannot be used.
+	expired := *invite
+	expired.MaxUses = 0
+	expired.Expires = time.Now().Add(-time.Minute)
+	is.True(!Valid(expired, time.Now()))
+
+	is.NoErr(r.SetMode(ctx, models.RegistrationApproval))
+	carolID, pending, err := r.Register(ctx, request("carol", ""))
+	is.NoErr(err)
+	is.True(pending)
+
+	carol, err := f.Users().Read(ctx, carolID)
+	is.NoErr(err)
+	is.True(carol.Pending)
+
+	is.NoErr(r.Approve(ctx, carolID))
+	carol, err = f.Users().Read(ctx, carolID)
+	is.NoErr(err)
+	is.True(!carol.Pending)
+}
+
+func TestMemberLimits(t *testing.T) {
+	is := is.New(t)
+
+	maxUses, ttl, err := MemberLimits(0, 0)
+	is.NoErr(err)
+	is.Equal(maxUses, MemberMaxUses)
+	is.Equal(ttl, MemberMaxTTL)
+
+	maxUses, ttl, err = MemberLimits(1, time.Hour)
+	is.NoErr(err)
+	is.Equal(maxUses, 1)
+	is.Equal(ttl, time.Hour)
+
+	_, _, err = MemberLimits(MemberMaxUses+1, time.Hour)
+	is.True(errors.Is(err, ErrInviteLimit))
+
+	_, _, err = MemberLimits(1, MemberMaxTTL+time.Hour)
+	is.True(errors.Is(err, ErrInviteLimit))
+}
@@ -4,6 +4,7 @@ package requests
 
 import (
 	"errors"
+	"net"
 	"net/http"
 
 	"github.com/go-chi/chi"
@@ -13,6 +14,15 @@ import (
 var ErrValueNotFound = errors.New("requests: value not found in ctx")
 var ErrEmptyParam = errors.New("requested URL parameter is empty")
 
+// RemoteIP returns address of client, that sent given request.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // UserID returns user id from url.
 func UserID(r *http.Request) (string, error) {
 	res := chi.URLParam(r, "user-id")
@@ -30,6 +40,69 @@ func DeviceID(r *http.Request) (string, error) {
 	return res, nil
 }
 
+// CardID returns card's id from url.
+func CardID(r *http.Request) (string, error) {
+	res := chi.URLParam(r, "card-id")
+	if res == "" {
+		return "", ErrEmptyParam
+	}
+	return res, nil
+}
+
+// SubscriptionID returns subscription's id from url.
+func SubscriptionID(r *http.Request) (string, error) {
+	res := chi.URLParam(r, "subscription-id")
+	if res == "" {
+		return "", ErrEmptyParam
+	}
+	return res, nil
+}
+
+// ScannerID returns scanner key's id from url.
+func ScannerID(r *http.Request) (string, error) {
+	res := chi.URLParam(r, "scanner-id")
+	if res == "" {
+		return "", ErrEmptyParam
+	}
+	return res, nil
+}
+
+// IgnoredID returns ignored address's id from url.
+func IgnoredID(r *http.Request) (string, error) {
+	res := chi.URLParam(r, "ignored-id")
+	if res == "" {
+		return "", ErrEmptyParam
+	}
+	return res, nil
+}
+
+// InviteID returns invite's id from url.
+func InviteID(r *http.Request) (string, error) {
+	res := chi.URLParam(r, "invite-id")
+	if res == "" {
+		return "", ErrEmptyParam
+	}
+	return res, nil
+}
+
+// SessionID returns session's id from url.
+func SessionID(r *http.Request) (string, error) {
+	res := chi.URLParam(r, "session-id")
+	if res == "" {
+		return "", ErrEmptyParam
+	}
+	return res, nil
+}
+
+// TokenID returns personal API token's id from url.
+func TokenID(r *http.Request) (string, error) {
+	res := chi.URLParam(r, "token-id")
+	if res == "" {
+		return "", ErrEmptyParam
+	}
+	return res, nil
+}
+
 // TwoFactorID returns two factor method's id from url.
 func TwoFactorID(r *http.Request) (string, error) {
 	res := chi.URLParam(r, "twofactor-id")
@@ -0,0 +1,255 @@
+// Package reset manages one-time tokens, that allow users
+// to set new password without knowing the old one. Tokens
+// are issued by admins and keyholders.
+package reset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/secrets"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+var (
+	// ErrInvalidToken is returned for unknown, expired or
+	// already used tokens.
+	ErrInvalidToken = errors.New("reset: invalid token")
+
+	// ErrForbidden is returned when issuer is not allowed
+	// to reset password of given user.
+	ErrForbidden = errors.New("reset: forbidden")
+)
+
+const (
+	// ActionIssue is audit action of issuing token.
+	ActionIssue = "password-reset:issue"
+
+	// ActionRequest is audit action of issuing token
+	// requested by user, who forgot password.
+	ActionRequest = "password-reset:request"
+
+	// ActionConsume is audit action of setting new
+	// password with token.
+	ActionConsume = "password-reset:consume"
+)
+
+// DefaultTTL is time-to-live of tokens used, when Resetter
+// has no TTL set.
+const DefaultTTL = 24 * time.Hour
+
+// Resetter issues and consumes password reset tokens. Every
+// issued and consumed token is recorded in audit log.
+type Resetter struct {
+	Tokens storage.ResetTokens
+	Users  storage.Users
+	Audit  storage.Audit
+
+	// APITokens of users are removed together with their
+	// sessions, when they set new password with token.
+	APITokens storage.APITokens
+
+	// TTL is time-to-live of issued tokens. Defaults
+	// to DefaultTTL.
+	TTL time.Duration
+}
+
+// audit records action performed by actor on target.
+func (r *Resetter) audit(ctx context.Context, actorID, action, targetID string) error {
+	err := r.Audit.Add(ctx, models.AuditEntry{
+		ID:       uuid.New().String(),
+		Time:     time.Now(),
+		ActorID:  actorID,
+		Action:   action,
+		TargetID: targetID,
+	})
+	if err != nil {
+		return fmt.Errorf("r.Audit.Add: %w", err)
+	}
+
+	return nil
+}
+
+// Issue creates token for user with given id and removes
+// tokens issued for the user before. Empty issuerID means
+// that token is issued by server operator, for example with
+// command line tool. Users without models.ManageUsers
+// permission cannot issue tokens for users with it. Returned
+// token is the only copy of the token, because only hash of
+// its secret part is stored.
+func (r *Resetter) Issue(ctx context.Context, issuerID, userID string) (string, *models.ResetToken, error) {
+	user, err := r.Users.Read(ctx, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("r.Users.Read: %w", err)
+	}
+
+	if issuerID != "" {
+		issuer, err := r.Users.Read(ctx, issuerID)
+		if err != nil {
+			return "", nil, fmt.Errorf("r.Users.Read: %w", err)
+		}
+		if !issuer.Role.Can(models.ResetPasswords) {
+			return "", nil, ErrForbidden
+		}
+		if user.Role.Can(models.ManageUsers) && !issuer.Role.Can(models.ManageUsers) {
+			return "", nil, ErrForbidden
+		}
+	}
+
+	if err := r.revoke(ctx, userID); err != nil {
+		return "", nil, err
+	}
+
+	token, t, err := r.issue(ctx, issuerID, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := r.audit(ctx, issuerID, ActionIssue, userID); err != nil {
+		return "", nil, fmt.Errorf("r.audit: %w", err)
+	}
+
+	return token, t, nil
+}
+
+// Request creates token for user with given id on their own
+// behalf, for example to send it to verified email address
+// of user. Tokens issued for the user before are kept, so
+// anyone, who knows the address, can't invalidate them by
+// requesting new ones. They are removed, when any token of
+// the user is consumed.
+func (r *Resetter) Request(ctx context.Context, userID string) (string, *models.ResetToken, error) {
+	if _, err := r.Users.Read(ctx, userID); err != nil {
+		return "", nil, fmt.Errorf("r.Users.Read: %w", err)
+	}
+
+	token, t, err := r.issue(ctx, userID, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := r.audit(ctx, userID, ActionRequest, userID); err != nil {
+		return "", nil, fmt.Errorf("r.audit: %w", err)
+	}
+
+	return token, t, nil
+}
+
+// revoke removes all tokens of user with given id.
+func (r *Resetter) revoke(ctx context.Context, userID string) error {
+	previous, err := r.Tokens.OfUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("r.Tokens.OfUser: %w", err)
+	}
+	for _, t := range previous {
+		err := r.Tokens.Remove(ctx, t.ID)
+		if err != nil && !errors.Is(err, serrors.ErrNoID) {
+			return fmt.Errorf("r.Tokens.Remove: %w", err)
+		}
+	}
+	return nil
+}
+
+// issue stores new token for user with given id.
+func (r *Resetter) issue(ctx context.Context, issuerID, userID string) (string, *models.ResetToken, error) {
+	secret, hashed, err := secrets.Generate()
+	if err != nil {
+		return "", nil, fmt.Errorf("secrets.Generate: %w", err)
+	}
+
+	ttl := r.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	now := time.Now()
+	token := models.ResetToken{
+		ID:       uuid.New().String(),
+		UserID:   userID,
+		IssuerID: issuerID,
+		Secret:   hashed,
+		Created:  now,
+		Expires:  now.Add(ttl),
+	}
+	if _, err := r.Tokens.New(ctx, token); err != nil {
+		return "", nil, fmt.Errorf("r.Tokens.New: %w", err)
+	}
+
+	return token.ID + "." + secret, &token, nil
+}
+
+// Lookup returns stored token matching given token, if it
+// can be still used.
+func (r *Resetter) Lookup(ctx context.Context, token string) (*models.ResetToken, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	t, err := r.Tokens.Read(ctx, id)
+	if errors.Is(err, serrors.ErrNoID) {
+		return nil, fmt.Errorf("%w: r.Tokens.Read: %s", ErrInvalidToken, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("r.Tokens.Read: %w", err)
+	}
+
+	if !time.Now().Before(t.Expires) {
+		return nil, ErrInvalidToken
+	}
+
+	if !secrets.Match(t.Secret, secret) {
+		return nil, ErrInvalidToken
+	}
+
+	return t, nil
+}
+
+// Consume sets new password of user, that given token was
+// issued for, and revokes all of user's sessions and other
+// reset tokens. Token can be consumed only once. Returns users.ErrInvaliPassword if
+// password is too weak.
+func (r *Resetter) Consume(ctx context.Context, token, password string) error {
+	t, err := r.Lookup(ctx, token)
+	if err != nil {
+		return fmt.Errorf("r.Lookup: %w", err)
+	}
+
+	// Check password before removing token, so user
+	// can try again with better one.
+	if !users.VerifyPassword(password) {
+		return users.ErrInvaliPassword
+	}
+
+	// Token is removed first, so it cannot be used
+	// twice by concurrent requests.
+	err = r.Tokens.Remove(ctx, t.ID)
+	if errors.Is(err, serrors.ErrNoID) {
+		return fmt.Errorf("%w: r.Tokens.Remove: %s", ErrInvalidToken, err)
+	}
+	if err != nil {
+		return fmt.Errorf("r.Tokens.Remove: %w", err)
+	}
+
+	if err := users.SetPassword(ctx, r.Users, r.APITokens, t.UserID, password); err != nil {
+		return fmt.Errorf("users.SetPassword: %w", err)
+	}
+
+	if err := r.revoke(ctx, t.UserID); err != nil {
+		return fmt.Errorf("r.revoke: %w", err)
+	}
+
+	if err := r.audit(ctx, t.UserID, ActionConsume, t.UserID); err != nil {
+		return fmt.Errorf("r.audit: %w", err)
+	}
+
+	return nil
+}
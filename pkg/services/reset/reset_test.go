@@ -0,0 +1,118 @@
+package reset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestResetter(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	// alice is keyholder, bob is member and carol is admin.
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice", Role: models.RoleKeyholder},
+		{ID: "2", Nickname: "bob", Role: models.RoleMember},
+		{ID: "3", Nickname: "carol", Role: models.RoleAdmin},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	r := &Resetter{
+		Tokens:    f.ResetTokens(),
+		Users:     f.Users(),
+		Audit:     f.Audit(),
+		APITokens: f.APITokens(),
+	}
+
+	_, _, err = r.Issue(ctx, "2", "1")
+	is.True(errors.Is(err, ErrForbidden))
+	_, _, err = r.Issue(ctx, "1", "3")
+	is.True(errors.Is(err, ErrForbidden))
+
+	first, _, err := r.Issue(ctx, "1", "2")
+	is.NoErr(err)
+
+	// Issuing new token invalidates the previous one.
+	token, _, err := r.Issue(ctx, "3", "2")
+	is.NoErr(err)
+	_, err = r.Lookup(ctx, first)
+	is.True(errors.Is(err, ErrInvalidToken))
+
+	_, err = r.Lookup(ctx, token+"x")
+	is.True(errors.Is(err, ErrInvalidToken))
+	_, err = r.Lookup(ctx, "nope")
+	is.True(errors.Is(err, ErrInvalidToken))
+
+	found, err := r.Lookup(ctx, token)
+	is.NoErr(err)
+	is.Equal(found.UserID, "2")
+	is.Equal(found.IssuerID, "3")
+
+	// Weak password doesn't use up the token.
+	is.True(errors.Is(r.Consume(ctx, token, "short"), users.ErrInvaliPassword))
+
+	_, err = f.APITokens().New(ctx, models.APIToken{ID: "t", UserID: "2", Name: "script", Secret: []byte("secret")})
+	is.NoErr(err)
+
+	before := time.Now()
+	is.NoErr(r.Consume(ctx, token, "u8dXHRi0JNo23JVeHkjh"))
+	is.True(errors.Is(r.Consume(ctx, token, "u8dXHRi0JNo23JVeHkjh"), ErrInvalidToken))
+
+	bob, err := f.Users().Read(ctx, "2")
+	is.NoErr(err)
+	is.NoErr(bcrypt.CompareHashAndPassword(bob.HashedPassword, []byte("u8dXHRi0JNo23JVeHkjh")))
+	is.True(!bob.SessionsRevoked.Before(before))
+
+	// API tokens are revoked together with sessions.
+	apiTokens, err := f.APITokens().OfUser(ctx, "2")
+	is.NoErr(err)
+	is.Equal(len(apiTokens), 0)
+
+	entries, err := f.Audit().Between(ctx, before.Add(-time.Hour), time.Now().Add(time.Second))
+	is.NoErr(err)
+	is.Equal(len(entries), 3)
+	is.Equal(entries[2].Action, ActionConsume)
+	is.Equal(entries[2].TargetID, "2")
+
+	// Users can request tokens for themselves. Requesting
+	// new token doesn't invalidate the previous one.
+	first, _, err = r.Request(ctx, "2")
+	is.NoErr(err)
+	token, _, err = r.Request(ctx, "2")
+	is.NoErr(err)
+	found, err = r.Lookup(ctx, token)
+	is.NoErr(err)
+	is.Equal(found.IssuerID, "2")
+	_, err = r.Lookup(ctx, first)
+	is.NoErr(err)
+
+	// Consuming any of them invalidates the rest.
+	is.NoErr(r.Consume(ctx, token, "u8dXHRi0JNo23JVeHkjh"))
+	_, err = r.Lookup(ctx, first)
+	is.True(errors.Is(err, ErrInvalidToken))
+
+	// Expired tokens cannot be used.
+	r.TTL = time.Nanosecond
+	token, _, err = r.Issue(ctx, "", "1")
+	is.NoErr(err)
+	time.Sleep(time.Millisecond)
+	_, err = r.Lookup(ctx, token)
+	is.True(errors.Is(err, ErrInvalidToken))
+}
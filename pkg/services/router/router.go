@@ -4,20 +4,28 @@ Package router implements http long-season server with routing logic.
 package router
 
 import (
-	"net"
 	"net/http"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 
 	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/email"
 	"github.com/hakierspejs/long-season/pkg/services/handlers"
 	"github.com/hakierspejs/long-season/pkg/services/handlers/api/v1"
 	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/keyring"
+	"github.com/hakierspejs/long-season/pkg/services/kiosk"
 	lsmiddleware "github.com/hakierspejs/long-season/pkg/services/middleware"
+	"github.com/hakierspejs/long-season/pkg/services/notify"
+	"github.com/hakierspejs/long-season/pkg/services/registration"
+	"github.com/hakierspejs/long-season/pkg/services/reset"
+	"github.com/hakierspejs/long-season/pkg/services/scanners"
 	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/services/space"
 	"github.com/hakierspejs/long-season/pkg/services/toussaint"
 	"github.com/hakierspejs/long-season/pkg/services/ui"
+	"github.com/hakierspejs/long-season/pkg/services/webpush"
 	"github.com/hakierspejs/long-season/pkg/storage"
 )
 
@@ -33,16 +41,46 @@ type Args struct {
 	Opener         handlers.Opener
 	Users          storage.Users
 	Devices        storage.Devices
+	DevicesKey     []byte
+	Cards          storage.Cards
+	Subscriptions  storage.Subscriptions
+	Notifier       *notify.Notifier
+	VAPID          *webpush.VAPID
+	CheckIns       storage.CheckIns
+	KioskCodes     *kiosk.Codes
+	Visits         storage.Visits
+	History        storage.History
 	StatusTx       storage.StatusTx
+	Space          *space.Tracker
+	Registration   *registration.Registration
+	Invites        storage.Invites
+	Resetter       *reset.Resetter
+	Emails         *email.Addresses
+	Audit          storage.Audit
 	TwoFactor      storage.TwoFactor
 	OnlineUsers    storage.OnlineUsers
 	UserAdapter    storage.UserAdapter
-	MacsChan       chan<- []net.HardwareAddr
+	Scanners       storage.Scanners
+	Ignored        storage.Ignored
+	APITokens      storage.APITokens
+	Keys           storage.Keys
+	ScanQueue      *scanners.Queue
 	PublicCors     Cors
 	Adapter        *happier.Adapter
 	SessionRenewer session.Renewer
 	SessionSaver   session.Saver
 	SessionKiller  session.Killer
+	SessionStore   *session.Store
+	SessionKeys    *keyring.Keyring
+
+	// SessionRefresher restores sessions from refresh tokens
+	// and SessionIssuer issues tokens for API clients.
+	SessionRefresher session.Renewer
+	SessionIssuer    session.Issuer
+	SessionLifetimes session.Lifetimes
+
+	// SessionSliding renews sessions of active users.
+	SessionSliding func(http.Handler) http.Handler
 }
 
 // NewRouter returns Handler, which contains all the handlers and
@@ -55,6 +93,7 @@ func NewRouter(config models.Config, args Args) http.Handler {
 	r.Use(middleware.RequestID)
 	r.Use(middleware.NoCache)
 	r.Use(lsmiddleware.Debug(config))
+	r.Use(args.SessionSliding)
 
 	sessionGuard := session.Guard(args.SessionRenewer)
 	twoFactorGuard := toussaint.Guard(args.TwoFactor, args.SessionRenewer)
@@ -88,7 +127,19 @@ func NewRouter(config models.Config, args Args) http.Handler {
 
 	r.With(guard, twoFactorCleaner).Get("/account", ui.Account(config, args.Opener))
 
-	r.Get("/logout", ui.Logout(args.SessionKiller))
+	r.With(
+		guard, twoFactorCleaner, lsmiddleware.Permission(args.SessionRenewer, models.ManageUsers),
+	).Get("/admin", ui.Admin(config, args.Opener))
+
+	r.Get("/logout", ui.Logout(args.SessionRenewer, args.SessionKiller, args.SessionStore))
+
+	r.Get("/kiosk", ui.Kiosk(config, args.Opener))
+
+	r.With(guard, twoFactorCleaner).Get("/checkin", ui.CheckIn(config, args.Opener))
+
+	r.Get("/reset", ui.Reset(config, args.Opener))
+
+	r.Get("/verify", ui.Verify(config, args.Opener))
 
 	r.With(
 		twoFactorCleaner, lsmiddleware.RedirectLoggedIn(args.SessionRenewer),
@@ -104,6 +155,35 @@ func NewRouter(config models.Config, args Args) http.Handler {
 		})),
 	)
 
+	statsArgs := api.StatsArgs{
+		Config:  config,
+		Renewer: args.SessionRenewer,
+		Users:   args.Users,
+		Visits:  args.Visits,
+	}
+
+	// Embeddable status badges.
+	badgeArgs := handlers.BadgeArgs{
+		Config:   config,
+		Counters: args.StatusTx,
+		Users:    args.Users,
+		Adapter:  args.UserAdapter,
+		Space:    args.Space,
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(args.PublicCors.Handler, lsmiddleware.Cache(config.RefreshTime))
+
+		r.Options("/badge.svg", nil)
+		r.Get("/badge.svg", args.Adapter.WithError(handlers.Badge(badgeArgs)))
+		r.Options("/status.png", nil)
+		r.Get("/status.png", args.Adapter.WithError(handlers.StatusImage(badgeArgs)))
+		r.Get("/embed", args.Adapter.WithError(handlers.Embed(badgeArgs)))
+
+		// Public keys for verifying session tokens.
+		r.Options("/.well-known/jwks.json", nil)
+		r.Get("/.well-known/jwks.json", args.Adapter.WithError(handlers.JWKS(args.SessionKeys)))
+	})
+
 	// API routes.
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Route("/users", func(r chi.Router) {
@@ -115,10 +195,15 @@ func NewRouter(config models.Config, args Args) http.Handler {
 			// application, and not to particular endpoints.
 			r.With(args.PublicCors.Handler).Options("/", nil)
 			r.With(args.PublicCors.Handler).Get("/", args.Adapter.WithError(api.UsersAll(args.Users, args.UserAdapter)))
-			r.Post("/", args.Adapter.WithError(api.UserCreate(args.Users)))
+			r.Post("/", args.Adapter.WithError(api.UserCreate(args.Registration)))
 
 			r.With(lsmiddleware.UserID).Route("/{user-id}", func(r chi.Router) {
-				r.Get("/", args.Adapter.WithError(api.UserRead(args.SessionRenewer, args.Users, args.UserAdapter)))
+				r.With(
+					lsmiddleware.Scope(args.SessionRenewer, models.ScopeReadStatus),
+				).Get("/", args.Adapter.WithError(api.UserRead(args.SessionRenewer, args.Users, args.UserAdapter)))
+				r.With(
+					lsmiddleware.Scope(args.SessionRenewer, models.ScopeReadStatus),
+				).Get("/stats", args.Adapter.WithError(api.UserStats(statsArgs)))
 
 				// Users can only delete themselves.
 				r.With(
@@ -131,7 +216,13 @@ func NewRouter(config models.Config, args Args) http.Handler {
 
 				r.With(
 					guard, lsmiddleware.Private(args.SessionRenewer),
-				).Put("/password", args.Adapter.WithError(api.UpdateUserPassword(args.Users)))
+				).Put("/password", args.Adapter.WithError(
+					api.UpdateUserPassword(args.SessionRenewer, args.Users, args.APITokens, args.SessionStore),
+				))
+
+				r.With(
+					guard, lsmiddleware.Permission(args.SessionRenewer, models.ResetPasswords),
+				).Post("/reset", args.Adapter.WithError(api.ResetIssue(args.SessionRenewer, args.Resetter)))
 
 				r.With(
 					guard, lsmiddleware.Private(args.SessionRenewer),
@@ -144,23 +235,205 @@ func NewRouter(config models.Config, args Args) http.Handler {
 				})
 
 				r.With(
+					lsmiddleware.Scope(args.SessionRenewer, models.ScopeManageDevices),
 					guard, lsmiddleware.Private(args.SessionRenewer),
 				).Route("/devices", func(r chi.Router) {
 					r.Get("/", args.Adapter.WithError(api.UserDevices(args.Devices)))
-					r.Post("/", args.Adapter.WithError(api.DeviceAdd(args.SessionRenewer, args.Devices)))
+					r.Post("/", args.Adapter.WithError(api.DeviceAdd(args.SessionRenewer, args.Devices, args.DevicesKey)))
 
 					r.With(lsmiddleware.DeviceID).Route("/{device-id}", func(r chi.Router) {
 						r.Get("/", args.Adapter.WithError(api.DeviceRead(args.SessionRenewer, args.Devices)))
+						r.Patch("/", args.Adapter.WithError(api.DeviceEdit(args.SessionRenewer, args.Devices, args.DevicesKey)))
 						r.Delete("/", args.Adapter.WithError(api.DeviceRemove(args.SessionRenewer, args.Devices)))
 					})
 				})
+
+				r.With(
+					guard, lsmiddleware.Private(args.SessionRenewer),
+				).Route("/cards", func(r chi.Router) {
+					r.Get("/", args.Adapter.WithError(api.UserCards(args.Cards)))
+					r.Post("/", args.Adapter.WithError(api.CardAdd(args.Cards)))
+					r.Delete("/{card-id}", args.Adapter.WithError(api.CardRemove(args.Cards)))
+				})
+
+				r.With(
+					guard, lsmiddleware.Private(args.SessionRenewer),
+				).Route("/email", func(r chi.Router) {
+					r.Get("/", args.Adapter.WithError(api.UserEmail(args.Users, args.Emails)))
+					r.Put("/", args.Adapter.WithError(api.UserEmailUpdate(args.Users, args.Emails)))
+					r.Post("/verification", args.Adapter.WithError(api.UserEmailVerification(args.Emails)))
+				})
+
+				r.With(
+					guard, lsmiddleware.Private(args.SessionRenewer),
+				).Route("/sessions", func(r chi.Router) {
+					r.Get("/", args.Adapter.WithError(api.UserSessions(args.SessionRenewer, args.Users, args.SessionStore)))
+					r.Delete("/", args.Adapter.WithError(api.SessionsRemove(args.SessionStore)))
+					r.Delete("/{session-id}", args.Adapter.WithError(api.SessionRemove(args.SessionStore)))
+				})
+
+				r.With(
+					guard, lsmiddleware.Private(args.SessionRenewer),
+				).Route("/tokens", func(r chi.Router) {
+					r.Get("/", args.Adapter.WithError(api.UserTokens(args.APITokens)))
+					r.Post("/", args.Adapter.WithError(api.TokenAdd(args.APITokens)))
+					r.Delete("/{token-id}", args.Adapter.WithError(api.TokenRemove(args.APITokens)))
+				})
+
+				r.With(
+					guard, lsmiddleware.Private(args.SessionRenewer),
+				).Route("/invites", func(r chi.Router) {
+					r.Get("/", args.Adapter.WithError(api.UserInvites(args.Invites)))
+					r.Post("/", args.Adapter.WithError(api.InviteAdd(args.SessionRenewer, args.Registration)))
+					r.Delete("/{invite-id}", args.Adapter.WithError(api.InviteRemove(args.Invites)))
+				})
+
+				r.With(
+					guard, lsmiddleware.Private(args.SessionRenewer),
+				).Route("/subscriptions", func(r chi.Router) {
+					r.Get("/", args.Adapter.WithError(api.UserSubscriptions(args.Subscriptions)))
+					r.Post("/", args.Adapter.WithError(api.SubscriptionAdd(args.Notifier)))
+					r.Get("/options", args.Adapter.WithError(api.SubscriptionOptions(args.Notifier, args.VAPID)))
+					r.Delete("/{subscription-id}", args.Adapter.WithError(api.SubscriptionRemove(args.Subscriptions)))
+				})
 			})
 		})
-		r.With(lsmiddleware.UpdateAuth(&config)).Put(
+		r.With(lsmiddleware.UpdateAuth(&config, args.Scanners)).Put(
 			"/update",
-			args.Adapter.WithError(api.UpdateStatus(args.MacsChan)),
+			args.Adapter.WithError(api.UpdateStatus(args.ScanQueue)),
+		)
+		r.With(lsmiddleware.UpdateAuth(&config, args.Scanners)).Put(
+			"/checkin/card",
+			args.Adapter.WithError(api.CardScan(api.CardScanArgs{
+				Config:   config,
+				Cards:    args.Cards,
+				Users:    args.Users,
+				CheckIns: args.CheckIns,
+			})),
+		)
+		// Codes prove presence only when they are displayed
+		// by kiosk, so there is nothing to check in with,
+		// when kiosk is disabled.
+		if config.KioskKey != "" {
+			r.With(
+				lsmiddleware.Scope(args.SessionRenewer, models.ScopeCheckIn), guard,
+			).Post(
+				"/checkin/code",
+				args.Adapter.WithError(api.CodeCheckIn(api.CodeCheckInArgs{
+					Config:   config,
+					Renewer:  args.SessionRenewer,
+					Codes:    args.KioskCodes,
+					CheckIns: args.CheckIns,
+				})),
+			)
+		}
+		r.With(lsmiddleware.KioskAuth(&config)).Get(
+			"/kiosk/code",
+			args.Adapter.WithError(api.KioskCode(args.KioskCodes)),
 		)
-		r.Get("/status", args.Adapter.WithError(api.Status(args.StatusTx)))
+		r.Get("/status", args.Adapter.WithError(api.Status(args.StatusTx, args.Space, args.Users, args.UserAdapter)))
+		r.Route("/space", func(r chi.Router) {
+			r.With(args.PublicCors.Handler).Options("/", nil)
+			r.With(args.PublicCors.Handler).Get("/", args.Adapter.WithError(api.SpaceState(args.Space)))
+			r.Group(func(r chi.Router) {
+				r.Use(
+					lsmiddleware.Scope(args.SessionRenewer, models.ScopeChangeSpace),
+					guard, lsmiddleware.Permission(args.SessionRenewer, models.ChangeSpaceState),
+				)
+				r.Put("/", args.Adapter.WithError(api.SpaceUpdate(args.Space)))
+				r.Delete("/", args.Adapter.WithError(api.SpaceReset(args.Space)))
+			})
+		})
+		adminArgs := api.AdminArgs{
+			Config:    config,
+			Users:     args.Users,
+			Devices:   args.Devices,
+			TwoFactor: args.TwoFactor,
+			Scanners:  args.Scanners,
+			Ignored:   args.Ignored,
+			APITokens: args.APITokens,
+			Queue:     args.ScanQueue,
+			Invites:   args.Invites,
+			Audit:     args.Audit,
+			Sessions:  args.SessionStore,
+			Keys:      args.Keys,
+
+			Registration: args.Registration,
+		}
+		r.With(
+			guard, lsmiddleware.Permission(args.SessionRenewer, models.ManageUsers),
+		).Route("/admin", func(r chi.Router) {
+			r.Route("/users", func(r chi.Router) {
+				r.Get("/", args.Adapter.WithError(api.AdminUsers(adminArgs)))
+				r.Post("/", args.Adapter.WithError(api.AdminUserCreate(adminArgs)))
+
+				r.With(lsmiddleware.UserID).Route("/{user-id}", func(r chi.Router) {
+					r.Get("/", args.Adapter.WithError(api.AdminUser(adminArgs)))
+					r.Patch("/", args.Adapter.WithError(api.AdminUserUpdate(adminArgs)))
+					r.Delete("/", args.Adapter.WithError(api.AdminUserRemove(adminArgs)))
+					r.Put("/password", args.Adapter.WithError(api.AdminUserPassword(adminArgs)))
+					r.Delete("/twofactor", args.Adapter.WithError(api.AdminTwoFactorDisable(adminArgs)))
+					r.Post("/logout", args.Adapter.WithError(api.AdminUserLogout(adminArgs)))
+					r.Post("/approve", args.Adapter.WithError(api.AdminUserApprove(adminArgs)))
+				})
+			})
+			r.Route("/devices", func(r chi.Router) {
+				r.Get("/", args.Adapter.WithError(api.AdminDevices(adminArgs)))
+
+				r.With(lsmiddleware.DeviceID).Route("/{device-id}", func(r chi.Router) {
+					r.Patch("/", args.Adapter.WithError(api.AdminDeviceUpdate(adminArgs)))
+					r.Delete("/", args.Adapter.WithError(api.AdminDeviceRemove(adminArgs)))
+				})
+			})
+			r.Route("/scanners", func(r chi.Router) {
+				r.Get("/", args.Adapter.WithError(api.AdminScanners(adminArgs)))
+				r.Post("/", args.Adapter.WithError(api.AdminScannerCreate(adminArgs)))
+				r.Delete("/{scanner-id}", args.Adapter.WithError(api.AdminScannerRemove(adminArgs)))
+			})
+			r.Route("/ignored", func(r chi.Router) {
+				r.Get("/", args.Adapter.WithError(api.AdminIgnored(adminArgs)))
+				r.Post("/", args.Adapter.WithError(api.AdminIgnoredCreate(adminArgs)))
+				r.Delete("/{ignored-id}", args.Adapter.WithError(api.AdminIgnoredRemove(adminArgs)))
+			})
+			r.Route("/registration", func(r chi.Router) {
+				r.Get("/", args.Adapter.WithError(api.AdminRegistration(adminArgs)))
+				r.Put("/", args.Adapter.WithError(api.AdminRegistrationUpdate(adminArgs)))
+			})
+			r.Route("/invites", func(r chi.Router) {
+				r.Get("/", args.Adapter.WithError(api.AdminInvites(adminArgs)))
+				r.Delete("/{invite-id}", args.Adapter.WithError(api.AdminInviteRemove(adminArgs)))
+			})
+			r.Get("/status", args.Adapter.WithError(api.AdminStatus(adminArgs)))
+			r.Get("/audit", args.Adapter.WithError(api.AdminAudit(adminArgs)))
+			r.Get("/export", args.Adapter.WithError(api.AdminExport(adminArgs)))
+			r.Post("/import", args.Adapter.WithError(api.AdminImport(adminArgs)))
+		})
+		r.Get("/registration", args.Adapter.WithError(api.RegistrationMode(args.Registration)))
+
+		authArgs := api.AuthArgs{
+			Users:     args.Users,
+			TwoFactor: args.TwoFactor,
+			Store:     args.SessionStore,
+			Issuer:    args.SessionIssuer,
+			Lifetimes: args.SessionLifetimes,
+			Refresher: args.SessionRefresher,
+		}
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/token", args.Adapter.WithError(api.AuthToken(authArgs)))
+			r.Post("/refresh", args.Adapter.WithError(api.AuthRefresh(authArgs)))
+		})
+		r.Route("/reset", func(r chi.Router) {
+			r.Post("/", args.Adapter.WithError(api.ResetConsume(args.Resetter)))
+			r.Post("/lookup", args.Adapter.WithError(api.ResetLookup(args.Resetter, args.Users)))
+			r.Post("/request", args.Adapter.WithError(api.ResetRequest(args.Emails)))
+		})
+		r.Post("/email/verify", args.Adapter.WithError(api.EmailVerify(args.Emails)))
+		r.Get("/stats", args.Adapter.WithError(api.SpaceStats(statsArgs)))
+		r.Get("/heatmap", args.Adapter.WithError(api.Heatmap(api.HeatmapArgs{
+			Config:   config,
+			History:  args.History,
+			Counters: args.StatusTx,
+		})))
 
 		r.With(guard).Route("/twofactor", func(r chi.Router) {
 			r.Get("/otp/options", args.Adapter.WithError(api.OptionsOTP(config, args.SessionRenewer)))
@@ -0,0 +1,130 @@
+// Package scanners manages keys of scanners, that report
+// devices connected to the hackerspace network and cards
+// scanned at the door, and keeps track of their reports.
+package scanners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/secrets"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+// ErrInvalidKey is returned for tokens, that don't match
+// any of stored keys.
+var ErrInvalidKey = errors.New("scanners: invalid key")
+
+// lastUsedAccuracy is minimal interval between updates of
+// the time of last usage, so keys are not written to storage
+// on every report.
+const lastUsedAccuracy = time.Minute
+
+// Generate creates key for scanner with given name. Returned
+// token is the only copy of the key, because only hash of its
+// secret part is stored.
+func Generate(ctx context.Context, s storage.Scanners, name string) (string, *models.ScannerKey, error) {
+	secret, hashed, err := secrets.Generate()
+	if err != nil {
+		return "", nil, fmt.Errorf("secrets.Generate: %w", err)
+	}
+
+	key := models.ScannerKey{
+		ID:      uuid.New().String(),
+		Name:    name,
+		Secret:  hashed,
+		Created: time.Now(),
+	}
+	if _, err := s.New(ctx, key); err != nil {
+		return "", nil, fmt.Errorf("s.New: %w", err)
+	}
+
+	return key.ID + "." + secret, &key, nil
+}
+
+// Authenticate returns key matching given token and marks
+// it as used.
+func Authenticate(ctx context.Context, s storage.Scanners, token string) (*models.ScannerKey, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.Read(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: s.Read: %s", ErrInvalidKey, err)
+	}
+
+	if !secrets.Match(key.Secret, secret) {
+		return nil, ErrInvalidKey
+	}
+
+	now := time.Now()
+	if now.Sub(key.LastUsed) < lastUsedAccuracy {
+		return key, nil
+	}
+
+	key.LastUsed = now
+	err = s.Update(ctx, id, func(k *models.ScannerKey) error {
+		k.LastUsed = now
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.Update: %w", err)
+	}
+
+	return key, nil
+}
+
+// Queue passes reports of scanners to the status daemon and
+// keeps track of reports waiting for the daemon.
+type Queue struct {
+	ch chan<- []models.Host
+
+	guard   sync.Mutex
+	pending int
+	last    time.Time
+}
+
+// NewQueue returns Queue sending reports to given channel.
+func NewQueue(ch chan<- []models.Host) *Queue {
+	return &Queue{ch: ch}
+}
+
+// Push sends given hosts to the daemon. It blocks until
+// the daemon receives them.
+func (q *Queue) Push(hosts []models.Host) {
+	q.guard.Lock()
+	q.pending++
+	q.last = time.Now()
+	q.guard.Unlock()
+
+	q.ch <- hosts
+
+	q.guard.Lock()
+	q.pending--
+	q.guard.Unlock()
+}
+
+// Pending returns number of reports waiting for
+// the daemon.
+func (q *Queue) Pending() int {
+	q.guard.Lock()
+	defer q.guard.Unlock()
+	return q.pending
+}
+
+// LastScan returns time of the latest report. It is zero
+// if there were no reports since start.
+func (q *Queue) LastScan() time.Time {
+	q.guard.Lock()
+	defer q.guard.Unlock()
+	return q.last
+}
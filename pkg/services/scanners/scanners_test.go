@@ -0,0 +1,76 @@
+package scanners
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestKeys(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	token, key, err := Generate(ctx, f.Scanners(), "router")
+	is.NoErr(err)
+	is.Equal(key.Name, "router")
+	is.True(key.LastUsed.IsZero())
+
+	stored, err := f.Scanners().Read(ctx, key.ID)
+	is.NoErr(err)
+	is.True(len(stored.Secret) > 0)
+
+	for _, invalid := range []string{"", "no-dot", key.ID + ".wrong", "missing." + token} {
+		_, err = Authenticate(ctx, f.Scanners(), invalid)
+		is.True(errors.Is(err, ErrInvalidKey))
+	}
+
+	matched, err := Authenticate(ctx, f.Scanners(), token)
+	is.NoErr(err)
+	is.Equal(matched.ID, key.ID)
+
+	stored, err = f.Scanners().Read(ctx, key.ID)
+	is.NoErr(err)
+	is.True(!stored.LastUsed.IsZero())
+
+	// Time of last usage is not written on every report.
+	_, err = Authenticate(ctx, f.Scanners(), token)
+	is.NoErr(err)
+	again, err := f.Scanners().Read(ctx, key.ID)
+	is.NoErr(err)
+	is.True(again.LastUsed.Equal(stored.LastUsed))
+}
+
+func TestQueue(t *testing.T) {
+	is := is.New(t)
+
+	ch := make(chan []models.Host)
+	q := NewQueue(ch)
+	is.Equal(q.Pending(), 0)
+	is.True(q.LastScan().IsZero())
+
+	done := make(chan struct{})
+	go func() {
+		q.Push([]models.Host{})
+		close(done)
+	}()
+
+	// Wait for report to be queued.
+	for q.Pending() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	is.True(!q.LastScan().IsZero())
+
+	<-ch
+	<-done
+	is.Equal(q.Pending(), 0)
+}
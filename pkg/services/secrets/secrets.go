@@ -0,0 +1,48 @@
+// Package secrets generates random secrets of tokens and keys,
+// that are given to users and scripts, and hashes them for
+// storage. Secrets are long and random, so unlike passwords
+// they don't need slow hashing, which would be repeated for
+// every request.
+package secrets
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Size is number of random bytes in every secret.
+const Size = 32
+
+// Generate returns new random secret encoded with base64url
+// and its hash, which should be stored instead of the secret.
+func Generate() (string, []byte, error) {
+	raw := make([]byte, Size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, fmt.Errorf("rand.Read: %w", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+
+	return secret, Hash(secret), nil
+}
+
+// Hash returns hash of given secret.
+func Hash(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// Match returns true if given secret matches given hash.
+// Secrets hashed with bcrypt by earlier versions of
+// long-season are matched too.
+func Match(hashed []byte, secret string) bool {
+	if len(hashed) != sha256.Size {
+		return bcrypt.CompareHashAndPassword(hashed, []byte(secret)) == nil
+	}
+
+	return subtle.ConstantTimeCompare(hashed, Hash(secret)) == 1
+}
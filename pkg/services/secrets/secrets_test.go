@@ -0,0 +1,31 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestSecrets(t *testing.T) {
+	is := is.New(t)
+
+	secret, hashed, err := Generate()
+	is.NoErr(err)
+	is.Equal(len(secret), 43)
+
+	other, _, err := Generate()
+	is.NoErr(err)
+	is.True(secret != other)
+
+	is.True(Match(hashed, secret))
+	is.True(!Match(hashed, other))
+	is.True(!Match(hashed, ""))
+	is.True(!Match(nil, secret))
+
+	// Secrets hashed before are still accepted.
+	legacy, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+	is.NoErr(err)
+	is.True(Match(legacy, secret))
+	is.True(!Match(legacy, other))
+}
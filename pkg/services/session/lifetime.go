@@ -0,0 +1,84 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Lifetimes are durations of sessions, after which they
+// expire, unless they are renewed before.
+type Lifetimes struct {
+	// Session is lifetime of sessions of users, who didn't
+	// ask to be remembered.
+	Session time.Duration
+
+	// Remember is lifetime of remembered sessions and
+	// sessions of API clients.
+	Remember time.Duration
+}
+
+// Of returns lifetime of given session.
+func (l Lifetimes) Of(s State) time.Duration {
+	if s.Remember {
+		return l.Remember
+	}
+	return l.Session
+}
+
+// Expiring returns Saver, that sets expiration time of saved
+// sessions according to given lifetimes, before saving them
+// with given saver. Every save extends lifetime of session.
+func Expiring(saver Saver, lifetimes Lifetimes) Saver {
+	return saverFunc(func(ctx context.Context, w http.ResponseWriter, s State) error {
+		s.Expires = time.Now().Add(lifetimes.Of(s))
+		return saver.Save(ctx, w, s)
+	})
+}
+
+// Stale returns true if less than half of lifetime of given
+// session is left at given time.
+func (l Lifetimes) Stale(s State, now time.Time) bool {
+	if s.Expires.IsZero() {
+		return false
+	}
+	return s.Expires.Sub(now) < l.Of(s)/2
+}
+
+// Sliding returns http middleware, that saves sessions restored
+// by given renewer again with given saver, when they are stale,
+// so sessions of active users do not expire. Use renewer, that
+// restores sessions from cookies only, because API clients
+// renew their sessions with refresh tokens.
+func Sliding(renewer Renewer, saver Saver, lifetimes Lifetimes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state, err := renewer.Renew(r)
+			if err == nil && state.Scopes == nil && lifetimes.Stale(*state, time.Now()) {
+				// Failed renewal does not end session, which is
+				// still valid, so request is served anyway.
+				_ = saver.Save(r.Context(), w, *state)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Tokens are credentials issued for API clients. Short-lived
+// access token authorizes requests and refresh token is
+// exchanged for new tokens, when access token expires.
+type Tokens struct {
+	Access  string
+	Refresh string
+
+	// Expires is expiration time of access token.
+	Expires time.Time
+}
+
+// Issuer issues tokens for API clients.
+type Issuer interface {
+	// Issue returns access and refresh tokens for
+	// given session.
+	Issue(context.Context, State) (*Tokens, error)
+}
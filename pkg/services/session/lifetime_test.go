@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+func TestLifetimes(t *testing.T) {
+	is := is.New(t)
+
+	lifetimes := Lifetimes{Session: 2 * time.Hour, Remember: 20 * time.Hour}
+	now := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+
+	is.Equal(lifetimes.Of(State{}), 2*time.Hour)
+	is.Equal(lifetimes.Of(State{Remember: true}), 20*time.Hour)
+
+	// Sessions without expiration time are never stale.
+	is.True(!lifetimes.Stale(State{}, now))
+
+	// Session is stale, when less than half of its
+	// lifetime is left.
+	s := State{Expires: now.Add(time.Hour)}
+	is.True(!lifetimes.Stale(s, now))
+	is.True(lifetimes.Stale(s, now.Add(time.Nanosecond)))
+	is.True(lifetimes.Stale(s, now.Add(2*time.Hour)))
+
+	s.Remember = true
+	is.True(lifetimes.Stale(s, now))
+	s.Expires = now.Add(10 * time.Hour)
+	is.True(!lifetimes.Stale(s, now))
+}
+
+func TestExpiring(t *testing.T) {
+	is := is.New(t)
+
+	var saved State
+	saver := Expiring(saverFunc(func(_ context.Context, _ http.ResponseWriter, s State) error {
+		saved = s
+		return nil
+	}), Lifetimes{Session: time.Hour, Remember: 24 * time.Hour})
+
+	before := time.Now()
+	is.NoErr(saver.Save(context.Background(), httptest.NewRecorder(), State{ID: "a"}))
+	is.True(!saved.Expires.Before(before.Add(time.Hour)))
+	is.True(!saved.Expires.After(time.Now().Add(time.Hour)))
+
+	is.NoErr(saver.Save(context.Background(), httptest.NewRecorder(), State{ID: "a", Remember: true}))
+	is.True(!saved.Expires.Before(before.Add(24 * time.Hour)))
+}
+
+func TestSliding(t *testing.T) {
+	is := is.New(t)
+
+	lifetimes := Lifetimes{Session: time.Hour, Remember: 24 * time.Hour}
+
+	var (
+		current *State
+		failure error
+		saved   []State
+	)
+	renewer := renewerFunc(func(*http.Request) (*State, error) {
+		return current, failure
+	})
+	saver := saverFunc(func(_ context.Context, _ http.ResponseWriter, s State) error {
+		saved = append(saved, s)
+		return nil
+	})
+
+	served := 0
+	handler := Sliding(renewer, saver, lifetimes)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		served++
+	}))
+	serve := func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	// Fresh sessions are not saved again.
+	current = &State{ID: "a", Expires: time.Now().Add(50 * time.Minute)}
+	serve()
+	is.Equal(len(saved), 0)
+
+	// Stale ones are.
+	current = &State{ID: "a", Expires: time.Now().Add(20 * time.Minute)}
+	serve()
+	is.Equal(len(saved), 1)
+	is.Equal(saved[0].ID, "a")
+
+	// Remembered sessions are stale after half of
+	// their own lifetime.
+	current = &State{ID: "b", Remember: true, Expires: time.Now().Add(20 * time.Hour)}
+	serve()
+	is.Equal(len(saved), 1)
+
+	// Sessions of API tokens and invalid sessions are
+	// passed without changes.
+	current = &State{ID: "c", Scopes: []models.Scope{models.ScopeCheckIn}, Expires: time.Now()}
+	serve()
+	current, failure = nil, errors.New("invalid session")
+	serve()
+	is.Equal(len(saved), 1)
+
+	is.Equal(served, 5)
+}
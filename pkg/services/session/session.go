@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/storage"
 )
 
 // State holds common data for storing in single
@@ -23,11 +26,49 @@ type State struct {
 	// Nickname is user name of session's owner.
 	Nickname string
 
+	// Role of session's owner at the moment of
+	// creating session.
+	Role models.Role
+
+	// Issued is the moment of creating session.
+	Issued time.Time
+
+	// Expires is the moment, after which session is no
+	// longer valid, unless it is renewed before.
+	Expires time.Time
+
+	// Remember is true for sessions, that should outlive
+	// closing the browser.
+	Remember bool
+
+	// Generation of refresh token, that session was
+	// restored from or is issued with.
+	Generation int
+
+	// Scopes limit session restored from personal API
+	// token. It is nil for sessions of logged in users,
+	// which are not limited.
+	Scopes []models.Scope
+
 	// Values are key/value storage for additional
 	// session data.
 	Values map[string]interface{}
 }
 
+// Allows returns true if session can be used for
+// endpoints with given scope.
+func (s State) Allows(scope models.Scope) bool {
+	if s.Scopes == nil {
+		return true
+	}
+	for _, granted := range s.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // Saver saves data in database and returns public information
 // about session to client.
 type Saver interface {
@@ -79,6 +120,10 @@ type Builder struct {
 
 	Nickname string
 
+	Role models.Role
+
+	Remember bool
+
 	Values map[string]interface{}
 }
 
@@ -97,6 +142,9 @@ func New(ctx context.Context, b Builder) *State {
 		ID:       uuid.New().String(),
 		UserID:   b.UserID,
 		Nickname: b.Nickname,
+		Role:     b.Role,
+		Issued:   time.Now(),
+		Remember: b.Remember,
 		Values:   values,
 	}
 }
@@ -155,3 +203,38 @@ func RenewerComposite(renewers ...Renewer) Renewer {
 		return nil, ErrNoRenewers
 	})
 }
+
+// Revocable returns Renewer, that rejects sessions of removed
+// users and sessions created before revoking all sessions of
+// their owner. Role of returned session is always up to date
+// with storage, so role changes take effect immediately.
+func Revocable(renewer Renewer, users storage.Users) Renewer {
+	return renewerFunc(func(r *http.Request) (*State, error) {
+		state, err := renewer.Renew(r)
+		if err != nil {
+			return nil, err
+		}
+
+		errFactory := happier.FromRequest(r)
+
+		user, err := users.Read(r.Context(), state.UserID)
+		if err != nil {
+			return nil, errFactory.Unauthorized(
+				fmt.Errorf("users.Read: %w", err),
+				"Session is no longer valid. Please login in.",
+			)
+		}
+
+		// Sessions store time of creation with accuracy
+		// to seconds.
+		if state.Issued.Before(user.SessionsRevoked.Truncate(time.Second)) {
+			return nil, errFactory.Unauthorized(
+				fmt.Errorf("session: session %s was revoked at %v", state.ID, user.SessionsRevoked),
+				"Session is no longer valid. Please login in.",
+			)
+		}
+
+		state.Role = user.Role
+		return state, nil
+	})
+}
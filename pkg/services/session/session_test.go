@@ -0,0 +1,62 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestRevocable(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "alice",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+		Role:           models.RoleKeyholder,
+	})
+	is.NoErr(err)
+
+	issued := time.Now().Add(-time.Hour)
+	renewer := Revocable(renewerFunc(func(*http.Request) (*State, error) {
+		return &State{ID: "s", UserID: "1", Role: models.RoleMember, Issued: issued}, nil
+	}), f.Users())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	// Role is taken from storage.
+	state, err := renewer.Renew(r)
+	is.NoErr(err)
+	is.Equal(state.Role, models.RoleKeyholder)
+
+	err = f.Users().Update(ctx, "1", func(u *storage.UserEntry) error {
+		u.SessionsRevoked = time.Now()
+		return nil
+	})
+	is.NoErr(err)
+
+	_, err = renewer.Renew(r)
+	is.True(err != nil)
+
+	// Sessions created after revoking are fine.
+	issued = time.Now().Add(time.Second)
+	_, err = renewer.Renew(r)
+	is.NoErr(err)
+
+	is.NoErr(f.Users().Remove(ctx, "1"))
+	_, err = renewer.Renew(r)
+	is.True(err != nil)
+}
@@ -0,0 +1,237 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/requests"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// ErrRefreshReused is returned by Store.Rotate, when refresh
+// token, that was already exchanged for new tokens, is used again.
+var ErrRefreshReused = errors.New("session: refresh token was already used")
+
+// usedAccuracy is minimal interval between updates of the
+// time of last usage, so sessions are not written to storage
+// on every request.
+const usedAccuracy = time.Minute
+
+// Store keeps records of sessions in storage, so their owners
+// can list and revoke them. Use both Saver and Renewer of the
+// store, because sessions missing in storage are rejected.
+// Records expire together with saved sessions, so wrap Saver
+// with Expiring.
+type Store struct {
+	Sessions storage.Sessions
+}
+
+type saverFunc func(context.Context, http.ResponseWriter, State) error
+
+func (f saverFunc) Save(ctx context.Context, w http.ResponseWriter, s State) error {
+	return f(ctx, w, s)
+}
+
+// Saver returns Saver, that records given session before saving
+// it with given saver. Sessions saved again, for example after
+// two factor authentication or renewal, keep their records.
+func (s *Store) Saver(saver Saver) Saver {
+	return saverFunc(func(ctx context.Context, w http.ResponseWriter, state State) error {
+		if err := s.Record(ctx, state); err != nil {
+			return happier.FromContext(ctx).InternalServerError(
+				fmt.Errorf("s.Record: %w", err),
+				"Internal server error. Please try again later.",
+			)
+		}
+
+		return saver.Save(ctx, w, state)
+	})
+}
+
+// Record stores given session or updates expiration time of
+// already stored one. Expired sessions of its owner are removed.
+func (s *Store) Record(ctx context.Context, state State) error {
+	err := s.Sessions.Update(ctx, state.ID, func(stored *models.Session) error {
+		stored.Expires = state.Expires
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, serrors.ErrNoID) {
+		return fmt.Errorf("s.Sessions.Update: %w", err)
+	}
+
+	if _, err := s.Active(ctx, state.UserID); err != nil {
+		return fmt.Errorf("s.Active: %w", err)
+	}
+
+	_, err = s.Sessions.New(ctx, models.Session{
+		ID:         state.ID,
+		UserID:     state.UserID,
+		Created:    state.Issued,
+		LastUsed:   state.Issued,
+		Expires:    state.Expires,
+		Generation: state.Generation,
+	})
+	if err != nil {
+		return fmt.Errorf("s.Sessions.New: %w", err)
+	}
+
+	return nil
+}
+
+// Rotate moves given session restored from refresh token to the
+// next generation and updates its expiration time, so refresh
+// token can be exchanged only once. Reused refresh token was most
+// likely stolen, so its session is revoked and ErrRefreshReused
+// is returned.
+func (s *Store) Rotate(ctx context.Context, state State) (*State, error) {
+	err := s.Sessions.Update(ctx, state.ID, func(stored *models.Session) error {
+		if stored.Generation != state.Generation {
+			return fmt.Errorf(
+				"session %s is at generation %d, got %d: %w",
+				state.ID, stored.Generation, state.Generation, ErrRefreshReused,
+			)
+		}
+		stored.Generation++
+		stored.Expires = state.Expires
+		return nil
+	})
+	if errors.Is(err, ErrRefreshReused) {
+		if err := s.Revoke(ctx, state.ID); err != nil && !errors.Is(err, serrors.ErrNoID) {
+			return nil, fmt.Errorf("s.Revoke: %w", err)
+		}
+		return nil, fmt.Errorf("s.Sessions.Update: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("s.Sessions.Update: %w", err)
+	}
+
+	state.Generation++
+	return &state, nil
+}
+
+// Renewer returns Renewer, that rejects sessions missing in
+// storage or expired and records user agent and address of
+// the latest request. Sessions restored from personal API
+// tokens are not stored, so they are passed without changes.
+func (s *Store) Renewer(renewer Renewer) Renewer {
+	return renewerFunc(func(r *http.Request) (*State, error) {
+		state, err := renewer.Renew(r)
+		if err != nil {
+			return nil, err
+		}
+		if state.Scopes != nil {
+			return state, nil
+		}
+
+		ctx := r.Context()
+		errFactory := happier.FromRequest(r)
+		invalid := func(err error) (*State, error) {
+			return nil, errFactory.Unauthorized(
+				err,
+				"Session is no longer valid. Please login in.",
+			)
+		}
+
+		record, err := s.Sessions.Read(ctx, state.ID)
+		if errors.Is(err, serrors.ErrNoID) {
+			return invalid(fmt.Errorf("s.Sessions.Read: %w", err))
+		}
+		if err != nil {
+			return nil, errFactory.InternalServerError(
+				fmt.Errorf("s.Sessions.Read: %w", err),
+				"Internal server error. Please try again later.",
+			)
+		}
+
+		now := time.Now()
+		if now.After(record.Expires) {
+			return invalid(fmt.Errorf("session: session %s expired at %v", state.ID, record.Expires))
+		}
+
+		agent, ip := r.UserAgent(), requests.RemoteIP(r)
+		if now.Sub(record.LastUsed) < usedAccuracy && agent == record.UserAgent && ip == record.IP {
+			return state, nil
+		}
+
+		err = s.Sessions.Update(ctx, state.ID, func(stored *models.Session) error {
+			stored.LastUsed = now
+			stored.UserAgent = agent
+			stored.IP = ip
+			return nil
+		})
+		if errors.Is(err, serrors.ErrNoID) {
+			return invalid(fmt.Errorf("s.Sessions.Update: %w", err))
+		}
+		if err != nil {
+			return nil, errFactory.InternalServerError(
+				fmt.Errorf("s.Sessions.Update: %w", err),
+				"Internal server error. Please try again later.",
+			)
+		}
+
+		return state, nil
+	})
+}
+
+// Active returns sessions of user with given id, that haven't
+// expired yet. Expired sessions are removed from storage.
+func (s *Store) Active(ctx context.Context, userID string) ([]models.Session, error) {
+	sessions, err := s.Sessions.OfUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("s.Sessions.OfUser: %w", err)
+	}
+
+	now := time.Now()
+	res := []models.Session{}
+	for _, session := range sessions {
+		if now.Before(session.Expires) {
+			res = append(res, session)
+			continue
+		}
+		err := s.Sessions.Remove(ctx, session.ID)
+		if err != nil && !errors.Is(err, serrors.ErrNoID) {
+			return nil, fmt.Errorf("s.Sessions.Remove: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// Revoke removes session with given id, so it can no
+// longer be used.
+func (s *Store) Revoke(ctx context.Context, id string) error {
+	if err := s.Sessions.Remove(ctx, id); err != nil {
+		return fmt.Errorf("s.Sessions.Remove: %w", err)
+	}
+	return nil
+}
+
+// RevokeAll removes every session of user with given id
+// except session with given id, which can be empty.
+func (s *Store) RevokeAll(ctx context.Context, userID string, except string) error {
+	sessions, err := s.Sessions.OfUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("s.Sessions.OfUser: %w", err)
+	}
+
+	for _, session := range sessions {
+		if session.ID == except {
+			continue
+		}
+		err := s.Sessions.Remove(ctx, session.ID)
+		if err != nil && !errors.Is(err, serrors.ErrNoID) {
+			return fmt.Errorf("s.Sessions.Remove: %w", err)
+		}
+	}
+
+	return nil
+}
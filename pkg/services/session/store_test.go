@@ -0,0 +1,216 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "alice",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+		Role:           models.RoleMember,
+	})
+	is.NoErr(err)
+
+	store := &Store{Sessions: f.Sessions()}
+
+	// Saved sessions are returned by renewer below.
+	saved := map[string]State{}
+	saver := Expiring(store.Saver(saverFunc(func(_ context.Context, _ http.ResponseWriter, s State) error {
+		saved[s.ID] = s
+		return nil
+	})), Lifetimes{Session: time.Hour, Remember: 24 * time.Hour})
+
+	for _, id := range []string{"a", "b", "c"} {
+		s := New(ctx, Builder{UserID: "1", Nickname: "alice"})
+		s.ID = id
+		is.NoErr(saver.Save(ctx, httptest.NewRecorder(), *s))
+	}
+
+	// Saving session again keeps its record and extends
+	// its lifetime.
+	first, err := f.Sessions().Read(ctx, "a")
+	is.NoErr(err)
+	is.True(first.Expires.Equal(saved["a"].Expires))
+
+	remembered := saved["a"]
+	remembered.Remember = true
+	is.NoErr(saver.Save(ctx, httptest.NewRecorder(), remembered))
+
+	record, err := f.Sessions().Read(ctx, "a")
+	is.NoErr(err)
+	is.True(record.Created.Equal(first.Created))
+	is.True(record.Expires.After(first.Expires.Add(22 * time.Hour)))
+
+	active, err := store.Active(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(active), 3)
+
+	current := "a"
+	renewer := store.Renewer(renewerFunc(func(*http.Request) (*State, error) {
+		s := saved[current]
+		return &s, nil
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("User-Agent", "curl/8.0")
+
+	state, err := renewer.Renew(r)
+	is.NoErr(err)
+	is.Equal(state.ID, "a")
+
+	record, err = f.Sessions().Read(ctx, "a")
+	is.NoErr(err)
+	is.Equal(record.UserAgent, "curl/8.0")
+	is.Equal(record.IP, "192.0.2.1")
+
+	// Revoked sessions are rejected.
+	is.NoErr(store.Revoke(ctx, "b"))
+	current = "b"
+	_, err = renewer.Renew(r)
+	is.True(err != nil)
+
+	is.NoErr(store.RevokeAll(ctx, "1", "a"))
+	current = "c"
+	_, err = renewer.Renew(r)
+	is.True(err != nil)
+
+	active, err = store.Active(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(active), 1)
+	is.Equal(active[0].ID, "a")
+
+	// Expired sessions are rejected and removed.
+	err = f.Sessions().Update(ctx, "a", func(s *models.Session) error {
+		s.Expires = time.Now().Add(-time.Minute)
+		return nil
+	})
+	is.NoErr(err)
+	current = "a"
+	_, err = renewer.Renew(r)
+	is.True(err != nil)
+
+	active, err = store.Active(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(active), 0)
+
+	// Sessions restored from API tokens are not stored.
+	saved["token"] = State{ID: "token", UserID: "1", Scopes: []models.Scope{models.ScopeCheckIn}}
+	current = "token"
+	state, err = renewer.Renew(r)
+	is.NoErr(err)
+	is.Equal(state.ID, "token")
+}
+
+func TestStoreRotate(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "alice",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+	})
+	is.NoErr(err)
+
+	store := &Store{Sessions: f.Sessions()}
+
+	// Refresh tokens are restored by renewer below.
+	var current State
+	renewer := store.Renewer(renewerFunc(func(*http.Request) (*State, error) {
+		s := current
+		return &s, nil
+	}))
+	refresh := func() (*State, error) {
+		restored, err := renewer.Renew(httptest.NewRequest(http.MethodPost, "/", nil))
+		if err != nil {
+			return nil, err
+		}
+		restored.Expires = time.Now().Add(time.Hour)
+		return store.Rotate(ctx, *restored)
+	}
+
+	issued := New(ctx, Builder{UserID: "1", Nickname: "alice", Remember: true})
+	issued.Expires = time.Now().Add(time.Hour)
+	is.NoErr(store.Record(ctx, *issued))
+
+	// Every exchange moves session to the next generation.
+	current = *issued
+	first, err := refresh()
+	is.NoErr(err)
+	is.Equal(first.Generation, 1)
+
+	current = *first
+	second, err := refresh()
+	is.NoErr(err)
+	is.Equal(second.Generation, 2)
+
+	record, err := f.Sessions().Read(ctx, issued.ID)
+	is.NoErr(err)
+	is.Equal(record.Generation, 2)
+	is.True(record.Expires.Equal(second.Expires))
+
+	// Reusing already exchanged refresh token revokes its
+	// session, so the latest tokens are rejected too.
+	another := New(ctx, Builder{UserID: "1", Nickname: "alice", Remember: true})
+	another.Expires = time.Now().Add(time.Hour)
+	is.NoErr(store.Record(ctx, *another))
+
+	current = *another
+	rotated, err := refresh()
+	is.NoErr(err)
+
+	_, err = refresh()
+	is.True(errors.Is(err, ErrRefreshReused))
+
+	current = *rotated
+	_, err = refresh()
+	is.True(err != nil)
+
+	_, err = f.Sessions().Read(ctx, another.ID)
+	is.True(errors.Is(err, serrors.ErrNoID))
+
+	// Refresh tokens of expired sessions are rejected,
+	// even when they were never used.
+	expiring := New(ctx, Builder{UserID: "1", Nickname: "alice", Remember: true})
+	expiring.Expires = time.Now().Add(time.Hour)
+	is.NoErr(store.Record(ctx, *expiring))
+
+	err = f.Sessions().Update(ctx, expiring.ID, func(s *models.Session) error {
+		s.Expires = time.Now().Add(-time.Second)
+		return nil
+	})
+	is.NoErr(err)
+
+	current = *expiring
+	_, err = refresh()
+	is.True(err != nil)
+
+	record, err = f.Sessions().Read(ctx, expiring.ID)
+	is.NoErr(err)
+	is.Equal(record.Generation, 0)
+}
@@ -0,0 +1,170 @@
+// Package space keeps state of the hackerspace, which tells
+// whether it is open to guests. State is derived from occupancy,
+// unless one of keyholders overrides it.
+package space
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+// ErrInvalidState is returned for unknown space states.
+var ErrInvalidState = errors.New("space: invalid state")
+
+// DefaultOverrideTTL is time-to-live of overrides set, when no
+// keyholder is inside, used when Tracker has no OverrideTTL set.
+const DefaultOverrideTTL = time.Hour
+
+// Tracker keeps state of the hackerspace. Overrides are kept
+// in memory, so they're lost on restart together with online
+// statuses. Tracker implements status.Observer interface.
+type Tracker struct {
+	Users    storage.Users
+	Counters storage.StatusTx
+
+	// Occupied is state of the hackerspace, when anyone
+	// is inside and there is no override. Defaults to
+	// models.SpaceOpen.
+	Occupied models.SpaceState
+
+	// OverrideTTL is how long override lasts, when no keyholder
+	// is inside, for example when it was set remotely. Defaults
+	// to DefaultOverrideTTL.
+	OverrideTTL time.Duration
+
+	guard sync.Mutex
+
+	// override is empty, when state is derived
+	// from occupancy.
+	override models.SpaceState
+
+	// overridden is time of setting override.
+	overridden time.Time
+
+	// keyholders is number of keyholders online
+	// during last status update.
+	keyholders int
+
+	// clock returns current time. time.Now is used,
+	// when it is nil.
+	clock func() time.Time
+}
+
+func (t *Tracker) now() time.Time {
+	if t.clock == nil {
+		return time.Now()
+	}
+	return t.clock()
+}
+
+// Derive returns state of the hackerspace with given number
+// of people inside, when there is no override.
+func (t *Tracker) Derive(online int) models.SpaceState {
+	switch {
+	case online == 0:
+		return models.SpaceClosed
+	case t.Occupied == "":
+		return models.SpaceOpen
+	default:
+		return t.Occupied
+	}
+}
+
+// State returns current state of the hackerspace.
+func (t *Tracker) State(ctx context.Context) (*models.Space, error) {
+	t.guard.Lock()
+	override := t.override
+	t.guard.Unlock()
+
+	if override != "" {
+		return &models.Space{State: override, Override: true}, nil
+	}
+
+	online := 0
+	err := t.Counters.DevicesStatus(ctx, func(ctx context.Context, s storage.Status) error {
+		var err error
+		online, err = s.OnlineUsers(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("t.Counters.DevicesStatus: %w", err)
+	}
+
+	return &models.Space{State: t.Derive(online)}, nil
+}
+
+// Set overrides state of the hackerspace. Override lasts until
+// it is reset or until the last keyholder leaves. When there is
+// no keyholder inside, it lasts for OverrideTTL at most. Make sure,
+// that only users with models.ChangeSpaceState permission can
+// call it.
+func (t *Tracker) Set(ctx context.Context, state models.SpaceState) (*models.Space, error) {
+	if !state.Valid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidState, state)
+	}
+
+	t.guard.Lock()
+	t.override = state
+	t.overridden = t.now()
+	t.guard.Unlock()
+
+	return t.State(ctx)
+}
+
+// Reset removes override, so state is derived from
+// occupancy again.
+func (t *Tracker) Reset(ctx context.Context) (*models.Space, error) {
+	t.guard.Lock()
+	t.override = ""
+	t.guard.Unlock()
+
+	return t.State(ctx)
+}
+
+// Observe resets override when the last keyholder leaves
+// the hackerspace or when override has expired and there
+// is no keyholder inside. Every user with permission to
+// change state of the hackerspace counts as keyholder.
+func (t *Tracker) Observe(ctx context.Context, tick storage.Tick) error {
+	entries, err := t.Users.All(ctx)
+	if err != nil {
+		return fmt.Errorf("t.Users.All: %w", err)
+	}
+
+	keyholders := map[string]bool{}
+	for _, u := range entries {
+		keyholders[u.ID] = u.Role.Can(models.ChangeSpaceState)
+	}
+
+	online := 0
+	for _, id := range tick.OnlineIDs {
+		if keyholders[id] {
+			online++
+		}
+	}
+
+	ttl := t.OverrideTTL
+	if ttl <= 0 {
+		ttl = DefaultOverrideTTL
+	}
+	now := tick.Time
+	if now.IsZero() {
+		now = t.now()
+	}
+
+	t.guard.Lock()
+	defer t.guard.Unlock()
+
+	if online == 0 && (t.keyholders > 0 || !now.Before(t.overridden.Add(ttl))) {
+		t.override = ""
+	}
+	t.keyholders = online
+
+	return nil
+}
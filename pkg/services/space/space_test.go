@@ -0,0 +1,111 @@
+package space
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+	"github.com/hakierspejs/long-season/pkg/storage/temp"
+)
+
+// setOnline overwrites number of online users.
+func setOnline(ctx context.Context, counters storage.StatusTx, online int) error {
+	return counters.DevicesStatus(ctx, func(ctx context.Context, s storage.Status) error {
+		return s.SetOnlineUsers(ctx, online)
+	})
+}
+
+func TestTracker(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	// alice is keyholder, carol is admin and bob is member.
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice", Role: models.RoleKeyholder},
+		{ID: "2", Nickname: "bob", Role: models.RoleMember},
+		{ID: "3", Nickname: "carol", Role: models.RoleAdmin},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	counters := temp.NewStatusTx()
+	tracker := &Tracker{Users: f.Users(), Counters: counters}
+
+	s, err := tracker.State(ctx)
+	is.NoErr(err)
+	is.Equal(*s, models.Space{State: models.SpaceClosed})
+
+	is.NoErr(setOnline(ctx, counters, 3))
+	s, err = tracker.State(ctx)
+	is.NoErr(err)
+	is.Equal(*s, models.Space{State: models.SpaceOpen})
+
+	_, err = tracker.Set(ctx, "party")
+	is.True(errors.Is(err, ErrInvalidState))
+
+	s, err = tracker.Set(ctx, models.SpaceMembersOnly)
+	is.NoErr(err)
+	is.Equal(*s, models.Space{State: models.SpaceMembersOnly, Override: true})
+
+	// Override stays as long as any keyholder is inside.
+	is.NoErr(tracker.Observe(ctx, storage.Tick{OnlineIDs: []string{"1", "2", "3"}}))
+	is.NoErr(tracker.Observe(ctx, storage.Tick{OnlineIDs: []string{"2", "3"}}))
+	s, err = tracker.State(ctx)
+	is.NoErr(err)
+	is.Equal(s.State, models.SpaceMembersOnly)
+
+	// The last keyholder leaves.
+	is.NoErr(tracker.Observe(ctx, storage.Tick{OnlineIDs: []string{"2"}}))
+	s, err = tracker.State(ctx)
+	is.NoErr(err)
+	is.Equal(*s, models.Space{State: models.SpaceOpen})
+
+	s, err = tracker.Set(ctx, models.SpaceClosed)
+	is.NoErr(err)
+	is.Equal(s.State, models.SpaceClosed)
+
+	s, err = tracker.Reset(ctx)
+	is.NoErr(err)
+	is.Equal(*s, models.Space{State: models.SpaceOpen})
+
+	// Overrides set, when no keyholder is inside, expire.
+	now := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+	tracker.clock = func() time.Time { return now }
+	tracker.OverrideTTL = time.Hour
+
+	_, err = tracker.Set(ctx, models.SpaceClosed)
+	is.NoErr(err)
+	is.NoErr(tracker.Observe(ctx, storage.Tick{Time: now.Add(time.Minute), OnlineIDs: []string{"2"}}))
+	s, err = tracker.State(ctx)
+	is.NoErr(err)
+	is.Equal(s.State, models.SpaceClosed)
+
+	is.NoErr(tracker.Observe(ctx, storage.Tick{Time: now.Add(time.Hour), OnlineIDs: []string{"2"}}))
+	s, err = tracker.State(ctx)
+	is.NoErr(err)
+	is.Equal(*s, models.Space{State: models.SpaceOpen})
+
+	// Keyholders inside keep override alive.
+	_, err = tracker.Set(ctx, models.SpaceClosed)
+	is.NoErr(err)
+	is.NoErr(tracker.Observe(ctx, storage.Tick{Time: now.Add(2 * time.Hour), OnlineIDs: []string{"1", "2"}}))
+	s, err = tracker.State(ctx)
+	is.NoErr(err)
+	is.Equal(s.State, models.SpaceClosed)
+
+	tracker.Occupied = models.SpaceMembersOnly
+	is.Equal(tracker.Derive(1), models.SpaceMembersOnly)
+	is.Equal(tracker.Derive(0), models.SpaceClosed)
+}
@@ -0,0 +1,336 @@
+// Package stats implements recording of users visits and
+// occupancy statistics computed from them.
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	// defaultDays is number of days in range, when
+	// beginning of range is not given.
+	defaultDays = 30
+
+	// maxDays limits length of range, so it is
+	// possible to compute statistics for few years,
+	// but not for the whole eternity.
+	maxDays = 366 * 5
+)
+
+// ErrInvalidRange is returned when given range of dates
+// cannot be parsed or is not valid.
+var ErrInvalidRange = errors.New("stats: invalid range")
+
+// Recorder records visits of online users from results of
+// status updates. It implements status.Observer interface.
+type Recorder struct {
+	Visits storage.Visits
+
+	// Gap is the longest break in presence, that doesn't
+	// split a visit into two.
+	Gap time.Duration
+}
+
+// Observe records visits of users online at given tick.
+func (r *Recorder) Observe(ctx context.Context, tick storage.Tick) error {
+	err := r.Visits.Record(ctx, tick.OnlineIDs, tick.Time, r.Gap)
+	if err != nil {
+		return fmt.Errorf("r.Visits.Record: %w", err)
+	}
+	return nil
+}
+
+// Range of whole days in given location.
+type Range struct {
+	// From is beginning of the first day.
+	From time.Time
+
+	// To is beginning of the day after the last one.
+	To time.Time
+
+	Location *time.Location
+}
+
+// ParseRange returns Range from first to last given date in
+// given timezone. Dates are in "YYYY-MM-DD" form. Missing last
+// date defaults to today and missing first date to 30 days
+// before the last one. Empty timezone defaults to given
+// fallback timezone.
+func ParseRange(from, to, timezone, fallback string, now time.Time) (*Range, error) {
+	if timezone == "" {
+		timezone = fallback
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %s", ErrInvalidRange, timezone)
+	}
+
+	now = now.In(loc)
+	last := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if to != "" {
+		last, err = time.ParseInLocation(dateLayout, to, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date %s", ErrInvalidRange, to)
+		}
+	}
+
+	first := last.AddDate(0, 0, 1-defaultDays)
+	if from != "" {
+		first, err = time.ParseInLocation(dateLayout, from, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid date %s", ErrInvalidRange, from)
+		}
+	}
+
+	if last.Before(first) {
+		return nil, fmt.Errorf("%w: %s is after %s", ErrInvalidRange, from, to)
+	}
+
+	if first.AddDate(0, 0, maxDays).Before(last) {
+		return nil, fmt.Errorf("%w: range is longer than %d days", ErrInvalidRange, maxDays)
+	}
+
+	return &Range{
+		From:     first,
+		To:       last.AddDate(0, 0, 1),
+		Location: loc,
+	}, nil
+}
+
+// days returns beginnings of every day in range.
+func (r Range) days() []time.Time {
+	res := []time.Time{}
+	for day := r.From; day.Before(r.To); day = day.AddDate(0, 0, 1) {
+		res = append(res, day)
+	}
+	return res
+}
+
+// clip returns given visit trimmed to the range. Returns false
+// if visit doesn't overlap range.
+func (r Range) clip(v models.Visit) (models.Visit, bool) {
+	if !v.Start.Before(r.To) || v.End.Before(r.From) {
+		return v, false
+	}
+	if v.Start.Before(r.From) {
+		v.Start = r.From
+	}
+	if !v.End.Before(r.To) {
+		v.End = r.To
+	}
+	return v, true
+}
+
+// visitsByDay returns sets of visiting users ids for every
+// day in the range, indexed by dates.
+func (r Range) visitsByDay(visits []models.Visit) map[string]map[string]struct{} {
+	res := map[string]map[string]struct{}{}
+
+	for _, visit := range visits {
+		v, ok := r.clip(visit)
+		if !ok {
+			continue
+		}
+
+		start := v.Start.In(r.Location)
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, r.Location)
+		for ; !v.End.Before(day) && day.Before(r.To); day = day.AddDate(0, 0, 1) {
+			date := day.Format(dateLayout)
+			if res[date] == nil {
+				res[date] = map[string]struct{}{}
+			}
+			res[date][v.UserID] = struct{}{}
+		}
+	}
+
+	return res
+}
+
+// User contains statistics of single user.
+type User struct {
+	// Hours spent in the hackerspace.
+	Hours float64 `json:"hours"`
+
+	// Visits is number of visits started in range.
+	Visits int `json:"visits"`
+
+	// VisitsPerWeek is average number of visits
+	// per week.
+	VisitsPerWeek float64 `json:"visitsPerWeek"`
+
+	// LongestStreak is the highest number of
+	// consecutive days with visits.
+	LongestStreak int `json:"longestStreak"`
+
+	// AverageArrival is average time of the first
+	// arrival during day in "HH:MM" form. Empty if
+	// there were no visits.
+	AverageArrival string `json:"averageArrival,omitempty"`
+}
+
+// ForUser computes statistics of user with given id from
+// given visits.
+func ForUser(visits []models.Visit, userID string, r Range) User {
+	res := User{}
+	own := []models.Visit{}
+	for _, v := range visits {
+		if v.UserID == userID {
+			own = append(own, v)
+		}
+	}
+
+	var total time.Duration
+	arrivals := map[string]int{}
+
+	for _, visit := range own {
+		v, ok := r.clip(visit)
+		if !ok {
+			continue
+		}
+		total += v.Duration()
+
+		// Visits that started before range are not
+		// counted as arrivals.
+		if !visit.Start.Equal(v.Start) {
+			continue
+		}
+		res.Visits += 1
+
+		start := v.Start.In(r.Location)
+		date := start.Format(dateLayout)
+		minutes := start.Hour()*60 + start.Minute()
+		if first, ok := arrivals[date]; !ok || minutes < first {
+			arrivals[date] = minutes
+		}
+	}
+
+	res.Hours = total.Hours()
+
+	days := r.days()
+	res.VisitsPerWeek = float64(res.Visits) / (float64(len(days)) / 7)
+
+	if len(arrivals) > 0 {
+		sum := 0
+		for _, minutes := range arrivals {
+			sum += minutes
+		}
+		avg := sum / len(arrivals)
+		res.AverageArrival = fmt.Sprintf("%02d:%02d", avg/60, avg%60)
+	}
+
+	byDay := r.visitsByDay(own)
+	streak := 0
+	for _, day := range days {
+		if _, ok := byDay[day.Format(dateLayout)]; ok {
+			streak += 1
+		} else {
+			streak = 0
+		}
+		if streak > res.LongestStreak {
+			res.LongestStreak = streak
+		}
+	}
+
+	return res
+}
+
+// Day contains statistics of single day.
+type Day struct {
+	// Date in "YYYY-MM-DD" form.
+	Date string `json:"date"`
+
+	// UniqueVisitors is number of users, that visited
+	// the hackerspace during the day.
+	UniqueVisitors int `json:"uniqueVisitors"`
+}
+
+// Space contains statistics of whole hackerspace.
+type Space struct {
+	Daily []Day `json:"daily"`
+
+	// PeakOccupancy is the highest number of users
+	// present at the same time.
+	PeakOccupancy int `json:"peakOccupancy"`
+
+	// PeakAt is the first moment of peak occupancy.
+	PeakAt *time.Time `json:"peakAt,omitempty"`
+}
+
+// ForSpace computes statistics of the whole hackerspace from
+// given visits.
+func ForSpace(visits []models.Visit, r Range) Space {
+	byDay := r.visitsByDay(visits)
+
+	res := Space{
+		Daily: []Day{},
+	}
+	for _, day := range r.days() {
+		date := day.Format(dateLayout)
+		res.Daily = append(res.Daily, Day{
+			Date:           date,
+			UniqueVisitors: len(byDay[date]),
+		})
+	}
+
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	events := []event{}
+	for _, visit := range visits {
+		if v, ok := r.clip(visit); ok {
+			events = append(events, event{v.Start, 1}, event{v.End, -1})
+		}
+	}
+
+	// Users are present until the end of their visits, so
+	// arrivals go before departures happening at the same time.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta > events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+
+	current := 0
+	for _, e := range events {
+		current += e.delta
+		if current > res.PeakOccupancy {
+			at := e.at.In(r.Location)
+			res.PeakOccupancy = current
+			res.PeakAt = &at
+		}
+	}
+
+	return res
+}
+
+// Public returns visits of users, that haven't enabled
+// private mode.
+func Public(visits []models.Visit, users []storage.UserEntry) []models.Visit {
+	public := map[string]struct{}{}
+	for _, u := range users {
+		if !u.Private {
+			public[u.ID] = struct{}{}
+		}
+	}
+
+	res := []models.Visit{}
+	for _, v := range visits {
+		if _, ok := public[v.UserID]; ok {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
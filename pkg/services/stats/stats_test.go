@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+func TestParseRange(t *testing.T) {
+	is := is.New(t)
+	now := time.Date(2023, time.March, 31, 23, 30, 0, 0, time.UTC)
+
+	// It is already April in Warsaw.
+	r, err := ParseRange("", "", "", "Europe/Warsaw", now)
+	is.NoErr(err)
+	is.Equal(r.To.Format(time.RFC3339), "2023-04-02T00:00:00+02:00")
+	is.Equal(len(r.days()), 30)
+
+	// Range with daylight saving time change.
+	r, err = ParseRange("2023-03-25", "2023-03-26", "Europe/Warsaw", "UTC", now)
+	is.NoErr(err)
+	is.Equal(r.From.Format(time.RFC3339), "2023-03-25T00:00:00+01:00")
+	is.Equal(r.To.Format(time.RFC3339), "2023-03-27T00:00:00+02:00")
+	is.Equal(len(r.days()), 2)
+
+	for _, tc := range [][3]string{
+		{"2023-03-26", "2023-03-25", ""},
+		{"yesterday", "", ""},
+		{"", "", "Mars/Olympus_Mons"},
+		{"1990-01-01", "2023-01-01", ""},
+	} {
+		_, err := ParseRange(tc[0], tc[1], tc[2], "UTC", now)
+		is.True(errors.Is(err, ErrInvalidRange))
+	}
+}
+
+func TestStats(t *testing.T) {
+	is := is.New(t)
+
+	r, err := ParseRange("2023-03-01", "2023-03-07", "UTC", "UTC", time.Now())
+	is.NoErr(err)
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+	visits := []models.Visit{
+		// Started before range.
+		{UserID: "1", Start: at(0, 20, 0), End: at(1, 2, 0)},
+		{UserID: "1", Start: at(1, 18, 0), End: at(1, 20, 0)},
+		{UserID: "1", Start: at(2, 17, 0), End: at(2, 21, 0)},
+		{UserID: "1", Start: at(2, 22, 0), End: at(2, 23, 0)},
+		{UserID: "1", Start: at(3, 18, 30), End: at(3, 19, 0)},
+		{UserID: "1", Start: at(5, 19, 0), End: at(5, 20, 0)},
+		{UserID: "2", Start: at(2, 20, 0), End: at(3, 1, 0)},
+		{UserID: "3", Start: at(2, 20, 30), End: at(2, 21, 30)},
+	}
+
+	u := ForUser(visits, "1", *r)
+	is.Equal(u.Hours, 10.5)
+	is.Equal(u.Visits, 5)
+	is.Equal(u.VisitsPerWeek, 5.0)
+	is.Equal(u.LongestStreak, 3)
+	// Average of 18:00, 17:00, 18:30 and 19:00.
+	is.Equal(u.AverageArrival, "18:07")
+
+	u = ForUser(visits, "4", *r)
+	is.Equal(u, User{})
+
+	s := ForSpace(visits, *r)
+	is.Equal(len(s.Daily), 7)
+	is.Equal(s.Daily[0], Day{"2023-03-01", 1})
+	is.Equal(s.Daily[1], Day{"2023-03-02", 3})
+	is.Equal(s.Daily[2], Day{"2023-03-03", 2})
+	is.Equal(s.Daily[3], Day{"2023-03-04", 0})
+	is.Equal(s.PeakOccupancy, 3)
+	is.True(s.PeakAt.Equal(at(2, 20, 30)))
+
+	public := Public(visits, []storage.UserEntry{
+		{ID: "1"},
+		{ID: "2", Private: true},
+	})
+	is.Equal(len(public), 6)
+	s = ForSpace(public, *r)
+	is.Equal(s.Daily[1], Day{"2023-03-02", 1})
+	is.Equal(s.PeakOccupancy, 1)
+}
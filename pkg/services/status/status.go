@@ -6,6 +6,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/services/macs"
 	"github.com/hakierspejs/long-season/pkg/storage"
 )
@@ -13,6 +14,21 @@ import (
 // Daemon is a background function
 type Daemon func()
 
+// Observer is notified about result of every successful
+// status update.
+type Observer interface {
+	Observe(ctx context.Context, tick storage.Tick) error
+}
+
+// ObserverFunc is functional implementation of
+// Observer interface.
+type ObserverFunc func(context.Context, storage.Tick) error
+
+// Observe calls f(ctx, tick).
+func (f ObserverFunc) Observe(ctx context.Context, tick storage.Tick) error {
+	return f(ctx, tick)
+}
+
 // DaemonArgs contains list of arguments for NewDeamon constructor.
 type DaemonArgs struct {
 	OnlineUsers storage.OnlineUsers
@@ -21,6 +37,26 @@ type DaemonArgs struct {
 
 	Counters storage.StatusTx
 
+	// Ignored holds addresses of infrastructure devices, that
+	// are excluded from counting known and unknown devices.
+	Ignored storage.Ignored
+
+	// Classify is optional function used to group unknown
+	// devices by category of their vendors.
+	Classify func(net.HardwareAddr) string
+
+	// CheckIns is optional storage with users, that checked
+	// in with their cards.
+	CheckIns storage.CheckIns
+
+	// LookupKey is optional key of lookup hashes of hardware
+	// addresses. See storage.UpdateStatusesArgs.
+	LookupKey []byte
+
+	// Observers are notified about result of every
+	// status update.
+	Observers []Observer
+
 	// RefreshTime is duration, that every time when passes, users
 	// get their online status updated.
 	RefreshTime time.Duration
@@ -34,8 +70,8 @@ type DaemonArgs struct {
 
 // NewDeamon returns channel for communicating with daemon and daemon
 // to be run in the background in the separate gourtine .
-func NewDaemon(ctx context.Context, args DaemonArgs) (chan<- []net.HardwareAddr, Daemon) {
-	ch := make(chan []net.HardwareAddr)
+func NewDaemon(ctx context.Context, args DaemonArgs) (chan<- []models.Host, Daemon) {
+	ch := make(chan []models.Host)
 
 	daemon := func() {
 		macs := macs.NewSetTTL(ctx)
@@ -47,24 +83,35 @@ func NewDaemon(ctx context.Context, args DaemonArgs) (chan<- []net.HardwareAddr,
 			select {
 			case <-ctx.Done():
 				break
-			case newMacs := <-ch: // Update mac addresses
+			case newHosts := <-ch: // Update mac addresses
 				log.Println("Received new macs")
-				for _, newMac := range newMacs {
-					macs.Push(newMac, args.SingleAddrTTL)
+				for _, newHost := range newHosts {
+					macs.PushHost(newHost, args.SingleAddrTTL)
 				}
-			case <-ticker.C:
+			case now := <-ticker.C:
 				// Update online status for every user in db
-				err := storage.UpdateStatuses(ctx, storage.UpdateStatusesArgs{
-					Addresses:          macs.Slice(),
+				tick, err := storage.UpdateStatuses(ctx, storage.UpdateStatusesArgs{
+					Hosts:              macs.Hosts(),
 					DevicesStorage:     args.Devices,
 					Counters:           args.Counters,
 					OnlineUsersStorage: args.OnlineUsers,
+					IgnoredStorage:     args.Ignored,
+					Classify:           args.Classify,
+					CheckIns:           args.CheckIns,
+					LookupKey:          args.LookupKey,
+					Time:               now,
 				})
 				if err != nil {
 					log.Println("Failed to update statuses, reason:  ", err.Error())
 					continue
 				}
 				log.Println("Succefully updated stauses.")
+
+				for _, observer := range args.Observers {
+					if err := observer.Observe(ctx, *tick); err != nil {
+						log.Println("Failed to observe status update, reason: ", err.Error())
+					}
+				}
 			}
 		}
 	}
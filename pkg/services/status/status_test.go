@@ -0,0 +1,80 @@
+package status
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/matryer/is"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+	"github.com/hakierspejs/long-season/pkg/storage/temp"
+)
+
+func TestUpdateStatusesLookups(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	mac, err := net.ParseMAC("11:11:11:11:11:11")
+	is.NoErr(err)
+	hashed, err := bcrypt.GenerateFromPassword(mac, bcrypt.MinCost)
+	is.NoErr(err)
+
+	// Both users registered the same address before lookup
+	// hashes were introduced.
+	for _, id := range []string{"1", "2"} {
+		_, err := f.Users().New(ctx, storage.UserEntry{
+			ID:             id,
+			Nickname:       "user-" + id,
+			HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+		})
+		is.NoErr(err)
+
+		_, err = f.Devices().New(ctx, id, models.Device{
+			DevicePublicData: models.DevicePublicData{ID: id, Tag: "phone"},
+			OwnerID:          id,
+			MAC:              hashed,
+		})
+		is.NoErr(err)
+	}
+
+	key := []byte("key")
+	update := func() *storage.Tick {
+		tick, err := storage.UpdateStatuses(ctx, storage.UpdateStatusesArgs{
+			Hosts:              []models.Host{{MAC: mac}},
+			DevicesStorage:     f.Devices(),
+			OnlineUsersStorage: temp.NewOnlineUsers(),
+			Counters:           temp.NewStatusTx(),
+			LookupKey:          key,
+		})
+		is.NoErr(err)
+		return tick
+	}
+
+	tick := update()
+	is.Equal(tick.Known, 1)
+	is.Equal(tick.OnlineIDs, []string{"1"})
+
+	// Lookup hash is stored only for the first matched
+	// device, the other one is left for collisions report.
+	first, err := f.Devices().Read(ctx, "1")
+	is.NoErr(err)
+	is.Equal(first.Lookups, [][]byte{storage.LookupHash(key, mac)})
+	is.True(storage.Verified(*first))
+
+	second, err := f.Devices().Read(ctx, "2")
+	is.NoErr(err)
+	is.True(!storage.Verified(*second))
+
+	// Next updates match by stored lookup hash.
+	tick = update()
+	is.Equal(tick.Known, 1)
+	is.Equal(tick.OnlineIDs, []string{"1"})
+}
@@ -0,0 +1,203 @@
+// Package tokens manages personal API tokens, that allow
+// scripts and bots to call API on behalf of their owners.
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/ctxkey"
+	"github.com/hakierspejs/long-season/pkg/services/happier"
+	"github.com/hakierspejs/long-season/pkg/services/secrets"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+var (
+	// ErrInvalidToken is returned for tokens, that don't
+	// match any of stored tokens or have expired.
+	ErrInvalidToken = errors.New("tokens: invalid token")
+
+	// ErrInvalidName is returned for empty or too long
+	// token names.
+	ErrInvalidName = errors.New("tokens: invalid name")
+
+	// ErrInvalidScopes is returned when there are no scopes
+	// or some of them are unknown.
+	ErrInvalidScopes = errors.New("tokens: invalid scopes")
+
+	// ErrInvalidExpiry is returned for expiry time in
+	// the past.
+	ErrInvalidExpiry = errors.New("tokens: invalid expiry time")
+)
+
+// Prefix is prefix of Authorization header value
+// containing personal API token.
+const Prefix = "Token"
+
+const (
+	// maxNameLength is maximal length of token name.
+	maxNameLength = 64
+
+	// lastUsedAccuracy is minimal interval between updates
+	// of the time of last usage, so tokens are not written
+	// to storage on every request.
+	lastUsedAccuracy = time.Minute
+)
+
+// Generate stores token with name, scopes and expiry time of
+// given token for its owner. Returned string is the only copy
+// of the token, because only hash of its secret part is stored.
+func Generate(ctx context.Context, s storage.APITokens, t models.APIToken) (string, *models.APIToken, error) {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" || len(t.Name) > maxNameLength {
+		return "", nil, fmt.Errorf("%w: should have from 1 to %d characters", ErrInvalidName, maxNameLength)
+	}
+
+	if len(t.Scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: token needs at least one scope", ErrInvalidScopes)
+	}
+	scopes := []models.Scope{}
+	seen := map[models.Scope]bool{}
+	for _, scope := range t.Scopes {
+		if !scope.Valid() {
+			return "", nil, fmt.Errorf("%w: unknown scope %s", ErrInvalidScopes, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	now := time.Now()
+	if !t.Expires.IsZero() && t.Expires.Before(now) {
+		return "", nil, ErrInvalidExpiry
+	}
+
+	secret, hashed, err := secrets.Generate()
+	if err != nil {
+		return "", nil, fmt.Errorf("secrets.Generate: %w", err)
+	}
+
+	t.ID = uuid.New().String()
+	t.Scopes = scopes
+	t.Secret = hashed
+	t.Created = now
+	t.LastUsed = time.Time{}
+	if _, err := s.New(ctx, t); err != nil {
+		return "", nil, fmt.Errorf("s.New: %w", err)
+	}
+
+	return t.ID + "." + secret, &t, nil
+}
+
+// Authenticate returns token matching given string and marks
+// it as used.
+func Authenticate(ctx context.Context, s storage.APITokens, token string) (*models.APIToken, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	t, err := s.Read(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: s.Read: %s", ErrInvalidToken, err)
+	}
+
+	if !secrets.Match(t.Secret, secret) {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if !t.Expires.IsZero() && now.After(t.Expires) {
+		return nil, fmt.Errorf("%w: token id=%s expired at %v", ErrInvalidToken, id, t.Expires)
+	}
+
+	if now.Sub(t.LastUsed) < lastUsedAccuracy {
+		return t, nil
+	}
+
+	t.LastUsed = now
+	err = s.Update(ctx, id, func(stored *models.APIToken) error {
+		stored.LastUsed = now
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s.Update: %w", err)
+	}
+
+	return t, nil
+}
+
+// Renewer implements session.Renewer interface for requests
+// with "Authorization: Token $TOKEN" header. Tokens are accepted
+// only by endpoints, that require scope with middleware.Scope,
+// which also checks whether token has required scope. Sessions
+// restored from tokens are issued at the moment of creating
+// token, so revoking all sessions of the owner revokes tokens
+// created before too.
+type Renewer struct {
+	Tokens storage.APITokens
+	Users  storage.Users
+}
+
+// Renew is method for restoring session from personal API
+// token provided by client.
+func (rn *Renewer) Renew(r *http.Request) (*session.State, error) {
+	errFactory := happier.FromRequest(r)
+	ctx := r.Context()
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, Prefix+" ") {
+		return nil, errFactory.Unauthorized(
+			fmt.Errorf("tokens: authorization header should has '%s ' prefix", Prefix),
+			"Failed to read authorization header.",
+		)
+	}
+
+	if _, err := ctxkey.Scope(ctx); err != nil {
+		return nil, errFactory.Unauthorized(
+			fmt.Errorf("ctxkey.Scope: %w", err),
+			"API tokens cannot be used for this resource.",
+		)
+	}
+
+	token, err := Authenticate(ctx, rn.Tokens, strings.TrimPrefix(header, Prefix+" "))
+	if errors.Is(err, ErrInvalidToken) {
+		return nil, errFactory.Unauthorized(
+			fmt.Errorf("tokens.Authenticate: %w", err),
+			"Invalid or expired API token.",
+		)
+	}
+	if err != nil {
+		return nil, errFactory.InternalServerError(
+			fmt.Errorf("tokens.Authenticate: %w", err),
+			"Internal server error. Please try again later.",
+		)
+	}
+
+	user, err := rn.Users.Read(ctx, token.UserID)
+	if err != nil {
+		return nil, errFactory.Unauthorized(
+			fmt.Errorf("rn.Users.Read: %w", err),
+			"Invalid or expired API token.",
+		)
+	}
+
+	return &session.State{
+		ID:       token.ID,
+		UserID:   user.ID,
+		Nickname: user.Nickname,
+		Role:     user.Role,
+		Issued:   token.Created,
+		Scopes:   token.Scopes,
+		Values:   map[string]interface{}{},
+	}, nil
+}
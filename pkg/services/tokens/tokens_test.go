@@ -0,0 +1,143 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/services/ctxkey"
+	"github.com/hakierspejs/long-season/pkg/services/session"
+	"github.com/hakierspejs/long-season/pkg/services/users"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestTokens(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "alice",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+		Role:           models.RoleMember,
+	})
+	is.NoErr(err)
+
+	for _, c := range []struct {
+		t   models.APIToken
+		err error
+	}{
+		{models.APIToken{UserID: "1", Name: " ", Scopes: []models.Scope{models.ScopeCheckIn}}, ErrInvalidName},
+		{models.APIToken{UserID: "1", Name: "bot"}, ErrInvalidScopes},
+		{models.APIToken{UserID: "1", Name: "bot", Scopes: []models.Scope{"everything"}}, ErrInvalidScopes},
+		{models.APIToken{
+			UserID: "1", Name: "bot", Scopes: []models.Scope{models.ScopeCheckIn},
+			Expires: time.Now().Add(-time.Hour),
+		}, ErrInvalidExpiry},
+	} {
+		_, _, err := Generate(ctx, f.APITokens(), c.t)
+		is.True(errors.Is(err, c.err))
+	}
+
+	token, created, err := Generate(ctx, f.APITokens(), models.APIToken{
+		UserID: "1",
+		Name:   " door bot ",
+		Scopes: []models.Scope{models.ScopeCheckIn, models.ScopeCheckIn, models.ScopeReadStatus},
+	})
+	is.NoErr(err)
+	is.Equal(created.Name, "door bot")
+	is.Equal(created.Scopes, []models.Scope{models.ScopeCheckIn, models.ScopeReadStatus})
+
+	for _, invalid := range []string{"", "no-dot", created.ID + ".wrong", "missing." + token} {
+		_, err = Authenticate(ctx, f.APITokens(), invalid)
+		is.True(errors.Is(err, ErrInvalidToken))
+	}
+
+	matched, err := Authenticate(ctx, f.APITokens(), token)
+	is.NoErr(err)
+	is.Equal(matched.ID, created.ID)
+
+	stored, err := f.APITokens().Read(ctx, created.ID)
+	is.NoErr(err)
+	is.True(!stored.LastUsed.IsZero())
+
+	// Expired tokens are rejected.
+	err = f.APITokens().Update(ctx, created.ID, func(t *models.APIToken) error {
+		t.Expires = time.Now().Add(-time.Minute)
+		return nil
+	})
+	is.NoErr(err)
+	_, err = Authenticate(ctx, f.APITokens(), token)
+	is.True(errors.Is(err, ErrInvalidToken))
+}
+
+func TestRenewer(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "alice",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+		Role:           models.RoleKeyholder,
+	})
+	is.NoErr(err)
+
+	token, _, err := Generate(ctx, f.APITokens(), models.APIToken{
+		UserID: "1",
+		Name:   "bot",
+		Scopes: []models.Scope{models.ScopeCheckIn},
+	})
+	is.NoErr(err)
+
+	renewer := &Renewer{Tokens: f.APITokens(), Users: f.Users()}
+	request := func(header string, scope models.Scope) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", header)
+		if scope != "" {
+			r = r.WithContext(context.WithValue(r.Context(), ctxkey.ScopeKey, scope))
+		}
+		return r
+	}
+
+	// Tokens are rejected by endpoints without scope.
+	_, err = renewer.Renew(request("Token "+token, ""))
+	is.True(err != nil)
+
+	_, err = renewer.Renew(request("Bearer "+token, models.ScopeCheckIn))
+	is.True(err != nil)
+
+	_, err = renewer.Renew(request("Token wrong.token", models.ScopeCheckIn))
+	is.True(err != nil)
+
+	state, err := renewer.Renew(request("Token "+token, models.ScopeCheckIn))
+	is.NoErr(err)
+	is.Equal(state.UserID, "1")
+	is.Equal(state.Nickname, "alice")
+	is.Equal(state.Role, models.RoleKeyholder)
+	is.True(state.Allows(models.ScopeCheckIn))
+	is.True(!state.Allows(models.ScopeManageDevices))
+
+	// Changing password revokes tokens.
+	revocable := session.Revocable(renewer, f.Users())
+	is.NoErr(users.SetPassword(ctx, f.Users(), f.APITokens(), "1", "u8dXHRi0JNo23JVeHkjh"))
+	_, err = revocable.Renew(request("Token "+token, models.ScopeCheckIn))
+	is.True(err != nil)
+	_, err = Authenticate(ctx, f.APITokens(), token)
+	is.True(errors.Is(err, ErrInvalidToken))
+}
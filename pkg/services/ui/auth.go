@@ -23,11 +23,13 @@ type AuthArguments struct {
 }
 
 // Auth authentiates user with given nickanem and password
-// as JSOn document in the payload.
+// as JSOn document in the payload. Sessions of users, who ask
+// to be remembered, outlive closing the browser.
 func Auth(args AuthArguments) horror.HandlerFunc {
 	type payload struct {
 		Nickname string `json:"nickname"`
 		Password string `json:"password"`
+		Remember bool   `json:"remember"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) error {
@@ -58,6 +60,8 @@ func Auth(args AuthArguments) horror.HandlerFunc {
 		newSession := session.New(ctx, session.Builder{
 			UserID:   match.UserID,
 			Nickname: match.Nickname,
+			Role:     match.Role,
+			Remember: input.Remember,
 		})
 
 		methods, err := args.TwoFactor.Get(ctx, match.UserID)
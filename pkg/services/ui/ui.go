@@ -81,8 +81,13 @@ func Register(config models.Config, opener handlers.Opener) http.HandlerFunc {
 	}
 }
 
-func Logout(killer session.Killer) http.HandlerFunc {
+func Logout(renewer session.Renewer, killer session.Killer, store *session.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Revoke stored session, so its token cannot be
+		// used anymore.
+		if state, err := renewer.Renew(r); err == nil && state.Scopes == nil {
+			_ = store.Revoke(r.Context(), state.ID)
+		}
 		_ = killer.Kill(r.Context(), w)
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 	}
@@ -108,3 +113,38 @@ func TwoFactor(config models.Config, opener handlers.Opener) http.HandlerFunc {
 		tmpl.ExecuteTemplate(w, "layout", newData(r, config))
 	}
 }
+
+func Kiosk(config models.Config, opener handlers.Opener) http.HandlerFunc {
+	tmpl := template.Must(renderTemplate(opener, "tmpl/kiosk.html"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl.ExecuteTemplate(w, "layout", newData(r, config))
+	}
+}
+
+func CheckIn(config models.Config, opener handlers.Opener) http.HandlerFunc {
+	tmpl := template.Must(renderTemplate(opener, "tmpl/checkin.html"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl.ExecuteTemplate(w, "layout", newData(r, config))
+	}
+}
+
+func Admin(config models.Config, opener handlers.Opener) http.HandlerFunc {
+	tmpl := template.Must(renderTemplate(opener, "tmpl/admin.html"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl.ExecuteTemplate(w, "layout", newData(r, config))
+	}
+}
+
+func Reset(config models.Config, opener handlers.Opener) http.HandlerFunc {
+	tmpl := template.Must(renderTemplate(opener, "tmpl/reset.html"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl.ExecuteTemplate(w, "layout", newData(r, config))
+	}
+}
+
+func Verify(config models.Config, opener handlers.Opener) http.HandlerFunc {
+	tmpl := template.Must(renderTemplate(opener, "tmpl/verify.html"))
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl.ExecuteTemplate(w, "layout", newData(r, config))
+	}
+}
@@ -0,0 +1,116 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+var (
+	// ErrInvalidRole is returned for unknown roles.
+	ErrInvalidRole = errors.New("role should be member, keyholder or admin")
+
+	// ErrLastAdmin is returned, when change would leave
+	// instance without any admin.
+	ErrLastAdmin = serrors.ErrLastAdmin
+)
+
+// SetRole changes role of user with given id. Storage
+// refuses to demote the last admin.
+func SetRole(ctx context.Context, s storage.Users, id string, role models.Role) error {
+	if !role.Valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+
+	err := s.Update(ctx, id, func(u *storage.UserEntry) error {
+		u.Role = role
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("s.Update: %w", err)
+	}
+
+	return nil
+}
+
+// Remove removes user with given id. Storage refuses to
+// remove the last admin.
+func Remove(ctx context.Context, s storage.Users, id string) error {
+	if err := s.Remove(ctx, id); err != nil {
+		return fmt.Errorf("s.Remove: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveAPITokens removes all personal API tokens of user
+// with given id.
+func RemoveAPITokens(ctx context.Context, t storage.APITokens, id string) error {
+	tokens, err := t.OfUser(ctx, id)
+	if err != nil {
+		return fmt.Errorf("t.OfUser: %w", err)
+	}
+
+	for _, token := range tokens {
+		err := t.Remove(ctx, token.ID)
+		if err != nil && !errors.Is(err, serrors.ErrNoID) {
+			return fmt.Errorf("t.Remove: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RevokeSessions makes all existing sessions of user
+// with given id invalid and removes user's API tokens.
+func RevokeSessions(ctx context.Context, s storage.Users, t storage.APITokens, id string) error {
+	err := s.Update(ctx, id, func(u *storage.UserEntry) error {
+		u.SessionsRevoked = time.Now()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("s.Update: %w", err)
+	}
+
+	if err := RemoveAPITokens(ctx, t, id); err != nil {
+		return fmt.Errorf("RemoveAPITokens: %w", err)
+	}
+
+	return nil
+}
+
+// SetPassword replaces password of user with given id,
+// revokes all of user's sessions and removes user's API
+// tokens.
+func SetPassword(ctx context.Context, s storage.Users, t storage.APITokens, id string, password string) error {
+	if !VerifyPassword(password) {
+		return ErrInvaliPassword
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("bcrypt.GenerateFromPassword: %w", err)
+	}
+
+	err = s.Update(ctx, id, func(u *storage.UserEntry) error {
+		u.HashedPassword = hashed
+		u.SessionsRevoked = time.Now()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("s.Update: %w", err)
+	}
+
+	if err := RemoveAPITokens(ctx, t, id); err != nil {
+		return fmt.Errorf("RemoveAPITokens: %w", err)
+	}
+
+	return nil
+}
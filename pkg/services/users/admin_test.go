@@ -0,0 +1,58 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestAdmin(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice", Role: models.RoleAdmin},
+		{ID: "2", Nickname: "bob"},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+	s := f.Users()
+
+	is.True(errors.Is(SetRole(ctx, s, "2", "owner"), ErrInvalidRole))
+	is.True(errors.Is(SetRole(ctx, s, "1", models.RoleMember), ErrLastAdmin))
+	is.True(errors.Is(Remove(ctx, s, "1"), ErrLastAdmin))
+
+	// Once there are two admins, any of them can go.
+	is.NoErr(SetRole(ctx, s, "2", models.RoleAdmin))
+	is.NoErr(SetRole(ctx, s, "1", models.RoleKeyholder))
+	is.NoErr(Remove(ctx, s, "1"))
+
+	_, err = f.APITokens().New(ctx, models.APIToken{ID: "t", UserID: "2", Name: "script", Secret: []byte("secret")})
+	is.NoErr(err)
+
+	is.True(errors.Is(SetPassword(ctx, s, f.APITokens(), "2", "short"), ErrInvaliPassword))
+	is.NoErr(SetPassword(ctx, s, f.APITokens(), "2", "bobpassword123"))
+
+	u, err := s.Read(ctx, "2")
+	is.NoErr(err)
+	is.NoErr(bcrypt.CompareHashAndPassword(u.HashedPassword, []byte("bobpassword123")))
+	is.True(!u.SessionsRevoked.IsZero())
+
+	// Changing password removes API tokens too.
+	tokens, err := f.APITokens().OfUser(ctx, "2")
+	is.NoErr(err)
+	is.Equal(len(tokens), 0)
+}
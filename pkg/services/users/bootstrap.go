@@ -0,0 +1,53 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+var (
+	// ErrAdminExists is returned, when there is already at least
+	// one admin, so there is nothing to bootstrap.
+	ErrAdminExists = errors.New("users: there is already an admin")
+
+	// ErrNoNickname is returned, when there is no user with
+	// given nickname.
+	ErrNoNickname = errors.New("users: there is no user with given nickname")
+)
+
+// Bootstrap promotes user with given nickname to admin. It works
+// only when there are no admins yet, so it is meant for promoting
+// the first one. Returns ID of promoted user.
+func Bootstrap(ctx context.Context, s storage.Users, nickname string) (string, error) {
+	entries, err := s.All(ctx)
+	if err != nil {
+		return "", fmt.Errorf("s.All: %w", err)
+	}
+
+	id := ""
+	for _, u := range entries {
+		if u.Role == models.RoleAdmin {
+			return "", fmt.Errorf("%w: %s", ErrAdminExists, u.Nickname)
+		}
+		if u.Nickname == nickname {
+			id = u.ID
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("%w: %s", ErrNoNickname, nickname)
+	}
+
+	err = s.Update(ctx, id, func(u *storage.UserEntry) error {
+		u.Role = models.RoleAdmin
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("s.Update: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,46 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+func TestBootstrap(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice"},
+		{ID: "2", Nickname: "bob", Role: models.RoleKeyholder},
+	} {
+		u.HashedPassword = []byte("71Hk4Rt2WY8xqgYoKxPm")
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	_, err = Bootstrap(ctx, f.Users(), "carol")
+	is.True(errors.Is(err, ErrNoNickname))
+
+	id, err := Bootstrap(ctx, f.Users(), "bob")
+	is.NoErr(err)
+	is.Equal(id, "2")
+
+	u, err := f.Users().Read(ctx, "2")
+	is.NoErr(err)
+	is.Equal(u.Role, models.RoleAdmin)
+
+	// The second admin has to be promoted by the first one.
+	_, err = Bootstrap(ctx, f.Users(), "alice")
+	is.True(errors.Is(err, ErrAdminExists))
+}
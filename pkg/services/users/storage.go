@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/services/happier"
 	"github.com/hakierspejs/long-season/pkg/storage"
 	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
@@ -25,6 +26,13 @@ type AddUserRequest struct {
 	// Raw password.
 	Password []byte
 
+	// Pending is true for accounts, that have to be
+	// approved by admin before first login.
+	Pending bool
+
+	// Role of new user, defaults to member.
+	Role models.Role
+
 	// Storage for users.
 	Storage storage.Users
 }
@@ -46,6 +54,8 @@ func Add(ctx context.Context, args AddUserRequest) (string, error) {
 		Nickname:       args.Nickname,
 		HashedPassword: pass,
 		Private:        false,
+		Pending:        args.Pending,
+		Role:           args.Role,
 	})
 	if errors.Is(err, serrors.ErrNicknameTaken) {
 		return "", errFactory.Conflict(
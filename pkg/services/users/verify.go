@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"regexp"
 
+	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/services/happier"
 	"github.com/hakierspejs/long-season/pkg/storage"
 	"golang.org/x/crypto/bcrypt"
@@ -100,6 +101,9 @@ type AuthenticationResponse struct {
 	// Nickname can be also used to find user as
 	// alternative to UserID.
 	Nickname string
+
+	// Role grants user permissions.
+	Role models.Role
 }
 
 // AuthenticateWithPassword takes aut dependencies with authenticate request
@@ -165,8 +169,17 @@ func AuthenticateWithPassword(ctx context.Context, deps AuthenticationDependenci
 		)
 	}
 
+	// Accounts waiting for approval cannot log in.
+	if match.Pending {
+		return nil, deps.ErrorFactory.Forbidden(
+			fmt.Errorf("user with id=%s is pending", match.ID),
+			"Your account is waiting for approval of admin.",
+		)
+	}
+
 	return &AuthenticationResponse{
 		UserID:   match.ID,
 		Nickname: match.Nickname,
+		Role:     match.Role,
 	}, nil
 }
@@ -0,0 +1,111 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// recordSize is size of the single record of encrypted
+	// content. Whole payload is sent in one record.
+	recordSize = 4096
+
+	// MaxPayload is the largest payload, that fits in
+	// single record with its padding delimiter and
+	// authentication tag.
+	MaxPayload = recordSize - 16 - 1
+
+	saltLength = 16
+)
+
+// ErrPayloadTooLarge is returned when payload doesn't fit in
+// single record of encrypted content.
+var ErrPayloadTooLarge = errors.New("webpush: payload too large")
+
+// encrypt encrypts given payload for the user agent with given
+// public key and authentication secret using aes128gcm content
+// coding, as described in RFC 8291 and RFC 8188. Salt and local
+// key pair are random for every message.
+func encrypt(payload []byte, uaPublic, authSecret []byte) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, ErrPayloadTooLarge
+	}
+
+	curve := elliptic.P256()
+	uaX, uaY := elliptic.Unmarshal(curve, uaPublic)
+	if uaX == nil {
+		return nil, errors.New("webpush: invalid p256dh key")
+	}
+
+	local, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa.GenerateKey: %w", err)
+	}
+	asPublic := elliptic.Marshal(curve, local.X, local.Y)
+
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("io.ReadFull: %w", err)
+	}
+
+	sharedX, _ := curve.ScalarMult(uaX, uaY, local.D.Bytes())
+	ecdhSecret := make([]byte, 32)
+	sharedX.FillBytes(ecdhSecret)
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := derive(ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, err := derive(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := derive(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM: %w", err)
+	}
+
+	// Header consists of salt, record size, length of
+	// key id and key id, which is local public key.
+	res := make([]byte, 0, saltLength+4+1+len(asPublic)+len(payload)+1+gcm.Overhead())
+	res = append(res, salt...)
+	res = binary.BigEndian.AppendUint32(res, recordSize)
+	res = append(res, byte(len(asPublic)))
+	res = append(res, asPublic...)
+
+	// Single record is also the last one, so it ends
+	// with 0x02 padding delimiter.
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(res, nonce, plaintext, nil), nil
+}
+
+// derive returns key of given length derived with HKDF-SHA256.
+func derive(secret, salt, info []byte, length int) ([]byte, error) {
+	res := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), res); err != nil {
+		return nil, fmt.Errorf("hkdf: %w", err)
+	}
+	return res, nil
+}
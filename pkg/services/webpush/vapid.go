@@ -0,0 +1,116 @@
+package webpush
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cristalhq/jwt/v3"
+
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+// KeyName is name of VAPID private key in keys storage.
+const KeyName = "vapid"
+
+// tokenLifetime is duration of validity of VAPID tokens. Push
+// services reject tokens valid for longer than 24 hours.
+const tokenLifetime = 12 * time.Hour
+
+// VAPID identifies application server to push services
+// with its P-256 key pair, as described in RFC 8292.
+type VAPID struct {
+	key *ecdsa.PrivateKey
+}
+
+// GenerateVAPID returns VAPID with new random key pair.
+func GenerateVAPID() (*VAPID, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa.GenerateKey: %w", err)
+	}
+	return &VAPID{key}, nil
+}
+
+// ParseVAPID returns VAPID with private key in the
+// ASN.1 DER form.
+func ParseVAPID(der []byte) (*VAPID, error) {
+	key, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("x509.ParseECPrivateKey: %w", err)
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, errors.New("webpush: vapid key is not P-256 key")
+	}
+	return &VAPID{key}, nil
+}
+
+// LoadVAPID reads VAPID key pair from given storage. New key
+// pair is generated and saved, if there is no stored one.
+func LoadVAPID(ctx context.Context, keys storage.Keys) (*VAPID, error) {
+	der, err := keys.Read(ctx, KeyName)
+	if err == nil {
+		return ParseVAPID(der)
+	}
+	if !errors.Is(err, serrors.ErrNoID) {
+		return nil, fmt.Errorf("keys.Read: %w", err)
+	}
+
+	v, err := GenerateVAPID()
+	if err != nil {
+		return nil, err
+	}
+
+	der, err = x509.MarshalECPrivateKey(v.key)
+	if err != nil {
+		return nil, fmt.Errorf("x509.MarshalECPrivateKey: %w", err)
+	}
+	if err := keys.Save(ctx, KeyName, der); err != nil {
+		return nil, fmt.Errorf("keys.Save: %w", err)
+	}
+
+	return v, nil
+}
+
+// PublicKey returns public key as uncompressed P-256 point
+// encoded with unpadded base64url, the form expected by
+// browsers as applicationServerKey.
+func (v *VAPID) PublicKey() string {
+	point := elliptic.Marshal(elliptic.P256(), v.key.X, v.key.Y)
+	return base64.RawURLEncoding.EncodeToString(point)
+}
+
+// Authorization returns value of Authorization header for
+// request to given push service endpoint. Subject is contact
+// URI of the application server operator, for example
+// "mailto:admin@example.com".
+func (v *VAPID) Authorization(endpoint, subject string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("url.Parse: %w", err)
+	}
+
+	signer, err := jwt.NewSignerES(jwt.ES256, v.key)
+	if err != nil {
+		return "", fmt.Errorf("jwt.NewSignerES: %w", err)
+	}
+
+	token, err := jwt.NewBuilder(signer).Build(&jwt.StandardClaims{
+		Audience:  jwt.Audience{u.Scheme + "://" + u.Host},
+		Subject:   subject,
+		ExpiresAt: jwt.NewNumericDate(now.Add(tokenLifetime)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("jwt.NewBuilder().Build: %w", err)
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", token.String(), v.PublicKey()), nil
+}
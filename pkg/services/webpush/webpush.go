@@ -0,0 +1,171 @@
+// Package webpush implements sending notifications to browsers
+// with Web Push protocol. Payloads are encrypted as described in
+// RFC 8291 and application server is identified with VAPID.
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSubscription is returned when push subscription
+// of browser is malformed.
+var ErrInvalidSubscription = errors.New("webpush: invalid subscription")
+
+// ErrExpired is returned when push service doesn't know
+// subscription anymore, so it should be forgotten.
+var ErrExpired = errors.New("webpush: subscription expired")
+
+// Subscription is push subscription of single browser, in
+// the form returned by PushSubscription.toJSON().
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+
+	p256dh []byte
+	auth   []byte
+}
+
+// DefaultServices are hosts of push services used by major
+// browsers: Chrome, Firefox, Safari and Edge.
+var DefaultServices = []string{
+	"fcm.googleapis.com",
+	"updates.push.services.mozilla.com",
+	".push.apple.com",
+	".notify.windows.com",
+}
+
+// allowed reports whether given endpoint belongs to one of
+// given push services. Services starting with dot match
+// all of their subdomains on default https port, other ones
+// have to be equal to host of endpoint, with port if any.
+func allowed(u *url.URL, services []string) bool {
+	for _, service := range services {
+		if strings.HasPrefix(service, ".") {
+			if u.Port() == "" && strings.HasSuffix(strings.ToLower(u.Hostname()), strings.ToLower(service)) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(u.Host, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// decode decodes base64url value with or without padding.
+func decode(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// ParseSubscription parses and validates push subscription
+// encoded as JSON. Endpoint of subscription has to belong to
+// one of given push services, or DefaultServices when there
+// are none, so notifications can't be sent anywhere else.
+func ParseSubscription(data string, services []string) (*Subscription, error) {
+	res := new(Subscription)
+	if err := json.Unmarshal([]byte(data), res); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSubscription, err.Error())
+	}
+
+	u, err := url.Parse(res.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return nil, fmt.Errorf("%w: endpoint must be https url", ErrInvalidSubscription)
+	}
+
+	if len(services) == 0 {
+		services = DefaultServices
+	}
+	if !allowed(u, services) {
+		return nil, fmt.Errorf("%w: unknown push service %s", ErrInvalidSubscription, u.Host)
+	}
+
+	res.p256dh, err = decode(res.Keys.P256dh)
+	if err != nil || len(res.p256dh) != 65 || res.p256dh[0] != 0x04 {
+		return nil, fmt.Errorf("%w: invalid p256dh key", ErrInvalidSubscription)
+	}
+
+	res.auth, err = decode(res.Keys.Auth)
+	if err != nil || len(res.auth) != 16 {
+		return nil, fmt.Errorf("%w: invalid auth secret", ErrInvalidSubscription)
+	}
+
+	return res, nil
+}
+
+// Client sends push messages to push services.
+type Client struct {
+	VAPID *VAPID
+
+	// Subject is contact URI of the application server
+	// operator, for example "mailto:admin@example.com".
+	Subject string
+
+	// TTL is how long push service should keep message
+	// for browsers, that are offline.
+	TTL time.Duration
+
+	// HTTPClient is optional http client used for requests.
+	HTTPClient *http.Client
+
+	// Services are hosts of push services, that subscriptions
+	// may point at. DefaultServices are used, when empty.
+	Services []string
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// Push sends given payload to browser with given subscription.
+// Returns ErrExpired if subscription is no longer valid.
+func (c *Client) Push(ctx context.Context, s *Subscription, payload []byte) error {
+	body, err := encrypt(payload, s.p256dh, s.auth)
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+
+	authorization, err := c.VAPID.Authorization(s.Endpoint, c.Subject, time.Now())
+	if err != nil {
+		return fmt.Errorf("c.VAPID.Authorization: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(c.TTL.Seconds())))
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("c.httpClient().Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrExpired
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("webpush: unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/jwt/v3"
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/storage/sqlite"
+)
+
+// browser holds keys of push subscription, that are
+// normally generated by user agent.
+type browser struct {
+	key  *ecdsa.PrivateKey
+	auth []byte
+}
+
+func newBrowser(t *testing.T) *browser {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := make([]byte, 16)
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatal(err)
+	}
+
+	return &browser{key, auth}
+}
+
+func (b *browser) subscription(endpoint string) string {
+	res, _ := json.Marshal(map[string]interface{}{
+		"endpoint": endpoint,
+		"keys": map[string]string{
+			"p256dh": base64.RawURLEncoding.EncodeToString(
+				elliptic.Marshal(elliptic.P256(), b.key.X, b.key.Y),
+			),
+			"auth": base64.RawURLEncoding.EncodeToString(b.auth),
+		},
+	})
+	return string(res)
+}
+
+// decrypt decrypts message encrypted for the browser with
+// aes128gcm content coding.
+func (b *browser) decrypt(body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errors.New("body too short")
+	}
+	salt := body[:16]
+	idLength := int(body[20])
+	asPublic := body[21 : 21+idLength]
+	ciphertext := body[21+idLength:]
+
+	if rs := binary.BigEndian.Uint32(body[16:20]); int(rs) < len(ciphertext) {
+		return nil, fmt.Errorf("record larger than record size %d", rs)
+	}
+
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, asPublic)
+	if x == nil {
+		return nil, errors.New("invalid key id")
+	}
+	sharedX, _ := curve.ScalarMult(x, y, b.key.D.Bytes())
+	ecdhSecret := make([]byte, 32)
+	sharedX.FillBytes(ecdhSecret)
+
+	uaPublic := elliptic.Marshal(curve, b.key.X, b.key.Y)
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+
+	ikm, err := derive(ecdhSecret, b.auth, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := derive(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := derive(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		return nil, errors.New("missing padding delimiter")
+	}
+	return plaintext[:len(plaintext)-1], nil
+}
+
+// verify checks VAPID authorization header of request sent
+// to push service with given origin.
+func verify(header, origin string) error {
+	var token, key string
+	for _, part := range strings.Split(strings.TrimPrefix(header, "vapid "), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "t":
+			token = value
+		case "k":
+			key = value
+		}
+	}
+
+	point, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil {
+		return err
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), point)
+	if x == nil {
+		return errors.New("invalid vapid key")
+	}
+
+	verifier, err := jwt.NewVerifierES(jwt.ES256, &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y})
+	if err != nil {
+		return err
+	}
+	parsed, err := jwt.ParseAndVerifyString(token, verifier)
+	if err != nil {
+		return err
+	}
+
+	claims := new(jwt.StandardClaims)
+	if err := json.Unmarshal(parsed.RawClaims(), claims); err != nil {
+		return err
+	}
+	if !claims.IsForAudience(origin) || claims.Subject != "mailto:admin@example.com" {
+		return errors.New("invalid claims")
+	}
+	if !claims.IsValidAt(time.Now()) {
+		return errors.New("expired token")
+	}
+	return nil
+}
+
+func TestPush(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	b := newBrowser(t)
+	received := make(chan []byte, 1)
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+
+		if err := verify(r.Header.Get("Authorization"), server.URL); err != nil {
+			t.Errorf("verify: %s", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		is.Equal(r.Header.Get("Content-Encoding"), "aes128gcm")
+		is.Equal(r.Header.Get("TTL"), "3600")
+
+		body, err := io.ReadAll(r.Body)
+		is.NoErr(err)
+		payload, err := b.decrypt(body)
+		is.NoErr(err)
+
+		received <- payload
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	f, closer, err := sqlite.NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	v, err := LoadVAPID(ctx, f.Keys())
+	is.NoErr(err)
+
+	// Generated key is stored and loaded again.
+	loaded, err := LoadVAPID(ctx, f.Keys())
+	is.NoErr(err)
+	is.Equal(loaded.PublicKey(), v.PublicKey())
+
+	c := &Client{
+		VAPID:      v,
+		Subject:    "mailto:admin@example.com",
+		TTL:        time.Hour,
+		HTTPClient: server.Client(),
+		Services:   []string{strings.TrimPrefix(server.URL, "https://")},
+	}
+
+	s, err := ParseSubscription(b.subscription(server.URL+"/push/1"), c.Services)
+	is.NoErr(err)
+
+	is.NoErr(c.Push(ctx, s, []byte(`{"title":"Hackerspace is open"}`)))
+	is.Equal(string(<-received), `{"title":"Hackerspace is open"}`)
+
+	s, err = ParseSubscription(b.subscription(server.URL+"/gone"), c.Services)
+	is.NoErr(err)
+	is.Equal(c.Push(ctx, s, []byte("hello")), ErrExpired)
+
+	err = c.Push(ctx, s, make([]byte, MaxPayload+1))
+	is.True(errors.Is(err, ErrPayloadTooLarge))
+}
+
+func TestParseSubscription(t *testing.T) {
+	is := is.New(t)
+
+	b := newBrowser(t)
+
+	for _, endpoint := range []string{
+		"https://fcm.googleapis.com/fcm/send/1",
+		"https://updates.push.services.mozilla.com/wpush/v2/1",
+		"https://web.push.apple.com/1",
+		"https://wns2-par02p.notify.windows.com/w/?token=1",
+	} {
+		_, err := ParseSubscription(b.subscription(endpoint), nil)
+		is.NoErr(err)
+	}
+
+	_, err := ParseSubscription(b.subscription("https://push.example.com/1"), []string{"push.example.com"})
+	is.NoErr(err)
+
+	for _, data := range []string{
+		"",
+		"{}",
+		b.subscription("http://fcm.googleapis.com/1"),
+		strings.Replace(b.subscription("https://fcm.googleapis.com/1"), `"auth":"`, `"auth":"AA`, 1),
+
+		// Only known push services are accepted.
+		b.subscription("https://push.example.com/1"),
+		b.subscription("https://127.0.0.1/1"),
+		b.subscription("https://169.254.169.254/latest/meta-data"),
+		b.subscription("https://[::1]/1"),
+		b.subscription("https://localhost/1"),
+		b.subscription("https://fcm.googleapis.com.example.com/1"),
+		b.subscription("https://evilpush.apple.com/1"),
+		b.subscription("https://web.push.apple.com:8443/1"),
+		b.subscription("https://user@fcm.googleapis.com/1"),
+	} {
+		_, err := ParseSubscription(data, nil)
+		is.True(errors.Is(err, ErrInvalidSubscription))
+	}
+}
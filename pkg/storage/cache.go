@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is cache of boolean values with limited number of
+// entries. When cache is full, least recently used entry
+// is evicted. It is safe for concurrent use.
+type lru struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	guard   sync.Mutex
+}
+
+type lruEntry struct {
+	key   string
+	value bool
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns value stored under given key and true, or
+// false twice if there is no such key.
+func (c *lru) Get(key string) (bool, bool) {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return false, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+// Set stores given value under given key.
+func (c *lru) Set(key string, value bool) {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns number of cached entries.
+func (c *lru) Len() int {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	return c.order.Len()
+}
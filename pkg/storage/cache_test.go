@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestLRU(t *testing.T) {
+	is := is.New(t)
+
+	c := newLRU(2)
+	c.Set("a", true)
+	c.Set("b", false)
+
+	// Reading entry makes it recently used.
+	value, ok := c.Get("a")
+	is.True(ok)
+	is.True(value)
+
+	c.Set("c", true)
+	is.Equal(c.Len(), 2)
+
+	_, ok = c.Get("b")
+	is.True(!ok)
+
+	value, ok = c.Get("c")
+	is.True(ok)
+	is.True(value)
+
+	// Updating entry doesn't grow cache.
+	c.Set("a", false)
+	is.Equal(c.Len(), 2)
+	value, ok = c.Get("a")
+	is.True(ok)
+	is.True(!value)
+}
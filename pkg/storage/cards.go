@@ -0,0 +1,21 @@
+package storage
+
+import "github.com/hakierspejs/long-season/pkg/models"
+
+// MatchCard returns true if given raw card identifier is the one
+// hashed and stored in given card. Results of comparisons are
+// cached, the same way as for hardware addresses.
+func MatchCard(card models.Card, uid []byte) bool {
+	return len(uid) > 0 && len(card.UID) > 0 && matchHash(card.UID, uid)
+}
+
+// CardWithUID returns card with given raw identifier and true or
+// false if there is no such card in given slice.
+func CardWithUID(cards []models.Card, uid []byte) (models.Card, bool) {
+	for _, c := range cards {
+		if MatchCard(c, uid) {
+			return c, true
+		}
+	}
+	return models.Card{}, false
+}
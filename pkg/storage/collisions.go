@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// LookupHash returns keyed hash of given device identifier,
+// for example hardware address. Lookup hashes of the same
+// identifier are always equal, so they can be indexed and
+// compared without slow bcrypt comparisons.
+func LookupHash(key, identifier []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(identifier)
+	return mac.Sum(nil)
+}
+
+// HasLookup returns true if given device has given lookup hash
+// of hardware address.
+func HasLookup(device models.Device, lookup []byte) bool {
+	for _, l := range device.Lookups {
+		if bytes.Equal(l, lookup) {
+			return true
+		}
+	}
+	return false
+}
+
+// SharedLookup returns true if both devices have the same
+// lookup hash of hardware address.
+func SharedLookup(a, b models.Device) bool {
+	for _, l := range a.Lookups {
+		if HasLookup(b, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// UniqueLookups returns true if every lookup hash of given
+// device occurs only once.
+func UniqueLookups(device models.Device) bool {
+	for i, l := range device.Lookups {
+		for _, other := range device.Lookups[i+1:] {
+			if bytes.Equal(l, other) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Verified returns true if every hardware address and client id
+// of given device has its lookup hash, so device can be checked
+// for collisions with other devices.
+func Verified(device models.Device) bool {
+	if len(device.ClientID) > 0 && len(device.ClientIDLookup) == 0 {
+		return false
+	}
+	return len(device.Lookups) >= len(device.Addresses())
+}
+
+// Collision describes single hardware address registered
+// by more than one device.
+type Collision struct {
+	// Lookup is lookup hash of colliding hardware address.
+	Lookup []byte
+
+	// Devices contains every device with given address.
+	Devices []models.Device
+}
+
+// Owners returns number of distinct owners of colliding devices.
+func (c Collision) Owners() int {
+	owners := map[string]struct{}{}
+	for _, d := range c.Devices {
+		owners[d.OwnerID] = struct{}{}
+	}
+	return len(owners)
+}
+
+// FindCollisions returns every lookup hash shared by at least
+// two of given devices. Storage refuses to store new collisions,
+// but devices registered before lookup hashes were introduced
+// are compared only after scanners report their addresses. Use
+// Verified to find devices, that can't be compared yet.
+func FindCollisions(devices []models.Device) []Collision {
+	res := []Collision{}
+	seen := map[string]struct{}{}
+	for i, d := range devices {
+		for _, l := range d.Lookups {
+			if _, ok := seen[string(l)]; ok {
+				continue
+			}
+			seen[string(l)] = struct{}{}
+
+			matched := []models.Device{d}
+			for _, other := range devices[i+1:] {
+				if HasLookup(other, l) {
+					matched = append(matched, other)
+				}
+			}
+			if len(matched) > 1 {
+				res = append(res, Collision{
+					Lookup:  l,
+					Devices: matched,
+				})
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+func TestFindCollisions(t *testing.T) {
+	is := is.New(t)
+
+	key := []byte("key")
+	lookup := func(mac string) []byte {
+		return LookupHash(key, []byte(mac))
+	}
+
+	devices := []models.Device{
+		{
+			DevicePublicData: models.DevicePublicData{ID: "1"},
+			OwnerID:          "1",
+			MAC:              []byte("hash"),
+			Lookups:          [][]byte{lookup("11:11:11:11:11:11")},
+		},
+		{
+			DevicePublicData: models.DevicePublicData{ID: "2"},
+			OwnerID:          "2",
+			MAC:              []byte("hash"),
+			MACs:             [][]byte{[]byte("hash")},
+			Lookups:          [][]byte{lookup("22:22:22:22:22:22"), lookup("11:11:11:11:11:11")},
+		},
+		{
+			DevicePublicData: models.DevicePublicData{ID: "3"},
+			OwnerID:          "2",
+			MAC:              []byte("hash"),
+		},
+	}
+
+	collisions := FindCollisions(devices)
+	is.Equal(len(collisions), 1)
+	is.Equal(collisions[0].Lookup, lookup("11:11:11:11:11:11"))
+	is.Equal(len(collisions[0].Devices), 2)
+	is.Equal(collisions[0].Owners(), 2)
+
+	is.True(Verified(devices[0]))
+	is.True(Verified(devices[1]))
+	is.True(!Verified(devices[2]))
+
+	is.True(SharedLookup(devices[0], devices[1]))
+	is.True(!SharedLookup(devices[0], devices[2]))
+	is.True(!UniqueLookups(models.Device{
+		Lookups: [][]byte{lookup("11:11:11:11:11:11"), lookup("11:11:11:11:11:11")},
+	}))
+}
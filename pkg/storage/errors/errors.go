@@ -8,10 +8,34 @@ var (
 	// ErrDeviceDuplication is returned, when there is already device with given owner and tag.
 	ErrDeviceDuplication = errors.New("there is already device with given owner and tag")
 
+	// ErrMACDuplication is returned, when there is already device with given
+	// hardware address, no matter who owns it.
+	ErrMACDuplication = errors.New("there is already device with given mac address")
+
+	// ErrHostnameTaken is returned, when there is already device
+	// with given hostname, no matter who owns it.
+	ErrHostnameTaken = errors.New("there is already device with given hostname")
+
+	// ErrClientIDTaken is returned, when there is already device
+	// with given DHCP client identifier, no matter who owns it.
+	ErrClientIDTaken = errors.New("there is already device with given client id")
+
+	// ErrPrefixDuplication is returned, when given hardware address
+	// prefix is already ignored.
+	ErrPrefixDuplication = errors.New("there is already ignored address with given prefix")
+
+	// ErrCardDuplication is returned, when there is already card
+	// with given uid, no matter who owns it.
+	ErrCardDuplication = errors.New("there is already card with given uid")
+
 	// ErrNoID is returned when there is no resource with given id
 	// stored in database.
 	ErrNoID = errors.New("resource with given id not found")
 
+	// ErrLastAdmin is returned, when removing or demoting
+	// user would leave instance without any admin.
+	ErrLastAdmin = errors.New("cannot remove or demote the last admin")
+
 	// ErrNicknameTaken is being returned when there is
 	// already a user with given username.
 	ErrNicknameTaken = errors.New("user with given username is already registered")
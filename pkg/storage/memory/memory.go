@@ -3,11 +3,14 @@
 package memory
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
 
@@ -21,6 +24,20 @@ const (
 	usersBucket          = "ls::users"
 	devicesBucket        = "ls::devices"
 	twoFactorBucket      = "ls::twofactor"
+	ignoredBucket        = "ls::ignored"
+	cardsBucket          = "ls::cards"
+	visitsBucket         = "ls::visits"
+	latestVisitsBucket   = "ls::visits::latest"
+	historyBucket        = "ls::history"
+	subscriptionsBucket  = "ls::subscriptions"
+	keysBucket           = "ls::keys"
+	scannersBucket       = "ls::scanners"
+	settingsBucket       = "ls::settings"
+	invitesBucket        = "ls::invites"
+	resetTokensBucket    = "ls::reset_tokens"
+	auditBucket          = "ls::audit"
+	apiTokensBucket      = "ls::api_tokens"
+	sessionsBucket       = "ls::sessions"
 	devicesBucketCounter = "ls::devices::counter"
 )
 
@@ -31,6 +48,19 @@ type Factory struct {
 	devices         *DevicesStorage
 	statusStorageTx *StatusStorageTx
 	twoFactor       *TwoFactorStorage
+	ignored         *IgnoredStorage
+	cards           *CardsStorage
+	visits          *VisitsStorage
+	history         *HistoryStorage
+	subscriptions   *SubscriptionsStorage
+	keys            *KeysStorage
+	scanners        *ScannersStorage
+	settings        *SettingsStorage
+	invites         *InvitesStorage
+	resetTokens     *ResetTokensStorage
+	audit           *AuditStorage
+	apiTokens       *APITokensStorage
+	sessions        *SessionsStorage
 }
 
 // Users returns storage interface for manipulating
@@ -47,6 +77,83 @@ func (f Factory) TwoFactor() storage.TwoFactor {
 	return f.twoFactor
 }
 
+// Ignored returns storage interface for manipulating
+// ignored hardware addresses.
+func (f Factory) Ignored() storage.Ignored {
+	return f.ignored
+}
+
+// Cards returns storage interface for manipulating
+// cards of users.
+func (f Factory) Cards() storage.Cards {
+	return f.cards
+}
+
+// Visits returns storage interface for manipulating
+// intervals of users presence.
+func (f Factory) Visits() storage.Visits {
+	return f.visits
+}
+
+// History returns storage interface for manipulating
+// snapshots of online users counter.
+func (f Factory) History() storage.History {
+	return f.history
+}
+
+// Subscriptions returns storage interface for manipulating
+// subscriptions of users to notifications.
+func (f Factory) Subscriptions() storage.Subscriptions {
+	return f.subscriptions
+}
+
+// Keys returns storage interface for manipulating
+// cryptographic keys generated by the server.
+func (f Factory) Keys() storage.Keys {
+	return f.keys
+}
+
+// Scanners returns storage interface for manipulating
+// keys of scanners.
+func (f Factory) Scanners() storage.Scanners {
+	return f.scanners
+}
+
+// Settings returns storage interface for manipulating
+// settings changed at runtime.
+func (f Factory) Settings() storage.Settings {
+	return f.settings
+}
+
+// Invites returns storage interface for manipulating
+// invite codes.
+func (f Factory) Invites() storage.Invites {
+	return f.invites
+}
+
+// ResetTokens returns storage interface for manipulating
+// password reset tokens.
+func (f Factory) ResetTokens() storage.ResetTokens {
+	return f.resetTokens
+}
+
+// Audit returns storage interface for audit log.
+func (f Factory) Audit() storage.Audit {
+	return f.audit
+}
+
+// APITokens returns storage interface for manipulating
+// personal API tokens.
+func (f Factory) APITokens() storage.APITokens {
+	return f.apiTokens
+}
+
+// Sessions returns storage interface for manipulating
+// records of sessions.
+func (f Factory) Sessions() storage.Sessions {
+	return f.sessions
+}
+
 // StatusTx returns storage interface for
 // reading and writing information about numbers
 // of online users and unkown devices.
@@ -61,6 +168,20 @@ func New(db *bolt.DB) (*Factory, error) {
 		usersBucket,
 		devicesBucket,
 		twoFactorBucket,
+		ignoredBucket,
+		cardsBucket,
+		visitsBucket,
+		latestVisitsBucket,
+		historyBucket,
+		subscriptionsBucket,
+		keysBucket,
+		scannersBucket,
+		settingsBucket,
+		invitesBucket,
+		resetTokensBucket,
+		auditBucket,
+		apiTokensBucket,
+		sessionsBucket,
 	}
 	err := db.Update(func(tx *bolt.Tx) error {
 		for _, b := range buckets {
@@ -81,6 +202,19 @@ func New(db *bolt.DB) (*Factory, error) {
 		devices:         &DevicesStorage{db},
 		statusStorageTx: &StatusStorageTx{db},
 		twoFactor:       &TwoFactorStorage{db},
+		ignored:         &IgnoredStorage{db},
+		cards:           &CardsStorage{db},
+		visits:          &VisitsStorage{db},
+		history:         &HistoryStorage{db},
+		subscriptions:   &SubscriptionsStorage{db},
+		keys:            &KeysStorage{db},
+		scanners:        &ScannersStorage{db},
+		settings:        &SettingsStorage{db},
+		invites:         &InvitesStorage{db},
+		resetTokens:     &ResetTokensStorage{db},
+		audit:           &AuditStorage{db},
+		apiTokens:       &APITokensStorage{db},
+		sessions:        &SessionsStorage{db},
 	}, nil
 }
 
@@ -91,10 +225,16 @@ type UsersStorage struct {
 }
 
 const (
-	userIDKey          = "ls::user::id"
-	userNicknameKey    = "ls::user::nickname"
-	userPasswordKey    = "ls::user::password"
-	userPrivateModeKey = "ls::user::private_mode"
+	userIDKey            = "ls::user::id"
+	userNicknameKey      = "ls::user::nickname"
+	userPasswordKey      = "ls::user::password"
+	userPrivateModeKey   = "ls::user::private_mode"
+	userUnfollowableKey  = "ls::user::unfollowable"
+	userRoleKey          = "ls::user::role"
+	userRevokedKey       = "ls::user::sessions_revoked"
+	userPendingKey       = "ls::user::pending"
+	userEmailKey         = "ls::user::email"
+	userEmailVerifiedKey = "ls::user::email_verified"
 )
 
 func boolToBytes(b bool) []byte {
@@ -144,6 +284,34 @@ func userFromBucket(b *bolt.Bucket) (*storage.UserEntry, error) {
 		result.Private = bytesToBool(priv)
 	}
 
+	unfollowable := b.Get([]byte(userUnfollowableKey))
+	if unfollowable != nil {
+		result.Unfollowable = bytesToBool(unfollowable)
+	}
+
+	if pending := b.Get([]byte(userPendingKey)); pending != nil {
+		result.Pending = bytesToBool(pending)
+	}
+
+	if email := b.Get([]byte(userEmailKey)); email != nil {
+		result.Email = string(email)
+	}
+
+	if verified := b.Get([]byte(userEmailVerifiedKey)); verified != nil {
+		result.EmailVerified = bytesToBool(verified)
+	}
+
+	result.Role = models.RoleMember
+	if role := b.Get([]byte(userRoleKey)); role != nil {
+		result.Role = models.Role(role)
+	}
+
+	if revoked := b.Get([]byte(userRevokedKey)); revoked != nil {
+		if err := result.SessionsRevoked.UnmarshalText(revoked); err != nil {
+			return nil, fmt.Errorf("result.SessionsRevoked.UnmarshalText: %w", err)
+		}
+	}
+
 	return result, nil
 }
 
@@ -168,12 +336,22 @@ func storeUserInBucket(user storage.UserEntry, b *bolt.Bucket) error {
 
 	id := []byte(user.ID)
 
+	role := user.Role
+	if role == "" {
+		role = models.RoleMember
+	}
+
 	// keys and values for user data model
 	kvs := []bucketMapping{
 		{[]byte(userIDKey), id},
 		{[]byte(userNicknameKey), []byte(user.Nickname)},
 		{[]byte(userPasswordKey), user.HashedPassword},
 		{[]byte(userPrivateModeKey), boolToBytes(user.Private)},
+		{[]byte(userUnfollowableKey), boolToBytes(user.Unfollowable)},
+		{[]byte(userRoleKey), []byte(role)},
+		{[]byte(userPendingKey), boolToBytes(user.Pending)},
+		{[]byte(userEmailKey), []byte(user.Email)},
+		{[]byte(userEmailVerifiedKey), boolToBytes(user.EmailVerified)},
 	}
 
 	for _, item := range kvs {
@@ -182,7 +360,16 @@ func storeUserInBucket(user storage.UserEntry, b *bolt.Bucket) error {
 		}
 	}
 
-	return nil
+	if user.SessionsRevoked.IsZero() {
+		return userBucket.Delete([]byte(userRevokedKey))
+	}
+
+	revoked, err := user.SessionsRevoked.MarshalText()
+	if err != nil {
+		return fmt.Errorf("user.SessionsRevoked.MarshalText: %w", err)
+	}
+
+	return userBucket.Put([]byte(userRevokedKey), revoked)
 }
 
 func readUser(tx *bolt.Tx, userID string) (*storage.UserEntry, error) {
@@ -297,15 +484,42 @@ func (s *UsersStorage) Update(ctx context.Context, id string, f func(*storage.Us
 		if err != nil {
 			return fmt.Errorf("readUser: %w", err)
 		}
+		wasAdmin := u.Role == models.RoleAdmin
 
 		if err := f(u); err != nil {
 			return err
 		}
 
+		if wasAdmin && u.Role != models.RoleAdmin {
+			if err := checkLastAdmin(tx, id); err != nil {
+				return err
+			}
+		}
+
 		return updateOneUser(tx.Bucket([]byte(usersBucket)), *u)
 	})
 }
 
+// checkLastAdmin returns serrors.ErrLastAdmin if there is
+// at most one admin.
+func checkLastAdmin(tx *bolt.Tx, id string) error {
+	admins := 0
+	err := forEachUser(tx, func(u storage.UserEntry) error {
+		if u.Role == models.RoleAdmin {
+			admins++
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("forEachUser: %w", err)
+	}
+
+	if admins <= 1 {
+		return fmt.Errorf("user id=%s: %w", id, serrors.ErrLastAdmin)
+	}
+	return nil
+}
+
 // Remove deletes user with given id from storage.
 func (s *UsersStorage) Remove(ctx context.Context, id string) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
@@ -317,6 +531,16 @@ func (s *UsersStorage) Remove(ctx context.Context, id string) error {
 			return serrors.ErrNoID
 		}
 
+		u, err := readUser(tx, id)
+		if err != nil {
+			return fmt.Errorf("readUser: %w", err)
+		}
+		if u.Role == models.RoleAdmin {
+			if err := checkLastAdmin(tx, id); err != nil {
+				return err
+			}
+		}
+
 		return b.DeleteBucket(key)
 	})
 }
@@ -328,11 +552,16 @@ type DevicesStorage struct {
 }
 
 const (
-	deviceIDKey      = "ls::device::id"
-	deviceTagKey     = "ls::device::tag"
-	deviceOwnerKey   = "ls::device::owner"
-	deviceOwnerIDKey = "ls::device::owner::id"
-	deviceMACKey     = "ls::device::mac"
+	deviceIDKey           = "ls::device::id"
+	deviceTagKey          = "ls::device::tag"
+	deviceOwnerKey        = "ls::device::owner"
+	deviceOwnerIDKey      = "ls::device::owner::id"
+	deviceMACKey          = "ls::device::mac"
+	deviceMACsKey         = "ls::device::macs"
+	deviceLookupsKey      = "ls::device::lookups"
+	deviceHostKey         = "ls::device::hostname"
+	deviceClientKey       = "ls::device::client::id"
+	deviceClientLookupKey = "ls::device::client::lookup"
 )
 
 func deviceBucketKey(id string) []byte {
@@ -376,6 +605,26 @@ func deviceFromBucket(b *bolt.Bucket) (*models.Device, error) {
 	}
 	result.Owner = string(owner)
 
+	// Alternative identifiers are optional, because they
+	// are missing in devices stored by older versions.
+	if macs := b.Get([]byte(deviceMACsKey)); macs != nil {
+		if err := json.Unmarshal(macs, &result.MACs); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal: %w", err)
+		}
+	}
+	if lookups := b.Get([]byte(deviceLookupsKey)); lookups != nil {
+		if err := json.Unmarshal(lookups, &result.Lookups); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal: %w", err)
+		}
+	}
+	result.Hostname = string(b.Get([]byte(deviceHostKey)))
+	if clientID := b.Get([]byte(deviceClientKey)); len(clientID) > 0 {
+		result.ClientID = append([]byte{}, clientID...)
+	}
+	if lookup := b.Get([]byte(deviceClientLookupKey)); len(lookup) > 0 {
+		result.ClientIDLookup = append([]byte{}, lookup...)
+	}
+
 	return result, nil
 }
 
@@ -389,6 +638,16 @@ func storeDeviceInBucket(device models.Device, b *bolt.Bucket) error {
 	deviceID := []byte(device.ID)
 	ownerID := []byte(device.OwnerID)
 
+	macs, err := json.Marshal(device.MACs)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	lookups, err := json.Marshal(device.Lookups)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
 	// keys and values for device data model
 	kvs := []bucketMapping{
 		{[]byte(deviceIDKey), deviceID},
@@ -396,6 +655,11 @@ func storeDeviceInBucket(device models.Device, b *bolt.Bucket) error {
 		{[]byte(deviceOwnerKey), []byte(device.Owner)},
 		{[]byte(deviceTagKey), []byte(device.Tag)},
 		{[]byte(deviceMACKey), device.MAC},
+		{[]byte(deviceMACsKey), macs},
+		{[]byte(deviceLookupsKey), lookups},
+		{[]byte(deviceHostKey), []byte(device.Hostname)},
+		{[]byte(deviceClientKey), device.ClientID},
+		{[]byte(deviceClientLookupKey), device.ClientIDLookup},
 	}
 
 	for _, item := range kvs {
@@ -429,6 +693,38 @@ func sameDevice(a, b models.Device) bool {
 	return a.Owner == b.Owner && a.Tag == b.Tag
 }
 
+// checkIdentifiers returns serrors.ErrMACDuplication if given
+// device shares lookup hash of hardware address with any other
+// stored device and serrors.ErrHostnameTaken or
+// serrors.ErrClientIDTaken for shared hostname or client id.
+// Hostname and client id are checked only if they differ from
+// previous ones, so devices stored by older versions with the
+// same hostname can still be updated.
+func checkIdentifiers(tx *bolt.Tx, d, previous models.Device) error {
+	if !storage.UniqueLookups(d) {
+		return serrors.ErrMACDuplication
+	}
+
+	checkHostname := !strings.EqualFold(d.Hostname, previous.Hostname)
+	checkClientID := !bytes.Equal(d.ClientIDLookup, previous.ClientIDLookup)
+
+	return forEachDevice(tx, func(device models.Device) error {
+		if device.ID == d.ID {
+			return nil
+		}
+		if storage.SharedLookup(d, device) {
+			return fmt.Errorf("device id=%s: %w", device.ID, serrors.ErrMACDuplication)
+		}
+		if checkHostname && storage.MatchHostname(d.Hostname, device.Hostname) {
+			return fmt.Errorf("device id=%s: %w", device.ID, serrors.ErrHostnameTaken)
+		}
+		if checkClientID && len(d.ClientIDLookup) > 0 && bytes.Equal(d.ClientIDLookup, device.ClientIDLookup) {
+			return fmt.Errorf("device id=%s: %w", device.ID, serrors.ErrClientIDTaken)
+		}
+		return nil
+	})
+}
+
 // userExists checks whether user with given id is stored in database.
 func userExists(tx *bolt.Tx, userID string) (bool, error) {
 	user, err := readUser(tx, userID)
@@ -462,6 +758,10 @@ func (d *DevicesStorage) New(ctx context.Context, userID string, newDevice model
 			return err
 		}
 
+		if err := checkIdentifiers(tx, newDevice, models.Device{}); err != nil {
+			return err
+		}
+
 		b := tx.Bucket([]byte(devicesBucket))
 		return storeDeviceInBucket(newDevice, b)
 	})
@@ -501,6 +801,10 @@ func (d *DevicesStorage) NewByOwner(ctx context.Context, deviceOwner string, new
 			return err
 		}
 
+		if err := checkIdentifiers(tx, newDevice, models.Device{}); err != nil {
+			return err
+		}
+
 		newDevice.OwnerID = targetUser.ID
 		newDevice.Owner = targetUser.Nickname
 
@@ -581,17 +885,39 @@ func (s *DevicesStorage) All(ctx context.Context) ([]models.Device, error) {
 	return res, nil
 }
 
-// Update overwrites existing device data.
-func (s *DevicesStorage) Update(ctx context.Context, d models.Device) error {
+// Update overwrites existing device data with changes
+// applied by given function.
+func (s *DevicesStorage) Update(ctx context.Context, id string, f func(*models.Device) error) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(devicesBucket))
 
 		// Check if there is device with given id in database.
-		if b.Bucket(deviceBucketKey(d.ID)) == nil {
+		deviceBucket := b.Bucket(deviceBucketKey(id))
+		if deviceBucket == nil {
 			return serrors.ErrNoID
 		}
 
-		return storeDeviceInBucket(d, b)
+		device, err := deviceFromBucket(deviceBucket)
+		if err != nil {
+			return fmt.Errorf("deviceFromBucket: %w", err)
+		}
+
+		ownerID, owner := device.OwnerID, device.Owner
+		previous := *device
+
+		if err := f(device); err != nil {
+			return fmt.Errorf("f: %w", err)
+		}
+
+		// Device id and owner can't be changed.
+		device.ID = id
+		device.OwnerID, device.Owner = ownerID, owner
+
+		if err := checkIdentifiers(tx, *device, previous); err != nil {
+			return err
+		}
+
+		return storeDeviceInBucket(*device, b)
 	})
 }
 
@@ -613,6 +939,7 @@ func (s *DevicesStorage) Remove(ctx context.Context, id string) error {
 const (
 	onlineUsersCounter    = "ls::users::online::counter"
 	unknownDevicesCounter = "ls::devices::unknown::counter"
+	unknownBreakdownKey   = "ls::devices::unknown::breakdown"
 )
 
 // status implements storage.Status interface.
@@ -690,6 +1017,44 @@ func (s *status) SetUnknownDevices(ctx context.Context, number int) error {
 	return b.Put([]byte(unknownDevicesCounter), []byte(strconv.Itoa(number)))
 }
 
+// UnknownBreakdown returns numbers of unknown devices grouped
+// by category of their vendors.
+func (s *status) UnknownBreakdown(ctx context.Context) (map[string]int, error) {
+	b, err := s.tx.CreateBucketIfNotExists([]byte(countersBucket))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s bucket: %w", countersBucket, err)
+	}
+
+	res := map[string]int{}
+
+	dat := b.Get([]byte(unknownBreakdownKey))
+	if dat == nil {
+		return res, nil
+	}
+
+	if err := json.Unmarshal(dat, &res); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return res, nil
+}
+
+// SetUnknownBreakdown overwrites numbers of unknown devices
+// grouped by category of their vendors.
+func (s *status) SetUnknownBreakdown(ctx context.Context, breakdown map[string]int) error {
+	b, err := s.tx.CreateBucketIfNotExists([]byte(countersBucket))
+	if err != nil {
+		return fmt.Errorf("failed to create %s bucket: %w", countersBucket, err)
+	}
+
+	dat, err := json.Marshal(breakdown)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	return b.Put([]byte(unknownBreakdownKey), dat)
+}
+
 // StatusStorageTx implements storage.StatusTx interface.
 type StatusStorageTx struct {
 	db *bolt.DB
@@ -797,3 +1162,1091 @@ func (t *TwoFactorStorage) Remove(ctx context.Context, userID string) error {
 		return bucket.Delete(twoFactorKey(userID))
 	})
 }
+
+// IgnoredStorage implements storage.Ignored interface
+// for bolt database.
+type IgnoredStorage struct {
+	db *bolt.DB
+}
+
+func forEachIgnored(tx *bolt.Tx, f func(models.IgnoredAddress) error) error {
+	b := tx.Bucket([]byte(ignoredBucket))
+	return b.ForEach(func(k, v []byte) error {
+		ignored := models.IgnoredAddress{}
+		if err := json.Unmarshal(v, &ignored); err != nil {
+			return fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		return f(ignored)
+	})
+}
+
+// New stores given ignored address and returns its id.
+func (s *IgnoredStorage) New(ctx context.Context, a models.IgnoredAddress) (string, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		err := forEachIgnored(tx, func(ignored models.IgnoredAddress) error {
+			if bytes.Equal(ignored.Prefix, a.Prefix) {
+				return serrors.ErrPrefixDuplication
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		dat, err := json.Marshal(a)
+		if err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+
+		return tx.Bucket([]byte(ignoredBucket)).Put([]byte(a.ID), dat)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return a.ID, nil
+}
+
+// All returns slice with every ignored address.
+func (s *IgnoredStorage) All(ctx context.Context) ([]models.IgnoredAddress, error) {
+	res := []models.IgnoredAddress{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachIgnored(tx, func(ignored models.IgnoredAddress) error {
+			res = append(res, ignored)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading all ignored addresses failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// Remove deletes ignored address with given id.
+func (s *IgnoredStorage) Remove(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(ignoredBucket))
+
+		if b.Get([]byte(id)) == nil {
+			return serrors.ErrNoID
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// CardsStorage implements storage.Cards interface
+// for bolt database.
+type CardsStorage struct {
+	db *bolt.DB
+}
+
+func forEachCard(tx *bolt.Tx, f func(models.Card) error) error {
+	b := tx.Bucket([]byte(cardsBucket))
+	return b.ForEach(func(k, v []byte) error {
+		card := models.Card{}
+		if err := json.Unmarshal(v, &card); err != nil {
+			return fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		return f(card)
+	})
+}
+
+// New stores given card of user with given id and returns
+// card id.
+func (s *CardsStorage) New(ctx context.Context, userID string, c models.Card) (string, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		exists, err := userExists(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("there is no user with id=%s, err=%w", userID, serrors.ErrNoID)
+		}
+
+		err = forEachCard(tx, func(card models.Card) error {
+			if bytes.Equal(card.UID, c.UID) {
+				return serrors.ErrCardDuplication
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		c.OwnerID = userID
+		dat, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+
+		return tx.Bucket([]byte(cardsBucket)).Put([]byte(c.ID), dat)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return c.ID, nil
+}
+
+// OfUser returns cards of user with given id.
+func (s *CardsStorage) OfUser(ctx context.Context, userID string) ([]models.Card, error) {
+	res := []models.Card{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachCard(tx, func(card models.Card) error {
+			if card.OwnerID == userID {
+				res = append(res, card)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading cards of user with id=%s failed: %w", userID, err)
+	}
+
+	return res, nil
+}
+
+// All returns slice with every card.
+func (s *CardsStorage) All(ctx context.Context) ([]models.Card, error) {
+	res := []models.Card{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachCard(tx, func(card models.Card) error {
+			res = append(res, card)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading all cards failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// Remove deletes card with given id.
+func (s *CardsStorage) Remove(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(cardsBucket))
+
+		if b.Get([]byte(id)) == nil {
+			return serrors.ErrNoID
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// VisitsStorage implements storage.Visits interface
+// for bolt database.
+type VisitsStorage struct {
+	db *bolt.DB
+}
+
+// visitKey returns key of given visit. Keys are sorted
+// by start time of visits.
+func visitKey(v models.Visit) []byte {
+	return []byte(fmt.Sprintf("%020d::%s", v.Start.UnixNano(), v.ID))
+}
+
+func putVisit(b *bolt.Bucket, v models.Visit) error {
+	dat, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	return b.Put(visitKey(v), dat)
+}
+
+// Record extends latest visit of every user with given id to
+// given time, if the visit ended no earlier than given gap
+// before. Otherwise new visit, that starts at given time,
+// is stored.
+func (s *VisitsStorage) Record(ctx context.Context, userIDs []string, at time.Time, gap time.Duration) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		visits := tx.Bucket([]byte(visitsBucket))
+		latest := tx.Bucket([]byte(latestVisitsBucket))
+
+		for _, userID := range userIDs {
+			if key := latest.Get([]byte(userID)); key != nil {
+				if dat := visits.Get(key); dat != nil {
+					visit := models.Visit{}
+					if err := json.Unmarshal(dat, &visit); err != nil {
+						return fmt.Errorf("json.Unmarshal: %w", err)
+					}
+
+					if !visit.End.Before(at.Add(-gap)) {
+						if at.After(visit.End) {
+							visit.End = at
+						}
+						if err := putVisit(visits, visit); err != nil {
+							return err
+						}
+						continue
+					}
+				}
+			}
+
+			seq, err := visits.NextSequence()
+			if err != nil {
+				return fmt.Errorf("visits.NextSequence: %w", err)
+			}
+
+			visit := models.Visit{
+				ID:     strconv.FormatUint(seq, 10),
+				UserID: userID,
+				Start:  at,
+				End:    at,
+			}
+			if err := putVisit(visits, visit); err != nil {
+				return err
+			}
+
+			if err := latest.Put([]byte(userID), visitKey(visit)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Between returns visits overlapping given time range,
+// sorted by start time.
+func (s *VisitsStorage) Between(ctx context.Context, from, to time.Time) ([]models.Visit, error) {
+	res := []models.Visit{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(visitsBucket)).Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			visit := models.Visit{}
+			if err := json.Unmarshal(v, &visit); err != nil {
+				return fmt.Errorf("json.Unmarshal: %w", err)
+			}
+
+			// Visits are sorted by start time, so there are
+			// no more visits in given range.
+			if !visit.Start.Before(to) {
+				break
+			}
+
+			if !visit.End.Before(from) {
+				res = append(res, visit)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading visits failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// HistoryStorage implements storage.History interface
+// for bolt database.
+type HistoryStorage struct {
+	db *bolt.DB
+}
+
+// snapshotKey returns key for snapshot taken at given time.
+// Keys are sorted by time.
+func snapshotKey(t time.Time) []byte {
+	return []byte(fmt.Sprintf("%020d", t.UnixNano()))
+}
+
+// Add stores given snapshot.
+func (s *HistoryStorage) Add(ctx context.Context, snapshot models.Snapshot) error {
+	dat, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(historyBucket)).Put(snapshotKey(snapshot.Time), dat)
+	})
+}
+
+// Between returns snapshots taken in given time range,
+// sorted by time.
+func (s *HistoryStorage) Between(ctx context.Context, from, to time.Time) ([]models.Snapshot, error) {
+	res := []models.Snapshot{}
+	end := snapshotKey(to)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(historyBucket)).Cursor()
+
+		for k, v := c.Seek(snapshotKey(from)); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			snapshot := models.Snapshot{}
+			if err := json.Unmarshal(v, &snapshot); err != nil {
+				return fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			res = append(res, snapshot)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshots failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// Prune removes snapshots taken before given time.
+func (s *HistoryStorage) Prune(ctx context.Context, before time.Time) error {
+	end := snapshotKey(before)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(historyBucket))
+		c := b.Cursor()
+
+		// Deleting with cursor while iterating skips keys,
+		// so keys are collected first.
+		keys := [][]byte{}
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			keys = append(keys, k)
+		}
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SubscriptionsStorage implements storage.Subscriptions
+// interface for bolt database.
+type SubscriptionsStorage struct {
+	db *bolt.DB
+}
+
+func forEachSubscription(tx *bolt.Tx, f func(models.Subscription) error) error {
+	b := tx.Bucket([]byte(subscriptionsBucket))
+	return b.ForEach(func(k, v []byte) error {
+		sub := models.Subscription{}
+		if err := json.Unmarshal(v, &sub); err != nil {
+			return fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		return f(sub)
+	})
+}
+
+// New stores given subscription and returns its id.
+func (s *SubscriptionsStorage) New(ctx context.Context, sub models.Subscription) (string, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		exists, err := userExists(tx, sub.UserID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("there is no user with id=%s, err=%w", sub.UserID, serrors.ErrNoID)
+		}
+
+		dat, err := json.Marshal(sub)
+		if err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+
+		return tx.Bucket([]byte(subscriptionsBucket)).Put([]byte(sub.ID), dat)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return sub.ID, nil
+}
+
+// OfUser returns subscriptions of user with given id.
+func (s *SubscriptionsStorage) OfUser(ctx context.Context, userID string) ([]models.Subscription, error) {
+	res := []models.Subscription{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachSubscription(tx, func(sub models.Subscription) error {
+			if sub.UserID == userID {
+				res = append(res, sub)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading subscriptions of user with id=%s failed: %w", userID, err)
+	}
+
+	return res, nil
+}
+
+// All returns slice with every subscription.
+func (s *SubscriptionsStorage) All(ctx context.Context) ([]models.Subscription, error) {
+	res := []models.Subscription{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEachSubscription(tx, func(sub models.Subscription) error {
+			res = append(res, sub)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading all subscriptions failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// Remove deletes subscription with given id.
+func (s *SubscriptionsStorage) Remove(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(subscriptionsBucket))
+
+		if b.Get([]byte(id)) == nil {
+			return serrors.ErrNoID
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// KeysStorage implements storage.Keys
+// interface for bolt database.
+type KeysStorage struct {
+	db *bolt.DB
+}
+
+// Read returns key with given name.
+func (k *KeysStorage) Read(ctx context.Context, name string) ([]byte, error) {
+	var res []byte
+
+	err := k.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket([]byte(keysBucket)).Get([]byte(name))
+		if key == nil {
+			return fmt.Errorf("there is no key with name=%s: %w", name, serrors.ErrNoID)
+		}
+
+		// Value returned by bolt is valid only during
+		// transaction, so it has to be copied.
+		res = append([]byte{}, key...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Save stores key with given name, replacing
+// previous one.
+func (k *KeysStorage) Save(ctx context.Context, name string, key []byte) error {
+	return k.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(keysBucket)).Put([]byte(name), key)
+	})
+}
+
+// ScannersStorage implements storage.Scanners interface
+// for bolt database.
+type ScannersStorage struct {
+	db *bolt.DB
+}
+
+func readScanner(tx *bolt.Tx, id string) (*models.ScannerKey, error) {
+	dat := tx.Bucket([]byte(scannersBucket)).Get([]byte(id))
+	if dat == nil {
+		return nil, fmt.Errorf("there is no scanner with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	res := new(models.ScannerKey)
+	if err := json.Unmarshal(dat, res); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return res, nil
+}
+
+func putScanner(tx *bolt.Tx, k models.ScannerKey) error {
+	dat, err := json.Marshal(k)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	return tx.Bucket([]byte(scannersBucket)).Put([]byte(k.ID), dat)
+}
+
+// New stores given key and returns its id.
+func (s *ScannersStorage) New(ctx context.Context, k models.ScannerKey) (string, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putScanner(tx, k)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return k.ID, nil
+}
+
+// Read returns key with given id.
+func (s *ScannersStorage) Read(ctx context.Context, id string) (*models.ScannerKey, error) {
+	var res *models.ScannerKey
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		res, err = readScanner(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// All returns slice with every key.
+func (s *ScannersStorage) All(ctx context.Context) ([]models.ScannerKey, error) {
+	res := []models.ScannerKey{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(scannersBucket)).ForEach(func(k, v []byte) error {
+			key := models.ScannerKey{}
+			if err := json.Unmarshal(v, &key); err != nil {
+				return fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			res = append(res, key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading all scanners failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// Update applies given function to key with given id.
+func (s *ScannersStorage) Update(ctx context.Context, id string, f func(*models.ScannerKey) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		k, err := readScanner(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := f(k); err != nil {
+			return err
+		}
+		k.ID = id
+		return putScanner(tx, *k)
+	})
+}
+
+// Remove deletes key with given id.
+func (s *ScannersStorage) Remove(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(scannersBucket))
+
+		if b.Get([]byte(id)) == nil {
+			return serrors.ErrNoID
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// SettingsStorage implements storage.Settings
+// interface for bolt database.
+type SettingsStorage struct {
+	db *bolt.DB
+}
+
+// Read returns value of setting with given name.
+func (s *SettingsStorage) Read(ctx context.Context, name string) (string, error) {
+	var res string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket([]byte(settingsBucket)).Get([]byte(name))
+		if value == nil {
+			return fmt.Errorf("there is no setting with name=%s: %w", name, serrors.ErrNoID)
+		}
+
+		res = string(value)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return res, nil
+}
+
+// Save stores value of setting with given name,
+// replacing previous one.
+func (s *SettingsStorage) Save(ctx context.Context, name string, value string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(settingsBucket)).Put([]byte(name), []byte(value))
+	})
+}
+
+// InvitesStorage implements storage.Invites interface
+// for bolt database.
+type InvitesStorage struct {
+	db *bolt.DB
+}
+
+func readInvite(tx *bolt.Tx, id string) (*models.Invite, error) {
+	dat := tx.Bucket([]byte(invitesBucket)).Get([]byte(id))
+	if dat == nil {
+		return nil, fmt.Errorf("there is no invite with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	res := new(models.Invite)
+	if err := json.Unmarshal(dat, res); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return res, nil
+}
+
+func putInvite(tx *bolt.Tx, i models.Invite) error {
+	dat, err := json.Marshal(i)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	return tx.Bucket([]byte(invitesBucket)).Put([]byte(i.ID), dat)
+}
+
+// New stores given invite and returns its id.
+func (s *InvitesStorage) New(ctx context.Context, i models.Invite) (string, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putInvite(tx, i)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return i.ID, nil
+}
+
+// Read returns invite with given id.
+func (s *InvitesStorage) Read(ctx context.Context, id string) (*models.Invite, error) {
+	var res *models.Invite
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		res, err = readInvite(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// filter returns invites, that passed given test.
+func (s *InvitesStorage) filter(test func(models.Invite) bool) ([]models.Invite, error) {
+	res := []models.Invite{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(invitesBucket)).ForEach(func(k, v []byte) error {
+			invite := models.Invite{}
+			if err := json.Unmarshal(v, &invite); err != nil {
+				return fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			if test(invite) {
+				res = append(res, invite)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading invites failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// OfUser returns invites created by user with given id.
+func (s *InvitesStorage) OfUser(ctx context.Context, userID string) ([]models.Invite, error) {
+	return s.filter(func(i models.Invite) bool {
+		return i.CreatorID == userID
+	})
+}
+
+// All returns slice with every invite.
+func (s *InvitesStorage) All(ctx context.Context) ([]models.Invite, error) {
+	return s.filter(func(models.Invite) bool {
+		return true
+	})
+}
+
+// Update applies given function to invite with given id.
+func (s *InvitesStorage) Update(ctx context.Context, id string, f func(*models.Invite) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		i, err := readInvite(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := f(i); err != nil {
+			return err
+		}
+		i.ID = id
+		return putInvite(tx, *i)
+	})
+}
+
+// Remove deletes invite with given id.
+func (s *InvitesStorage) Remove(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(invitesBucket))
+
+		if b.Get([]byte(id)) == nil {
+			return serrors.ErrNoID
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// ResetTokensStorage implements storage.ResetTokens
+// interface for bolt database.
+type ResetTokensStorage struct {
+	db *bolt.DB
+}
+
+// New stores given token and returns its id.
+func (s *ResetTokensStorage) New(ctx context.Context, t models.ResetToken) (string, error) {
+	dat, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(resetTokensBucket)).Put([]byte(t.ID), dat)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return t.ID, nil
+}
+
+// Read returns token with given id.
+func (s *ResetTokensStorage) Read(ctx context.Context, id string) (*models.ResetToken, error) {
+	res := new(models.ResetToken)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		dat := tx.Bucket([]byte(resetTokensBucket)).Get([]byte(id))
+		if dat == nil {
+			return fmt.Errorf("there is no reset token with id=%s: %w", id, serrors.ErrNoID)
+		}
+		return json.Unmarshal(dat, res)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// OfUser returns tokens for user with given id.
+func (s *ResetTokensStorage) OfUser(ctx context.Context, userID string) ([]models.ResetToken, error) {
+	res := []models.ResetToken{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(resetTokensBucket)).ForEach(func(k, v []byte) error {
+			t := models.ResetToken{}
+			if err := json.Unmarshal(v, &t); err != nil {
+				return fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			if t.UserID == userID {
+				res = append(res, t)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading reset tokens failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// Remove deletes token with given id.
+func (s *ResetTokensStorage) Remove(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(resetTokensBucket))
+
+		if b.Get([]byte(id)) == nil {
+			return fmt.Errorf("there is no reset token with id=%s: %w", id, serrors.ErrNoID)
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// APITokensStorage implements storage.APITokens
+// interface for bolt database.
+type APITokensStorage struct {
+	db *bolt.DB
+}
+
+func readAPIToken(tx *bolt.Tx, id string) (*models.APIToken, error) {
+	dat := tx.Bucket([]byte(apiTokensBucket)).Get([]byte(id))
+	if dat == nil {
+		return nil, fmt.Errorf("there is no api token with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	res := new(models.APIToken)
+	if err := json.Unmarshal(dat, res); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return res, nil
+}
+
+func putAPIToken(tx *bolt.Tx, t models.APIToken) error {
+	dat, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	return tx.Bucket([]byte(apiTokensBucket)).Put([]byte(t.ID), dat)
+}
+
+// New stores given token and returns its id.
+func (s *APITokensStorage) New(ctx context.Context, t models.APIToken) (string, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putAPIToken(tx, t)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return t.ID, nil
+}
+
+// Read returns token with given id.
+func (s *APITokensStorage) Read(ctx context.Context, id string) (*models.APIToken, error) {
+	var res *models.APIToken
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		res, err = readAPIToken(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// OfUser returns tokens of user with given id.
+func (s *APITokensStorage) OfUser(ctx context.Context, userID string) ([]models.APIToken, error) {
+	res := []models.APIToken{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(apiTokensBucket)).ForEach(func(k, v []byte) error {
+			t := models.APIToken{}
+			if err := json.Unmarshal(v, &t); err != nil {
+				return fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			if t.UserID == userID {
+				res = append(res, t)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading api tokens failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// Update applies given function to token with given id.
+func (s *APITokensStorage) Update(ctx context.Context, id string, f func(*models.APIToken) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		t, err := readAPIToken(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := f(t); err != nil {
+			return err
+		}
+		t.ID = id
+		return putAPIToken(tx, *t)
+	})
+}
+
+// Remove deletes token with given id.
+func (s *APITokensStorage) Remove(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(apiTokensBucket))
+
+		if b.Get([]byte(id)) == nil {
+			return fmt.Errorf("there is no api token with id=%s: %w", id, serrors.ErrNoID)
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// SessionsStorage implements storage.Sessions
+// interface for bolt database.
+type SessionsStorage struct {
+	db *bolt.DB
+}
+
+func readSession(tx *bolt.Tx, id string) (*models.Session, error) {
+	dat := tx.Bucket([]byte(sessionsBucket)).Get([]byte(id))
+	if dat == nil {
+		return nil, fmt.Errorf("there is no session with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	res := new(models.Session)
+	if err := json.Unmarshal(dat, res); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	return res, nil
+}
+
+func putSession(tx *bolt.Tx, s models.Session) error {
+	dat, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	return tx.Bucket([]byte(sessionsBucket)).Put([]byte(s.ID), dat)
+}
+
+// New stores given session and returns its id.
+func (s *SessionsStorage) New(ctx context.Context, session models.Session) (string, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putSession(tx, session)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return session.ID, nil
+}
+
+// Read returns session with given id.
+func (s *SessionsStorage) Read(ctx context.Context, id string) (*models.Session, error) {
+	var res *models.Session
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		res, err = readSession(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// OfUser returns sessions of user with given id.
+func (s *SessionsStorage) OfUser(ctx context.Context, userID string) ([]models.Session, error) {
+	res := []models.Session{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(sessionsBucket)).ForEach(func(k, v []byte) error {
+			session := models.Session{}
+			if err := json.Unmarshal(v, &session); err != nil {
+				return fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			if session.UserID == userID {
+				res = append(res, session)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading sessions failed: %w", err)
+	}
+
+	return res, nil
+}
+
+// Update applies given function to session with given id.
+func (s *SessionsStorage) Update(ctx context.Context, id string, f func(*models.Session) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		session, err := readSession(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := f(session); err != nil {
+			return err
+		}
+		session.ID = id
+		return putSession(tx, *session)
+	})
+}
+
+// Remove deletes session with given id.
+func (s *SessionsStorage) Remove(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(sessionsBucket))
+
+		if b.Get([]byte(id)) == nil {
+			return fmt.Errorf("there is no session with id=%s: %w", id, serrors.ErrNoID)
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// AuditStorage implements storage.Audit interface
+// for bolt database.
+type AuditStorage struct {
+	db *bolt.DB
+}
+
+// auditKey returns key of given entry. Keys are sorted
+// by time.
+func auditKey(e models.AuditEntry) []byte {
+	return []byte(fmt.Sprintf("%020d::%s", e.Time.UnixNano(), e.ID))
+}
+
+// Add stores given entry.
+func (s *AuditStorage) Add(ctx context.Context, e models.AuditEntry) error {
+	dat, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(auditBucket)).Put(auditKey(e), dat)
+	})
+}
+
+// Between returns entries of actions performed in given
+// time range, sorted by time.
+func (s *AuditStorage) Between(ctx context.Context, from, to time.Time) ([]models.AuditEntry, error) {
+	res := []models.AuditEntry{}
+	start := snapshotKey(from)
+	end := snapshotKey(to)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(auditBucket)).Cursor()
+
+		for k, v := c.Seek(start); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			e := models.AuditEntry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("json.Unmarshal: %w", err)
+			}
+			res = append(res, e)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading audit log failed: %w", err)
+	}
+
+	return res, nil
+}
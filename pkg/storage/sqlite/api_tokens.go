@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// APITokens storage implements storage.APITokens
+// interface for sqlite database.
+type APITokens struct {
+	cs *coreStorage
+}
+
+// New stores given token and returns its id.
+func (a *APITokens) New(ctx context.Context, t models.APIToken) (string, error) {
+	return a.cs.newAPIToken(ctx, t)
+}
+
+// Read returns token with given id.
+func (a *APITokens) Read(ctx context.Context, id string) (*models.APIToken, error) {
+	return a.cs.readAPIToken(ctx, id)
+}
+
+// OfUser returns tokens of user with given id.
+func (a *APITokens) OfUser(ctx context.Context, userID string) ([]models.APIToken, error) {
+	return a.cs.queryAPITokens(ctx, "apiTokenUserID = $1", userID)
+}
+
+// Update applies given function to token with given id.
+func (a *APITokens) Update(ctx context.Context, id string, f func(*models.APIToken) error) error {
+	return a.cs.updateAPIToken(ctx, id, f)
+}
+
+// Remove deletes token with given id.
+func (a *APITokens) Remove(ctx context.Context, id string) error {
+	return a.cs.removeAPIToken(ctx, id)
+}
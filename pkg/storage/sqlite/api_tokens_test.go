@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestAPITokens(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice", HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm")},
+		{ID: "2", Nickname: "bob", HashedPassword: []byte("u8dXHRi0JNo23JVeHkjh")},
+	} {
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	created := time.Unix(1700000000, 0)
+	tokensData := map[string]models.APIToken{
+		"a": {
+			ID: "a", UserID: "1", Name: "door bot", Secret: []byte("secret-a"),
+			Scopes:  []models.Scope{models.ScopeCheckIn, models.ScopeReadStatus},
+			Created: created, Expires: created.Add(time.Hour),
+		},
+		"b": {
+			ID: "b", UserID: "1", Name: "script", Secret: []byte("secret-b"),
+			Scopes: []models.Scope{}, Created: created,
+		},
+		"c": {
+			ID: "c", UserID: "2", Name: "phone", Secret: []byte("secret-c"),
+			Scopes:  []models.Scope{models.ScopeManageDevices},
+			Created: created,
+		},
+	}
+
+	tokens := f.APITokens()
+	for _, tok := range tokensData {
+		id, err := tokens.New(ctx, tok)
+		is.NoErr(err)
+		is.Equal(id, tok.ID)
+	}
+
+	ofAlice, err := tokens.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(ofAlice), 2)
+
+	for _, tok := range ofAlice {
+		current, ok := tokensData[tok.ID]
+		is.True(ok)
+		is.Equal(current.UserID, tok.UserID)
+		is.Equal(current.Name, tok.Name)
+		is.Equal(current.Scopes, tok.Scopes)
+		is.Equal(current.Secret, tok.Secret)
+		is.True(current.Created.Equal(tok.Created))
+		is.True(current.Expires.Equal(tok.Expires))
+		is.True(tok.LastUsed.IsZero())
+	}
+
+	used := created.Add(time.Minute)
+	err = tokens.Update(ctx, "c", func(t *models.APIToken) error {
+		t.LastUsed = used
+		return nil
+	})
+	is.NoErr(err)
+
+	tok, err := tokens.Read(ctx, "c")
+	is.NoErr(err)
+	is.Equal(tok.UserID, "2")
+	is.True(tok.LastUsed.Equal(used))
+
+	is.NoErr(tokens.Remove(ctx, "c"))
+	is.True(errors.Is(tokens.Remove(ctx, "c"), serrors.ErrNoID))
+
+	_, err = tokens.Read(ctx, "c")
+	is.True(errors.Is(err, serrors.ErrNoID))
+
+	// Tokens are removed together with their user.
+	is.NoErr(f.Users().Remove(ctx, "1"))
+	ofAlice, err = tokens.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(ofAlice), 0)
+}
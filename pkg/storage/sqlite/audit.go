@@ -0,0 +1,25 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Audit storage implements storage.Audit interface for
+// sqlite database.
+type Audit struct {
+	cs *coreStorage
+}
+
+// Add stores given entry.
+func (a *Audit) Add(ctx context.Context, e models.AuditEntry) error {
+	return a.cs.addAuditEntry(ctx, e)
+}
+
+// Between returns entries of actions performed in given
+// time range, sorted by time.
+func (a *Audit) Between(ctx context.Context, from, to time.Time) ([]models.AuditEntry, error) {
+	return a.cs.auditBetween(ctx, from, to)
+}
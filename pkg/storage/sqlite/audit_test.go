@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+func TestAudit(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	audit := f.Audit()
+	start := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		is.NoErr(audit.Add(ctx, models.AuditEntry{
+			ID:       fmt.Sprint(i),
+			Time:     start.Add(time.Duration(i) * time.Hour),
+			ActorID:  "1",
+			Action:   "password-reset:issue",
+			TargetID: "2",
+		}))
+	}
+
+	entries, err := audit.Between(ctx, start.Add(time.Hour), start.Add(3*time.Hour))
+	is.NoErr(err)
+	is.Equal(len(entries), 2)
+	for i, e := range entries {
+		is.Equal(e.ID, fmt.Sprint(i+1))
+		is.Equal(e.ActorID, "1")
+		is.Equal(e.Action, "password-reset:issue")
+		is.Equal(e.TargetID, "2")
+		is.True(e.Time.Equal(start.Add(time.Duration(i+1) * time.Hour)))
+	}
+}
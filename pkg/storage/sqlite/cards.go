@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Cards storage implements storage.Cards interface for
+// sqlite database.
+type Cards struct {
+	cs *coreStorage
+}
+
+// New stores given card of user with given id and returns
+// card id.
+func (c *Cards) New(ctx context.Context, userID string, card models.Card) (string, error) {
+	return c.cs.newCard(ctx, userID, card)
+}
+
+// OfUser returns cards of user with given id.
+func (c *Cards) OfUser(ctx context.Context, userID string) ([]models.Card, error) {
+	return c.cs.cardsOfUser(ctx, userID)
+}
+
+// All returns slice with every card.
+func (c *Cards) All(ctx context.Context) ([]models.Card, error) {
+	return c.cs.allCards(ctx)
+}
+
+// Remove deletes card with given id.
+func (c *Cards) Remove(ctx context.Context, id string) error {
+	return c.cs.removeCard(ctx, id)
+}
@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestCards(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "johnny", HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm")},
+		{ID: "2", Nickname: "marco", HashedPassword: []byte("u8dXHRi0JNo23JVeHkjh")},
+	} {
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	cardsData := map[string]models.Card{
+		"1": {ID: "1", OwnerID: "1", Tag: "student id", UID: []byte("04a25b1a")},
+		"2": {ID: "2", OwnerID: "1", Tag: "keyfob", UID: []byte("9a8b7c6d")},
+		"3": {ID: "3", OwnerID: "2", Tag: "bus pass", UID: []byte("04112233445566")},
+	}
+
+	sc := f.Cards()
+	for _, c := range cardsData {
+		id, err := sc.New(ctx, c.OwnerID, c)
+		is.NoErr(err)
+		is.Equal(id, c.ID)
+	}
+
+	// Try to register the same card twice.
+	_, err = sc.New(ctx, "2", models.Card{ID: "4", UID: []byte("04a25b1a")})
+	is.True(errors.Is(err, serrors.ErrCardDuplication))
+
+	// Try to add card of user that doesn't exist.
+	_, err = sc.New(ctx, "5", models.Card{ID: "5", UID: []byte("ffffffff")})
+	is.True(err != nil)
+
+	all, err := sc.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), len(cardsData))
+
+	for _, c := range all {
+		current, ok := cardsData[c.ID]
+		is.True(ok)
+		is.Equal(current, c)
+	}
+
+	johnnyCards, err := sc.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(johnnyCards), 2)
+
+	is.NoErr(sc.Remove(ctx, "1"))
+	is.True(errors.Is(sc.Remove(ctx, "1"), serrors.ErrNoID))
+
+	// Cards are removed along with theirs owner.
+	is.NoErr(f.Users().Remove(ctx, "2"))
+
+	all, err = sc.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), 1)
+	is.Equal(all[0].ID, "2")
+}
@@ -37,3 +37,9 @@ func (d *Devices) All(ctx context.Context) ([]models.Device, error) {
 func (d *Devices) Remove(ctx context.Context, id string) error {
 	return d.cs.removeDevice(ctx, id)
 }
+
+// Update overwrites existing device data with changes
+// applied by given function.
+func (d *Devices) Update(ctx context.Context, id string, f func(*models.Device) error) error {
+	return d.cs.updateDevice(ctx, id, f)
+}
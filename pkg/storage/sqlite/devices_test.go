@@ -2,12 +2,14 @@ package sqlite
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/matryer/is"
 
 	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
 )
 
 func TestDevices(t *testing.T) {
@@ -135,3 +137,211 @@ func TestDevices(t *testing.T) {
 	_, err = sd.New(ctx, "5", models.Device{})
 	is.True(err != nil)
 }
+
+func TestDevicesUpdate(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "johnny",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+	})
+	is.NoErr(err)
+
+	sd := f.Devices()
+	_, err = sd.New(ctx, "1", models.Device{
+		DevicePublicData: models.DevicePublicData{
+			ID:  "1",
+			Tag: "phone",
+		},
+		OwnerID: "1",
+		MAC:     []byte("11:11:11:11:11:11"),
+		MACs:    [][]byte{[]byte("22:22:22:22:22:22")},
+	})
+	is.NoErr(err)
+
+	d, err := sd.Read(ctx, "1")
+	is.NoErr(err)
+	is.Equal(d.MACs, [][]byte{[]byte("22:22:22:22:22:22")})
+	is.Equal(d.Hostname, "")
+	is.True(d.ClientID == nil)
+
+	err = sd.Update(ctx, "1", func(d *models.Device) error {
+		d.Tag = "pixel"
+		d.MACs = append(d.MACs, []byte("33:33:33:33:33:33"))
+		d.Hostname = "pixel-*"
+		d.ClientID = []byte("client")
+		return nil
+	})
+	is.NoErr(err)
+
+	d, err = sd.Read(ctx, "1")
+	is.NoErr(err)
+	is.Equal(d.Tag, "pixel")
+	is.Equal(d.Owner, "johnny")
+	is.Equal(d.MAC, []byte("11:11:11:11:11:11"))
+	is.Equal(d.MACs, [][]byte{
+		[]byte("22:22:22:22:22:22"),
+		[]byte("33:33:33:33:33:33"),
+	})
+	is.Equal(d.Hostname, "pixel-*")
+	is.Equal(d.ClientID, []byte("client"))
+
+	err = sd.Update(ctx, "2", func(d *models.Device) error { return nil })
+	is.True(errors.Is(err, serrors.ErrNoID))
+
+	// Additional MACs are removed along with device.
+	is.NoErr(sd.Remove(ctx, "1"))
+	all, err := sd.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), 0)
+}
+
+func TestDevicesLookups(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "johnny",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+	})
+	is.NoErr(err)
+
+	sd := f.Devices()
+	device := func(id string, lookups ...string) models.Device {
+		d := models.Device{
+			DevicePublicData: models.DevicePublicData{
+				ID:  id,
+				Tag: "device-" + id,
+			},
+			OwnerID: "1",
+			MAC:     []byte("mac-" + id),
+		}
+		for _, l := range lookups {
+			d.Lookups = append(d.Lookups, []byte(l))
+		}
+		return d
+	}
+
+	_, err = sd.New(ctx, "1", device("1", "one"))
+	is.NoErr(err)
+
+	d, err := sd.Read(ctx, "1")
+	is.NoErr(err)
+	is.Equal(d.Lookups, [][]byte{[]byte("one")})
+
+	// Lookup hash used by other device is rejected and
+	// nothing is stored.
+	_, err = sd.New(ctx, "1", device("2", "two", "one"))
+	is.True(errors.Is(err, serrors.ErrMACDuplication))
+
+	_, err = sd.Read(ctx, "2")
+	is.True(errors.Is(err, serrors.ErrNoID))
+
+	_, err = sd.New(ctx, "1", device("2", "two"))
+	is.NoErr(err)
+
+	err = sd.Update(ctx, "2", func(d *models.Device) error {
+		d.Lookups = append(d.Lookups, []byte("one"))
+		return nil
+	})
+	is.True(errors.Is(err, serrors.ErrMACDuplication))
+
+	err = sd.Update(ctx, "2", func(d *models.Device) error {
+		d.Lookups = append(d.Lookups, []byte("three"))
+		return nil
+	})
+	is.NoErr(err)
+
+	d, err = sd.Read(ctx, "2")
+	is.NoErr(err)
+	is.Equal(d.Lookups, [][]byte{[]byte("two"), []byte("three")})
+
+	// Lookups of removed devices can be used again.
+	is.NoErr(sd.Remove(ctx, "1"))
+	_, err = sd.New(ctx, "1", device("3", "one"))
+	is.NoErr(err)
+}
+
+func TestDevicesIdentifiers(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	_, err = f.Users().New(ctx, storage.UserEntry{
+		ID:             "1",
+		Nickname:       "johnny",
+		HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
+	})
+	is.NoErr(err)
+
+	sd := f.Devices()
+	for _, id := range []string{"1", "2"} {
+		_, err = sd.New(ctx, "1", models.Device{
+			DevicePublicData: models.DevicePublicData{ID: id, Tag: "device-" + id},
+			OwnerID:          "1",
+			MAC:              []byte("mac-" + id),
+		})
+		is.NoErr(err)
+	}
+
+	err = sd.Update(ctx, "1", func(d *models.Device) error {
+		d.Hostname = "Pixel-7"
+		d.ClientIDLookup = []byte("client")
+		return nil
+	})
+	is.NoErr(err)
+
+	// Device can keep its own identifiers.
+	err = sd.Update(ctx, "1", func(d *models.Device) error {
+		d.Tag = "pixel"
+		return nil
+	})
+	is.NoErr(err)
+
+	err = sd.Update(ctx, "2", func(d *models.Device) error {
+		d.Hostname = "pixel-7"
+		return nil
+	})
+	is.True(errors.Is(err, serrors.ErrHostnameTaken))
+
+	err = sd.Update(ctx, "2", func(d *models.Device) error {
+		d.ClientIDLookup = []byte("client")
+		return nil
+	})
+	is.True(errors.Is(err, serrors.ErrClientIDTaken))
+
+	_, err = sd.New(ctx, "1", models.Device{
+		DevicePublicData: models.DevicePublicData{ID: "3", Tag: "device-3"},
+		OwnerID:          "1",
+		MAC:              []byte("mac-3"),
+		Hostname:         "PIXEL-7",
+	})
+	is.True(errors.Is(err, serrors.ErrHostnameTaken))
+
+	// Empty identifiers are never taken.
+	_, err = sd.New(ctx, "1", models.Device{
+		DevicePublicData: models.DevicePublicData{ID: "3", Tag: "device-3"},
+		OwnerID:          "1",
+		MAC:              []byte("mac-3"),
+	})
+	is.NoErr(err)
+
+	d, err := sd.Read(ctx, "1")
+	is.NoErr(err)
+	is.Equal(d.Hostname, "Pixel-7")
+	is.Equal(d.ClientIDLookup, []byte("client"))
+}
@@ -0,0 +1,30 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// History storage implements storage.History interface for
+// sqlite database.
+type History struct {
+	cs *coreStorage
+}
+
+// Add stores given snapshot.
+func (h *History) Add(ctx context.Context, s models.Snapshot) error {
+	return h.cs.addSnapshot(ctx, s)
+}
+
+// Between returns snapshots taken in given time range,
+// sorted by time.
+func (h *History) Between(ctx context.Context, from, to time.Time) ([]models.Snapshot, error) {
+	return h.cs.snapshotsBetween(ctx, from, to)
+}
+
+// Prune removes snapshots taken before given time.
+func (h *History) Prune(ctx context.Context, before time.Time) error {
+	return h.cs.pruneSnapshots(ctx, before)
+}
@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+func TestHistory(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	sh := f.History()
+	start := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 6; i++ {
+		is.NoErr(sh.Add(ctx, models.Snapshot{
+			Time:   start.Add(time.Duration(i) * time.Hour),
+			Online: i,
+		}))
+	}
+
+	snapshots, err := sh.Between(ctx, start.Add(time.Hour), start.Add(4*time.Hour))
+	is.NoErr(err)
+	is.Equal(len(snapshots), 3)
+	for i, s := range snapshots {
+		is.Equal(s.Online, i+1)
+		is.True(s.Time.Equal(start.Add(time.Duration(i+1) * time.Hour)))
+	}
+
+	is.NoErr(sh.Prune(ctx, start.Add(2*time.Hour)))
+	snapshots, err = sh.Between(ctx, start, start.Add(24*time.Hour))
+	is.NoErr(err)
+	is.Equal(len(snapshots), 4)
+	is.Equal(snapshots[0].Online, 2)
+}
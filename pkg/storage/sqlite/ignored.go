@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Ignored storage implements storage.Ignored interface for
+// sqlite database.
+type Ignored struct {
+	cs *coreStorage
+}
+
+// New stores given ignored address and returns its id.
+func (i *Ignored) New(ctx context.Context, a models.IgnoredAddress) (string, error) {
+	return i.cs.newIgnored(ctx, a)
+}
+
+// All returns slice with every ignored address.
+func (i *Ignored) All(ctx context.Context) ([]models.IgnoredAddress, error) {
+	return i.cs.allIgnored(ctx)
+}
+
+// Remove deletes ignored address with given id.
+func (i *Ignored) Remove(ctx context.Context, id string) error {
+	return i.cs.removeIgnored(ctx, id)
+}
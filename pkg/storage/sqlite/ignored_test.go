@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestIgnored(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	ignoredData := map[string]models.IgnoredAddress{
+		"1": {
+			ID:     "1",
+			Tag:    "raspberry pi",
+			Prefix: []byte{0xb8, 0x27, 0xeb},
+		},
+		"2": {
+			ID:     "2",
+			Tag:    "printer",
+			Prefix: []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		},
+	}
+
+	si := f.Ignored()
+	for _, i := range ignoredData {
+		id, err := si.New(ctx, i)
+		is.NoErr(err)
+		is.Equal(id, i.ID)
+	}
+
+	// Try to ignore the same prefix twice.
+	_, err = si.New(ctx, models.IgnoredAddress{
+		ID:     "3",
+		Tag:    "another raspberry pi",
+		Prefix: []byte{0xb8, 0x27, 0xeb},
+	})
+	is.True(errors.Is(err, serrors.ErrPrefixDuplication))
+
+	all, err := si.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), len(ignoredData))
+
+	for _, i := range all {
+		current, ok := ignoredData[i.ID]
+		is.True(ok)
+		is.Equal(current.Tag, i.Tag)
+		is.Equal(current.Prefix, i.Prefix)
+	}
+
+	is.NoErr(si.Remove(ctx, "1"))
+	is.True(errors.Is(si.Remove(ctx, "1"), serrors.ErrNoID))
+
+	all, err = si.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), 1)
+	is.Equal(all[0].ID, "2")
+}
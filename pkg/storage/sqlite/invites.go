@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Invites storage implements storage.Invites interface
+// for sqlite database.
+type Invites struct {
+	cs *coreStorage
+}
+
+// New stores given invite and returns its id.
+func (i *Invites) New(ctx context.Context, invite models.Invite) (string, error) {
+	return i.cs.newInvite(ctx, invite)
+}
+
+// Read returns invite with given id.
+func (i *Invites) Read(ctx context.Context, id string) (*models.Invite, error) {
+	return i.cs.readInvite(ctx, id)
+}
+
+// OfUser returns invites created by user with given id.
+func (i *Invites) OfUser(ctx context.Context, userID string) ([]models.Invite, error) {
+	return i.cs.queryInvites(ctx, "inviteCreatorID = $1", userID)
+}
+
+// All returns slice with every invite.
+func (i *Invites) All(ctx context.Context) ([]models.Invite, error) {
+	return i.cs.queryInvites(ctx, "1 = 1")
+}
+
+// Update applies given function to invite with given id.
+func (i *Invites) Update(ctx context.Context, id string, f func(*models.Invite) error) error {
+	return i.cs.updateInvite(ctx, id, f)
+}
+
+// Remove deletes invite with given id.
+func (i *Invites) Remove(ctx context.Context, id string) error {
+	return i.cs.removeInvite(ctx, id)
+}
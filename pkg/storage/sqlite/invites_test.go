@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestInvites(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice", HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm")},
+		{ID: "2", Nickname: "bob", HashedPassword: []byte("u8dXHRi0JNo23JVeHkjh")},
+	} {
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	created := time.Unix(1700000000, 0)
+	invitesData := map[string]models.Invite{
+		"a": {ID: "a", CreatorID: "1", MaxUses: 1, Created: created},
+		"b": {ID: "b", CreatorID: "1", Created: created, Expires: created.Add(time.Hour)},
+		"c": {ID: "c", CreatorID: "2", MaxUses: 5, Uses: 2, Created: created},
+	}
+
+	invites := f.Invites()
+	for _, i := range invitesData {
+		id, err := invites.New(ctx, i)
+		is.NoErr(err)
+		is.Equal(id, i.ID)
+	}
+
+	all, err := invites.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), len(invitesData))
+
+	for _, i := range all {
+		current, ok := invitesData[i.ID]
+		is.True(ok)
+		is.Equal(current.CreatorID, i.CreatorID)
+		is.Equal(current.MaxUses, i.MaxUses)
+		is.Equal(current.Uses, i.Uses)
+		is.True(current.Created.Equal(i.Created))
+		is.True(current.Expires.Equal(i.Expires))
+	}
+
+	ofAlice, err := invites.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(ofAlice), 2)
+
+	is.NoErr(invites.Update(ctx, "c", func(i *models.Invite) error {
+		i.Uses++
+		return nil
+	}))
+	i, err := invites.Read(ctx, "c")
+	is.NoErr(err)
+	is.Equal(i.Uses, 3)
+
+	is.NoErr(invites.Remove(ctx, "a"))
+	is.True(errors.Is(invites.Remove(ctx, "a"), serrors.ErrNoID))
+
+	_, err = invites.Read(ctx, "a")
+	is.True(errors.Is(err, serrors.ErrNoID))
+	is.True(errors.Is(invites.Update(ctx, "a", func(i *models.Invite) error {
+		return nil
+	}), serrors.ErrNoID))
+
+	// Invites are removed together with their creator.
+	is.NoErr(f.Users().Remove(ctx, "2"))
+	_, err = invites.Read(ctx, "c")
+	is.True(errors.Is(err, serrors.ErrNoID))
+}
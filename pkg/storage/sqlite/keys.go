@@ -0,0 +1,20 @@
+package sqlite
+
+import "context"
+
+// Keys storage implements storage.Keys
+// interface for sqlite database.
+type Keys struct {
+	cs *coreStorage
+}
+
+// Read returns key with given name.
+func (k *Keys) Read(ctx context.Context, name string) ([]byte, error) {
+	return k.cs.readKey(ctx, name)
+}
+
+// Save stores key with given name, replacing
+// previous one.
+func (k *Keys) Save(ctx context.Context, name string, key []byte) error {
+	return k.cs.saveKey(ctx, name, key)
+}
@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestKeys(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	sk := f.Keys()
+
+	_, err = sk.Read(ctx, "vapid")
+	is.True(errors.Is(err, serrors.ErrNoID))
+
+	is.NoErr(sk.Save(ctx, "vapid", []byte{0x01, 0x02}))
+	key, err := sk.Read(ctx, "vapid")
+	is.NoErr(err)
+	is.Equal(key, []byte{0x01, 0x02})
+
+	// Saved keys are replaced.
+	is.NoErr(sk.Save(ctx, "vapid", []byte{0x03}))
+	key, err = sk.Read(ctx, "vapid")
+	is.NoErr(err)
+	is.Equal(key, []byte{0x03})
+}
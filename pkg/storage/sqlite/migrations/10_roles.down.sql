@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN userKeyholder INTEGER NOT NULL DEFAULT 0;
+UPDATE users SET userKeyholder = 1 WHERE userRole IN ('keyholder', 'admin');
+ALTER TABLE users DROP COLUMN userRole;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN userRole TEXT NOT NULL DEFAULT 'member';
+UPDATE users SET userRole = 'keyholder' WHERE userKeyholder >= 1;
+ALTER TABLE users DROP COLUMN userKeyholder;
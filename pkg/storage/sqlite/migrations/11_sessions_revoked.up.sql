@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN userSessionsRevoked INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+CREATE TABLE scanners (
+    scannerID TEXT PRIMARY KEY,
+    scannerName TEXT NOT NULL,
+    scannerSecret BLOB NOT NULL,
+    scannerCreated INTEGER NOT NULL DEFAULT 0,
+    scannerLastUsed INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1,3 @@
+DROP TABLE invites;
+DROP TABLE settings;
+ALTER TABLE users DROP COLUMN userPending;
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN userPending INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE settings (
+    settingName TEXT PRIMARY KEY,
+    settingValue TEXT NOT NULL
+);
+CREATE TABLE invites (
+    inviteID TEXT PRIMARY KEY,
+    inviteCreatorID TEXT NOT NULL,
+    inviteMaxUses INTEGER NOT NULL DEFAULT 0,
+    inviteUses INTEGER NOT NULL DEFAULT 0,
+    inviteCreated INTEGER NOT NULL DEFAULT 0,
+    inviteExpires INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fkInvites
+        FOREIGN KEY(inviteCreatorID)
+        REFERENCES users(userID)
+        ON DELETE CASCADE
+);
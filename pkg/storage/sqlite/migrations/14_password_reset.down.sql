@@ -0,0 +1,3 @@
+DROP INDEX auditTimeIndex;
+DROP TABLE audit;
+DROP TABLE resetTokens;
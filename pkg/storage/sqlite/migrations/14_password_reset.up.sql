@@ -0,0 +1,20 @@
+CREATE TABLE resetTokens (
+    resetTokenID TEXT PRIMARY KEY,
+    resetTokenUserID TEXT NOT NULL,
+    resetTokenIssuerID TEXT NOT NULL DEFAULT '',
+    resetTokenSecret BLOB NOT NULL,
+    resetTokenCreated INTEGER NOT NULL DEFAULT 0,
+    resetTokenExpires INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fkResetTokens
+        FOREIGN KEY(resetTokenUserID)
+        REFERENCES users(userID)
+        ON DELETE CASCADE
+);
+CREATE TABLE audit (
+    auditID TEXT PRIMARY KEY,
+    auditTime INTEGER NOT NULL,
+    auditActorID TEXT NOT NULL DEFAULT '',
+    auditAction TEXT NOT NULL,
+    auditTargetID TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX auditTimeIndex ON audit(auditTime);
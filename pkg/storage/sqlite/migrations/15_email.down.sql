@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN userEmailVerified;
+ALTER TABLE users DROP COLUMN userEmail;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN userEmail TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN userEmailVerified INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+CREATE TABLE apiTokens (
+    apiTokenID TEXT PRIMARY KEY,
+    apiTokenUserID TEXT NOT NULL,
+    apiTokenName TEXT NOT NULL,
+    apiTokenScopes TEXT NOT NULL DEFAULT '',
+    apiTokenSecret BLOB NOT NULL,
+    apiTokenCreated INTEGER NOT NULL DEFAULT 0,
+    apiTokenExpires INTEGER NOT NULL DEFAULT 0,
+    apiTokenLastUsed INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fkAPITokens
+        FOREIGN KEY(apiTokenUserID)
+        REFERENCES users(userID)
+        ON DELETE CASCADE
+);
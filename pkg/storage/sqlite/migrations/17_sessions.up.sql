@@ -0,0 +1,13 @@
+CREATE TABLE sessions (
+    sessionID TEXT PRIMARY KEY,
+    sessionUserID TEXT NOT NULL,
+    sessionUserAgent TEXT NOT NULL DEFAULT '',
+    sessionIP TEXT NOT NULL DEFAULT '',
+    sessionCreated INTEGER NOT NULL DEFAULT 0,
+    sessionLastUsed INTEGER NOT NULL DEFAULT 0,
+    sessionExpires INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fkSessions
+        FOREIGN KEY(sessionUserID)
+        REFERENCES users(userID)
+        ON DELETE CASCADE
+);
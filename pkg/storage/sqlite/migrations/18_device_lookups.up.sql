@@ -0,0 +1,8 @@
+CREATE TABLE deviceLookups (
+    deviceLookupsDeviceID TEXT NOT NULL,
+    deviceLookupsHash BLOB UNIQUE NOT NULL,
+    CONSTRAINT fkDeviceLookups
+        FOREIGN KEY(deviceLookupsDeviceID)
+        REFERENCES devices(deviceID)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP INDEX devicesClientIDLookupIndex;
+DROP INDEX devicesHostnameIndex;
+ALTER TABLE devices DROP COLUMN deviceClientIDLookup;
@@ -0,0 +1,16 @@
+-- Hostnames shared by many devices matched any of them, so
+-- they are removed and owners have to set them again.
+UPDATE devices SET deviceHostname = ''
+WHERE deviceHostname != '' AND lower(deviceHostname) IN (
+    SELECT lower(deviceHostname) FROM devices
+    WHERE deviceHostname != ''
+    GROUP BY lower(deviceHostname)
+    HAVING COUNT(*) > 1
+);
+
+ALTER TABLE devices ADD COLUMN deviceClientIDLookup BLOB;
+
+CREATE UNIQUE INDEX devicesHostnameIndex ON devices(deviceHostname COLLATE NOCASE)
+WHERE deviceHostname != '';
+CREATE UNIQUE INDEX devicesClientIDLookupIndex ON devices(deviceClientIDLookup)
+WHERE deviceClientIDLookup IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Generation of refresh tokens lets API clients exchange each
+-- refresh token only once.
+ALTER TABLE sessions ADD COLUMN sessionGeneration INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+CREATE TABLE ignored (
+    ignoredID TEXT PRIMARY KEY,
+    ignoredTag TEXT,
+    ignoredPrefix BLOB UNIQUE NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP TABLE deviceMACs;
+
+ALTER TABLE devices DROP COLUMN deviceClientID;
+ALTER TABLE devices DROP COLUMN deviceHostname;
@@ -0,0 +1,11 @@
+ALTER TABLE devices ADD COLUMN deviceHostname TEXT NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN deviceClientID BLOB;
+
+CREATE TABLE deviceMACs (
+    deviceMACsDeviceID TEXT NOT NULL,
+    deviceMACsMAC BLOB NOT NULL,
+    CONSTRAINT fkDeviceMACs
+        FOREIGN KEY(deviceMACsDeviceID)
+        REFERENCES devices(deviceID)
+        ON DELETE CASCADE
+);
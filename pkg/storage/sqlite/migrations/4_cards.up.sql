@@ -0,0 +1,10 @@
+CREATE TABLE cards (
+    cardID TEXT PRIMARY KEY,
+    cardOwnerID TEXT NOT NULL,
+    cardTag TEXT,
+    cardUID BLOB UNIQUE NOT NULL,
+    CONSTRAINT fkCards
+        FOREIGN KEY(cardOwnerID)
+        REFERENCES users(userID)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP INDEX visitsUserEnd;
+DROP INDEX visitsStart;
+DROP TABLE visits;
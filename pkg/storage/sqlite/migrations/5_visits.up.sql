@@ -0,0 +1,13 @@
+CREATE TABLE visits (
+    visitID INTEGER PRIMARY KEY AUTOINCREMENT,
+    visitUserID TEXT NOT NULL,
+    visitStart INTEGER NOT NULL,
+    visitEnd INTEGER NOT NULL,
+    CONSTRAINT fkVisits
+        FOREIGN KEY(visitUserID)
+        REFERENCES users(userID)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX visitsStart ON visits(visitStart);
+CREATE INDEX visitsUserEnd ON visits(visitUserID, visitEnd);
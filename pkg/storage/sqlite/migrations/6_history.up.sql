@@ -0,0 +1,4 @@
+CREATE TABLE snapshots (
+    snapshotTime INTEGER PRIMARY KEY,
+    snapshotOnline INTEGER NOT NULL
+);
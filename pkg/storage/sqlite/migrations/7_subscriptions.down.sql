@@ -0,0 +1,3 @@
+DROP INDEX subscriptionsEventIndex;
+DROP TABLE subscriptions;
+ALTER TABLE users DROP COLUMN userUnfollowable;
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN userUnfollowable INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE subscriptions (
+    subscriptionID TEXT PRIMARY KEY,
+    subscriptionUserID TEXT NOT NULL,
+    subscriptionEvent TEXT NOT NULL,
+    subscriptionTargetID TEXT NOT NULL DEFAULT '',
+    subscriptionChannel TEXT NOT NULL,
+    subscriptionAddress TEXT NOT NULL,
+    CONSTRAINT fkSubscriptions
+        FOREIGN KEY(subscriptionUserID)
+        REFERENCES users(userID)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX subscriptionsEventIndex ON subscriptions(subscriptionEvent, subscriptionTargetID);
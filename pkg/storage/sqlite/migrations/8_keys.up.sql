@@ -0,0 +1,4 @@
+CREATE TABLE keys (
+    keyName TEXT PRIMARY KEY,
+    keyValue BLOB NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN userKeyholder INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// ResetTokens storage implements storage.ResetTokens
+// interface for sqlite database.
+type ResetTokens struct {
+	cs *coreStorage
+}
+
+// New stores given token and returns its id.
+func (r *ResetTokens) New(ctx context.Context, t models.ResetToken) (string, error) {
+	return r.cs.newResetToken(ctx, t)
+}
+
+// Read returns token with given id.
+func (r *ResetTokens) Read(ctx context.Context, id string) (*models.ResetToken, error) {
+	return r.cs.readResetToken(ctx, id)
+}
+
+// OfUser returns tokens for user with given id.
+func (r *ResetTokens) OfUser(ctx context.Context, userID string) ([]models.ResetToken, error) {
+	return r.cs.queryResetTokens(ctx, "resetTokenUserID = $1", userID)
+}
+
+// Remove deletes token with given id.
+func (r *ResetTokens) Remove(ctx context.Context, id string) error {
+	return r.cs.removeResetToken(ctx, id)
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestResetTokens(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice", HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm")},
+		{ID: "2", Nickname: "bob", HashedPassword: []byte("u8dXHRi0JNo23JVeHkjh")},
+	} {
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	created := time.Unix(1700000000, 0)
+	tokensData := map[string]models.ResetToken{
+		"a": {ID: "a", UserID: "1", IssuerID: "2", Secret: []byte("secret-a"), Created: created, Expires: created.Add(time.Hour)},
+		"b": {ID: "b", UserID: "1", Secret: []byte("secret-b"), Created: created, Expires: created.Add(time.Hour)},
+		"c": {ID: "c", UserID: "2", IssuerID: "1", Secret: []byte("secret-c"), Created: created, Expires: created.Add(time.Hour)},
+	}
+
+	tokens := f.ResetTokens()
+	for _, tok := range tokensData {
+		id, err := tokens.New(ctx, tok)
+		is.NoErr(err)
+		is.Equal(id, tok.ID)
+	}
+
+	ofAlice, err := tokens.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(ofAlice), 2)
+
+	for _, tok := range ofAlice {
+		current, ok := tokensData[tok.ID]
+		is.True(ok)
+		is.Equal(current.UserID, tok.UserID)
+		is.Equal(current.IssuerID, tok.IssuerID)
+		is.Equal(current.Secret, tok.Secret)
+		is.True(current.Created.Equal(tok.Created))
+		is.True(current.Expires.Equal(tok.Expires))
+	}
+
+	tok, err := tokens.Read(ctx, "c")
+	is.NoErr(err)
+	is.Equal(tok.UserID, "2")
+
+	is.NoErr(tokens.Remove(ctx, "c"))
+	is.True(errors.Is(tokens.Remove(ctx, "c"), serrors.ErrNoID))
+
+	_, err = tokens.Read(ctx, "c")
+	is.True(errors.Is(err, serrors.ErrNoID))
+
+	// Tokens are removed together with their user.
+	is.NoErr(f.Users().Remove(ctx, "1"))
+	ofAlice, err = tokens.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(ofAlice), 0)
+}
@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Scanners storage implements storage.Scanners interface
+// for sqlite database.
+type Scanners struct {
+	cs *coreStorage
+}
+
+// New stores given key and returns its id.
+func (s *Scanners) New(ctx context.Context, k models.ScannerKey) (string, error) {
+	return s.cs.newScanner(ctx, k)
+}
+
+// Read returns key with given id.
+func (s *Scanners) Read(ctx context.Context, id string) (*models.ScannerKey, error) {
+	return s.cs.readScanner(ctx, id)
+}
+
+// All returns slice with every key.
+func (s *Scanners) All(ctx context.Context) ([]models.ScannerKey, error) {
+	return s.cs.queryScanners(ctx, "1 = 1")
+}
+
+// Update applies given function to key with given id.
+func (s *Scanners) Update(ctx context.Context, id string, f func(*models.ScannerKey) error) error {
+	return s.cs.updateScanner(ctx, id, f)
+}
+
+// Remove deletes key with given id.
+func (s *Scanners) Remove(ctx context.Context, id string) error {
+	return s.cs.removeScanner(ctx, id)
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestScanners(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	created := time.Unix(1700000000, 0)
+	scannersData := map[string]models.ScannerKey{
+		"1": {ID: "1", Name: "router", Secret: []byte("71Hk4Rt2WY8xqgYoKxPm"), Created: created},
+		"2": {ID: "2", Name: "door", Secret: []byte("u8dXHRi0JNo23JVeHkjh"), Created: created},
+	}
+
+	ss := f.Scanners()
+	for _, k := range scannersData {
+		id, err := ss.New(ctx, k)
+		is.NoErr(err)
+		is.Equal(id, k.ID)
+	}
+
+	all, err := ss.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), len(scannersData))
+
+	for _, k := range all {
+		current, ok := scannersData[k.ID]
+		is.True(ok)
+		is.Equal(current.Name, k.Name)
+		is.Equal(current.Secret, k.Secret)
+		is.True(current.Created.Equal(k.Created))
+		is.True(k.LastUsed.IsZero())
+	}
+
+	used := time.Unix(1700000600, 0)
+	is.NoErr(ss.Update(ctx, "1", func(k *models.ScannerKey) error {
+		k.LastUsed = used
+		return nil
+	}))
+
+	k, err := ss.Read(ctx, "1")
+	is.NoErr(err)
+	is.True(k.LastUsed.Equal(used))
+
+	is.NoErr(ss.Remove(ctx, "1"))
+	is.True(errors.Is(ss.Remove(ctx, "1"), serrors.ErrNoID))
+
+	_, err = ss.Read(ctx, "1")
+	is.True(errors.Is(err, serrors.ErrNoID))
+	is.True(errors.Is(ss.Update(ctx, "1", func(k *models.ScannerKey) error {
+		return nil
+	}), serrors.ErrNoID))
+}
@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Sessions storage implements storage.Sessions
+// interface for sqlite database.
+type Sessions struct {
+	cs *coreStorage
+}
+
+// New stores given session and returns its id.
+func (s *Sessions) New(ctx context.Context, session models.Session) (string, error) {
+	return s.cs.newSession(ctx, session)
+}
+
+// Read returns session with given id.
+func (s *Sessions) Read(ctx context.Context, id string) (*models.Session, error) {
+	return s.cs.readSession(ctx, id)
+}
+
+// OfUser returns sessions of user with given id.
+func (s *Sessions) OfUser(ctx context.Context, userID string) ([]models.Session, error) {
+	return s.cs.querySessions(ctx, "sessionUserID = $1", userID)
+}
+
+// Update applies given function to session with given id.
+func (s *Sessions) Update(ctx context.Context, id string, f func(*models.Session) error) error {
+	return s.cs.updateSession(ctx, id, f)
+}
+
+// Remove deletes session with given id.
+func (s *Sessions) Remove(ctx context.Context, id string) error {
+	return s.cs.removeSession(ctx, id)
+}
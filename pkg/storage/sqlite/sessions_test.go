@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestSessions(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "alice", HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm")},
+		{ID: "2", Nickname: "bob", HashedPassword: []byte("u8dXHRi0JNo23JVeHkjh")},
+	} {
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	created := time.Unix(1700000000, 0)
+	sessionsData := map[string]models.Session{
+		"a": {ID: "a", UserID: "1", UserAgent: "curl/8.0", IP: "10.0.0.2", Created: created, Expires: created.Add(time.Hour)},
+		"b": {ID: "b", UserID: "1", Created: created, LastUsed: created, Expires: created.Add(time.Hour)},
+		"c": {ID: "c", UserID: "2", Created: created, Expires: created.Add(time.Hour), Generation: 2},
+	}
+
+	sessions := f.Sessions()
+	for _, s := range sessionsData {
+		id, err := sessions.New(ctx, s)
+		is.NoErr(err)
+		is.Equal(id, s.ID)
+	}
+
+	ofAlice, err := sessions.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(ofAlice), 2)
+
+	for _, s := range ofAlice {
+		current, ok := sessionsData[s.ID]
+		is.True(ok)
+		is.Equal(current.UserID, s.UserID)
+		is.Equal(current.UserAgent, s.UserAgent)
+		is.Equal(current.IP, s.IP)
+		is.True(current.Created.Equal(s.Created))
+		is.True(current.LastUsed.Equal(s.LastUsed))
+		is.True(current.Expires.Equal(s.Expires))
+		is.Equal(current.Generation, s.Generation)
+	}
+
+	used := created.Add(time.Minute)
+	err = sessions.Update(ctx, "c", func(s *models.Session) error {
+		s.LastUsed = used
+		s.UserAgent = "firefox"
+		s.Generation++
+		return nil
+	})
+	is.NoErr(err)
+
+	s, err := sessions.Read(ctx, "c")
+	is.NoErr(err)
+	is.Equal(s.UserAgent, "firefox")
+	is.Equal(s.Generation, 3)
+	is.True(s.LastUsed.Equal(used))
+
+	is.NoErr(sessions.Remove(ctx, "c"))
+	is.True(errors.Is(sessions.Remove(ctx, "c"), serrors.ErrNoID))
+
+	_, err = sessions.Read(ctx, "c")
+	is.True(errors.Is(err, serrors.ErrNoID))
+
+	// Sessions are removed together with their user.
+	is.NoErr(f.Users().Remove(ctx, "1"))
+	ofAlice, err = sessions.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(ofAlice), 0)
+}
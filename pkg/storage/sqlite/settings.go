@@ -0,0 +1,20 @@
+package sqlite
+
+import "context"
+
+// Settings storage implements storage.Settings
+// interface for sqlite database.
+type Settings struct {
+	cs *coreStorage
+}
+
+// Read returns value of setting with given name.
+func (s *Settings) Read(ctx context.Context, name string) (string, error) {
+	return s.cs.readSetting(ctx, name)
+}
+
+// Save stores value of setting with given name,
+// replacing previous one.
+func (s *Settings) Save(ctx context.Context, name string, value string) error {
+	return s.cs.saveSetting(ctx, name, value)
+}
@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestSettings(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	ss := f.Settings()
+
+	_, err = ss.Read(ctx, "registration")
+	is.True(errors.Is(err, serrors.ErrNoID))
+
+	is.NoErr(ss.Save(ctx, "registration", "closed"))
+	value, err := ss.Read(ctx, "registration")
+	is.NoErr(err)
+	is.Equal(value, "closed")
+
+	// Saved settings are replaced.
+	is.NoErr(ss.Save(ctx, "registration", "invite"))
+	value, err = ss.Read(ctx, "registration")
+	is.NoErr(err)
+	is.Equal(value, "invite")
+}
@@ -6,7 +6,10 @@ import (
 	"embed"
 	"fmt"
 	"io/fs"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
@@ -17,12 +20,13 @@ import (
 	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/models/set"
 	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
 )
 
 //go:embed migrations
 var migrations embed.FS
 
-const migrationsCurrentVersion = 1
+const migrationsCurrentVersion = 20
 
 func migrateWithFS(db *sql.DB, fileSystem fs.FS) error {
 	sourceInstance, err := iofs.New(fileSystem, "migrations")
@@ -53,6 +57,20 @@ type Factory struct {
 	UsersStorage     *Users
 	DevicesStorage   *Devices
 	TwoFactorStorage *TwoFactor
+	IgnoredStorage   *Ignored
+	CardsStorage     *Cards
+	VisitsStorage    *Visits
+	HistoryStorage   *History
+
+	SubscriptionsStorage *Subscriptions
+	KeysStorage          *Keys
+	ScannersStorage      *Scanners
+	SettingsStorage      *Settings
+	InvitesStorage       *Invites
+	ResetTokensStorage   *ResetTokens
+	AuditStorage         *Audit
+	APITokensStorage     *APITokens
+	SessionsStorage      *Sessions
 }
 
 // NewFactory returns Factory, database closer for sqlite connection and
@@ -86,6 +104,45 @@ func NewFactory(filename string) (*Factory, func() error, error) {
 		TwoFactorStorage: &TwoFactor{
 			cs: cs,
 		},
+		IgnoredStorage: &Ignored{
+			cs: cs,
+		},
+		CardsStorage: &Cards{
+			cs: cs,
+		},
+		VisitsStorage: &Visits{
+			cs: cs,
+		},
+		HistoryStorage: &History{
+			cs: cs,
+		},
+		SubscriptionsStorage: &Subscriptions{
+			cs: cs,
+		},
+		KeysStorage: &Keys{
+			cs: cs,
+		},
+		ScannersStorage: &Scanners{
+			cs: cs,
+		},
+		SettingsStorage: &Settings{
+			cs: cs,
+		},
+		InvitesStorage: &Invites{
+			cs: cs,
+		},
+		ResetTokensStorage: &ResetTokens{
+			cs: cs,
+		},
+		AuditStorage: &Audit{
+			cs: cs,
+		},
+		APITokensStorage: &APITokens{
+			cs: cs,
+		},
+		SessionsStorage: &Sessions{
+			cs: cs,
+		},
 	}, closer, nil
 }
 
@@ -107,6 +164,84 @@ func (f *Factory) TwoFactor() storage.TwoFactor {
 	return f.TwoFactorStorage
 }
 
+// Ignored returns sqlite implementation of
+// storage Ignored interface.
+func (f *Factory) Ignored() storage.Ignored {
+	return f.IgnoredStorage
+}
+
+// Cards returns sqlite implementation of
+// storage Cards interface.
+func (f *Factory) Cards() storage.Cards {
+	return f.CardsStorage
+}
+
+// Visits returns sqlite implementation of
+// storage Visits interface.
+func (f *Factory) Visits() storage.Visits {
+	return f.VisitsStorage
+}
+
+// History returns sqlite implementation of
+// storage History interface.
+func (f *Factory) History() storage.History {
+	return f.HistoryStorage
+}
+
+// Subscriptions returns sqlite implementation of
+// storage Subscriptions interface.
+func (f *Factory) Subscriptions() storage.Subscriptions {
+	return f.SubscriptionsStorage
+}
+
+// Keys returns sqlite implementation of
+// storage Keys interface.
+func (f *Factory) Keys() storage.Keys {
+	return f.KeysStorage
+}
+
+// Scanners returns sqlite implementation of
+// storage Scanners interface.
+func (f *Factory) Scanners() storage.Scanners {
+	return f.ScannersStorage
+}
+
+// Settings returns sqlite implementation of
+// storage Settings interface.
+func (f *Factory) Settings() storage.Settings {
+	return f.SettingsStorage
+}
+
+// Invites returns sqlite implementation of
+// storage Invites interface.
+func (f *Factory) Invites() storage.Invites {
+	return f.InvitesStorage
+}
+
+// ResetTokens returns sqlite implementation of
+// storage ResetTokens interface.
+func (f *Factory) ResetTokens() storage.ResetTokens {
+	return f.ResetTokensStorage
+}
+
+// Audit returns sqlite implementation of
+// storage Audit interface.
+func (f *Factory) Audit() storage.Audit {
+	return f.AuditStorage
+}
+
+// APITokens returns sqlite implementation of
+// storage APITokens interface.
+func (f *Factory) APITokens() storage.APITokens {
+	return f.APITokensStorage
+}
+
+// Sessions returns sqlite implementation of
+// storage Sessions interface.
+func (f *Factory) Sessions() storage.Sessions {
+	return f.SessionsStorage
+}
+
 func pragma(query string) string {
 	res := ""
 	res += "PRAGMA foreign_keys = ON;"
@@ -115,6 +250,41 @@ func pragma(query string) string {
 
 }
 
+// sqliteRole returns given role or member role, if
+// given one is empty.
+func sqliteRole(r models.Role) string {
+	if r == "" {
+		return string(models.RoleMember)
+	}
+	return string(r)
+}
+
+// sqliteTime returns given time as unix nanoseconds or
+// zero for zero time.
+func sqliteTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromSqliteTime reverses sqliteTime.
+func fromSqliteTime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// sqliteBytes returns NULL for empty slice, so empty values
+// are skipped by unique indexes.
+func sqliteBytes(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
+
 func sqliteBoolean(v bool) int {
 	if !v {
 		return 0
@@ -133,9 +303,10 @@ type coreStorage struct {
 func (cs *coreStorage) newUser(ctx context.Context, u storage.UserEntry) (string, error) {
 	query := pragma(`
 	INSERT INTO users
-		(userID, userNickname, userPassword, userPrivate)
+		(userID, userNickname, userPassword, userPrivate, userUnfollowable,
+		userRole, userSessionsRevoked, userPending, userEmail, userEmailVerified)
 	VALUES
-		($1, $2, $3, $4);
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
 	`)
 
 	cs.writeGuard.Lock()
@@ -148,6 +319,12 @@ func (cs *coreStorage) newUser(ctx context.Context, u storage.UserEntry) (string
 		u.Nickname,
 		u.HashedPassword,
 		sqliteBoolean(u.Private),
+		sqliteBoolean(u.Unfollowable),
+		sqliteRole(u.Role),
+		sqliteTime(u.SessionsRevoked),
+		sqliteBoolean(u.Pending),
+		u.Email,
+		sqliteBoolean(u.EmailVerified),
 	)
 	if err != nil {
 		return "", fmt.Errorf("cs.db.ExecContext: %w", err)
@@ -159,31 +336,53 @@ func (cs *coreStorage) newUser(ctx context.Context, u storage.UserEntry) (string
 func (cs *coreStorage) readUser(ctx context.Context, id string) (*storage.UserEntry, error) {
 	query := `
 	SELECT
-		userNickname, userPassword, userPrivate
+		userNickname, userPassword, userPrivate, userUnfollowable,
+		userRole, userSessionsRevoked, userPending, userEmail, userEmailVerified
 	FROM
 		users
 	WHERE
 		userID = $1
 	`
 	var (
-		userNickname string
-		userPassword []byte
-		userPrivate  int
+		userNickname      string
+		userPassword      []byte
+		userPrivate       int
+		userUnfollowable  int
+		userRole          string
+		userRevoked       int64
+		userPending       int
+		userEmail         string
+		userEmailVerified int
 	)
 	err := cs.db.QueryRowContext(ctx, query, id).Scan(
 		&userNickname,
 		&userPassword,
 		&userPrivate,
+		&userUnfollowable,
+		&userRole,
+		&userRevoked,
+		&userPending,
+		&userEmail,
+		&userEmailVerified,
 	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("there is no user with id=%s: %w", id, serrors.ErrNoID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("cs.db.QueryRowContext: %w", err)
 	}
 
 	return &storage.UserEntry{
-		ID:             id,
-		Nickname:       userNickname,
-		HashedPassword: userPassword,
-		Private:        userPrivate >= 1,
+		ID:              id,
+		Nickname:        userNickname,
+		HashedPassword:  userPassword,
+		Private:         userPrivate >= 1,
+		Unfollowable:    userUnfollowable >= 1,
+		Role:            models.Role(userRole),
+		SessionsRevoked: fromSqliteTime(userRevoked),
+		Pending:         userPending >= 1,
+		Email:           userEmail,
+		EmailVerified:   userEmailVerified >= 1,
 	}, nil
 }
 
@@ -196,16 +395,23 @@ func copyBytes(src []byte) []byte {
 func (cs *coreStorage) allUsers(ctx context.Context) ([]storage.UserEntry, error) {
 	query := `
 	SELECT
-		userID, userNickname, userPassword, userPrivate
+		userID, userNickname, userPassword, userPrivate, userUnfollowable,
+		userRole, userSessionsRevoked, userPending, userEmail, userEmailVerified
 	FROM
 		users
 	`
 
 	var (
-		userID       string
-		userNickname string
-		userPassword []byte
-		userPrivate  int
+		userID            string
+		userNickname      string
+		userPassword      []byte
+		userPrivate       int
+		userUnfollowable  int
+		userRole          string
+		userRevoked       int64
+		userPending       int
+		userEmail         string
+		userEmailVerified int
 	)
 
 	rows, err := cs.db.QueryContext(ctx, query)
@@ -222,36 +428,100 @@ func (cs *coreStorage) allUsers(ctx context.Context) ([]storage.UserEntry, error
 			&userNickname,
 			&userPassword,
 			&userPrivate,
+			&userUnfollowable,
+			&userRole,
+			&userRevoked,
+			&userPending,
+			&userEmail,
+			&userEmailVerified,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("rows.Scan: %w", err)
 		}
 
 		res = append(res, storage.UserEntry{
-			ID:             userID,
-			Nickname:       userNickname,
-			HashedPassword: copyBytes(userPassword),
-			Private:        userPrivate >= 1,
+			ID:              userID,
+			Nickname:        userNickname,
+			HashedPassword:  copyBytes(userPassword),
+			Private:         userPrivate >= 1,
+			Unfollowable:    userUnfollowable >= 1,
+			Role:            models.Role(userRole),
+			SessionsRevoked: fromSqliteTime(userRevoked),
+			Pending:         userPending >= 1,
+			Email:           userEmail,
+			EmailVerified:   userEmailVerified >= 1,
 		})
 	}
 
 	return res, nil
 }
 
-func (cs *coreStorage) removeUser(ctx context.Context, id string) error {
+// lastAdminWithTx returns true if there is at most one admin.
+func lastAdminWithTx(ctx context.Context, tx *sql.Tx) (bool, error) {
 	query := `
-	DELETE FROM
+	SELECT
+		COUNT(*)
+	FROM
 		users
 	WHERE
-		userID = $1;
+		userRole = $1
 	`
 
+	admins := 0
+	err := tx.QueryRowContext(ctx, query, string(models.RoleAdmin)).Scan(&admins)
+	if err != nil {
+		return false, fmt.Errorf("tx.QueryRowContext: %w", err)
+	}
+
+	return admins <= 1, nil
+}
+
+func (cs *coreStorage) removeUser(ctx context.Context, id string) error {
 	cs.writeGuard.Lock()
 	defer cs.writeGuard.Unlock()
 
-	_, err := cs.db.ExecContext(ctx, query, id)
+	tx, err := cs.db.Begin()
 	if err != nil {
-		return fmt.Errorf("cs.db.ExecContext: %w", err)
+		return fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	var userRole string
+	err = tx.QueryRowContext(ctx, `SELECT userRole FROM users WHERE userID = $1`, id).Scan(&userRole)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return fmt.Errorf("there is no user with id=%s: %w", id, serrors.ErrNoID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.QueryRowContext: %w", err)
+	}
+
+	if models.Role(userRole) == models.RoleAdmin {
+		last, err := lastAdminWithTx(ctx, tx)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("lastAdminWithTx: %w", err)
+		}
+		if last {
+			tx.Rollback()
+			return fmt.Errorf("user id=%s: %w", id, serrors.ErrLastAdmin)
+		}
+	}
+
+	query := `
+	DELETE FROM
+		users
+	WHERE
+		userID = $1;
+	`
+
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
 	}
 
 	return nil
@@ -268,7 +538,8 @@ func (cs *coreStorage) updateUser(ctx context.Context, id string, f func(*storag
 
 	selectUserQuery := `
 	SELECT
-		userNickname, userPassword, userPrivate
+		userNickname, userPassword, userPrivate, userUnfollowable,
+		userRole, userSessionsRevoked, userPending, userEmail, userEmailVerified
 	FROM
 		users
 	WHERE
@@ -276,26 +547,48 @@ func (cs *coreStorage) updateUser(ctx context.Context, id string, f func(*storag
 	`
 
 	var (
-		userNickname string
-		userPassword []byte
-		userPrivate  int
+		userNickname      string
+		userPassword      []byte
+		userPrivate       int
+		userUnfollowable  int
+		userRole          string
+		userRevoked       int64
+		userPending       int
+		userEmail         string
+		userEmailVerified int
 	)
 
 	err = tx.QueryRowContext(ctx, selectUserQuery, id).Scan(
 		&userNickname,
 		&userPassword,
 		&userPrivate,
+		&userUnfollowable,
+		&userRole,
+		&userRevoked,
+		&userPending,
+		&userEmail,
+		&userEmailVerified,
 	)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return fmt.Errorf("there is no user with id=%s: %w", id, serrors.ErrNoID)
+	}
 	if err != nil {
 		tx.Rollback()
 		return fmt.Errorf("tx.QueryRowContext: %w", err)
 	}
 
 	entry := &storage.UserEntry{
-		ID:             id,
-		Nickname:       userNickname,
-		HashedPassword: userPassword,
-		Private:        userPrivate >= 1,
+		ID:              id,
+		Nickname:        userNickname,
+		HashedPassword:  userPassword,
+		Private:         userPrivate >= 1,
+		Unfollowable:    userUnfollowable >= 1,
+		Role:            models.Role(userRole),
+		SessionsRevoked: fromSqliteTime(userRevoked),
+		Pending:         userPending >= 1,
+		Email:           userEmail,
+		EmailVerified:   userEmailVerified >= 1,
 	}
 
 	err = f(entry)
@@ -304,11 +597,25 @@ func (cs *coreStorage) updateUser(ctx context.Context, id string, f func(*storag
 		return fmt.Errorf("f: %w", err)
 	}
 
+	if models.Role(userRole) == models.RoleAdmin && entry.Role != models.RoleAdmin {
+		last, err := lastAdminWithTx(ctx, tx)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("lastAdminWithTx: %w", err)
+		}
+		if last {
+			tx.Rollback()
+			return fmt.Errorf("user id=%s: %w", id, serrors.ErrLastAdmin)
+		}
+	}
+
 	updateQuery := pragma(`
 	UPDATE
 		users
 	SET
-		userNickname = $2, userPassword = $3, userPrivate = $4
+		userNickname = $2, userPassword = $3, userPrivate = $4,
+		userUnfollowable = $5, userRole = $6, userSessionsRevoked = $7,
+		userPending = $8, userEmail = $9, userEmailVerified = $10
 	WHERE
 		userID = $1;
 	`)
@@ -320,6 +627,12 @@ func (cs *coreStorage) updateUser(ctx context.Context, id string, f func(*storag
 		entry.Nickname,
 		entry.HashedPassword,
 		sqliteBoolean(entry.Private),
+		sqliteBoolean(entry.Unfollowable),
+		sqliteRole(entry.Role),
+		sqliteTime(entry.SessionsRevoked),
+		sqliteBoolean(entry.Pending),
+		entry.Email,
+		sqliteBoolean(entry.EmailVerified),
 	)
 	if err != nil {
 		tx.Rollback()
@@ -333,55 +646,197 @@ func (cs *coreStorage) updateUser(ctx context.Context, id string, f func(*storag
 	return nil
 }
 
+func insertDeviceMACsWithTx(ctx context.Context, tx *sql.Tx, d models.Device) error {
+	stmt, err := tx.PrepareContext(ctx, pragma(`
+	INSERT INTO deviceMACs
+		(deviceMACsDeviceID, deviceMACsMAC)
+	VALUES
+		($1, $2);
+	`))
+	if err != nil {
+		return fmt.Errorf("tx.PrepareContext: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, mac := range d.MACs {
+		if _, err := stmt.ExecContext(ctx, d.ID, mac); err != nil {
+			return fmt.Errorf("stmt.ExecContext: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// insertDeviceLookupsWithTx stores lookup hashes of given
+// device. It returns serrors.ErrMACDuplication, if any of them
+// is already stored, so the same hardware address can't be
+// registered twice.
+func insertDeviceLookupsWithTx(ctx context.Context, tx *sql.Tx, d models.Device) error {
+	selectQuery := `
+	SELECT
+		deviceLookupsDeviceID
+	FROM
+		deviceLookups
+	WHERE
+		deviceLookupsHash = $1;
+	`
+
+	insertQuery := pragma(`
+	INSERT INTO deviceLookups
+		(deviceLookupsDeviceID, deviceLookupsHash)
+	VALUES
+		($1, $2);
+	`)
+
+	for _, lookup := range d.Lookups {
+		var deviceID string
+		err := tx.QueryRowContext(ctx, selectQuery, lookup).Scan(&deviceID)
+		if err == nil {
+			return fmt.Errorf("device id=%s: %w", deviceID, serrors.ErrMACDuplication)
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("tx.QueryRowContext: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, insertQuery, d.ID, lookup); err != nil {
+			return fmt.Errorf("tx.ExecContext: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkDeviceIdentifiersWithTx returns serrors.ErrHostnameTaken
+// or serrors.ErrClientIDTaken, if device other than given one has
+// the same hostname or lookup hash of client identifier.
+func checkDeviceIdentifiersWithTx(ctx context.Context, tx *sql.Tx, d models.Device) error {
+	var deviceID string
+
+	if d.Hostname != "" {
+		query := `
+		SELECT
+			deviceID
+		FROM
+			devices
+		WHERE
+			deviceHostname = $1 COLLATE NOCASE AND deviceID != $2;
+		`
+
+		err := tx.QueryRowContext(ctx, query, d.Hostname, d.ID).Scan(&deviceID)
+		if err == nil {
+			return fmt.Errorf("device id=%s: %w", deviceID, serrors.ErrHostnameTaken)
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("tx.QueryRowContext: %w", err)
+		}
+	}
+
+	if len(d.ClientIDLookup) > 0 {
+		query := `
+		SELECT
+			deviceID
+		FROM
+			devices
+		WHERE
+			deviceClientIDLookup = $1 AND deviceID != $2;
+		`
+
+		err := tx.QueryRowContext(ctx, query, d.ClientIDLookup, d.ID).Scan(&deviceID)
+		if err == nil {
+			return fmt.Errorf("device id=%s: %w", deviceID, serrors.ErrClientIDTaken)
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("tx.QueryRowContext: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (cs *coreStorage) newDevice(ctx context.Context, userID string, d models.Device) (string, error) {
 	query := pragma(`
 	INSERT INTO devices
-		(deviceID, deviceOwnerID, deviceTag, deviceMAC)
+		(deviceID, deviceOwnerID, deviceTag, deviceMAC, deviceHostname, deviceClientID,
+		deviceClientIDLookup)
 	VALUES
-		($1, $2, $3, $4)
+		($1, $2, $3, $4, $5, $6, $7)
 	`)
 
 	cs.writeGuard.Lock()
 	defer cs.writeGuard.Unlock()
 
-	_, err := cs.db.ExecContext(
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	if err := checkDeviceIdentifiersWithTx(ctx, tx, d); err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("checkDeviceIdentifiersWithTx: %w", err)
+	}
+
+	_, err = tx.ExecContext(
 		ctx,
 		query,
 		d.ID,
 		userID,
 		d.Tag,
 		d.MAC,
+		d.Hostname,
+		d.ClientID,
+		sqliteBytes(d.ClientIDLookup),
 	)
 	if err != nil {
-		return "", fmt.Errorf("cs.db.ExecContext: %w", err)
+		tx.Rollback()
+		return "", fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := insertDeviceMACsWithTx(ctx, tx, d); err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("insertDeviceMACsWithTx: %w", err)
+	}
+
+	if err := insertDeviceLookupsWithTx(ctx, tx, d); err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("insertDeviceLookupsWithTx: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("tx.Commit: %w", err)
 	}
 
 	return d.ID, nil
 }
 
-func (cs *coreStorage) deviceOfUser(ctx context.Context, userID string) ([]models.Device, error) {
+// devicesFromTx returns devices matching given condition
+// along with theirs additional MAC addresses.
+func devicesFromTx(ctx context.Context, tx *sql.Tx, condition string, args ...interface{}) ([]models.Device, error) {
 	query := `
 	SELECT
-		deviceID, deviceOwnerID, userNickname, deviceTag, deviceMAC
+		deviceID, deviceOwnerID, userNickname, deviceTag, deviceMAC,
+		deviceHostname, deviceClientID, deviceClientIDLookup
 	FROM
 		users INNER JOIN devices
 	ON
 		users.userID = devices.deviceOwnerID
 	WHERE
-		users.userID = $1;
+		` + condition + `;
 	`
 
 	var (
-		deviceID      string
-		deviceOwnerID string
-		userNickname  string
-		deviceTag     string
-		deviceMAC     []byte
+		deviceID       string
+		deviceOwnerID  string
+		userNickname   string
+		deviceTag      string
+		deviceMAC      []byte
+		deviceHostname string
+		deviceClientID []byte
+		clientIDLookup []byte
 	)
 
-	rows, err := cs.db.QueryContext(ctx, query, userID)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+		return nil, fmt.Errorf("tx.QueryContext: %w", err)
 	}
 	defer rows.Close()
 
@@ -394,126 +849,247 @@ func (cs *coreStorage) deviceOfUser(ctx context.Context, userID string) ([]model
 			&userNickname,
 			&deviceTag,
 			&deviceMAC,
+			&deviceHostname,
+			&deviceClientID,
+			&clientIDLookup,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("rows.Scan: %w", err)
 		}
 
+		var clientID, lookup []byte
+		if len(deviceClientID) > 0 {
+			clientID = copyBytes(deviceClientID)
+		}
+		if len(clientIDLookup) > 0 {
+			lookup = copyBytes(clientIDLookup)
+		}
+
 		res = append(res, models.Device{
 			DevicePublicData: models.DevicePublicData{
 				ID:    deviceID,
 				Tag:   deviceTag,
 				Owner: userNickname,
 			},
-			OwnerID: deviceOwnerID,
-			MAC:     copyBytes(deviceMAC),
+			OwnerID:        deviceOwnerID,
+			MAC:            copyBytes(deviceMAC),
+			Hostname:       deviceHostname,
+			ClientID:       clientID,
+			ClientIDLookup: lookup,
 		})
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+	rows.Close()
 
-	return res, nil
-}
-
-func (cs *coreStorage) allDevices(ctx context.Context) ([]models.Device, error) {
-	query := `
+	macsQuery := `
 	SELECT
-		deviceID, deviceOwnerID, userNickname, deviceTag, deviceMAC
+		deviceMACsMAC
 	FROM
-		users INNER JOIN devices
-	ON
-		users.userID = devices.deviceOwnerID;
+		deviceMACs
+	WHERE
+		deviceMACsDeviceID = $1;
 	`
 
-	var (
-		deviceID      string
-		deviceOwnerID string
-		userNickname  string
-		deviceTag     string
-		deviceMAC     []byte
-	)
+	lookupsQuery := `
+	SELECT
+		deviceLookupsHash
+	FROM
+		deviceLookups
+	WHERE
+		deviceLookupsDeviceID = $1;
+	`
 
-	rows, err := cs.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
-	}
-	defer rows.Close()
+	for i := range res {
+		lookupRows, err := tx.QueryContext(ctx, lookupsQuery, res[i].ID)
+		if err != nil {
+			return nil, fmt.Errorf("lookups/tx.QueryContext: %w", err)
+		}
 
-	res := []models.Device{}
+		var lookup []byte
+		for lookupRows.Next() {
+			if err := lookupRows.Scan(&lookup); err != nil {
+				lookupRows.Close()
+				return nil, fmt.Errorf("lookups/rows.Scan: %w", err)
+			}
+			res[i].Lookups = append(res[i].Lookups, copyBytes(lookup))
+		}
+		if err := lookupRows.Err(); err != nil {
+			lookupRows.Close()
+			return nil, fmt.Errorf("lookups/rows.Err: %w", err)
+		}
+		lookupRows.Close()
 
-	for rows.Next() {
-		err = rows.Scan(
-			&deviceID,
-			&deviceOwnerID,
-			&userNickname,
-			&deviceTag,
-			&deviceMAC,
-		)
+		macRows, err := tx.QueryContext(ctx, macsQuery, res[i].ID)
 		if err != nil {
-			return nil, fmt.Errorf("rows.Scan: %w", err)
+			return nil, fmt.Errorf("macs/tx.QueryContext: %w", err)
 		}
 
-		res = append(res, models.Device{
-			DevicePublicData: models.DevicePublicData{
-				ID:    deviceID,
-				Tag:   deviceTag,
-				Owner: userNickname,
-			},
-			OwnerID: deviceOwnerID,
-			MAC:     copyBytes(deviceMAC),
-		})
+		var mac []byte
+		for macRows.Next() {
+			if err := macRows.Scan(&mac); err != nil {
+				macRows.Close()
+				return nil, fmt.Errorf("macs/rows.Scan: %w", err)
+			}
+			res[i].MACs = append(res[i].MACs, copyBytes(mac))
+		}
+		if err := macRows.Err(); err != nil {
+			macRows.Close()
+			return nil, fmt.Errorf("macs/rows.Err: %w", err)
+		}
+		macRows.Close()
 	}
 
 	return res, nil
 }
 
-func (cs *coreStorage) readDevice(ctx context.Context, id string) (*models.Device, error) {
-	query := `
-	SELECT
-		deviceID, deviceOwnerID, userNickname, deviceTag, deviceMAC
-	FROM
-		users INNER JOIN devices
-	ON
-		users.userID = devices.deviceOwnerID
-	WHERE
-		devices.deviceID = $1;
-	`
+func (cs *coreStorage) queryDevices(ctx context.Context, condition string, args ...interface{}) ([]models.Device, error) {
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.Begin: %w", err)
+	}
 
-	var (
-		deviceID      string
-		deviceOwnerID string
-		userNickname  string
-		deviceTag     string
-		deviceMAC     []byte
-	)
+	res, err := devicesFromTx(ctx, tx, condition, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("devicesFromTx: %w", err)
+	}
 
-	err := cs.db.QueryRowContext(ctx, query, id).Scan(
-		&deviceID,
-		&deviceOwnerID,
-		&userNickname,
-		&deviceTag,
-		&deviceMAC,
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return res, nil
+}
+
+func (cs *coreStorage) deviceOfUser(ctx context.Context, userID string) ([]models.Device, error) {
+	return cs.queryDevices(ctx, "users.userID = $1", userID)
+}
+
+func (cs *coreStorage) allDevices(ctx context.Context) ([]models.Device, error) {
+	return cs.queryDevices(ctx, "1 = 1")
+}
+
+func (cs *coreStorage) readDevice(ctx context.Context, id string) (*models.Device, error) {
+	res, err := cs.queryDevices(ctx, "devices.deviceID = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("there is no device with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return &res[0], nil
+}
+
+func (cs *coreStorage) updateDevice(ctx context.Context, id string, f func(*models.Device) error) error {
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	devices, err := devicesFromTx(ctx, tx, "devices.deviceID = $1", id)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("devicesFromTx: %w", err)
+	}
+	if len(devices) == 0 {
+		tx.Rollback()
+		return fmt.Errorf("there is no device with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	device := devices[0]
+
+	if err := f(&device); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("f: %w", err)
+	}
+
+	// Device id and owner can't be changed.
+	device.ID = id
+
+	if err := checkDeviceIdentifiersWithTx(ctx, tx, device); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("checkDeviceIdentifiersWithTx: %w", err)
+	}
+
+	updateQuery := pragma(`
+	UPDATE
+		devices
+	SET
+		deviceTag = $2, deviceMAC = $3, deviceHostname = $4, deviceClientID = $5,
+		deviceClientIDLookup = $6
+	WHERE
+		deviceID = $1;
+	`)
+
+	_, err = tx.ExecContext(
+		ctx,
+		updateQuery,
+		device.ID,
+		device.Tag,
+		device.MAC,
+		device.Hostname,
+		device.ClientID,
+		sqliteBytes(device.ClientIDLookup),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("cs.db.QueryRowContext: %w", err)
+		tx.Rollback()
+		return fmt.Errorf("tx.ExecContext: %w", err)
 	}
 
-	return &models.Device{
-		DevicePublicData: models.DevicePublicData{
-			ID:    deviceID,
-			Tag:   deviceTag,
-			Owner: userNickname,
-		},
-		OwnerID: deviceOwnerID,
-		MAC:     deviceMAC,
-	}, nil
+	deleteQuery := pragma(`
+	DELETE FROM
+		deviceMACs
+	WHERE
+		deviceMACsDeviceID = $1;
+	`)
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, device.ID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := insertDeviceMACsWithTx(ctx, tx, device); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("insertDeviceMACsWithTx: %w", err)
+	}
+
+	deleteLookupsQuery := pragma(`
+	DELETE FROM
+		deviceLookups
+	WHERE
+		deviceLookupsDeviceID = $1;
+	`)
+
+	if _, err := tx.ExecContext(ctx, deleteLookupsQuery, device.ID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := insertDeviceLookupsWithTx(ctx, tx, device); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("insertDeviceLookupsWithTx: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return nil
 }
 
 func (cs *coreStorage) removeDevice(ctx context.Context, id string) error {
-	query := `
+	query := pragma(`
 	DELETE FROM
 		devices
 	WHERE
 		deviceID = $1;
-	`
+	`)
 	cs.writeGuard.Lock()
 	defer cs.writeGuard.Unlock()
 
@@ -800,3 +1376,1653 @@ func (cs *coreStorage) updateTwoFactor(ctx context.Context, userID string, f fun
 
 	return nil
 }
+
+func (cs *coreStorage) newIgnored(ctx context.Context, a models.IgnoredAddress) (string, error) {
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	selectQuery := `
+	SELECT
+		count(*)
+	FROM
+		ignored
+	WHERE
+		ignoredPrefix = $1;
+	`
+
+	var count int
+	err = tx.QueryRowContext(ctx, selectQuery, a.Prefix).Scan(&count)
+	if err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("tx.QueryRowContext: %w", err)
+	}
+	if count > 0 {
+		tx.Rollback()
+		return "", serrors.ErrPrefixDuplication
+	}
+
+	insertQuery := pragma(`
+	INSERT INTO ignored
+		(ignoredID, ignoredTag, ignoredPrefix)
+	VALUES
+		($1, $2, $3);
+	`)
+
+	_, err = tx.ExecContext(ctx, insertQuery, a.ID, a.Tag, a.Prefix)
+	if err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return a.ID, nil
+}
+
+func (cs *coreStorage) allIgnored(ctx context.Context) ([]models.IgnoredAddress, error) {
+	query := `
+	SELECT
+		ignoredID, ignoredTag, ignoredPrefix
+	FROM
+		ignored;
+	`
+
+	var (
+		ignoredID     string
+		ignoredTag    string
+		ignoredPrefix []byte
+	)
+
+	rows, err := cs.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	res := []models.IgnoredAddress{}
+
+	for rows.Next() {
+		err = rows.Scan(&ignoredID, &ignoredTag, &ignoredPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.IgnoredAddress{
+			ID:     ignoredID,
+			Tag:    ignoredTag,
+			Prefix: copyBytes(ignoredPrefix),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+func (cs *coreStorage) removeIgnored(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM
+		ignored
+	WHERE
+		ignoredID = $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	res, err := cs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected: %w", err)
+	}
+	if affected == 0 {
+		return serrors.ErrNoID
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) newCard(ctx context.Context, userID string, c models.Card) (string, error) {
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	selectQuery := `
+	SELECT
+		count(*)
+	FROM
+		cards
+	WHERE
+		cardUID = $1;
+	`
+
+	var count int
+	err = tx.QueryRowContext(ctx, selectQuery, c.UID).Scan(&count)
+	if err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("tx.QueryRowContext: %w", err)
+	}
+	if count > 0 {
+		tx.Rollback()
+		return "", serrors.ErrCardDuplication
+	}
+
+	insertQuery := pragma(`
+	INSERT INTO cards
+		(cardID, cardOwnerID, cardTag, cardUID)
+	VALUES
+		($1, $2, $3, $4);
+	`)
+
+	_, err = tx.ExecContext(ctx, insertQuery, c.ID, userID, c.Tag, c.UID)
+	if err != nil {
+		tx.Rollback()
+		return "", fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return c.ID, nil
+}
+
+func (cs *coreStorage) queryCards(ctx context.Context, condition string, args ...interface{}) ([]models.Card, error) {
+	query := `
+	SELECT
+		cardID, cardOwnerID, cardTag, cardUID
+	FROM
+		cards
+	WHERE
+		` + condition + `;
+	`
+
+	var (
+		cardID      string
+		cardOwnerID string
+		cardTag     string
+		cardUID     []byte
+	)
+
+	rows, err := cs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	res := []models.Card{}
+
+	for rows.Next() {
+		err = rows.Scan(&cardID, &cardOwnerID, &cardTag, &cardUID)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.Card{
+			ID:      cardID,
+			OwnerID: cardOwnerID,
+			Tag:     cardTag,
+			UID:     copyBytes(cardUID),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+func (cs *coreStorage) cardsOfUser(ctx context.Context, userID string) ([]models.Card, error) {
+	return cs.queryCards(ctx, "cardOwnerID = $1", userID)
+}
+
+func (cs *coreStorage) allCards(ctx context.Context) ([]models.Card, error) {
+	return cs.queryCards(ctx, "1 = 1")
+}
+
+func (cs *coreStorage) removeCard(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM
+		cards
+	WHERE
+		cardID = $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	res, err := cs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected: %w", err)
+	}
+	if affected == 0 {
+		return serrors.ErrNoID
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) recordVisits(ctx context.Context, userIDs []string, at time.Time, gap time.Duration) error {
+	selectQuery := `
+	SELECT
+		visitID, visitEnd
+	FROM
+		visits
+	WHERE
+		visitUserID = $1
+	ORDER BY
+		visitEnd DESC
+	LIMIT 1;
+	`
+
+	updateQuery := `
+	UPDATE
+		visits
+	SET
+		visitEnd = max(visitEnd, $1)
+	WHERE
+		visitID = $2;
+	`
+
+	// Visits of users, that don't exist anymore, are skipped.
+	insertQuery := pragma(`
+	INSERT INTO visits
+		(visitUserID, visitStart, visitEnd)
+	SELECT
+		$1, $2, $2
+	WHERE EXISTS
+		(SELECT 1 FROM users WHERE userID = $1);
+	`)
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		var (
+			visitID  int64
+			visitEnd int64
+		)
+
+		err := tx.QueryRowContext(ctx, selectQuery, userID).Scan(&visitID, &visitEnd)
+		if err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			return fmt.Errorf("tx.QueryRowContext: %w", err)
+		}
+
+		if err == nil && visitEnd >= at.Add(-gap).UnixNano() {
+			_, err = tx.ExecContext(ctx, updateQuery, at.UnixNano(), visitID)
+		} else {
+			_, err = tx.ExecContext(ctx, insertQuery, userID, at.UnixNano())
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("tx.ExecContext: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) visitsBetween(ctx context.Context, from, to time.Time) ([]models.Visit, error) {
+	query := `
+	SELECT
+		visitID, visitUserID, visitStart, visitEnd
+	FROM
+		visits
+	WHERE
+		visitStart < $1 AND visitEnd >= $2
+	ORDER BY
+		visitStart, visitID;
+	`
+
+	var (
+		visitID     int64
+		visitUserID string
+		visitStart  int64
+		visitEnd    int64
+	)
+
+	rows, err := cs.db.QueryContext(ctx, query, to.UnixNano(), from.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	res := []models.Visit{}
+
+	for rows.Next() {
+		err = rows.Scan(&visitID, &visitUserID, &visitStart, &visitEnd)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.Visit{
+			ID:     strconv.FormatInt(visitID, 10),
+			UserID: visitUserID,
+			Start:  time.Unix(0, visitStart),
+			End:    time.Unix(0, visitEnd),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+func (cs *coreStorage) addSnapshot(ctx context.Context, s models.Snapshot) error {
+	query := `
+	INSERT OR REPLACE INTO snapshots
+		(snapshotTime, snapshotOnline)
+	VALUES
+		($1, $2);
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(ctx, query, s.Time.UnixNano(), s.Online)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) snapshotsBetween(ctx context.Context, from, to time.Time) ([]models.Snapshot, error) {
+	query := `
+	SELECT
+		snapshotTime, snapshotOnline
+	FROM
+		snapshots
+	WHERE
+		snapshotTime >= $1 AND snapshotTime < $2
+	ORDER BY
+		snapshotTime;
+	`
+
+	var (
+		snapshotTime   int64
+		snapshotOnline int
+	)
+
+	rows, err := cs.db.QueryContext(ctx, query, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	res := []models.Snapshot{}
+
+	for rows.Next() {
+		err = rows.Scan(&snapshotTime, &snapshotOnline)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.Snapshot{
+			Time:   time.Unix(0, snapshotTime),
+			Online: snapshotOnline,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+func (cs *coreStorage) pruneSnapshots(ctx context.Context, before time.Time) error {
+	query := `
+	DELETE FROM
+		snapshots
+	WHERE
+		snapshotTime < $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(ctx, query, before.UnixNano())
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) newSubscription(ctx context.Context, s models.Subscription) (string, error) {
+	query := pragma(`
+	INSERT INTO subscriptions
+		(subscriptionID, subscriptionUserID, subscriptionEvent,
+		subscriptionTargetID, subscriptionChannel, subscriptionAddress)
+	VALUES
+		($1, $2, $3, $4, $5, $6);
+	`)
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(
+		ctx,
+		query,
+		s.ID,
+		s.UserID,
+		string(s.Event),
+		s.TargetID,
+		s.Channel,
+		s.Address,
+	)
+	if err != nil {
+		return "", fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return s.ID, nil
+}
+
+func (cs *coreStorage) querySubscriptions(ctx context.Context, condition string, args ...interface{}) ([]models.Subscription, error) {
+	query := `
+	SELECT
+		subscriptionID, subscriptionUserID, subscriptionEvent,
+		subscriptionTargetID, subscriptionChannel, subscriptionAddress
+	FROM
+		subscriptions
+	WHERE
+		` + condition + `;
+	`
+
+	var (
+		subscriptionID       string
+		subscriptionUserID   string
+		subscriptionEvent    string
+		subscriptionTargetID string
+		subscriptionChannel  string
+		subscriptionAddress  string
+	)
+
+	rows, err := cs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	res := []models.Subscription{}
+
+	for rows.Next() {
+		err = rows.Scan(
+			&subscriptionID,
+			&subscriptionUserID,
+			&subscriptionEvent,
+			&subscriptionTargetID,
+			&subscriptionChannel,
+			&subscriptionAddress,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.Subscription{
+			ID:       subscriptionID,
+			UserID:   subscriptionUserID,
+			Event:    models.SubscriptionEvent(subscriptionEvent),
+			TargetID: subscriptionTargetID,
+			Channel:  subscriptionChannel,
+			Address:  subscriptionAddress,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+func (cs *coreStorage) subscriptionsOfUser(ctx context.Context, userID string) ([]models.Subscription, error) {
+	return cs.querySubscriptions(ctx, "subscriptionUserID = $1", userID)
+}
+
+func (cs *coreStorage) allSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	return cs.querySubscriptions(ctx, "1 = 1")
+}
+
+func (cs *coreStorage) removeSubscription(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM
+		subscriptions
+	WHERE
+		subscriptionID = $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	res, err := cs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected: %w", err)
+	}
+	if affected == 0 {
+		return serrors.ErrNoID
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) readKey(ctx context.Context, name string) ([]byte, error) {
+	query := `
+	SELECT
+		keyValue
+	FROM
+		keys
+	WHERE
+		keyName = $1;
+	`
+
+	var keyValue []byte
+	err := cs.db.QueryRowContext(ctx, query, name).Scan(&keyValue)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("there is no key with name=%s: %w", name, serrors.ErrNoID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryRowContext: %w", err)
+	}
+
+	return keyValue, nil
+}
+
+func (cs *coreStorage) saveKey(ctx context.Context, name string, key []byte) error {
+	query := `
+	INSERT INTO keys
+		(keyName, keyValue)
+	VALUES
+		($1, $2)
+	ON CONFLICT(keyName) DO UPDATE SET
+		keyValue = excluded.keyValue;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(ctx, query, name, key)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) newScanner(ctx context.Context, k models.ScannerKey) (string, error) {
+	query := `
+	INSERT INTO scanners
+		(scannerID, scannerName, scannerSecret, scannerCreated, scannerLastUsed)
+	VALUES
+		($1, $2, $3, $4, $5);
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(
+		ctx,
+		query,
+		k.ID,
+		k.Name,
+		k.Secret,
+		sqliteTime(k.Created),
+		sqliteTime(k.LastUsed),
+	)
+	if err != nil {
+		return "", fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return k.ID, nil
+}
+
+func scannersFromRows(rows *sql.Rows) ([]models.ScannerKey, error) {
+	var (
+		scannerID       string
+		scannerName     string
+		scannerSecret   []byte
+		scannerCreated  int64
+		scannerLastUsed int64
+	)
+
+	res := []models.ScannerKey{}
+
+	for rows.Next() {
+		err := rows.Scan(
+			&scannerID,
+			&scannerName,
+			&scannerSecret,
+			&scannerCreated,
+			&scannerLastUsed,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.ScannerKey{
+			ID:       scannerID,
+			Name:     scannerName,
+			Secret:   copyBytes(scannerSecret),
+			Created:  fromSqliteTime(scannerCreated),
+			LastUsed: fromSqliteTime(scannerLastUsed),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+const selectScannersQuery = `
+	SELECT
+		scannerID, scannerName, scannerSecret, scannerCreated, scannerLastUsed
+	FROM
+		scanners
+	WHERE
+		`
+
+func (cs *coreStorage) queryScanners(ctx context.Context, condition string, args ...interface{}) ([]models.ScannerKey, error) {
+	rows, err := cs.db.QueryContext(ctx, selectScannersQuery+condition+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	return scannersFromRows(rows)
+}
+
+func (cs *coreStorage) readScanner(ctx context.Context, id string) (*models.ScannerKey, error) {
+	res, err := cs.queryScanners(ctx, "scannerID = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("there is no scanner with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return &res[0], nil
+}
+
+func (cs *coreStorage) updateScanner(ctx context.Context, id string, f func(*models.ScannerKey) error) error {
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, selectScannersQuery+"scannerID = $1;", id)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.QueryContext: %w", err)
+	}
+	scanners, err := scannersFromRows(rows)
+	rows.Close()
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("scannersFromRows: %w", err)
+	}
+	if len(scanners) == 0 {
+		tx.Rollback()
+		return fmt.Errorf("there is no scanner with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	scanner := scanners[0]
+
+	if err := f(&scanner); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("f: %w", err)
+	}
+
+	updateQuery := `
+	UPDATE
+		scanners
+	SET
+		scannerName = $2, scannerSecret = $3, scannerCreated = $4,
+		scannerLastUsed = $5
+	WHERE
+		scannerID = $1;
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		updateQuery,
+		id,
+		scanner.Name,
+		scanner.Secret,
+		sqliteTime(scanner.Created),
+		sqliteTime(scanner.LastUsed),
+	)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) removeScanner(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM
+		scanners
+	WHERE
+		scannerID = $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	res, err := cs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected: %w", err)
+	}
+	if affected == 0 {
+		return serrors.ErrNoID
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) readSetting(ctx context.Context, name string) (string, error) {
+	query := `
+	SELECT
+		settingValue
+	FROM
+		settings
+	WHERE
+		settingName = $1;
+	`
+
+	var settingValue string
+	err := cs.db.QueryRowContext(ctx, query, name).Scan(&settingValue)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("there is no setting with name=%s: %w", name, serrors.ErrNoID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cs.db.QueryRowContext: %w", err)
+	}
+
+	return settingValue, nil
+}
+
+func (cs *coreStorage) saveSetting(ctx context.Context, name string, value string) error {
+	query := `
+	INSERT INTO settings
+		(settingName, settingValue)
+	VALUES
+		($1, $2)
+	ON CONFLICT(settingName) DO UPDATE SET
+		settingValue = excluded.settingValue;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(ctx, query, name, value)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) newInvite(ctx context.Context, i models.Invite) (string, error) {
+	query := pragma(`
+	INSERT INTO invites
+		(inviteID, inviteCreatorID, inviteMaxUses, inviteUses, inviteCreated,
+		inviteExpires)
+	VALUES
+		($1, $2, $3, $4, $5, $6);
+	`)
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(
+		ctx,
+		query,
+		i.ID,
+		i.CreatorID,
+		i.MaxUses,
+		i.Uses,
+		sqliteTime(i.Created),
+		sqliteTime(i.Expires),
+	)
+	if err != nil {
+		return "", fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return i.ID, nil
+}
+
+func invitesFromRows(rows *sql.Rows) ([]models.Invite, error) {
+	var (
+		inviteID        string
+		inviteCreatorID string
+		inviteMaxUses   int
+		inviteUses      int
+		inviteCreated   int64
+		inviteExpires   int64
+	)
+
+	res := []models.Invite{}
+
+	for rows.Next() {
+		err := rows.Scan(
+			&inviteID,
+			&inviteCreatorID,
+			&inviteMaxUses,
+			&inviteUses,
+			&inviteCreated,
+			&inviteExpires,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.Invite{
+			ID:        inviteID,
+			CreatorID: inviteCreatorID,
+			MaxUses:   inviteMaxUses,
+			Uses:      inviteUses,
+			Created:   fromSqliteTime(inviteCreated),
+			Expires:   fromSqliteTime(inviteExpires),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+const selectInvitesQuery = `
+	SELECT
+		inviteID, inviteCreatorID, inviteMaxUses, inviteUses, inviteCreated,
+		inviteExpires
+	FROM
+		invites
+	WHERE
+		`
+
+func (cs *coreStorage) queryInvites(ctx context.Context, condition string, args ...interface{}) ([]models.Invite, error) {
+	rows, err := cs.db.QueryContext(ctx, selectInvitesQuery+condition+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	return invitesFromRows(rows)
+}
+
+func (cs *coreStorage) readInvite(ctx context.Context, id string) (*models.Invite, error) {
+	res, err := cs.queryInvites(ctx, "inviteID = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("there is no invite with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return &res[0], nil
+}
+
+func (cs *coreStorage) updateInvite(ctx context.Context, id string, f func(*models.Invite) error) error {
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, selectInvitesQuery+"inviteID = $1;", id)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.QueryContext: %w", err)
+	}
+	invites, err := invitesFromRows(rows)
+	rows.Close()
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("invitesFromRows: %w", err)
+	}
+	if len(invites) == 0 {
+		tx.Rollback()
+		return fmt.Errorf("there is no invite with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	invite := invites[0]
+
+	if err := f(&invite); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("f: %w", err)
+	}
+
+	updateQuery := `
+	UPDATE
+		invites
+	SET
+		inviteMaxUses = $2, inviteUses = $3, inviteCreated = $4,
+		inviteExpires = $5
+	WHERE
+		inviteID = $1;
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		updateQuery,
+		id,
+		invite.MaxUses,
+		invite.Uses,
+		sqliteTime(invite.Created),
+		sqliteTime(invite.Expires),
+	)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) removeInvite(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM
+		invites
+	WHERE
+		inviteID = $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	res, err := cs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("there is no invite with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) newResetToken(ctx context.Context, t models.ResetToken) (string, error) {
+	query := pragma(`
+	INSERT INTO resetTokens
+		(resetTokenID, resetTokenUserID, resetTokenIssuerID, resetTokenSecret,
+		resetTokenCreated, resetTokenExpires)
+	VALUES
+		($1, $2, $3, $4, $5, $6);
+	`)
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(
+		ctx,
+		query,
+		t.ID,
+		t.UserID,
+		t.IssuerID,
+		t.Secret,
+		sqliteTime(t.Created),
+		sqliteTime(t.Expires),
+	)
+	if err != nil {
+		return "", fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return t.ID, nil
+}
+
+const selectResetTokensQuery = `
+	SELECT
+		resetTokenID, resetTokenUserID, resetTokenIssuerID, resetTokenSecret,
+		resetTokenCreated, resetTokenExpires
+	FROM
+		resetTokens
+	WHERE
+		`
+
+func (cs *coreStorage) queryResetTokens(ctx context.Context, condition string, args ...interface{}) ([]models.ResetToken, error) {
+	rows, err := cs.db.QueryContext(ctx, selectResetTokensQuery+condition+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		resetTokenID       string
+		resetTokenUserID   string
+		resetTokenIssuerID string
+		resetTokenSecret   []byte
+		resetTokenCreated  int64
+		resetTokenExpires  int64
+	)
+
+	res := []models.ResetToken{}
+
+	for rows.Next() {
+		err := rows.Scan(
+			&resetTokenID,
+			&resetTokenUserID,
+			&resetTokenIssuerID,
+			&resetTokenSecret,
+			&resetTokenCreated,
+			&resetTokenExpires,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.ResetToken{
+			ID:       resetTokenID,
+			UserID:   resetTokenUserID,
+			IssuerID: resetTokenIssuerID,
+			Secret:   copyBytes(resetTokenSecret),
+			Created:  fromSqliteTime(resetTokenCreated),
+			Expires:  fromSqliteTime(resetTokenExpires),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+func (cs *coreStorage) readResetToken(ctx context.Context, id string) (*models.ResetToken, error) {
+	res, err := cs.queryResetTokens(ctx, "resetTokenID = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("there is no reset token with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return &res[0], nil
+}
+
+func (cs *coreStorage) removeResetToken(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM
+		resetTokens
+	WHERE
+		resetTokenID = $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	res, err := cs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("there is no reset token with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) addAuditEntry(ctx context.Context, e models.AuditEntry) error {
+	query := `
+	INSERT INTO audit
+		(auditID, auditTime, auditActorID, auditAction, auditTargetID)
+	VALUES
+		($1, $2, $3, $4, $5);
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(
+		ctx,
+		query,
+		e.ID,
+		e.Time.UnixNano(),
+		e.ActorID,
+		e.Action,
+		e.TargetID,
+	)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) auditBetween(ctx context.Context, from, to time.Time) ([]models.AuditEntry, error) {
+	query := `
+	SELECT
+		auditID, auditTime, auditActorID, auditAction, auditTargetID
+	FROM
+		audit
+	WHERE
+		auditTime >= $1 AND auditTime < $2
+	ORDER BY
+		auditTime;
+	`
+
+	var (
+		auditID       string
+		auditTime     int64
+		auditActorID  string
+		auditAction   string
+		auditTargetID string
+	)
+
+	rows, err := cs.db.QueryContext(ctx, query, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	res := []models.AuditEntry{}
+
+	for rows.Next() {
+		err = rows.Scan(&auditID, &auditTime, &auditActorID, &auditAction, &auditTargetID)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.AuditEntry{
+			ID:       auditID,
+			Time:     time.Unix(0, auditTime),
+			ActorID:  auditActorID,
+			Action:   auditAction,
+			TargetID: auditTargetID,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+func (cs *coreStorage) newAPIToken(ctx context.Context, t models.APIToken) (string, error) {
+	query := pragma(`
+	INSERT INTO apiTokens
+		(apiTokenID, apiTokenUserID, apiTokenName, apiTokenScopes,
+		apiTokenSecret, apiTokenCreated, apiTokenExpires, apiTokenLastUsed)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8);
+	`)
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(
+		ctx,
+		query,
+		t.ID,
+		t.UserID,
+		t.Name,
+		joinScopes(t.Scopes),
+		t.Secret,
+		sqliteTime(t.Created),
+		sqliteTime(t.Expires),
+		sqliteTime(t.LastUsed),
+	)
+	if err != nil {
+		return "", fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return t.ID, nil
+}
+
+// joinScopes returns scopes separated with commas.
+func joinScopes(scopes []models.Scope) string {
+	res := make([]string, len(scopes))
+	for i, s := range scopes {
+		res[i] = string(s)
+	}
+	return strings.Join(res, ",")
+}
+
+// splitScopes parses scopes joined with joinScopes.
+func splitScopes(joined string) []models.Scope {
+	res := []models.Scope{}
+	if joined == "" {
+		return res
+	}
+	for _, s := range strings.Split(joined, ",") {
+		res = append(res, models.Scope(s))
+	}
+	return res
+}
+
+func apiTokensFromRows(rows *sql.Rows) ([]models.APIToken, error) {
+	var (
+		apiTokenID       string
+		apiTokenUserID   string
+		apiTokenName     string
+		apiTokenScopes   string
+		apiTokenSecret   []byte
+		apiTokenCreated  int64
+		apiTokenExpires  int64
+		apiTokenLastUsed int64
+	)
+
+	res := []models.APIToken{}
+
+	for rows.Next() {
+		err := rows.Scan(
+			&apiTokenID,
+			&apiTokenUserID,
+			&apiTokenName,
+			&apiTokenScopes,
+			&apiTokenSecret,
+			&apiTokenCreated,
+			&apiTokenExpires,
+			&apiTokenLastUsed,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.APIToken{
+			ID:       apiTokenID,
+			UserID:   apiTokenUserID,
+			Name:     apiTokenName,
+			Scopes:   splitScopes(apiTokenScopes),
+			Secret:   copyBytes(apiTokenSecret),
+			Created:  fromSqliteTime(apiTokenCreated),
+			Expires:  fromSqliteTime(apiTokenExpires),
+			LastUsed: fromSqliteTime(apiTokenLastUsed),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+const selectAPITokensQuery = `
+	SELECT
+		apiTokenID, apiTokenUserID, apiTokenName, apiTokenScopes,
+		apiTokenSecret, apiTokenCreated, apiTokenExpires, apiTokenLastUsed
+	FROM
+		apiTokens
+	WHERE
+		`
+
+func (cs *coreStorage) queryAPITokens(ctx context.Context, condition string, args ...interface{}) ([]models.APIToken, error) {
+	rows, err := cs.db.QueryContext(ctx, selectAPITokensQuery+condition+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	return apiTokensFromRows(rows)
+}
+
+func (cs *coreStorage) readAPIToken(ctx context.Context, id string) (*models.APIToken, error) {
+	res, err := cs.queryAPITokens(ctx, "apiTokenID = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("there is no api token with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return &res[0], nil
+}
+
+func (cs *coreStorage) updateAPIToken(ctx context.Context, id string, f func(*models.APIToken) error) error {
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, selectAPITokensQuery+"apiTokenID = $1;", id)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.QueryContext: %w", err)
+	}
+	tokens, err := apiTokensFromRows(rows)
+	rows.Close()
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("apiTokensFromRows: %w", err)
+	}
+	if len(tokens) == 0 {
+		tx.Rollback()
+		return fmt.Errorf("there is no api token with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	token := tokens[0]
+
+	if err := f(&token); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("f: %w", err)
+	}
+
+	updateQuery := `
+	UPDATE
+		apiTokens
+	SET
+		apiTokenName = $2, apiTokenScopes = $3, apiTokenSecret = $4,
+		apiTokenCreated = $5, apiTokenExpires = $6, apiTokenLastUsed = $7
+	WHERE
+		apiTokenID = $1;
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		updateQuery,
+		id,
+		token.Name,
+		joinScopes(token.Scopes),
+		token.Secret,
+		sqliteTime(token.Created),
+		sqliteTime(token.Expires),
+		sqliteTime(token.LastUsed),
+	)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) removeAPIToken(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM
+		apiTokens
+	WHERE
+		apiTokenID = $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	res, err := cs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("there is no api token with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) newSession(ctx context.Context, s models.Session) (string, error) {
+	query := pragma(`
+	INSERT INTO sessions
+		(sessionID, sessionUserID, sessionUserAgent, sessionIP,
+		sessionCreated, sessionLastUsed, sessionExpires, sessionGeneration)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8);
+	`)
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	_, err := cs.db.ExecContext(
+		ctx,
+		query,
+		s.ID,
+		s.UserID,
+		s.UserAgent,
+		s.IP,
+		sqliteTime(s.Created),
+		sqliteTime(s.LastUsed),
+		sqliteTime(s.Expires),
+		s.Generation,
+	)
+	if err != nil {
+		return "", fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	return s.ID, nil
+}
+
+func sessionsFromRows(rows *sql.Rows) ([]models.Session, error) {
+	var (
+		sessionID         string
+		sessionUserID     string
+		sessionUserAgent  string
+		sessionIP         string
+		sessionCreated    int64
+		sessionLastUsed   int64
+		sessionExpires    int64
+		sessionGeneration int
+	)
+
+	res := []models.Session{}
+
+	for rows.Next() {
+		err := rows.Scan(
+			&sessionID,
+			&sessionUserID,
+			&sessionUserAgent,
+			&sessionIP,
+			&sessionCreated,
+			&sessionLastUsed,
+			&sessionExpires,
+			&sessionGeneration,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		res = append(res, models.Session{
+			ID:         sessionID,
+			UserID:     sessionUserID,
+			UserAgent:  sessionUserAgent,
+			IP:         sessionIP,
+			Created:    fromSqliteTime(sessionCreated),
+			LastUsed:   fromSqliteTime(sessionLastUsed),
+			Expires:    fromSqliteTime(sessionExpires),
+			Generation: sessionGeneration,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return res, nil
+}
+
+const selectSessionsQuery = `
+	SELECT
+		sessionID, sessionUserID, sessionUserAgent, sessionIP,
+		sessionCreated, sessionLastUsed, sessionExpires, sessionGeneration
+	FROM
+		sessions
+	WHERE
+		`
+
+func (cs *coreStorage) querySessions(ctx context.Context, condition string, args ...interface{}) ([]models.Session, error) {
+	rows, err := cs.db.QueryContext(ctx, selectSessionsQuery+condition+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("cs.db.QueryContext: %w", err)
+	}
+	defer rows.Close()
+
+	return sessionsFromRows(rows)
+}
+
+func (cs *coreStorage) readSession(ctx context.Context, id string) (*models.Session, error) {
+	res, err := cs.querySessions(ctx, "sessionID = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("there is no session with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return &res[0], nil
+}
+
+func (cs *coreStorage) updateSession(ctx context.Context, id string, f func(*models.Session) error) error {
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	tx, err := cs.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cs.db.Begin: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, selectSessionsQuery+"sessionID = $1;", id)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.QueryContext: %w", err)
+	}
+	sessions, err := sessionsFromRows(rows)
+	rows.Close()
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("sessionsFromRows: %w", err)
+	}
+	if len(sessions) == 0 {
+		tx.Rollback()
+		return fmt.Errorf("there is no session with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	s := sessions[0]
+
+	if err := f(&s); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("f: %w", err)
+	}
+
+	updateQuery := `
+	UPDATE
+		sessions
+	SET
+		sessionUserAgent = $2, sessionIP = $3, sessionCreated = $4,
+		sessionLastUsed = $5, sessionExpires = $6, sessionGeneration = $7
+	WHERE
+		sessionID = $1;
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		updateQuery,
+		id,
+		s.UserAgent,
+		s.IP,
+		sqliteTime(s.Created),
+		sqliteTime(s.LastUsed),
+		sqliteTime(s.Expires),
+		s.Generation,
+	)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("tx.ExecContext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return nil
+}
+
+func (cs *coreStorage) removeSession(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM
+		sessions
+	WHERE
+		sessionID = $1;
+	`
+
+	cs.writeGuard.Lock()
+	defer cs.writeGuard.Unlock()
+
+	res, err := cs.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("cs.db.ExecContext: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("there is no session with id=%s: %w", id, serrors.ErrNoID)
+	}
+
+	return nil
+}
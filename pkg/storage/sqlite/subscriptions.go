@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Subscriptions storage implements storage.Subscriptions
+// interface for sqlite database.
+type Subscriptions struct {
+	cs *coreStorage
+}
+
+// New stores given subscription and returns its id.
+func (s *Subscriptions) New(ctx context.Context, sub models.Subscription) (string, error) {
+	return s.cs.newSubscription(ctx, sub)
+}
+
+// OfUser returns subscriptions of user with given id.
+func (s *Subscriptions) OfUser(ctx context.Context, userID string) ([]models.Subscription, error) {
+	return s.cs.subscriptionsOfUser(ctx, userID)
+}
+
+// All returns slice with every subscription.
+func (s *Subscriptions) All(ctx context.Context) ([]models.Subscription, error) {
+	return s.cs.allSubscriptions(ctx)
+}
+
+// Remove deletes subscription with given id.
+func (s *Subscriptions) Remove(ctx context.Context, id string) error {
+	return s.cs.removeSubscription(ctx, id)
+}
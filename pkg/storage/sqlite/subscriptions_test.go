@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
+)
+
+func TestSubscriptions(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "johnny", HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm")},
+		{ID: "2", Nickname: "marco", HashedPassword: []byte("u8dXHRi0JNo23JVeHkjh")},
+	} {
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	subscriptionsData := map[string]models.Subscription{
+		"1": {ID: "1", UserID: "1", Event: models.ArrivalEvent, TargetID: "2", Channel: "email", Address: "johnny@example.com"},
+		"2": {ID: "2", UserID: "1", Event: models.OpenEvent, Channel: "ntfy", Address: "https://ntfy.sh/hs"},
+		"3": {ID: "3", UserID: "2", Event: models.CloseEvent, Channel: "ntfy", Address: "https://ntfy.sh/marco"},
+	}
+
+	ss := f.Subscriptions()
+	for _, s := range subscriptionsData {
+		id, err := ss.New(ctx, s)
+		is.NoErr(err)
+		is.Equal(id, s.ID)
+	}
+
+	// Try to subscribe by user that doesn't exist.
+	_, err = ss.New(ctx, models.Subscription{ID: "4", UserID: "5", Event: models.OpenEvent})
+	is.True(err != nil)
+
+	all, err := ss.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), len(subscriptionsData))
+
+	for _, s := range all {
+		current, ok := subscriptionsData[s.ID]
+		is.True(ok)
+		is.Equal(current, s)
+	}
+
+	johnnySubscriptions, err := ss.OfUser(ctx, "1")
+	is.NoErr(err)
+	is.Equal(len(johnnySubscriptions), 2)
+
+	is.NoErr(ss.Remove(ctx, "1"))
+	is.True(errors.Is(ss.Remove(ctx, "1"), serrors.ErrNoID))
+
+	// Subscriptions are removed along with subscribers.
+	is.NoErr(f.Users().Remove(ctx, "2"))
+
+	all, err = ss.All(ctx)
+	is.NoErr(err)
+	is.Equal(len(all), 1)
+	is.Equal(all[0].ID, "2")
+}
@@ -2,11 +2,15 @@ package sqlite
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/matryer/is"
 
+	"github.com/hakierspejs/long-season/pkg/models"
 	"github.com/hakierspejs/long-season/pkg/storage"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
 )
 
 func TestUsers(t *testing.T) {
@@ -24,18 +28,25 @@ func TestUsers(t *testing.T) {
 			Nickname:       "user3000",
 			HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm"),
 			Private:        false,
+			Role:           models.RoleMember,
 		},
 		"2": {
 			ID:             "2",
 			Nickname:       "user3001",
 			HashedPassword: []byte("u8dXHRi0JNo23JVeHkjh"),
 			Private:        true,
+			Role:           models.RoleKeyholder,
+			Email:          "user3001@example.com",
+			EmailVerified:  true,
 		},
 		"3": {
 			ID:             "3",
 			Nickname:       "user3002",
 			HashedPassword: []byte("6eaOciUcg5EGSTkfQYvL"),
 			Private:        false,
+			Unfollowable:   true,
+			Role:           models.RoleMember,
+			Pending:        true,
 		},
 	}
 
@@ -51,9 +62,15 @@ func TestUsers(t *testing.T) {
 		readUser, err := s.Read(ctx, u.ID)
 		is.NoErr(err)
 		is.Equal(readUser.Private, u.Private)
+		is.Equal(readUser.Unfollowable, u.Unfollowable)
+		is.Equal(readUser.Role, u.Role)
+		is.Equal(readUser.Pending, u.Pending)
+		is.Equal(readUser.Email, u.Email)
+		is.Equal(readUser.EmailVerified, u.EmailVerified)
 		is.Equal(readUser.Nickname, u.Nickname)
 		is.Equal(readUser.HashedPassword, u.HashedPassword)
 		is.Equal(readUser.ID, u.ID)
+		is.True(readUser.SessionsRevoked.IsZero())
 	}
 
 	allUsers, err := s.All(ctx)
@@ -64,15 +81,26 @@ func TestUsers(t *testing.T) {
 		u, found := usersData[curr.ID]
 		is.True(found)
 		is.Equal(u.Private, curr.Private)
+		is.Equal(u.Unfollowable, curr.Unfollowable)
+		is.Equal(u.Role, curr.Role)
+		is.Equal(u.Pending, curr.Pending)
+		is.Equal(u.Email, curr.Email)
+		is.Equal(u.EmailVerified, curr.EmailVerified)
 		is.Equal(u.ID, curr.ID)
 		is.Equal(u.Nickname, curr.Nickname)
 		is.Equal(u.HashedPassword, curr.HashedPassword)
 	}
 
+	revoked := time.Unix(1700000000, 42)
 	err = s.Update(ctx, "1", func(u *storage.UserEntry) error {
+		u.SessionsRevoked = revoked
 		u.HashedPassword = []byte("new password")
 		u.Nickname = "new nickname"
 		u.Private = true
+		u.Unfollowable = true
+		u.Role = models.RoleAdmin
+		u.Pending = true
+		u.Email = "user3000@example.com"
 		return nil
 	})
 	is.NoErr(err)
@@ -81,13 +109,41 @@ func TestUsers(t *testing.T) {
 	is.NoErr(err)
 	is.Equal(newUser.HashedPassword, []byte("new password"))
 	is.Equal(newUser.Private, true)
+	is.Equal(newUser.Unfollowable, true)
+	is.Equal(newUser.Role, models.RoleAdmin)
+	is.Equal(newUser.Pending, true)
+	is.Equal(newUser.Email, "user3000@example.com")
+	is.Equal(newUser.EmailVerified, false)
 	is.Equal(newUser.ID, "1")
 	is.Equal(newUser.Nickname, "new nickname")
+	is.True(newUser.SessionsRevoked.Equal(revoked))
+
+	// The only admin can be neither demoted nor removed.
+	is.True(errors.Is(s.Update(ctx, "1", func(u *storage.UserEntry) error {
+		u.Role = models.RoleMember
+		return nil
+	}), serrors.ErrLastAdmin))
+	is.True(errors.Is(s.Remove(ctx, "1"), serrors.ErrLastAdmin))
+
+	newUser, err = s.Read(ctx, "1")
+	is.NoErr(err)
+	is.Equal(newUser.Role, models.RoleAdmin)
+
+	err = s.Update(ctx, "2", func(u *storage.UserEntry) error {
+		u.Role = models.RoleAdmin
+		return nil
+	})
+	is.NoErr(err)
 
 	err = s.Remove(ctx, "1")
 	is.NoErr(err)
 
 	deletedUser, err := s.Read(ctx, "1")
-	is.True(err != nil)
+	is.True(errors.Is(err, serrors.ErrNoID))
 	is.Equal(deletedUser, nil)
+
+	is.True(errors.Is(s.Remove(ctx, "1"), serrors.ErrNoID))
+	is.True(errors.Is(s.Update(ctx, "1", func(u *storage.UserEntry) error {
+		return nil
+	}), serrors.ErrNoID))
 }
@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+// Visits storage implements storage.Visits interface for
+// sqlite database.
+type Visits struct {
+	cs *coreStorage
+}
+
+// Record extends latest visit of every user with given id to
+// given time, if the visit ended no earlier than given gap
+// before. Otherwise new visit, that starts at given time,
+// is stored.
+func (v *Visits) Record(ctx context.Context, userIDs []string, at time.Time, gap time.Duration) error {
+	return v.cs.recordVisits(ctx, userIDs, at, gap)
+}
+
+// Between returns visits overlapping given time range,
+// sorted by start time.
+func (v *Visits) Between(ctx context.Context, from, to time.Time) ([]models.Visit, error) {
+	return v.cs.visitsBetween(ctx, from, to)
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+
+	"github.com/hakierspejs/long-season/pkg/storage"
+)
+
+func TestVisits(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	f, closer, err := NewFactory(":memory:")
+	is.NoErr(err)
+	defer closer()
+
+	for _, u := range []storage.UserEntry{
+		{ID: "1", Nickname: "johnny", HashedPassword: []byte("71Hk4Rt2WY8xqgYoKxPm")},
+		{ID: "2", Nickname: "marco", HashedPassword: []byte("u8dXHRi0JNo23JVeHkjh")},
+	} {
+		_, err := f.Users().New(ctx, u)
+		is.NoErr(err)
+	}
+
+	sv := f.Visits()
+	start := time.Date(2023, time.March, 1, 18, 0, 0, 0, time.UTC)
+	gap := 2 * time.Minute
+
+	// Every minute for an hour.
+	for i := 0; i <= 60; i++ {
+		is.NoErr(sv.Record(ctx, []string{"1"}, start.Add(time.Duration(i)*time.Minute), gap))
+	}
+
+	// Second user comes back after a break.
+	is.NoErr(sv.Record(ctx, []string{"2", "2"}, start, gap))
+	is.NoErr(sv.Record(ctx, []string{"2"}, start.Add(time.Minute), gap))
+	is.NoErr(sv.Record(ctx, []string{"2"}, start.Add(30*time.Minute), gap))
+
+	// Visits of unknown users are skipped.
+	is.NoErr(sv.Record(ctx, []string{"3"}, start, gap))
+
+	visits, err := sv.Between(ctx, start, start.Add(time.Hour))
+	is.NoErr(err)
+	is.Equal(len(visits), 3)
+
+	byUser := map[string]int{}
+	for _, v := range visits {
+		byUser[v.UserID] += 1
+		if v.UserID == "1" {
+			is.Equal(v.Duration(), time.Hour)
+		}
+	}
+	is.Equal(byUser["1"], 1)
+	is.Equal(byUser["2"], 2)
+
+	// Only visit of the first user lasts past 18:45.
+	visits, err = sv.Between(ctx, start.Add(45*time.Minute), start.Add(2*time.Hour))
+	is.NoErr(err)
+	is.Equal(len(visits), 1)
+	is.Equal(visits[0].UserID, "1")
+
+	visits, err = sv.Between(ctx, start.Add(-time.Hour), start)
+	is.NoErr(err)
+	is.Equal(len(visits), 0)
+
+	// Removing user removes visits.
+	is.NoErr(f.Users().Remove(ctx, "2"))
+	visits, err = sv.Between(ctx, start, start.Add(time.Hour))
+	is.NoErr(err)
+	is.Equal(len(visits), 1)
+}
@@ -3,62 +3,188 @@ package storage
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+	serrors "github.com/hakierspejs/long-season/pkg/storage/errors"
 )
 
 // UpdateStatusesArgs contains arguments for UpdateStatuses function.
 type UpdateStatusesArgs struct {
-	Addresses          []net.HardwareAddr
+	Hosts              []models.Host
 	DevicesStorage     Devices
 	OnlineUsersStorage OnlineUsers
 	Counters           StatusTx
+
+	// IgnoredStorage is optional. Addresses matching one of
+	// ignored prefixes are excluded from both known and
+	// unknown devices.
+	IgnoredStorage Ignored
+
+	// Classify is optional. It is used to group unknown
+	// devices by category of their vendors.
+	Classify func(net.HardwareAddr) string
+
+	// CheckIns is optional. Users checked in, for example
+	// with their cards, are online even without any known
+	// device connected to the network.
+	CheckIns CheckIns
+
+	// LookupKey is optional key of lookup hashes. With key,
+	// hosts are matched by lookup hashes of their addresses
+	// first and devices without lookup hashes of matched
+	// addresses get them stored.
+	LookupKey []byte
+
+	// Time is optional time of update. Defaults
+	// to current time.
+	Time time.Time
 }
 
-var bcryptCache = make(map[string]bool)
+// Tick is result of single status update.
+type Tick struct {
+	// Time of update.
+	Time time.Time
+
+	// OnlineIDs contains ids of online users. Every id
+	// occurs only once.
+	OnlineIDs []string
+
+	// Known is number of online users.
+	Known int
+
+	// Unknown is number of unknown devices.
+	Unknown int
+}
+
+// bcryptCacheSize is maximal number of cached results of
+// bcrypt comparisons. Every status update compares every host
+// with every device, so it should be greater than number of
+// hosts in the network multiplied by number of hashed device
+// identifiers. Single entry takes about hundred bytes.
+const bcryptCacheSize = 1 << 16
+
+var bcryptCache = newLRU(bcryptCacheSize)
 
-// UpdateStatuses set online user fields, with any device's MAC equal to one
-// of addresses from given slice, to true and writes them to database.
-func UpdateStatuses(ctx context.Context, args UpdateStatusesArgs) error {
+// UpdateStatuses set online user fields, with any device matching one
+// of hosts from given slice, to true and writes them to database.
+// Returns result of update.
+func UpdateStatuses(ctx context.Context, args UpdateStatusesArgs) (*Tick, error) {
 
 	known, unknown := 0, 0
 	onlineIDs := []string{}
 
 	devices, err := args.DevicesStorage.All(ctx)
 	if err != nil {
-		return fmt.Errorf("args.DevicesStorage.All: %w", err)
+		return nil, fmt.Errorf("args.DevicesStorage.All: %w", err)
 	}
 
-	for _, address := range args.Addresses {
-		for _, device := range devices {
-			var matched bool
-			cacheKey := generateCacheKey(device.MAC, address)
+	hosts := args.Hosts
+	if args.IgnoredStorage != nil {
+		ignored, err := args.IgnoredStorage.All(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("args.IgnoredStorage.All: %w", err)
+		}
+		hosts = WithoutIgnored(hosts, ignored)
+	}
+
+	breakdown := map[string]int{}
+
+	byLookup := map[string]models.Device{}
+	for _, device := range devices {
+		for _, l := range device.Lookups {
+			byLookup[string(l)] = device
+		}
+	}
 
-			if val, ok := bcryptCache[cacheKey]; ok {
-				matched = val
-			} else {
-				err := bcrypt.CompareHashAndPassword(device.MAC, address)
-				matched = err == nil
-				bcryptCache[cacheKey] = matched
+	for _, host := range hosts {
+		var (
+			lookup  []byte
+			device  models.Device
+			matched bool
+		)
+		if args.LookupKey != nil && len(host.MAC) > 0 {
+			lookup = LookupHash(args.LookupKey, host.MAC)
+			device, matched = byLookup[string(lookup)]
+		}
+
+		if !matched {
+			for _, d := range devices {
+				if MatchHost(d, host) {
+					device, matched = d, true
+					break
+				}
 			}
 
-			if matched {
+			if matched && lookup != nil && MatchAddress(device, host.MAC) {
+				err := storeLookups(ctx, args.DevicesStorage, device.ID, func(d *models.Device) {
+					if !HasLookup(*d, lookup) {
+						d.Lookups = append(d.Lookups, lookup)
+					}
+				})
+				if err != nil {
+					return nil, fmt.Errorf("storeLookups: %w", err)
+				}
+				byLookup[string(lookup)] = device
+			}
+		}
+
+		if matched && args.LookupKey != nil && len(device.ClientIDLookup) == 0 &&
+			MatchClientID(device, host.ClientID) {
+			clientIDLookup := LookupHash(args.LookupKey, []byte(host.ClientID))
+			err := storeLookups(ctx, args.DevicesStorage, device.ID, func(d *models.Device) {
+				if len(d.ClientIDLookup) == 0 {
+					d.ClientIDLookup = clientIDLookup
+				}
+			})
+			if err != nil {
+				return nil, fmt.Errorf("storeLookups: %w", err)
+			}
+		}
+
+		if matched {
+			known += 1
+			onlineIDs = append(onlineIDs, device.OwnerID)
+		}
+
+		if !matched && args.Classify != nil {
+			breakdown[args.Classify(host.MAC)] += 1
+		}
+	}
+
+	unknown = len(hosts) - known
+
+	if args.CheckIns != nil {
+		checkedIn, err := args.CheckIns.All(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("args.CheckIns.All: %w", err)
+		}
+
+		online := map[string]struct{}{}
+		for _, id := range onlineIDs {
+			online[id] = struct{}{}
+		}
+
+		for _, id := range checkedIn {
+			if _, ok := online[id]; !ok {
 				known += 1
-				onlineIDs = append(onlineIDs, device.OwnerID)
-				break
+				onlineIDs = append(onlineIDs, id)
+				online[id] = struct{}{}
 			}
 		}
 	}
 
 	if err := args.OnlineUsersStorage.Update(ctx, onlineIDs); err != nil {
-		return fmt.Errorf("args.OnlineUsersStorage.Update: %w", err)
+		return nil, fmt.Errorf("args.OnlineUsersStorage.Update: %w", err)
 	}
 
-	unknown = len(args.Addresses) - known
-
-	return args.Counters.DevicesStatus(ctx,
+	err = args.Counters.DevicesStatus(ctx,
 		func(ctx context.Context, s Status) error {
 			if err := s.SetOnlineUsers(ctx, known); err != nil {
 				return fmt.Errorf("failed to set online users: %w", err)
@@ -68,10 +194,148 @@ func UpdateStatuses(ctx context.Context, args UpdateStatusesArgs) error {
 				return fmt.Errorf("failed to set unknown devices: %w", err)
 			}
 
+			if err := s.SetUnknownBreakdown(ctx, breakdown); err != nil {
+				return fmt.Errorf("failed to set unknown breakdown: %w", err)
+			}
+
 			return nil
 		})
+	if err != nil {
+		return nil, err
+	}
+
+	tick := &Tick{
+		Time:    args.Time,
+		Known:   known,
+		Unknown: unknown,
+	}
+	if tick.Time.IsZero() {
+		tick.Time = time.Now()
+	}
+
+	seen := map[string]struct{}{}
+	for _, id := range onlineIDs {
+		if _, ok := seen[id]; !ok {
+			tick.OnlineIDs = append(tick.OnlineIDs, id)
+			seen[id] = struct{}{}
+		}
+	}
+
+	return tick, nil
+}
+
+// storeLookups adds lookup hashes with given function to device
+// with given id, which has been registered before lookup hashes
+// were introduced. Devices removed in the meantime and lookups
+// already used by other devices are skipped, so they don't stop
+// status updates.
+func storeLookups(ctx context.Context, db Devices, id string, f func(*models.Device)) error {
+	err := db.Update(ctx, id, func(d *models.Device) error {
+		f(d)
+		return nil
+	})
+	if errors.Is(err, serrors.ErrNoID) ||
+		errors.Is(err, serrors.ErrMACDuplication) ||
+		errors.Is(err, serrors.ErrClientIDTaken) {
+		return nil
+	}
+	return err
+}
+
+// WithoutIgnored returns new slice with hosts, which addresses
+// don't match any of given ignored addresses.
+func WithoutIgnored(hosts []models.Host, ignored []models.IgnoredAddress) []models.Host {
+	res := make([]models.Host, 0, len(hosts))
+
+	for _, host := range hosts {
+		skip := false
+		for _, i := range ignored {
+			if i.Matches(host.MAC) {
+				skip = true
+				break
+			}
+		}
+
+		if !skip {
+			res = append(res, host)
+		}
+	}
+
+	return res
+}
+
+// MatchHost returns true if given host is the given device.
+// Host matches device if one of the following is true:
+//   - host address is one of hashed device addresses,
+//   - host DHCP client identifier is equal to hashed device
+//     client identifier,
+//   - host hostname is equal to device hostname.
+func MatchHost(device models.Device, host models.Host) bool {
+	if MatchAddress(device, host.MAC) {
+		return true
+	}
+
+	if MatchClientID(device, host.ClientID) {
+		return true
+	}
+
+	return MatchHostname(device.Hostname, host.Hostname)
+}
+
+// MatchHostname returns true if given hostnames are equal.
+// Comparison is case insensitive. Empty hostnames never match.
+func MatchHostname(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+
+	return strings.EqualFold(a, b)
+}
+
+// MatchClientID returns true if given DHCP client identifier
+// is equal to hashed client identifier of given device. Empty
+// identifiers never match.
+func MatchClientID(device models.Device, clientID string) bool {
+	if clientID == "" || len(device.ClientID) == 0 {
+		return false
+	}
+
+	return matchHash(device.ClientID, []byte(clientID))
+}
+
+// MatchAddress returns true if given hardware address is one of
+// addresses hashed and stored in given device. Results of
+// comparisons are cached, so it is cheap to call MatchAddress many
+// times with the same arguments.
+func MatchAddress(device models.Device, address net.HardwareAddr) bool {
+	if len(address) == 0 {
+		return false
+	}
+
+	for _, mac := range device.Addresses() {
+		if matchHash(mac, address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchHash compares bcrypt hash with given value and caches
+// result of comparison in bounded cache.
+func matchHash(hash, value []byte) bool {
+	cacheKey := generateCacheKey(hash, value)
+
+	if matched, ok := bcryptCache.Get(cacheKey); ok {
+		return matched
+	}
+
+	matched := bcrypt.CompareHashAndPassword(hash, value) == nil
+	bcryptCache.Set(cacheKey, matched)
+
+	return matched
 }
 
-func generateCacheKey(mac []byte, address net.HardwareAddr) string {
-	return string(mac) + ":" + hex.EncodeToString(address)
+func generateCacheKey(hash, value []byte) string {
+	return string(hash) + ":" + hex.EncodeToString(value)
 }
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"net"
+	"testing"
+
+	"github.com/matryer/is"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hakierspejs/long-season/pkg/models"
+)
+
+func TestMatchHost(t *testing.T) {
+	is := is.New(t)
+
+	hash := func(s []byte) []byte {
+		res, err := bcrypt.GenerateFromPassword(s, bcrypt.MinCost)
+		is.NoErr(err)
+		return res
+	}
+
+	mac := func(s string) net.HardwareAddr {
+		res, err := net.ParseMAC(s)
+		is.NoErr(err)
+		return res
+	}
+
+	device := models.Device{
+		MAC:      hash(mac("11:11:11:11:11:11")),
+		MACs:     [][]byte{hash(mac("22:22:22:22:22:22"))},
+		Hostname: "Pixel-7",
+		ClientID: hash([]byte("01:aa:bb")),
+	}
+
+	for _, tc := range []struct {
+		host    models.Host
+		matches bool
+	}{
+		{models.Host{MAC: mac("11:11:11:11:11:11")}, true},
+		{models.Host{MAC: mac("22:22:22:22:22:22")}, true},
+		{models.Host{MAC: mac("33:33:33:33:33:33")}, false},
+		{models.Host{MAC: mac("33:33:33:33:33:33"), Hostname: "pixel-7"}, true},
+		{models.Host{MAC: mac("33:33:33:33:33:33"), Hostname: "pixel-8"}, false},
+		{models.Host{MAC: mac("33:33:33:33:33:33"), Hostname: "iphone"}, false},
+		{models.Host{MAC: mac("33:33:33:33:33:33"), ClientID: "01:aa:bb"}, true},
+		{models.Host{MAC: mac("33:33:33:33:33:33"), ClientID: "01:aa:cc"}, false},
+	} {
+		is.Equal(MatchHost(device, tc.host), tc.matches)
+	}
+
+	// Devices without alternative identifiers match only
+	// by their main address.
+	plain := models.Device{MAC: hash(mac("11:11:11:11:11:11"))}
+	is.True(!MatchHost(plain, models.Host{MAC: mac("33:33:33:33:33:33"), Hostname: "pixel-7"}))
+}
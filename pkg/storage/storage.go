@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/hakierspejs/long-season/pkg/models"
 )
@@ -12,6 +13,19 @@ type Factory interface {
 	Users() Users
 	Devices() Devices
 	TwoFactor() TwoFactor
+	Ignored() Ignored
+	Cards() Cards
+	Visits() Visits
+	History() History
+	Subscriptions() Subscriptions
+	Keys() Keys
+	Scanners() Scanners
+	Settings() Settings
+	Invites() Invites
+	ResetTokens() ResetTokens
+	Audit() Audit
+	APITokens() APITokens
+	Sessions() Sessions
 }
 
 // UserEntry represents user data stored in data storage.
@@ -29,6 +43,30 @@ type UserEntry struct {
 	// Private is flag for enabling private-mode that hides
 	// user activity from others.
 	Private bool
+
+	// Unfollowable is flag for opting out of notifications
+	// about user arrivals sent to other users.
+	Unfollowable bool
+
+	// Role grants user permissions. Users without
+	// role are members.
+	Role models.Role
+
+	// SessionsRevoked is the moment of revoking all sessions
+	// of user. Sessions created earlier are no longer valid.
+	SessionsRevoked time.Time
+
+	// Pending is flag for accounts waiting for approval
+	// of admin. Pending users cannot log in.
+	Pending bool
+
+	// Email is optional email address of user. It is
+	// never exposed to public.
+	Email string
+
+	// EmailVerified is flag for email addresses confirmed
+	// by following link sent to them.
+	EmailVerified bool
 }
 
 // Users interface handles generic create, read,
@@ -39,16 +77,261 @@ type Users interface {
 	New(ctx context.Context, u UserEntry) (string, error)
 	Read(ctx context.Context, id string) (*UserEntry, error)
 	All(ctx context.Context) ([]UserEntry, error)
+
+	// Remove and Update return errors.ErrLastAdmin, when
+	// they would remove or demote the only admin. Admins
+	// are counted in the same transaction as the change,
+	// so concurrent changes can't remove all of them.
 	Remove(ctx context.Context, id string) error
 	Update(ctx context.Context, id string, f func(*UserEntry) error) error
 }
 
 type Devices interface {
+	// New stores given device and returns its id. It returns
+	// errors.ErrMACDuplication if any lookup hash of the device
+	// is already used by other device and errors.ErrHostnameTaken
+	// or errors.ErrClientIDTaken if other device has the same
+	// hostname or lookup hash of client id.
 	New(ctx context.Context, userID string, d models.Device) (string, error)
 	OfUser(ctx context.Context, userID string) ([]models.Device, error)
 	Read(ctx context.Context, id string) (*models.Device, error)
 	All(ctx context.Context) ([]models.Device, error)
 	Remove(ctx context.Context, id string) error
+
+	// Update changes device with given id. Like New, it returns
+	// errors.ErrMACDuplication, errors.ErrHostnameTaken or
+	// errors.ErrClientIDTaken if updated device shares identifier
+	// with other device.
+	Update(ctx context.Context, id string, f func(*models.Device) error) error
+}
+
+// Ignored storage keeps hardware addresses and prefixes of
+// addresses of space owned infrastructure devices, which
+// should be excluded from online statuses.
+type Ignored interface {
+	// New stores given ignored address and returns
+	// its id.
+	New(ctx context.Context, a models.IgnoredAddress) (string, error)
+
+	// All returns slice with every ignored address.
+	All(ctx context.Context) ([]models.IgnoredAddress, error)
+
+	// Remove deletes ignored address with given id.
+	Remove(ctx context.Context, id string) error
+}
+
+// Cards storage keeps NFC and RFID cards of users.
+type Cards interface {
+	// New stores given card of user with given id and
+	// returns card id.
+	New(ctx context.Context, userID string, c models.Card) (string, error)
+
+	// OfUser returns cards of user with given id.
+	OfUser(ctx context.Context, userID string) ([]models.Card, error)
+
+	// All returns slice with every card.
+	All(ctx context.Context) ([]models.Card, error)
+
+	// Remove deletes card with given id.
+	Remove(ctx context.Context, id string) error
+}
+
+// CheckIns keeps users, that declared their presence in
+// the hackerspace explicitly, for example by scanning their
+// cards at the door, instead of connecting devices to network.
+type CheckIns interface {
+	// CheckIn marks user with given id as present for
+	// given amount of time.
+	CheckIn(ctx context.Context, userID string, ttl time.Duration) error
+
+	// CheckOut marks user with given id as absent.
+	CheckOut(ctx context.Context, userID string) error
+
+	// IsCheckedIn returns true if user with given id
+	// is present.
+	IsCheckedIn(ctx context.Context, userID string) (bool, error)
+
+	// All returns ids of every present user.
+	All(ctx context.Context) ([]string, error)
+}
+
+// Visits keeps intervals of users presence in the hackerspace,
+// recorded by status daemon.
+type Visits interface {
+	// Record extends latest visit of every user with given
+	// id to given time, if the visit ended no earlier than
+	// given gap before. Otherwise new visit, that starts at
+	// given time, is stored.
+	Record(ctx context.Context, userIDs []string, at time.Time, gap time.Duration) error
+
+	// Between returns visits overlapping given time range,
+	// sorted by start time.
+	Between(ctx context.Context, from, to time.Time) ([]models.Visit, error)
+}
+
+// History keeps snapshots of online users counter.
+type History interface {
+	// Add stores given snapshot.
+	Add(ctx context.Context, s models.Snapshot) error
+
+	// Between returns snapshots taken in given time
+	// range, sorted by time.
+	Between(ctx context.Context, from, to time.Time) ([]models.Snapshot, error)
+
+	// Prune removes snapshots taken before given time.
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// Subscriptions storage keeps subscriptions of users to
+// notifications.
+type Subscriptions interface {
+	// New stores given subscription and returns its id.
+	New(ctx context.Context, s models.Subscription) (string, error)
+
+	// OfUser returns subscriptions of user with given id.
+	OfUser(ctx context.Context, userID string) ([]models.Subscription, error)
+
+	// All returns slice with every subscription.
+	All(ctx context.Context) ([]models.Subscription, error)
+
+	// Remove deletes subscription with given id.
+	Remove(ctx context.Context, id string) error
+}
+
+// Scanners storage keeps keys of scanners allowed to
+// report devices and cards.
+type Scanners interface {
+	// New stores given key and returns its id.
+	New(ctx context.Context, k models.ScannerKey) (string, error)
+
+	// Read returns key with given id. Returns
+	// errors.ErrNoID if there is no such key.
+	Read(ctx context.Context, id string) (*models.ScannerKey, error)
+
+	// All returns slice with every key.
+	All(ctx context.Context) ([]models.ScannerKey, error)
+
+	// Update applies given function to key with given id.
+	Update(ctx context.Context, id string, f func(*models.ScannerKey) error) error
+
+	// Remove deletes key with given id.
+	Remove(ctx context.Context, id string) error
+}
+
+// Keys storage keeps cryptographic keys generated
+// by the server.
+type Keys interface {
+	// Read returns key with given name. Returns
+	// errors.ErrNoID if there is no such key.
+	Read(ctx context.Context, name string) ([]byte, error)
+
+	// Save stores key with given name, replacing
+	// previous one.
+	Save(ctx context.Context, name string, key []byte) error
+}
+
+// Settings storage keeps settings changed at runtime,
+// which override defaults from configuration.
+type Settings interface {
+	// Read returns value of setting with given name. Returns
+	// errors.ErrNoID if setting has never been saved.
+	Read(ctx context.Context, name string) (string, error)
+
+	// Save stores value of setting with given name,
+	// replacing previous one.
+	Save(ctx context.Context, name string, value string) error
+}
+
+// Invites interface handles invite codes, that allow
+// registration when it is invite-only.
+type Invites interface {
+	// New stores given invite and returns its id.
+	New(ctx context.Context, i models.Invite) (string, error)
+
+	// Read returns invite with given id. Returns
+	// errors.ErrNoID if there is no such invite.
+	Read(ctx context.Context, id string) (*models.Invite, error)
+
+	// OfUser returns invites created by user with given id.
+	OfUser(ctx context.Context, userID string) ([]models.Invite, error)
+
+	// All returns every invite.
+	All(ctx context.Context) ([]models.Invite, error)
+
+	// Update applies given function to invite with given id.
+	Update(ctx context.Context, id string, f func(*models.Invite) error) error
+
+	// Remove deletes invite with given id.
+	Remove(ctx context.Context, id string) error
+}
+
+// ResetTokens interface handles one-time tokens for
+// resetting passwords.
+type ResetTokens interface {
+	// New stores given token and returns its id.
+	New(ctx context.Context, t models.ResetToken) (string, error)
+
+	// Read returns token with given id. Returns
+	// errors.ErrNoID if there is no such token.
+	Read(ctx context.Context, id string) (*models.ResetToken, error)
+
+	// OfUser returns tokens for user with given id.
+	OfUser(ctx context.Context, userID string) ([]models.ResetToken, error)
+
+	// Remove deletes token with given id. Returns
+	// errors.ErrNoID if there is no such token.
+	Remove(ctx context.Context, id string) error
+}
+
+// APITokens interface handles personal API tokens.
+type APITokens interface {
+	// New stores given token and returns its id.
+	New(ctx context.Context, t models.APIToken) (string, error)
+
+	// Read returns token with given id. Returns
+	// errors.ErrNoID if there is no such token.
+	Read(ctx context.Context, id string) (*models.APIToken, error)
+
+	// OfUser returns tokens of user with given id.
+	OfUser(ctx context.Context, userID string) ([]models.APIToken, error)
+
+	// Update applies given function to token with given id.
+	Update(ctx context.Context, id string, f func(*models.APIToken) error) error
+
+	// Remove deletes token with given id. Returns
+	// errors.ErrNoID if there is no such token.
+	Remove(ctx context.Context, id string) error
+}
+
+// Sessions interface handles records of sessions of
+// logged in users.
+type Sessions interface {
+	// New stores given session and returns its id.
+	New(ctx context.Context, s models.Session) (string, error)
+
+	// Read returns session with given id. Returns
+	// errors.ErrNoID if there is no such session.
+	Read(ctx context.Context, id string) (*models.Session, error)
+
+	// OfUser returns sessions of user with given id.
+	OfUser(ctx context.Context, userID string) ([]models.Session, error)
+
+	// Update applies given function to session with given id.
+	Update(ctx context.Context, id string, f func(*models.Session) error) error
+
+	// Remove deletes session with given id. Returns
+	// errors.ErrNoID if there is no such session.
+	Remove(ctx context.Context, id string) error
+}
+
+// Audit interface keeps log of security relevant actions.
+type Audit interface {
+	// Add stores given entry.
+	Add(ctx context.Context, e models.AuditEntry) error
+
+	// Between returns entries of actions performed in
+	// given time range, sorted by time.
+	Between(ctx context.Context, from, to time.Time) ([]models.AuditEntry, error)
 }
 
 // Status interface provides methods for reading and
@@ -70,6 +353,15 @@ type Status interface {
 	// SetUnknownDevices overwrites number of unknown devices
 	// connected to the network.
 	SetUnknownDevices(ctx context.Context, number int) error
+
+	// UnknownBreakdown returns numbers of unknown devices
+	// connected to the network grouped by category of
+	// their vendors.
+	UnknownBreakdown(ctx context.Context) (map[string]int, error)
+
+	// SetUnknownBreakdown overwrites numbers of unknown
+	// devices grouped by category of their vendors.
+	SetUnknownBreakdown(ctx context.Context, breakdown map[string]int) error
 }
 
 // StatusTx interface provides methods for reading and
@@ -0,0 +1,82 @@
+package temp
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CheckIns implements storage.CheckIns interface for temporary
+// in memory storage. Every check in expires after its TTL.
+type CheckIns struct {
+	expires map[string]time.Time
+	guard   *sync.Mutex
+
+	// now returns current time. It is replaced in tests.
+	now func() time.Time
+}
+
+// NewCheckIns is the only one safe constructor for CheckIns.
+func NewCheckIns() *CheckIns {
+	return &CheckIns{
+		expires: map[string]time.Time{},
+		guard:   new(sync.Mutex),
+		now:     time.Now,
+	}
+}
+
+// prune removes expired check ins. Caller has to hold the guard.
+func (c *CheckIns) prune() {
+	now := c.now()
+	for id, expires := range c.expires {
+		if !now.Before(expires) {
+			delete(c.expires, id)
+		}
+	}
+}
+
+// CheckIn marks user with given id as present for given
+// amount of time. Checking in already present user refreshes
+// their presence.
+func (c *CheckIns) CheckIn(ctx context.Context, userID string, ttl time.Duration) error {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	c.expires[userID] = c.now().Add(ttl)
+	return nil
+}
+
+// CheckOut marks user with given id as absent.
+func (c *CheckIns) CheckOut(ctx context.Context, userID string) error {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	delete(c.expires, userID)
+	return nil
+}
+
+// IsCheckedIn returns true if user with given id is present.
+func (c *CheckIns) IsCheckedIn(ctx context.Context, userID string) (bool, error) {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	c.prune()
+	_, ok := c.expires[userID]
+	return ok, nil
+}
+
+// All returns sorted ids of every present user.
+func (c *CheckIns) All(ctx context.Context) ([]string, error) {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	c.prune()
+	res := make([]string, 0, len(c.expires))
+	for id := range c.expires {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
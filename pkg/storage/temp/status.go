@@ -9,8 +9,9 @@ import (
 )
 
 type status struct {
-	onlineUsers    int
-	unknownDevices int
+	onlineUsers      int
+	unknownDevices   int
+	unknownBreakdown map[string]int
 }
 
 // OnlineUsers returns number of people being
@@ -39,6 +40,27 @@ func (s *status) SetUnknownDevices(ctx context.Context, number int) error {
 	return nil
 }
 
+// UnknownBreakdown returns numbers of unknown devices
+// grouped by category of their vendors.
+func (s status) UnknownBreakdown(ctx context.Context) (map[string]int, error) {
+	return copyBreakdown(s.unknownBreakdown), nil
+}
+
+// SetUnknownBreakdown overwrites numbers of unknown
+// devices grouped by category of their vendors.
+func (s *status) SetUnknownBreakdown(ctx context.Context, breakdown map[string]int) error {
+	s.unknownBreakdown = copyBreakdown(breakdown)
+	return nil
+}
+
+func copyBreakdown(breakdown map[string]int) map[string]int {
+	res := make(map[string]int, len(breakdown))
+	for k, v := range breakdown {
+		res[k] = v
+	}
+	return res
+}
+
 // StatusTx implements storage.StatusTx interface for temporary in
 // memory storage.
 type StatusTx struct {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/matryer/is"
 )
@@ -68,3 +69,32 @@ func TestOnlineUsers(t *testing.T) {
 		is.True(!got)
 	})
 }
+
+func TestCheckIns(t *testing.T) {
+	is := is.New(t)
+	ctx := context.TODO()
+
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	c := NewCheckIns()
+	c.now = func() time.Time { return now }
+
+	is.NoErr(c.CheckIn(ctx, "1", time.Hour))
+	is.NoErr(c.CheckIn(ctx, "2", time.Minute))
+
+	got, err := c.All(ctx)
+	is.NoErr(err)
+	is.Equal(got, []string{"1", "2"})
+
+	now = now.Add(2 * time.Minute)
+
+	present, err := c.IsCheckedIn(ctx, "2")
+	is.NoErr(err)
+	is.True(!present) // check in should expire
+
+	is.NoErr(c.CheckOut(ctx, "1"))
+
+	got, err = c.All(ctx)
+	is.NoErr(err)
+	is.Equal(got, []string{})
+}
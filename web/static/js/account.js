@@ -1,7 +1,13 @@
 import { el, main, render, withErr } from "/static/js/utils.js";
 import * as api from "/static/js/api.js";
+import * as cards from "/static/js/cards.js";
+import * as email from "/static/js/email.js";
+import * as invites from "/static/js/invites.js";
 import * as otp from "/static/js/otp.js";
 import * as recovery from "/static/js/recovery.js";
+import * as sessions from "/static/js/sessions.js";
+import * as subscriptions from "/static/js/subscriptions.js";
+import * as tokens from "/static/js/tokens.js";
 
 const PasswordInput = (label, props) => {
   props.type = "password";
@@ -205,4 +211,22 @@ main(() => {
     targetForm: twoFactorForm,
     targetButton: addRecoveryButton,
   });
+
+  // Mount cards used for checking in at the door.
+  cards.mount({ target: document.getElementById("cards") });
+
+  // Mount active sessions.
+  sessions.mount({ target: document.getElementById("sessions") });
+
+  // Mount personal API tokens.
+  tokens.mount({ target: document.getElementById("tokens") });
+
+  // Mount email address.
+  email.mount({ target: document.getElementById("email") });
+
+  // Mount invite codes.
+  invites.mount({ target: document.getElementById("invites") });
+
+  // Mount notification subscriptions.
+  subscriptions.mount({ target: document.getElementById("subscriptions") });
 });
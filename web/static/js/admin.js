@@ -0,0 +1,464 @@
+import { el, main, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+const roles = ["member", "keyholder", "admin"];
+
+const registrationModes = {
+  "open": "Open to everybody",
+  "closed": "Closed",
+  "invite": "Invite code required",
+  "approval": "Approval of admin required",
+};
+
+const formatTime = (t) => t ? new Date(t).toLocaleString() : "never";
+
+// Returns link, that calls given action and reports its
+// error in given container. Optional confirm message is
+// shown before calling action.
+const Action = (text, action, { errContainer, refresh, confirmMsg }) =>
+  el("a", {
+    "class": "rm",
+    onClick: async () => {
+      if (confirmMsg && !window.confirm(confirmMsg)) {
+        return;
+      }
+      errContainer.textContent = "";
+      let [_, err] = await action();
+      if (err) {
+        errContainer.textContent = err.message;
+        return;
+      }
+      refresh();
+    },
+  }, text);
+
+const FormInput = ({ label, props }) =>
+  el(
+    "p",
+    null,
+    el("label", { "for": props.name }, label),
+    el("br", null, null),
+    el("input", props),
+  );
+
+const Status = (status) =>
+  el(
+    "ul",
+    null,
+    el("li", null, `Storage backend: ${status.storage}`),
+    el("li", null, `Last scan: ${formatTime(status.lastScan)}`),
+    el("li", null, `Scans waiting in queue: ${status.queue}`),
+    el("li", null, `Users: ${status.users}`),
+    el("li", null, `Devices: ${status.devices}`),
+    el("li", null, `Scanner keys: ${status.scanners}`),
+  );
+
+const RoleSelect = (user, ctx) =>
+  el(
+    "select",
+    {
+      name: `role-${user.id}`,
+      onChange: async (e) => {
+        ctx.errContainer.textContent = "";
+        let [_, err] = await api.adminUpdateUser(user.id, {
+          role: e.currentTarget.value,
+        });
+        if (err) {
+          ctx.errContainer.textContent = err.message;
+        }
+        ctx.refresh();
+      },
+    },
+    ...roles.map((role) =>
+      el(
+        "option",
+        role === user.role ? { value: role, selected: "" } : { value: role },
+        role,
+      )
+    ),
+  );
+
+const User = (user, ctx) =>
+  el(
+    "li",
+    null,
+    el("b", null, user.nickname),
+    ` (${user.devices} devices${user.twoFactor ? ", 2FA" : ""}`,
+    user.email
+      ? `, ${user.email}${user.emailVerified ? "" : " unverified"}) `
+      : ") ",
+    ...(user.pending
+      ? [
+        "waiting for approval ",
+        Action("approve", () => api.adminApproveUser(user.id), ctx),
+        " ",
+      ]
+      : [RoleSelect(user, ctx), " "]),
+    Action("reset password", () => {
+      let password = window.prompt(`New password for ${user.nickname}`);
+      if (!password) {
+        return [null, null];
+      }
+      return api.adminSetPassword(user.id, password);
+    }, ctx),
+    " ",
+    Action("reset link", async () => {
+      let [res, err] = await api.issueReset(user.id);
+      if (err) {
+        return [null, err];
+      }
+      window.prompt(
+        `One-time reset link for ${user.nickname}, valid until ` +
+          formatTime(res.expires),
+        window.location.origin + res.link,
+      );
+      return [res, null];
+    }, ctx),
+    " ",
+    ...(user.twoFactor
+      ? [
+        Action("disable 2FA", () => api.adminDisableTwoFactor(user.id), {
+          ...ctx,
+          confirmMsg: `Disable two factor of ${user.nickname}?`,
+        }),
+        " ",
+      ]
+      : []),
+    Action("log out", () => api.adminLogoutUser(user.id), ctx),
+    " ",
+    Action("remove", () => api.adminRemoveUser(user.id), {
+      ...ctx,
+      confirmMsg: `Remove ${user.nickname} with all devices?`,
+    }),
+  );
+
+const UserForm = (ctx) => {
+  let state = { nickname: "", password: "", role: "member" };
+
+  return el(
+    "form",
+    {
+      onSubmit: async (e) => {
+        e.preventDefault();
+        ctx.errContainer.textContent = "";
+        let [_, err] = await api.adminNewUser(state);
+        if (err) {
+          ctx.errContainer.textContent = err.message;
+          return;
+        }
+        e.target.reset();
+        state.role = "member";
+        ctx.refresh();
+      },
+    },
+    FormInput({
+      label: "Nickname",
+      props: {
+        type: "text",
+        name: "admin-nickname",
+        required: "",
+        onInput: (e) => state.nickname = e.currentTarget.value,
+      },
+    }),
+    FormInput({
+      label: "Password",
+      props: {
+        type: "password",
+        name: "admin-password",
+        required: "",
+        onInput: (e) => state.password = e.currentTarget.value,
+      },
+    }),
+    el(
+      "p",
+      null,
+      el(
+        "select",
+        {
+          name: "admin-role",
+          onChange: (e) => state.role = e.currentTarget.value,
+        },
+        ...roles.map((role) => el("option", { value: role }, role)),
+      ),
+    ),
+    el("p", null, el("button", { type: "submit" }, "Add user")),
+  );
+};
+
+const Device = (device, ctx) =>
+  el(
+    "li",
+    null,
+    el("b", null, device.tag),
+    ` of ${device.owner} `,
+    Action("rename", () => {
+      let tag = window.prompt("New tag", device.tag);
+      if (!tag) {
+        return [null, null];
+      }
+      return api.adminUpdateDevice(device.id, tag);
+    }, ctx),
+    " ",
+    Action("remove", () => api.adminRemoveDevice(device.id), {
+      ...ctx,
+      confirmMsg: `Remove ${device.tag} of ${device.owner}?`,
+    }),
+  );
+
+const Registration = ({ mode }, ctx) =>
+  el(
+    "p",
+    null,
+    el("label", { "for": "admin-registration-mode" }, "Registration mode"),
+    el("br", null, null),
+    el(
+      "select",
+      {
+        name: "admin-registration-mode",
+        onChange: async (e) => {
+          ctx.errContainer.textContent = "";
+          let [_, err] = await api.adminUpdateRegistration(
+            e.currentTarget.value,
+          );
+          if (err) {
+            ctx.errContainer.textContent = err.message;
+          }
+          ctx.refresh();
+        },
+      },
+      ...Object.entries(registrationModes).map(([value, text]) =>
+        el(
+          "option",
+          value === mode ? { value: value, selected: "" } : { value: value },
+          text,
+        )
+      ),
+    ),
+  );
+
+const Invite = (invite, ctx) =>
+  el(
+    "li",
+    null,
+    el("code", null, invite.id),
+    ` by ${invite.creator}, used ${invite.uses}`,
+    invite.maxUses ? ` of ${invite.maxUses}` : " times",
+    invite.expires ? `, expires ${formatTime(invite.expires)}` : "",
+    invite.valid ? " " : ", no longer valid ",
+    Action("remove", () => api.adminRemoveInvite(invite.id), ctx),
+  );
+
+const Scanner = (scanner, ctx) =>
+  el(
+    "li",
+    null,
+    el("b", null, scanner.name),
+    ` last used: ${formatTime(scanner.lastUsed)} `,
+    Action("remove", () => api.adminRemoveScanner(scanner.id), {
+      ...ctx,
+      confirmMsg: `Remove ${scanner.name} key? Scanner will stop working.`,
+    }),
+  );
+
+const Ignored = (ignored, ctx) =>
+  el(
+    "li",
+    null,
+    el("code", null, ignored.prefix),
+    ignored.tag ? ` ${ignored.tag} ` : " ",
+    Action("remove", () => api.adminRemoveIgnored(ignored.id), {
+      ...ctx,
+      confirmMsg: `Stop ignoring ${ignored.prefix}?`,
+    }),
+  );
+
+const AuditEntry = (entry) =>
+  el(
+    "li",
+    null,
+    `${formatTime(entry.time)} `,
+    el("code", null, entry.action),
+    entry.actorId ? ` by ${entry.actor || entry.actorId}` : " by operator",
+    entry.targetId ? ` on ${entry.target || entry.targetId}` : "",
+  );
+
+const ScannerForm = (ctx) => {
+  let name = "";
+  const keyContainer = el("p", null, "");
+
+  return el(
+    "div",
+    null,
+    el(
+      "form",
+      {
+        onSubmit: async (e) => {
+          e.preventDefault();
+          ctx.errContainer.textContent = "";
+          let [res, err] = await api.adminNewScanner(name);
+          if (err) {
+            ctx.errContainer.textContent = err.message;
+            return;
+          }
+          e.target.reset();
+          render(keyContainer, [
+            `Key for ${res.name}: `,
+            el("code", null, res.key),
+          ]);
+          ctx.refresh();
+        },
+      },
+      FormInput({
+        label: "Scanner name",
+        props: {
+          type: "text",
+          name: "admin-scanner-name",
+          required: "",
+          onInput: (e) => name = e.currentTarget.value,
+        },
+      }),
+      el("p", null, el("button", { type: "submit" }, "Create key")),
+    ),
+    keyContainer,
+  );
+};
+
+const IgnoredForm = (ctx) => {
+  let prefix = "";
+  let tag = "";
+
+  return el(
+    "form",
+    {
+      onSubmit: async (e) => {
+        e.preventDefault();
+        ctx.errContainer.textContent = "";
+        let [_, err] = await api.adminNewIgnored(prefix, tag);
+        if (err) {
+          ctx.errContainer.textContent = err.message;
+          return;
+        }
+        e.target.reset();
+        prefix = "";
+        tag = "";
+        ctx.refresh();
+      },
+    },
+    FormInput({
+      label: "Address or prefix",
+      props: {
+        type: "text",
+        name: "admin-ignored-prefix",
+        placeholder: "b8:27:eb",
+        required: "",
+        onInput: (e) => prefix = e.currentTarget.value,
+      },
+    }),
+    FormInput({
+      label: "Tag",
+      props: {
+        type: "text",
+        name: "admin-ignored-tag",
+        onInput: (e) => tag = e.currentTarget.value,
+      },
+    }),
+    el("p", null, el("button", { type: "submit" }, "Ignore")),
+  );
+};
+
+// list renders items fetched with given function in target
+// node or reports error in given container.
+async function list({ target, errContainer, fetchItems, item, ctx }) {
+  let [items, err] = await fetchItems();
+  if (err) {
+    errContainer.textContent = err.message;
+    return;
+  }
+  render(target, el("ul", null, ...items.map((i) => item(i, ctx))));
+}
+
+main(async () => {
+  const statusTarget = document.getElementById("admin-status");
+
+  const refreshStatus = async () => {
+    let [status, err] = await api.adminStatus();
+    if (err) {
+      render(statusTarget, el("p", null, err.message));
+      return;
+    }
+    render(statusTarget, Status(status));
+  };
+
+  const sections = [
+    {
+      name: "users",
+      fetchItems: api.adminUsers,
+      item: User,
+      form: UserForm,
+    },
+    {
+      name: "invites",
+      fetchItems: api.adminInvites,
+      item: Invite,
+    },
+    {
+      name: "devices",
+      fetchItems: api.adminDevices,
+      item: Device,
+    },
+    {
+      name: "scanners",
+      fetchItems: api.adminScanners,
+      item: Scanner,
+      form: ScannerForm,
+    },
+    {
+      name: "ignored",
+      fetchItems: api.adminIgnored,
+      item: Ignored,
+      form: IgnoredForm,
+    },
+    {
+      name: "audit",
+      fetchItems: api.adminAudit,
+      item: AuditEntry,
+    },
+  ];
+
+  const registrationTarget = document.getElementById("admin-registration");
+  const registrationErr = document.getElementById("admin-registration-err");
+
+  const refreshRegistration = async () => {
+    let [registration, err] = await api.adminRegistration();
+    if (err) {
+      registrationErr.textContent = err.message;
+      return;
+    }
+    render(
+      registrationTarget,
+      Registration(registration, {
+        errContainer: registrationErr,
+        refresh: refreshRegistration,
+      }),
+    );
+  };
+
+  const refreshAll = [refreshStatus, refreshRegistration];
+
+  sections.forEach(({ name, fetchItems, item, form }) => {
+    const target = document.getElementById(`admin-${name}`);
+    const errContainer = document.getElementById(`admin-${name}-err`);
+
+    let ctx = { errContainer };
+    const refresh = () => list({ target, errContainer, fetchItems, item, ctx });
+    ctx.refresh = () => refreshAll.forEach((f) => f());
+
+    refreshAll.push(refresh);
+
+    if (form) {
+      render(document.getElementById(`admin-${name}-form`), form(ctx));
+    }
+  });
+
+  refreshAll.forEach((f) => f());
+});
@@ -201,13 +201,613 @@ async function authWithCodes(code) {
   return null;
 }
 
+async function userCards(userID) {
+  let [res, errRes] = await withErr(fetch(`/api/v1/users/${userID}/cards`, {
+    method: "GET",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  }));
+  if (errRes) {
+    return [null, errRes];
+  }
+
+  if (!res.ok) {
+    let httpErr = new HTTPError("Failed to fetch cards.");
+    return [null, httpErr];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+async function newCard(userID, { tag, uid }) {
+  let [res, errPost] = await withErr(fetch(`/api/v1/users/${userID}/cards`, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify({ tag: tag, uid: uid }),
+  }));
+  if (errPost) {
+    return errPost;
+  }
+
+  if (res.status === 400) {
+    return new HTTPError("Invalid card uid.");
+  }
+  if (res.status === 409) {
+    return new HTTPError("Card is already registered.");
+  }
+  if (!res.ok) {
+    return new HTTPError("Failed to add new card.");
+  }
+
+  return null;
+}
+
+async function removeCard(userID, cardID) {
+  let uri = `/api/v1/users/${userID}/cards/${cardID}`;
+  let [res, errDel] = await withErr(fetch(uri, {
+    method: "DELETE",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  }));
+  if (errDel) {
+    return errDel;
+  }
+  if (!res.ok) {
+    return new HTTPError("Failed to remove card.");
+  }
+
+  return null;
+}
+
+async function userInvites(userID) {
+  let [res, errRes] = await withErr(fetch(`/api/v1/users/${userID}/invites`, {
+    method: "GET",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  }));
+  if (errRes) {
+    return [null, errRes];
+  }
+
+  if (!res.ok) {
+    let httpErr = new HTTPError("Failed to fetch invites.");
+    return [null, httpErr];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+const newInvite = (userID, { maxUses, expires }) =>
+  request(`/users/${userID}/invites`, {
+    method: "POST",
+    body: { maxUses: maxUses, expires: expires },
+  });
+
+async function removeInvite(userID, inviteID) {
+  let uri = `/api/v1/users/${userID}/invites/${inviteID}`;
+  let [res, errDel] = await withErr(fetch(uri, {
+    method: "DELETE",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  }));
+  if (errDel) {
+    return errDel;
+  }
+  if (!res.ok) {
+    return new HTTPError("Failed to remove invite.");
+  }
+
+  return null;
+}
+
+async function readUser(userID) {
+  let [res, errRes] = await withErr(fetch(`/api/v1/users/${userID}`, {
+    method: "GET",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  }));
+  if (errRes) {
+    return [null, errRes];
+  }
+
+  if (!res.ok) {
+    let httpErr = new HTTPError("Failed to fetch user.");
+    return [null, httpErr];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+async function updateUser(userID, changes) {
+  let [res, errPatch] = await withErr(fetch(`/api/v1/users/${userID}`, {
+    method: "PATCH",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify(changes),
+  }));
+  if (errPatch) {
+    return errPatch;
+  }
+  if (!res.ok) {
+    return new HTTPError("Failed to update user.");
+  }
+
+  return null;
+}
+
+async function userSubscriptions(userID) {
+  let uri = `/api/v1/users/${userID}/subscriptions`;
+  let [res, errRes] = await withErr(fetch(uri, {
+    method: "GET",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  }));
+  if (errRes) {
+    return [null, errRes];
+  }
+
+  if (!res.ok) {
+    let httpErr = new HTTPError("Failed to fetch subscriptions.");
+    return [null, httpErr];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+async function subscriptionOptions(userID) {
+  let uri = `/api/v1/users/${userID}/subscriptions/options`;
+  let [res, errRes] = await withErr(fetch(uri, {
+    method: "GET",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  }));
+  if (errRes) {
+    return [null, errRes];
+  }
+
+  if (!res.ok) {
+    let httpErr = new HTTPError("Failed to fetch subscription options.");
+    return [null, httpErr];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+async function newSubscription(userID, { event, targetId, channel, address }) {
+  let uri = `/api/v1/users/${userID}/subscriptions`;
+  let [res, errPost] = await withErr(fetch(uri, {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify({
+      event: event,
+      targetId: targetId,
+      channel: channel,
+      address: address,
+    }),
+  }));
+  if (errPost) {
+    return errPost;
+  }
+
+  if (res.status === 400) {
+    return new HTTPError("Invalid address.");
+  }
+  if (res.status === 404) {
+    return new HTTPError("This user can't be followed.");
+  }
+  if (res.status === 409) {
+    return new HTTPError("Subscription already exists.");
+  }
+  if (!res.ok) {
+    return new HTTPError("Failed to add new subscription.");
+  }
+
+  return null;
+}
+
+async function removeSubscription(userID, subscriptionID) {
+  let uri = `/api/v1/users/${userID}/subscriptions/${subscriptionID}`;
+  let [res, errDel] = await withErr(fetch(uri, {
+    method: "DELETE",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  }));
+  if (errDel) {
+    return errDel;
+  }
+  if (!res.ok) {
+    return new HTTPError("Failed to remove subscription.");
+  }
+
+  return null;
+}
+
+async function kioskCode(key) {
+  let [res, errRes] = await withErr(fetch("/api/v1/kiosk/code", {
+    method: "GET",
+    headers: {
+      "Content-Type": "application/json",
+      "Authorization": `Kiosk ${key}`,
+    },
+  }));
+  if (errRes) {
+    return [null, errRes];
+  }
+
+  if (res.status === 401) {
+    let authErr = new AuthorizationRequiredError("Invalid kiosk key.");
+    return [null, authErr];
+  }
+  if (!res.ok) {
+    let httpErr = new HTTPError("Failed to fetch check-in code.");
+    return [null, httpErr];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+async function checkInWithCode(code) {
+  let [res, errPost] = await withErr(fetch("/api/v1/checkin/code", {
+    method: "POST",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify({ code: code }),
+  }));
+  if (errPost) {
+    return [null, errPost];
+  }
+
+  if (res.status === 400) {
+    let httpErr = new HTTPError(
+      "Invalid or expired code. Please scan the code again.",
+    );
+    return [null, httpErr];
+  }
+  if (res.status === 401) {
+    let authErr = new AuthorizationRequiredError("Please login in.");
+    return [null, authErr];
+  }
+  if (!res.ok) {
+    let httpErr = new HTTPError("Failed to check in.");
+    return [null, httpErr];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+async function heatmap() {
+  let [res, errRes] = await withErr(fetch("/api/v1/heatmap", {
+    method: "GET",
+    headers: {
+      "Content-Type": "application/json",
+    },
+  }));
+  if (errRes) {
+    return [null, errRes];
+  }
+
+  if (!res.ok) {
+    let httpErr = new HTTPError("Failed to fetch occupancy heatmap.");
+    return [null, httpErr];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+async function updateSpace(state) {
+  let [res, errPut] = await withErr(fetch("/api/v1/space", {
+    method: "PUT",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: JSON.stringify({ state: state }),
+  }));
+  if (errPut) {
+    return errPut;
+  }
+  if (res.status === 403) {
+    return new HTTPError(
+      "Only keyholders can change state of the hackerspace.",
+    );
+  }
+  if (!res.ok) {
+    return new HTTPError("Failed to change state of the hackerspace.");
+  }
+
+  return null;
+}
+
+async function resetSpace() {
+  let [res, errDelete] = await withErr(fetch("/api/v1/space", {
+    method: "DELETE",
+    credentials: "include",
+  }));
+  if (errDelete) {
+    return errDelete;
+  }
+  if (res.status === 403) {
+    return new HTTPError(
+      "Only keyholders can change state of the hackerspace.",
+    );
+  }
+  if (!res.ok) {
+    return new HTTPError("Failed to change state of the hackerspace.");
+  }
+
+  return null;
+}
+
+// request sends request to api at given path and returns
+// parsed response or error with message from the server.
+async function request(path, { method, body } = {}) {
+  let [res, errFetch] = await withErr(fetch(`/api/v1${path}`, {
+    method: method || "GET",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+    body: body === undefined ? undefined : JSON.stringify(body),
+  }));
+  if (errFetch) {
+    return [null, errFetch];
+  }
+
+  if (res.status === 401) {
+    let authErr = new AuthorizationRequiredError("Please login in.");
+    return [null, authErr];
+  }
+  if (!res.ok) {
+    let [parsed, _] = await withErr(res.json());
+    let msg = parsed?.error?.message || "Request failed.";
+    return [null, new HTTPError(msg)];
+  }
+  if (res.status === 202 || res.status === 204) {
+    return [null, null];
+  }
+
+  let [jsonRes, errJson] = await withErr(res.json());
+  if (errJson) {
+    return [null, errJson];
+  }
+
+  return [jsonRes, null];
+}
+
+const admin = (path, options) => request(`/admin${path}`, options);
+
+const adminStatus = () => admin("/status");
+
+const adminUsers = () => admin("/users");
+
+const adminNewUser = (user) => admin("/users", { method: "POST", body: user });
+
+const adminUpdateUser = (userID, changes) =>
+  admin(`/users/${userID}`, { method: "PATCH", body: changes });
+
+const adminRemoveUser = (userID) =>
+  admin(`/users/${userID}`, { method: "DELETE" });
+
+const adminSetPassword = (userID, password) =>
+  admin(`/users/${userID}/password`, {
+    method: "PUT",
+    body: { password: password },
+  });
+
+const adminDisableTwoFactor = (userID) =>
+  admin(`/users/${userID}/twofactor`, { method: "DELETE" });
+
+const adminLogoutUser = (userID) =>
+  admin(`/users/${userID}/logout`, { method: "POST" });
+
+const adminApproveUser = (userID) =>
+  admin(`/users/${userID}/approve`, { method: "POST" });
+
+const adminDevices = () => admin("/devices");
+
+const adminUpdateDevice = (deviceID, tag) =>
+  admin(`/devices/${deviceID}`, { method: "PATCH", body: { tag: tag } });
+
+const adminRemoveDevice = (deviceID) =>
+  admin(`/devices/${deviceID}`, { method: "DELETE" });
+
+const adminRegistration = () => admin("/registration");
+
+const adminUpdateRegistration = (mode) =>
+  admin("/registration", { method: "PUT", body: { mode: mode } });
+
+const adminInvites = () => admin("/invites");
+
+const adminRemoveInvite = (inviteID) =>
+  admin(`/invites/${inviteID}`, { method: "DELETE" });
+
+const adminScanners = () => admin("/scanners");
+
+const adminNewScanner = (name) =>
+  admin("/scanners", { method: "POST", body: { name: name } });
+
+const adminRemoveScanner = (scannerID) =>
+  admin(`/scanners/${scannerID}`, { method: "DELETE" });
+
+const adminIgnored = () => admin("/ignored");
+
+const adminNewIgnored = (prefix, tag) =>
+  admin("/ignored", { method: "POST", body: { prefix: prefix, tag: tag } });
+
+const adminRemoveIgnored = (ignoredID) =>
+  admin(`/ignored/${ignoredID}`, { method: "DELETE" });
+
+const adminAudit = () => admin("/audit");
+
+const userEmail = (userID) => request(`/users/${userID}/email`);
+
+const updateEmail = (userID, email) =>
+  request(`/users/${userID}/email`, { method: "PUT", body: { email: email } });
+
+const resendVerification = (userID) =>
+  request(`/users/${userID}/email/verification`, { method: "POST" });
+
+const userSessions = (userID) => request(`/users/${userID}/sessions`);
+
+const removeSession = (userID, sessionID) =>
+  request(`/users/${userID}/sessions/${sessionID}`, { method: "DELETE" });
+
+const removeSessions = (userID) =>
+  request(`/users/${userID}/sessions`, { method: "DELETE" });
+
+const userTokens = (userID) => request(`/users/${userID}/tokens`);
+
+const newToken = (userID, token) =>
+  request(`/users/${userID}/tokens`, { method: "POST", body: token });
+
+const removeToken = (userID, tokenID) =>
+  request(`/users/${userID}/tokens/${tokenID}`, { method: "DELETE" });
+
+const verifyEmail = (token) =>
+  request("/email/verify", { method: "POST", body: { token: token } });
+
+const requestReset = (email) =>
+  request("/reset/request", { method: "POST", body: { email: email } });
+
+const issueReset = (userID) =>
+  request(`/users/${userID}/reset`, { method: "POST" });
+
+const lookupReset = (token) =>
+  request("/reset/lookup", { method: "POST", body: { token: token } });
+
+const consumeReset = (token, password) =>
+  request("/reset", {
+    method: "POST",
+    body: { token: token, password: password },
+  });
+
 export {
+  adminApproveUser,
+  adminAudit,
+  adminDevices,
+  adminDisableTwoFactor,
+  adminIgnored,
+  adminInvites,
+  adminLogoutUser,
+  adminNewIgnored,
+  adminNewScanner,
+  adminNewUser,
+  adminRegistration,
+  adminRemoveDevice,
+  adminRemoveIgnored,
+  adminRemoveInvite,
+  adminRemoveScanner,
+  adminRemoveUser,
+  adminScanners,
+  adminSetPassword,
+  adminStatus,
+  adminUpdateDevice,
+  adminUpdateRegistration,
+  adminUpdateUser,
+  adminUsers,
   authWithCodes,
+  checkInWithCode,
+  consumeReset,
+  heatmap,
+  issueReset,
+  kioskCode,
+  lookupReset,
+  newCard,
+  newInvite,
   newOTP,
   newRecovery,
+  newSubscription,
+  newToken,
   optionsOTP,
+  readUser,
+  removeCard,
+  removeInvite,
+  removeSession,
+  removeSessions,
+  removeSubscription,
+  removeToken,
   removeTwoFactorMethod,
+  requestReset,
+  resendVerification,
+  resetSpace,
+  subscriptionOptions,
   twoFactorMethods,
+  updateEmail,
   updatePassword,
+  updateSpace,
+  updateUser,
+  userCards,
+  userEmail,
+  userInvites,
+  userSessions,
+  userSubscriptions,
+  userTokens,
+  verifyEmail,
   who,
 };
@@ -0,0 +1,130 @@
+import { el, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+const FormInput = ({ label, props }) =>
+  el(
+    "p",
+    null,
+    el("label", { "for": props.name }, label),
+    el("br", null, null),
+    el("input", props),
+  );
+
+// Returns single card component.
+const Card = ({ tag, onRemove }) =>
+  el(
+    "li",
+    {},
+    el("span", {}, el("b", {}, tag)),
+    el(
+      "span",
+      {},
+      el("a", {
+        onClick: onRemove,
+        "class": "rm",
+      }, "remove"),
+    ),
+  );
+
+const Cards = (userID, cards, { refresh, errContainer }) =>
+  el(
+    "ul",
+    null,
+    ...cards.map((card) =>
+      Card({
+        tag: card.tag,
+        onRemove: async () => {
+          let err = await api.removeCard(userID, card.id);
+          if (err) {
+            errContainer.textContent = err.message;
+            return;
+          }
+          refresh();
+        },
+      })
+    ),
+  );
+
+const CardForm = (userID, { refresh, errContainer }) => {
+  let state = { tag: "", uid: "" };
+
+  const tagInput = FormInput({
+    label: "Tag",
+    props: {
+      type: "text",
+      name: "card-tag",
+      required: "",
+      onInput: (e) => {
+        errContainer.textContent = "";
+        state.tag = e.currentTarget.value;
+      },
+    },
+  });
+
+  const uidInput = FormInput({
+    label: "Card UID, for example 04:A2:5B:1A",
+    props: {
+      type: "text",
+      name: "card-uid",
+      required: "",
+      onInput: (e) => {
+        errContainer.textContent = "";
+        state.uid = e.currentTarget.value;
+      },
+    },
+  });
+
+  return el(
+    "form",
+    {
+      onSubmit: async (e) => {
+        e.preventDefault();
+
+        let err = await api.newCard(userID, state);
+        if (err) {
+          errContainer.textContent = err.message;
+          return;
+        }
+
+        e.target.reset();
+        state = { tag: "", uid: "" };
+        refresh();
+      },
+    },
+    tagInput,
+    uidInput,
+    el("button", { type: "submit" }, "Add card"),
+  );
+};
+
+// mount renders cards of current user with form for
+// registering new cards in given target node.
+async function mount({ target }) {
+  let [user, errWho] = await api.who();
+  if (errWho) {
+    return;
+  }
+
+  const errContainer = el("strong", null, "");
+  const list = el("section", null, "");
+
+  const refresh = async () => {
+    let [cards, err] = await api.userCards(user.id);
+    if (err) {
+      errContainer.textContent = err.message;
+      return;
+    }
+    render(list, Cards(user.id, cards, { refresh, errContainer }));
+  };
+
+  render(target, [
+    el("p", null, errContainer),
+    list,
+    el("h3", null, "Add new card"),
+    CardForm(user.id, { refresh, errContainer }),
+  ]);
+
+  refresh();
+}
+
+export { mount };
@@ -0,0 +1,20 @@
+import { el, main, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+const resultComp = ({ nickname }) =>
+  el("p", null, `Welcome ${nickname}! You are checked in.`);
+
+const errComp = (err) => el("p", null, el("strong", null, err.message));
+
+main(async () => {
+  const target = document.getElementById("checkin");
+  const code = new URLSearchParams(window.location.search).get("code") || "";
+
+  let [res, err] = await api.checkInWithCode(code);
+  if (err) {
+    render(target, errComp(err));
+    return;
+  }
+
+  render(target, resultComp(res));
+});
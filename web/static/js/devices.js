@@ -1,19 +1,70 @@
 import { el, valoo } from "/static/js/utils.js";
 
+// Returns short description of alternative identifiers
+// of given device.
+const identifiersText = ({ addresses, hostname, clientId }) => {
+  const res = [];
+  if (addresses) {
+    res.push("+" + addresses + " mac");
+  }
+  if (hostname) {
+    res.push("hostname " + hostname);
+  }
+  if (clientId) {
+    res.push("client id");
+  }
+  return res.length > 0 ? " (" + res.join(", ") + ")" : "";
+};
+
+// Returns form for editing alternative identifiers of
+// device with given id.
+const identifiersComp = ({ id, hostname }) => {
+  const mac = el("input", { "type": "text", "placeholder": "mac address" });
+  const host = el("input", { "type": "text", "placeholder": "hostname" });
+  const client = el("input", { "type": "text", "placeholder": "client id" });
+  host.value = hostname || "";
+
+  const form = el(
+    "form",
+    {
+      onSubmit: (e) => {
+        e.preventDefault();
+
+        const changes = { hostname: host.value };
+        if (mac.value !== "") {
+          changes.mac = mac.value;
+        }
+        if (client.value !== "") {
+          changes.clientId = client.value;
+        }
+
+        patchDevice(id, changes);
+      },
+    },
+    el("p", {}, el("label", {}, "Additional MAC", el("br", {}), mac)),
+    el("p", {}, el("label", {}, "Hostname", el("br", {}), host)),
+    el("p", {}, el("label", {}, "DHCP client id", el("br", {}), client)),
+    el("button", { "type": "submit" }, "Save"),
+  );
+
+  return el("details", {}, el("summary", {}, "identifiers"), form);
+};
+
 // Returns single device component.
-const deviceComp = ({ tag, id }) =>
+const deviceComp = (device) =>
   el(
     "li",
     {},
-    el("span", {}, el("b", {}, tag)),
+    el("span", {}, el("b", {}, device.tag), identifiersText(device)),
     el(
       "span",
       {},
       el("a", {
-        onClick: () => deleteDevice(id),
+        onClick: () => deleteDevice(device.id),
         "class": "rm",
       }, "remove"),
     ),
+    identifiersComp(device),
   );
 
 const privMode = valoo(false);
@@ -85,7 +136,9 @@ const handleErrors = (error) => {
       errorMessage(serverError("invalid user data, please login in"));
       break;
     case 409:
-      errorMessage(serverError("there is already resource with given tag"));
+      errorMessage(
+        serverError("there is already device with given tag or mac address"),
+      );
       break;
     default:
       errorMessage(serverError("internal server error, please try again"));
@@ -244,6 +297,42 @@ const togglePrivateMode = (mode) => {
     .catch(handleErrors);
 };
 
+// replaceDevice replaces device with the same id as given
+// one in device state manager.
+const replaceDevice = (device) => {
+  devices(
+    devices().map((item) => item.id == device.id ? device : item),
+  );
+};
+
+// patchDevice sends changes of alternative identifiers of
+// device with given id to API.
+const patchDevice = (deviceID, changes) => {
+  fetch("/who", {
+    method: "GET",
+    headers: {
+      "Content-Type": "application/json",
+    },
+    credentials: "include",
+  })
+    .then(checkResponse)
+    .then(responseJSON)
+    .then((data) => {
+      return fetch("/api/v1/users/" + data.id + "/devices/" + deviceID, {
+        method: "PATCH",
+        headers: {
+          "Content-Type": "application/json",
+        },
+        credentials: "include",
+        body: JSON.stringify(changes),
+      });
+    })
+    .then(checkResponse)
+    .then(responseJSON)
+    .then(replaceDevice)
+    .catch(handleErrors);
+};
+
 // removeDevice removes device with given device id
 // from device state manager.
 const removeDevice = (deviceID) => {
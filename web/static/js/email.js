@@ -0,0 +1,105 @@
+import { el, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+const status = ({ email, verified }) => {
+  if (!email) {
+    return "You haven't added email address yet.";
+  }
+  return verified
+    ? `Your email address is ${email}.`
+    : `Your email address ${email} is waiting for verification.`;
+};
+
+const EmailForm = (userID, current, { refresh, errContainer, info }) => {
+  let address = current.email;
+
+  return el(
+    "form",
+    {
+      onSubmit: async (e) => {
+        e.preventDefault();
+        errContainer.textContent = "";
+        let [res, err] = await api.updateEmail(userID, address);
+        if (err) {
+          errContainer.textContent = err.message;
+          return;
+        }
+        if (res.email && !res.verified) {
+          info.textContent = `Verification link has been sent to ${res.email}.`;
+        }
+        refresh();
+      },
+    },
+    el(
+      "p",
+      null,
+      el("label", { "for": "email" }, "Email address"),
+      el("br", null, null),
+      el("input", {
+        type: "email",
+        name: "email",
+        id: "email",
+        value: current.email,
+        onInput: (e) => address = e.currentTarget.value,
+      }),
+    ),
+    el("button", { type: "submit" }, "Save"),
+  );
+};
+
+const Resend = (userID, { errContainer, info }) =>
+  el("a", {
+    "class": "rm",
+    onClick: async () => {
+      errContainer.textContent = "";
+      let [_, err] = await api.resendVerification(userID);
+      if (err) {
+        errContainer.textContent = err.message;
+        return;
+      }
+      info.textContent = "Verification link has been sent again.";
+    },
+  }, "send verification link again");
+
+// mount renders email address of current user with form
+// for changing it in given target node.
+async function mount({ target }) {
+  let [user, errWho] = await api.who();
+  if (errWho) {
+    return;
+  }
+
+  const errContainer = el("strong", null, "");
+  const info = el("span", null, "");
+  const content = el("section", null, "");
+  const ctx = { errContainer, info };
+
+  ctx.refresh = async () => {
+    let [current, err] = await api.userEmail(user.id);
+    if (err) {
+      errContainer.textContent = err.message;
+      return;
+    }
+    if (!current.enabled && !current.email) {
+      render(content, el("p", null, "Emails are not enabled on this server."));
+      return;
+    }
+    render(content, [
+      el(
+        "p",
+        null,
+        status(current),
+        ...(current.email && !current.verified
+          ? [" ", Resend(user.id, ctx)]
+          : []),
+      ),
+      EmailForm(user.id, current, ctx),
+    ]);
+  };
+
+  render(target, [el("p", null, errContainer), el("p", null, info), content]);
+
+  ctx.refresh();
+}
+
+export { mount };
@@ -0,0 +1,80 @@
+import { el, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+// Weekdays are sent starting with Sunday, but displayed
+// starting with Monday.
+const WEEK_ORDER = [1, 2, 3, 4, 5, 6, 0];
+
+const HOURS = [...Array(24).keys()];
+
+const formatHour = (hour) => `${String(hour).padStart(2, "0")}:00`;
+
+const round = (value) => Math.round(value * 10) / 10;
+
+const Cell = (value, max) =>
+  el(
+    "td",
+    {
+      "class": "heat",
+      title: `${round(value)} people on average`,
+      style: `opacity:${max > 0 ? 0.1 + 0.9 * (value / max) : 0.1};`,
+    },
+    "",
+  );
+
+const HeatmapTable = ({ weekdays, heatmap }) => {
+  const max = Math.max(...heatmap.flat());
+
+  return el(
+    "table",
+    { "class": "heatmap" },
+    el(
+      "tr",
+      null,
+      el("th", null, ""),
+      ...HOURS.map((hour) =>
+        el("th", null, hour % 3 === 0 ? String(hour) : "")
+      ),
+    ),
+    ...WEEK_ORDER.map((day) =>
+      el(
+        "tr",
+        null,
+        el("th", null, weekdays[day].slice(0, 3)),
+        ...HOURS.map((hour) => Cell(heatmap[day][hour], max)),
+      )
+    ),
+  );
+};
+
+const Forecast = ({ forecast }) =>
+  el(
+    "ul",
+    { "class": "forecast" },
+    ...forecast.map(({ time, online }) =>
+      el(
+        "li",
+        null,
+        `${formatHour(new Date(time).getHours())}: ${round(online)}`,
+      )
+    ),
+  );
+
+// mount renders weekly occupancy heatmap with forecast
+// for the coming hours in given target node.
+async function mount({ target }) {
+  let [data, err] = await api.heatmap();
+  if (err) {
+    render(target, el("p", null, err.message));
+    return;
+  }
+
+  render(target, [
+    el("h3", null, "Is anyone usually there?"),
+    HeatmapTable(data),
+    el("h3", null, "Expected occupancy"),
+    Forecast(data),
+  ]);
+}
+
+export { mount };
@@ -1,19 +1,24 @@
 import { el, valoo } from "/static/js/utils.js";
+import * as heatmap from "/static/js/heatmap.js";
+import * as space from "/static/js/space.js";
+
+const spaceStatus = (state, override) =>
+  el(
+    "p",
+    null,
+    el("b", null, SPACE_STATE[state] || SPACE_STATE.closed),
+    override ? " (set by keyholder)" : "",
+  );
 
 const onlineStatus = (usersCount) => {
-  let text = "";
   switch (usersCount) {
     case 0:
-      text = HACKER_STATE.CLOSED;
-      break;
+      return el("p", { style: "display:none;" }, "");
     case 1:
-      text = HACKER_STATE.FOREVER_ALONE;
-      break;
+      return el("p", null, HACKER_STATE.FOREVER_ALONE);
     default:
-      text = HACKER_STATE.PARTY(usersCount);
-      break;
+      return el("p", null, HACKER_STATE.PARTY(usersCount));
   }
-  return el("p", null, text);
 };
 
 const unknownStatus = (unknownDevicesCount) => {
@@ -47,14 +52,20 @@ const homeComp = (data) =>
   el(
     "div",
     { id: "app" },
+    spaceStatus(data.state, data.override),
     onlineStatus(data.users.length),
     unknownStatus(data.unknownDevices),
     onlineTitle(data.users.length),
     usersComp(data.users),
   );
 
+const SPACE_STATE = {
+  "open": "Hackerspace is open.",
+  "members-only": "Hackerspace is open to members only.",
+  "closed": "Hackerspace is closed.",
+};
+
 const HACKER_STATE = {
-  CLOSED: "Hackerspace is closed.",
   FOREVER_ALONE: "There is one person in the hackerspace.",
   PARTY: (num) => "There are " + num + " people in the hackerspace.",
 };
@@ -65,6 +76,8 @@ const UNKNOWN_STATE = {
 };
 
 const homeStorage = valoo({
+  state: "closed",
+  override: false,
   users: [],
   onlineUsers: 0,
   unknownDevices: 0,
@@ -107,6 +120,8 @@ const fetchData = () => {
     .then((data) =>
       homeStorage({
         ...homeStorage(),
+        state: data.state,
+        override: data.override,
         onlineUsers: data.online,
         unknownDevices: data.unknown,
       })
@@ -119,3 +134,6 @@ const fetchData = () => {
 
 fetchData();
 window.setInterval(fetchData, 1000 * 60 * 2);
+
+heatmap.mount({ target: document.getElementById("heatmap") });
+space.mount({ target: document.getElementById("space"), onChange: fetchData });
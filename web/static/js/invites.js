@@ -0,0 +1,159 @@
+import { el, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+const FormInput = ({ label, props }) =>
+  el(
+    "p",
+    null,
+    el("label", { "for": props.name }, label),
+    el("br", null, null),
+    el("input", props),
+  );
+
+// inviteLink returns link to register page with given
+// invite code filled in.
+const inviteLink = (code) =>
+  `${window.location.origin}/register?invite=${encodeURIComponent(code)}`;
+
+const usage = ({ uses, maxUses, expires, valid }) => {
+  let res = maxUses ? `used ${uses} of ${maxUses}` : `used ${uses} times`;
+  if (expires) {
+    res += `, expires ${new Date(expires).toLocaleString()}`;
+  }
+  return valid ? res : `${res}, no longer valid`;
+};
+
+// Returns single invite component. Codes are shown only once,
+// after creating invite, so invites are told apart by creation time.
+const Invite = ({ invite, onRemove }) =>
+  el(
+    "li",
+    {},
+    el("span", {}, `created ${new Date(invite.created).toLocaleString()}`),
+    el("span", {}, ` (${usage(invite)}) `),
+    el(
+      "span",
+      {},
+      el("a", {
+        onClick: onRemove,
+        "class": "rm",
+      }, "remove"),
+    ),
+  );
+
+const Invites = (userID, invites, { refresh, errContainer }) =>
+  el(
+    "ul",
+    null,
+    ...invites.map((invite) =>
+      Invite({
+        invite: invite,
+        onRemove: async () => {
+          let err = await api.removeInvite(userID, invite.id);
+          if (err) {
+            errContainer.textContent = err.message;
+            return;
+          }
+          refresh();
+        },
+      })
+    ),
+  );
+
+const InviteForm = (userID, { refresh, errContainer, created }) => {
+  let state = { maxUses: 1, expires: "" };
+
+  const maxUsesInput = FormInput({
+    label: "Number of uses, 0 means the highest allowed",
+    props: {
+      type: "number",
+      name: "invite-max-uses",
+      min: "0",
+      value: "1",
+      onInput: (e) => {
+        errContainer.textContent = "";
+        state.maxUses = parseInt(e.currentTarget.value, 10) || 0;
+      },
+    },
+  });
+
+  const expiresInput = FormInput({
+    label: "Valid until, leave empty for the longest allowed time",
+    props: {
+      type: "date",
+      name: "invite-expires",
+      onInput: (e) => {
+        errContainer.textContent = "";
+        state.expires = e.currentTarget.value;
+      },
+    },
+  });
+
+  return el(
+    "form",
+    {
+      onSubmit: async (e) => {
+        e.preventDefault();
+
+        // Invite is valid until the end of chosen day.
+        let expires = state.expires
+          ? new Date(`${state.expires}T23:59:59`).toISOString()
+          : undefined;
+
+        let [res, err] = await api.newInvite(userID, {
+          maxUses: state.maxUses,
+          expires: expires,
+        });
+        if (err) {
+          errContainer.textContent = err.message;
+          return;
+        }
+
+        e.target.reset();
+        state = { maxUses: 1, expires: "" };
+        render(created, [
+          "Share this link now, it won't be shown again: ",
+          el("code", null, inviteLink(res.code)),
+        ]);
+        refresh();
+      },
+    },
+    maxUsesInput,
+    expiresInput,
+    el("button", { type: "submit" }, "Create invite"),
+  );
+};
+
+// mount renders invites of current user with form for
+// creating new invites in given target node.
+async function mount({ target }) {
+  let [user, errWho] = await api.who();
+  if (errWho) {
+    return;
+  }
+
+  const errContainer = el("strong", null, "");
+  const created = el("p", null, "");
+  const list = el("section", null, "");
+
+  const refresh = async () => {
+    let [invites, err] = await api.userInvites(user.id);
+    if (err) {
+      errContainer.textContent = err.message;
+      return;
+    }
+    render(list, Invites(user.id, invites, { refresh, errContainer }));
+  };
+
+  render(target, [
+    el("p", null, errContainer),
+    list,
+    el("h3", null, "Create new invite"),
+    created,
+    InviteForm(user.id, { refresh, errContainer, created }),
+  ]);
+
+  refresh();
+}
+
+export { mount };
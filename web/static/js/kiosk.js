@@ -0,0 +1,34 @@
+import { el, main, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+// Delay before retrying after failed request, in milliseconds.
+const RETRY_DELAY = 10 * 1000;
+
+const codeComp = ({ image, url }) => [
+  el("p", null, "Scan the code with your phone to check in."),
+  el("img", { src: image, alt: url, style: "width:100%;max-width:512px;" }),
+];
+
+const errComp = (err) => el("p", null, el("strong", null, err.message));
+
+const refresh = async (target, key) => {
+  let [code, err] = await api.kioskCode(key);
+  if (err) {
+    render(target, errComp(err));
+    setTimeout(() => refresh(target, key), RETRY_DELAY);
+    return;
+  }
+
+  render(target, codeComp(code));
+
+  // Fetch next code right after current one expires.
+  let delay = Math.max(new Date(code.expires) - new Date(), 0) + 1000;
+  setTimeout(() => refresh(target, key), delay);
+};
+
+main(() => {
+  const target = document.getElementById("kiosk");
+  const key = new URLSearchParams(window.location.search).get("key") || "";
+
+  refresh(target, key);
+});
@@ -1,7 +1,7 @@
 import { valoo } from "/static/js/utils.js";
 
 const errorState = valoo("");
-const loginData = valoo({ login: "", password: "" });
+const loginData = valoo({ login: "", password: "", remember: false });
 
 errorState((msg) => {
   const elements = document.querySelectorAll(".err-msg");
@@ -16,6 +16,7 @@ loginData(() => {
 
 const ERROR_MSGS = {
   401: "password does not match",
+  403: "account is waiting for approval of admin",
   404: "there is no user with given nickname",
   default: "invalid server response, please try again later",
 };
@@ -24,10 +25,11 @@ const err = (msg) => {
   errorState("server error: " + msg);
 };
 
-const submitData = ({ login, password }) => {
+const submitData = ({ login, password, remember }) => {
   let data = {
     nickname: login,
     password: password,
+    remember: remember,
   };
 
   fetch("/login", {
@@ -49,6 +51,9 @@ const submitData = ({ login, password }) => {
         case 401:
           err(ERROR_MSGS[response.status]);
           break;
+        case 403:
+          err(ERROR_MSGS[response.status]);
+          break;
         case 404:
           err(ERROR_MSGS[response.status]);
           break;
@@ -79,6 +84,13 @@ document.getElementById("password").addEventListener("input", (e) => {
   });
 });
 
+document.getElementById("remember").addEventListener("change", (e) => {
+  loginData({
+    ...loginData(),
+    remember: e.currentTarget.checked,
+  });
+});
+
 document.getElementById("login-form").addEventListener("submit", (e) => {
   e.preventDefault();
   submitData(loginData());
@@ -7,12 +7,13 @@ const a = (link, ...children) =>
     ...children,
   );
 
-const navbar = ({ nickname }) => {
+const navbar = ({ nickname, role }) => {
   return [
     a("/", "Home"),
     ...(nickname
       ? [
         a("/devices", "Devices"),
+        ...(role === "admin" ? [a("/admin", "Admin")] : []),
         a("/account", `${nickname}@lodz`),
         a("/logout", "Logout"),
       ]
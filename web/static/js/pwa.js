@@ -0,0 +1,58 @@
+// Registers service worker, which makes long-season installable
+// and displays push notifications.
+
+// registration returns promise with service worker registration
+// or null, if service workers are not supported.
+async function registration() {
+  if (!("serviceWorker" in navigator)) {
+    return null;
+  }
+  return navigator.serviceWorker.register("/static/sw.js", { scope: "/" });
+}
+
+// base64ToBytes decodes unpadded base64url string,
+// used for VAPID keys.
+const base64ToBytes = (value) => {
+  const padded = value + "=".repeat((4 - value.length % 4) % 4);
+  const raw = atob(padded.replace(/-/g, "+").replace(/_/g, "/"));
+  return Uint8Array.from(raw, (c) => c.charCodeAt(0));
+};
+
+// pushSupported returns true if browser can receive
+// push notifications.
+const pushSupported = () =>
+  "serviceWorker" in navigator && "PushManager" in window &&
+  "Notification" in window;
+
+// pushSubscription asks user for permission to display
+// notifications and returns push subscription of this
+// browser encoded as JSON.
+async function pushSubscription(vapidKey) {
+  if (!pushSupported()) {
+    throw new Error("This browser doesn't support push notifications.");
+  }
+
+  const permission = await Notification.requestPermission();
+  if (permission !== "granted") {
+    throw new Error("Permission for notifications was not granted.");
+  }
+
+  await registration();
+  const reg = await navigator.serviceWorker.ready;
+
+  let subscription = await reg.pushManager.getSubscription();
+  if (!subscription) {
+    subscription = await reg.pushManager.subscribe({
+      userVisibleOnly: true,
+      applicationServerKey: base64ToBytes(vapidKey),
+    });
+  }
+
+  return JSON.stringify(subscription.toJSON());
+}
+
+registration().catch((err) => {
+  console.log("Failed to register service worker:", err);
+});
+
+export { pushSubscription, pushSupported };
@@ -5,8 +5,36 @@ const registerData = valoo({
   login: "",
   password: "",
   confirmPassword: "",
+  invite: "",
 });
 
+const MODE_INFO = {
+  closed: "Registration is closed. Ask admins of the hackerspace for account.",
+  invite: "Registration requires invite code from one of members.",
+  approval: "New accounts have to be approved by admin before first login.",
+};
+
+const info = (msg) => {
+  document.getElementById("registration-info").innerText = msg;
+};
+
+fetch("/api/v1/registration")
+  .then((response) => response.json())
+  .then(({ mode }) => {
+    info(MODE_INFO[mode] || "");
+    document.getElementById("register-form").hidden = mode === "closed";
+    document.getElementById("invite-field").hidden = mode !== "invite";
+    document.getElementById("invite").required = mode === "invite";
+  })
+  .catch(() => {});
+
+// Invite code can be shared as link to the register page.
+const sharedInvite = new URLSearchParams(window.location.search).get("invite");
+if (sharedInvite) {
+  document.getElementById("invite").value = sharedInvite;
+  registerData({ ...registerData(), invite: sharedInvite });
+}
+
 errorState((msg) => {
   const elements = document.querySelectorAll(".err-msg");
   Array.prototype.forEach.call(elements, (el, i) => {
@@ -41,6 +69,7 @@ const submitData = (store) => {
   let data = {
     nickname: store.login,
     password: store.password,
+    invite: store.invite,
   };
 
   fetch("/api/v1/users", {
@@ -66,11 +95,31 @@ const submitData = (store) => {
         case 401:
           err(ERROR_MSGS[response.status]);
           break;
+        case 403:
+          response.json()
+            .then((data) => {
+              err(data.error.message);
+            })
+            .catch(() => {
+              err(ERROR_MSGS.default);
+            });
+          break;
         case 409:
           err(ERROR_MSGS[response.status]);
           break;
         case 200:
-          window.location.href = "/";
+          response.json()
+            .then(({ pending }) => {
+              if (!pending) {
+                window.location.href = "/";
+                return;
+              }
+              document.getElementById("register-form").hidden = true;
+              info("Account has been created. You can log in after approval.");
+            })
+            .catch(() => {
+              window.location.href = "/";
+            });
           break;
         default:
           err(ERROR_MSGS.default);
@@ -103,6 +152,13 @@ document.getElementById("r-password").addEventListener("input", (e) => {
   });
 });
 
+document.getElementById("invite").addEventListener("input", (e) => {
+  registerData({
+    ...registerData(),
+    invite: e.currentTarget.value,
+  });
+});
+
 document.getElementById("register-form").addEventListener("submit", (e) => {
   e.preventDefault();
   submitData(registerData());
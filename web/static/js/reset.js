@@ -0,0 +1,77 @@
+import { main } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+// mountRequest renders form for requesting reset link
+// sent to verified email address.
+const mountRequest = (info) => {
+  const form = document.getElementById("request-form");
+  const errContainer = document.getElementById("request-err");
+  const email = document.getElementById("email");
+
+  info.textContent = "Ask admins or keyholders for reset link or get it " +
+    "by email, if your account has verified email address.";
+  form.hidden = false;
+
+  form.addEventListener("submit", async (e) => {
+    e.preventDefault();
+    errContainer.textContent = "";
+
+    let [_, err] = await api.requestReset(email.value);
+    if (err) {
+      errContainer.textContent = err.message;
+      return;
+    }
+
+    form.hidden = true;
+    info.textContent = "If the address belongs to any account, " +
+      "reset link has been sent to it.";
+  });
+};
+
+main(async () => {
+  const info = document.getElementById("reset-info");
+  const form = document.getElementById("reset-form");
+  const errContainer = document.getElementById("reset-err");
+  const password = document.getElementById("password");
+  const repeatPassword = document.getElementById("r-password");
+
+  // Token is kept in fragment of the link, so it is never
+  // sent to the server with the page request.
+  const token = window.location.hash.slice(1);
+  if (!token) {
+    mountRequest(info);
+    return;
+  }
+
+  let [res, err] = await api.lookupReset(token);
+  if (err) {
+    info.textContent = err.message;
+    return;
+  }
+  info.textContent = `Set new password for ${res.nickname}.`;
+  form.hidden = false;
+
+  const checkRepeat = () => {
+    repeatPassword.setCustomValidity(
+      password.value === repeatPassword.value ? "" : "Passwords don't match.",
+    );
+  };
+  password.addEventListener("input", checkRepeat);
+  repeatPassword.addEventListener("input", checkRepeat);
+
+  form.addEventListener("submit", async (e) => {
+    e.preventDefault();
+    errContainer.textContent = "";
+
+    let [_, err] = await api.consumeReset(token, password.value);
+    if (err) {
+      errContainer.textContent = err.message;
+      return;
+    }
+
+    // Drop token from address bar and history.
+    window.history.replaceState(null, "", "/reset");
+    form.hidden = true;
+    info.textContent = "Password has been changed. You can log in now.";
+  });
+});
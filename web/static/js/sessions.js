@@ -0,0 +1,75 @@
+import { el, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+const dateTime = (value) => new Date(value).toLocaleString();
+
+// Returns single session component.
+const Session = (userID, session, { refresh, errContainer }) =>
+  el(
+    "li",
+    null,
+    el("b", null, session.userAgent || "unknown device"),
+    ` from ${session.ip || "unknown address"}, `,
+    `last used ${dateTime(session.lastUsed)} `,
+    session.current ? el("i", null, "this session") : el("a", {
+      "class": "rm",
+      onClick: async () => {
+        let [_, err] = await api.removeSession(userID, session.id);
+        if (err) {
+          errContainer.textContent = err.message;
+          return;
+        }
+        refresh();
+      },
+    }, "revoke"),
+  );
+
+const LogoutEverywhere = (userID, { errContainer }) =>
+  el("button", {
+    onClick: async () => {
+      if (!window.confirm("Log out on every device, including this one?")) {
+        return;
+      }
+      let [_, err] = await api.removeSessions(userID);
+      if (err) {
+        errContainer.textContent = err.message;
+        return;
+      }
+      window.location.assign("/logout");
+    },
+  }, "Log out everywhere");
+
+// mount renders active sessions of current user with button
+// for revoking all of them in given target node.
+async function mount({ target }) {
+  let [user, errWho] = await api.who();
+  if (errWho) {
+    return;
+  }
+
+  const errContainer = el("strong", null, "");
+  const list = el("section", null, "");
+  const ctx = { errContainer };
+
+  ctx.refresh = async () => {
+    let [sessions, err] = await api.userSessions(user.id);
+    if (err) {
+      errContainer.textContent = err.message;
+      return;
+    }
+    render(
+      list,
+      el("ul", null, ...sessions.map((s) => Session(user.id, s, ctx))),
+    );
+  };
+
+  render(target, [
+    el("p", null, errContainer),
+    list,
+    LogoutEverywhere(user.id, ctx),
+  ]);
+
+  ctx.refresh();
+}
+
+export { mount };
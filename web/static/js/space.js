@@ -0,0 +1,56 @@
+import { el, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+const stateNames = {
+  "open": "Open",
+  "members-only": "Members only",
+  "closed": "Closed",
+};
+
+// Returns button, that calls given action and reports
+// its error in given container.
+const Button = (text, action, { errContainer, onChange }) =>
+  el("button", {
+    type: "button",
+    onClick: async () => {
+      errContainer.textContent = "";
+      let err = await action();
+      if (err) {
+        errContainer.textContent = err.message;
+        return;
+      }
+      onChange();
+    },
+  }, text);
+
+// Roles with permission to change state of the hackerspace.
+const keyholderRoles = ["keyholder", "admin"];
+
+// mount renders controls for overriding state of the
+// hackerspace in given target node, if current user is
+// allowed to change it. Given onChange function is called
+// after every successful change.
+async function mount({ target, onChange }) {
+  let [user, err] = await api.who();
+  if (err || !keyholderRoles.includes(user.role)) {
+    return;
+  }
+
+  const errContainer = el("strong", null, "");
+  const ctx = { errContainer, onChange };
+
+  render(target, [
+    el("h3", null, "Set state of the hackerspace"),
+    el(
+      "p",
+      null,
+      ...Object.entries(stateNames).map(([state, text]) =>
+        Button(text, () => api.updateSpace(state), ctx)
+      ),
+      Button("Automatic", () => api.resetSpace(), ctx),
+    ),
+    el("p", null, errContainer),
+  ]);
+}
+
+export { mount };
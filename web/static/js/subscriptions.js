@@ -0,0 +1,295 @@
+import { el, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+import * as pwa from "/static/js/pwa.js";
+
+const eventNames = {
+  arrival: "Followed user arrives",
+  open: "Hackerspace opens",
+  close: "Hackerspace closes",
+};
+
+const addressHints = {
+  ntfy: "Topic URL on server allowed by admins, e.g. https://ntfy.sh/my-topic",
+};
+
+const channelNames = {
+  webpush: "push notification in this browser",
+};
+
+const Select = ({ label, name, options, onChange }) => {
+  const select = el(
+    "select",
+    { name: name, id: name, onChange: onChange },
+    ...options.map(([value, text]) => el("option", { value: value }, text)),
+  );
+
+  return el(
+    "p",
+    null,
+    el("label", { "for": name }, label),
+    el("br", null, null),
+    select,
+  );
+};
+
+// Returns checkbox for opting out of being followed.
+const Followable = (userID, followable, { errContainer }) => {
+  const checkbox = el("input", { type: "checkbox" });
+  checkbox.checked = followable;
+
+  checkbox.onclick = async () => {
+    let err = await api.updateUser(userID, { followable: checkbox.checked });
+    if (err) {
+      errContainer.textContent = err.message;
+      checkbox.checked = !checkbox.checked;
+    }
+  };
+
+  return el(
+    "p",
+    null,
+    el("label", null, checkbox, " Let others follow my arrivals"),
+  );
+};
+
+// Returns single subscription component.
+const Subscription = ({ description, onRemove }) =>
+  el(
+    "li",
+    {},
+    el("span", {}, el("b", {}, description)),
+    el(
+      "span",
+      {},
+      el("a", {
+        onClick: onRemove,
+        "class": "rm",
+      }, "remove"),
+    ),
+  );
+
+// webPushDescription returns host of push service, which
+// is the only human readable part of push subscription.
+const webPushDescription = (address) => {
+  try {
+    const host = new URL(JSON.parse(address).endpoint).host;
+    return `push notification (${host})`;
+  } catch (_) {
+    return "push notification";
+  }
+};
+
+const describe = (subscription, users) => {
+  let what = eventNames[subscription.event];
+  if (subscription.event === "arrival") {
+    let target = users.find((u) => u.id === subscription.targetId);
+    what = `${target ? target.nickname : "Unavailable user"} arrives`;
+  }
+  if (subscription.channel === "webpush") {
+    return `${what}: ${webPushDescription(subscription.address)}`;
+  }
+  let address = subscription.channel === "email"
+    ? "verified email of your account"
+    : subscription.address;
+  return `${what}: ${subscription.channel} (${address})`;
+};
+
+const Subscriptions = (userID, subscriptions, users, ctx) =>
+  el(
+    "ul",
+    null,
+    ...subscriptions.map((subscription) =>
+      Subscription({
+        description: describe(subscription, users),
+        onRemove: async () => {
+          let err = await api.removeSubscription(userID, subscription.id);
+          if (err) {
+            ctx.errContainer.textContent = err.message;
+            return;
+          }
+          ctx.refresh();
+        },
+      })
+    ),
+  );
+
+const SubscriptionForm = (userID, options, { refresh, errContainer }) => {
+  // Browser has to support push notifications to subscribe
+  // to them.
+  const channels = options.channels.filter((channel) =>
+    channel !== "webpush" || (pwa.pushSupported() && options.vapidKey)
+  );
+
+  if (channels.length === 0) {
+    return el(
+      "p",
+      null,
+      "There are no notification channels configured on this server.",
+    );
+  }
+
+  let state = {
+    event: options.events[0],
+    targetId: options.users.length > 0 ? options.users[0].id : "",
+    channel: channels[0],
+    address: "",
+  };
+
+  const clear = () => errContainer.textContent = "";
+
+  const targetSelect = Select({
+    label: "User",
+    name: "subscription-target",
+    options: options.users.map((u) => [u.id, u.nickname]),
+    onChange: (e) => {
+      clear();
+      state.targetId = e.currentTarget.value;
+    },
+  });
+
+  const showTarget = () => {
+    targetSelect.style.display = state.event === "arrival" ? "" : "none";
+  };
+  showTarget();
+
+  const addressInput = el("input", {
+    type: "text",
+    name: "subscription-address",
+    id: "subscription-address",
+    onInput: (e) => {
+      clear();
+      state.address = e.currentTarget.value;
+    },
+  });
+  const addressField = el(
+    "p",
+    null,
+    el("label", { "for": "subscription-address" }, ""),
+    el("br", null, null),
+    addressInput,
+  );
+
+  // Push subscription of the browser is used as address
+  // of web push channel and emails are sent to verified
+  // email of the account.
+  const showAddress = () => {
+    const hidden = state.channel === "webpush" || state.channel === "email";
+    addressField.style.display = hidden ? "none" : "";
+    addressInput.required = !hidden;
+    addressField.querySelector("label").textContent =
+      addressHints[state.channel] || "Address";
+  };
+  showAddress();
+
+  return el(
+    "form",
+    {
+      onSubmit: async (e) => {
+        e.preventDefault();
+
+        let subscription = { ...state };
+        if (state.channel === "email") {
+          subscription.address = "";
+        }
+        if (state.channel === "webpush") {
+          try {
+            subscription.address = await pwa.pushSubscription(
+              options.vapidKey,
+            );
+          } catch (err) {
+            errContainer.textContent = err.message;
+            return;
+          }
+        }
+
+        let err = await api.newSubscription(userID, subscription);
+        if (err) {
+          errContainer.textContent = err.message;
+          return;
+        }
+
+        addressInput.value = "";
+        state.address = "";
+        refresh();
+      },
+    },
+    Select({
+      label: "Notify me when",
+      name: "subscription-event",
+      options: options.events.map((event) => [event, eventNames[event]]),
+      onChange: (e) => {
+        clear();
+        state.event = e.currentTarget.value;
+        showTarget();
+      },
+    }),
+    targetSelect,
+    Select({
+      label: "Channel",
+      name: "subscription-channel",
+      options: channels.map((channel) => [
+        channel,
+        channelNames[channel] || channel,
+      ]),
+      onChange: (e) => {
+        clear();
+        state.channel = e.currentTarget.value;
+        showAddress();
+      },
+    }),
+    addressField,
+    el("button", { type: "submit" }, "Subscribe"),
+  );
+};
+
+// mount renders subscriptions of current user with form for
+// subscribing to new notifications in given target node.
+async function mount({ target }) {
+  let [user, errWho] = await api.who();
+  if (errWho) {
+    return;
+  }
+
+  const errContainer = el("strong", null, "");
+
+  let [data, errUser] = await api.readUser(user.id);
+  if (errUser) {
+    render(target, el("p", null, el("strong", null, errUser.message)));
+    return;
+  }
+
+  let [options, errOptions] = await api.subscriptionOptions(user.id);
+  if (errOptions) {
+    render(target, el("p", null, el("strong", null, errOptions.message)));
+    return;
+  }
+
+  const list = el("section", null, "");
+
+  const refresh = async () => {
+    let [subscriptions, err] = await api.userSubscriptions(user.id);
+    if (err) {
+      errContainer.textContent = err.message;
+      return;
+    }
+    render(
+      list,
+      Subscriptions(user.id, subscriptions, options.users, {
+        refresh,
+        errContainer,
+      }),
+    );
+  };
+
+  render(target, [
+    Followable(user.id, data.followable, { errContainer }),
+    el("p", null, errContainer),
+    list,
+    el("h3", null, "Add new subscription"),
+    SubscriptionForm(user.id, options, { refresh, errContainer }),
+  ]);
+
+  refresh();
+}
+
+export { mount };
@@ -0,0 +1,173 @@
+import { el, render } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+const scopes = [
+  ["read-status", "read your profile and statistics"],
+  ["manage-devices", "manage your devices"],
+  ["check-in", "check in with kiosk codes"],
+  ["change-space", "change state of the hackerspace"],
+];
+
+const lifetimes = [
+  ["30 days", 30],
+  ["90 days", 90],
+  ["1 year", 365],
+  ["never expires", 0],
+];
+
+const day = 24 * 60 * 60 * 1000;
+
+const date = (value) => new Date(value).toLocaleDateString();
+
+// Returns single token component.
+const Token = (userID, token, { refresh, errContainer }) =>
+  el(
+    "li",
+    null,
+    el("b", null, token.name),
+    ` (${token.scopes.join(", ")}) `,
+    token.expires ? `expires ${date(token.expires)}, ` : "",
+    token.lastUsed ? `last used ${date(token.lastUsed)} ` : "never used ",
+    el("a", {
+      "class": "rm",
+      onClick: async () => {
+        if (!window.confirm(`Revoke token ${token.name}?`)) {
+          return;
+        }
+        let [_, err] = await api.removeToken(userID, token.id);
+        if (err) {
+          errContainer.textContent = err.message;
+          return;
+        }
+        refresh();
+      },
+    }, "revoke"),
+  );
+
+const TokenForm = (userID, { refresh, errContainer, created }) => {
+  let state = { name: "", scopes: new Set(), days: lifetimes[0][1] };
+
+  const checkboxes = scopes.map(([scope, label]) => {
+    const checkbox = el("input", {
+      type: "checkbox",
+      onChange: (e) => {
+        if (e.currentTarget.checked) {
+          state.scopes.add(scope);
+        } else {
+          state.scopes.delete(scope);
+        }
+      },
+    });
+    return el("p", null, el("label", null, checkbox, ` ${scope}: ${label}`));
+  });
+
+  return el(
+    "form",
+    {
+      onSubmit: async (e) => {
+        e.preventDefault();
+        errContainer.textContent = "";
+
+        const expires = state.days
+          ? new Date(Date.now() + state.days * day).toISOString()
+          : null;
+        let [res, err] = await api.newToken(userID, {
+          name: state.name,
+          scopes: [...state.scopes],
+          expires: expires,
+        });
+        if (err) {
+          errContainer.textContent = err.message;
+          return;
+        }
+
+        e.target.reset();
+        state = { name: "", scopes: new Set(), days: lifetimes[0][1] };
+        render(created, [
+          "Copy your new token now, it won't be shown again: ",
+          el("code", null, res.token),
+        ]);
+        refresh();
+      },
+    },
+    el(
+      "p",
+      null,
+      el("label", { "for": "token-name" }, "Name"),
+      el("br", null, null),
+      el("input", {
+        type: "text",
+        name: "token-name",
+        id: "token-name",
+        maxlength: "64",
+        required: "",
+        onInput: (e) => state.name = e.currentTarget.value,
+      }),
+    ),
+    ...checkboxes,
+    el(
+      "p",
+      null,
+      el("label", { "for": "token-expires" }, "Expiration"),
+      el("br", null, null),
+      el(
+        "select",
+        {
+          name: "token-expires",
+          id: "token-expires",
+          onChange: (e) => state.days = Number(e.currentTarget.value),
+        },
+        ...lifetimes.map(([label, days]) =>
+          el("option", { value: days }, label)
+        ),
+      ),
+    ),
+    el("button", { type: "submit" }, "Create token"),
+  );
+};
+
+// mount renders personal API tokens of current user with
+// form for creating new tokens in given target node.
+async function mount({ target }) {
+  let [user, errWho] = await api.who();
+  if (errWho) {
+    return;
+  }
+
+  const errContainer = el("strong", null, "");
+  const created = el("p", null, "");
+  const list = el("section", null, "");
+  const ctx = { errContainer, created };
+
+  ctx.refresh = async () => {
+    let [tokens, err] = await api.userTokens(user.id);
+    if (err) {
+      errContainer.textContent = err.message;
+      return;
+    }
+    render(
+      list,
+      tokens.length === 0
+        ? el("p", null, "You don't have any tokens.")
+        : el("ul", null, ...tokens.map((t) => Token(user.id, t, ctx))),
+    );
+  };
+
+  render(target, [
+    el(
+      "p",
+      null,
+      "Tokens stay valid after logging out everywhere, but they are ",
+      "removed when your password changes.",
+    ),
+    el("p", null, errContainer),
+    created,
+    list,
+    el("h3", null, "Create new token"),
+    TokenForm(user.id, ctx),
+  ]);
+
+  ctx.refresh();
+}
+
+export { mount };
@@ -0,0 +1,17 @@
+import { main } from "/static/js/utils.js";
+import * as api from "/static/js/api.js";
+
+main(async () => {
+  const info = document.getElementById("verify-info");
+
+  // Token is kept in fragment of the link, so it is never
+  // sent to the server with the page request.
+  let [_, err] = await api.verifyEmail(window.location.hash.slice(1));
+  if (err) {
+    info.textContent = err.message;
+    return;
+  }
+
+  window.history.replaceState(null, "", "/verify");
+  info.textContent = "Your email address has been verified.";
+});
@@ -0,0 +1,102 @@
+// Service worker of long-season web UI. It keeps copies of
+// static files and home page for offline use and displays
+// push notifications sent by the server.
+
+const CACHE = "long-season-v1";
+
+const PRECACHED = [
+  "/",
+  "/static/neat.css",
+  "/static/custom.css",
+  "/static/js/utils.js",
+  "/static/js/navbar.js",
+  "/static/js/home.js",
+  "/static/js/space.js",
+  "/static/js/api.js",
+  "/static/js/pwa.js",
+  "/static/icons/icon-192.png",
+];
+
+// cacheable returns true for responses, that can be
+// served when the server is unreachable.
+const cacheable = (url) =>
+  url.origin === self.location.origin &&
+  (url.pathname === "/" || url.pathname.startsWith("/static/"));
+
+self.addEventListener("install", (event) => {
+  event.waitUntil(
+    caches.open(CACHE)
+      .then((cache) => cache.addAll(PRECACHED))
+      .then(() => self.skipWaiting()),
+  );
+});
+
+self.addEventListener("activate", (event) => {
+  event.waitUntil(
+    caches.keys()
+      .then((keys) =>
+        Promise.all(
+          keys.filter((key) => key !== CACHE).map((key) => caches.delete(key)),
+        )
+      )
+      .then(() => self.clients.claim()),
+  );
+});
+
+// Network is always tried first, so status is never stale
+// when the server is reachable.
+self.addEventListener("fetch", (event) => {
+  const url = new URL(event.request.url);
+  if (event.request.method !== "GET" || !cacheable(url)) {
+    return;
+  }
+
+  event.respondWith(
+    fetch(event.request)
+      .then((response) => {
+        if (response.ok) {
+          const copy = response.clone();
+          caches.open(CACHE).then((cache) => cache.put(event.request, copy));
+        }
+        return response;
+      })
+      .catch(() =>
+        caches.match(event.request).then((cached) =>
+          cached || Promise.reject(new Error("offline"))
+        )
+      ),
+  );
+});
+
+self.addEventListener("push", (event) => {
+  let data = { title: "long-season", body: "" };
+  if (event.data) {
+    try {
+      data = event.data.json();
+    } catch (_) {
+      data.body = event.data.text();
+    }
+  }
+
+  event.waitUntil(
+    self.registration.showNotification(data.title, {
+      body: data.body,
+      icon: "/static/icons/icon-192.png",
+    }),
+  );
+});
+
+self.addEventListener("notificationclick", (event) => {
+  event.notification.close();
+
+  event.waitUntil(
+    self.clients.matchAll({ type: "window" }).then((windows) => {
+      for (const client of windows) {
+        if ("focus" in client) {
+          return client.focus();
+        }
+      }
+      return self.clients.openWindow("/");
+    }),
+  );
+});